@@ -0,0 +1,393 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Canonical beverage attributes
+const (
+	AttributeABV      = "abv"
+	AttributeIBU      = "ibu"
+	AttributeStyle    = "style"
+	AttributeVarietal = "varietal"
+	AttributeRegion   = "region"
+)
+
+var attributeNames = []string{AttributeABV, AttributeIBU, AttributeStyle, AttributeVarietal, AttributeRegion}
+
+var (
+	ErrUnknownAttribute = errors.New("unknown beverage attribute")
+	ErrInvalidAttribute = errors.New("invalid beverage attribute value")
+)
+
+const (
+	// Values typed into a post's detail form count for more than values
+	// OpenAI pulled out of the tasting notes
+	postObservationWeight = 1.0
+	aiObservationWeight   = 0.6
+
+	// Agreeing observations needed before consensus is fully trusted
+	consensusFullSupport = 3
+
+	attributeRefreshBatch = 100
+)
+
+// BeverageAttribute is a canonical attribute value with its provenance
+type BeverageAttribute struct {
+	Attribute   string    `json:"attribute"`
+	Value       string    `json:"value"`
+	Source      string    `json:"source"`
+	Confidence  float64   `json:"confidence"`
+	SampleCount int       `json:"sample_count"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AttributeService derives canonical beverage attributes from post details
+// and extracted tags, and lets curators override them. Curator values are
+// never replaced by consensus.
+type AttributeService struct {
+	DB CurationDB
+	Q  *sqlc.Queries
+}
+
+func NewAttributeService(db CurationDB) *AttributeService {
+	return &AttributeService{DB: db, Q: sqlc.New(db)}
+}
+
+// Get returns every attribute known for a beverage
+func (s *AttributeService) Get(ctx context.Context, beverageID pgtype.UUID) ([]BeverageAttribute, error) {
+	rows, err := s.Q.GetBeverageAttributes(ctx, beverageID)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make([]BeverageAttribute, 0, len(rows))
+	for _, row := range rows {
+		attrs = append(attrs, attributeFromRow(row))
+	}
+	return attrs, nil
+}
+
+// Recompute rebuilds the consensus attributes for one beverage and copies
+// the winning values onto the beverages row, in one transaction
+func (s *AttributeService) Recompute(ctx context.Context, beverageID pgtype.UUID) error {
+	return s.inTx(ctx, func(q *sqlc.Queries) error {
+		return recomputeAttributes(ctx, q, beverageID)
+	})
+}
+
+func recomputeAttributes(ctx context.Context, q *sqlc.Queries, beverageID pgtype.UUID) error {
+	observations, err := q.ListBeverageAttributeObservations(ctx, beverageID)
+	if err != nil {
+		return err
+	}
+
+	current, err := q.GetBeverageAttributes(ctx, beverageID)
+	if err != nil {
+		return err
+	}
+
+	upserts, deletes := consensusChanges(current, computeConsensus(observations))
+	for _, attr := range deletes {
+		if err := q.DeleteConsensusAttribute(ctx, sqlc.DeleteConsensusAttributeParams{
+			BeverageID: beverageID,
+			Attribute:  attr,
+		}); err != nil {
+			return err
+		}
+	}
+	for _, attr := range attributeNames {
+		c, ok := upserts[attr]
+		if !ok {
+			continue
+		}
+		if err := q.UpsertConsensusAttribute(ctx, sqlc.UpsertConsensusAttributeParams{
+			BeverageID:  beverageID,
+			Attribute:   attr,
+			Value:       c.value,
+			Confidence:  confidenceNumeric(c.confidence),
+			SampleCount: int32(c.samples),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return q.SyncBeverageAttributeColumns(ctx, beverageID)
+}
+
+func (s *AttributeService) inTx(ctx context.Context, fn func(q *sqlc.Queries) error) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.Q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RecomputePending recomputes beverages whose posts or tags changed, or that
+// lost a post, since their attributes were last computed. Returns how many
+// were processed.
+func (s *AttributeService) RecomputePending(ctx context.Context, limit int32) (int, error) {
+	ids, err := s.Q.ListBeveragesNeedingAttributes(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, id := range ids {
+		if err := s.Recompute(ctx, id); err != nil {
+			log.Printf("Failed to recompute attributes for beverage %s: %v", uuidString(id), err)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// Run recomputes one batch of pending beverages; the job runner schedules it
+func (s *AttributeService) Run(ctx context.Context) error {
+	n, err := s.RecomputePending(ctx, attributeRefreshBatch)
+	if err != nil {
+		return err
+	}
+	log.Printf("Beverage attribute refresh updated %d beverages", n)
+	return nil
+}
+
+// SetOverride pins an attribute to a curator-supplied value
+func (s *AttributeService) SetOverride(ctx context.Context, beverageID pgtype.UUID, attribute, value string, curatorID pgtype.UUID) (*BeverageAttribute, error) {
+	normalized, _, err := normalizeAttributeValue(attribute, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var row sqlc.BeverageAttribute
+	err = s.inTx(ctx, func(q *sqlc.Queries) error {
+		row, err = q.SetCuratorAttribute(ctx, sqlc.SetCuratorAttributeParams{
+			BeverageID: beverageID,
+			Attribute:  attribute,
+			Value:      normalized,
+			UpdatedBy:  curatorID,
+		})
+		if err != nil {
+			return err
+		}
+		return q.SyncBeverageAttributeColumns(ctx, beverageID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attr := attributeFromRow(row)
+	return &attr, nil
+}
+
+// ClearOverride removes a curator value and falls back to consensus
+func (s *AttributeService) ClearOverride(ctx context.Context, beverageID pgtype.UUID, attribute string) error {
+	if !isKnownAttribute(attribute) {
+		return ErrUnknownAttribute
+	}
+
+	return s.inTx(ctx, func(q *sqlc.Queries) error {
+		if err := q.DeleteCuratorAttribute(ctx, sqlc.DeleteCuratorAttributeParams{
+			BeverageID: beverageID,
+			Attribute:  attribute,
+		}); err != nil {
+			return err
+		}
+		return recomputeAttributes(ctx, q, beverageID)
+	})
+}
+
+// consensusValue is the winning value for one attribute
+type consensusValue struct {
+	value      string
+	confidence float64
+	samples    int
+}
+
+type valueVotes struct {
+	spellings map[string]int
+	weight    float64
+	count     int
+}
+
+// computeConsensus picks the most heavily weighted value per attribute.
+// Confidence is the winner's share of the total weight, scaled down until
+// enough observations agree.
+func computeConsensus(observations []sqlc.ListBeverageAttributeObservationsRow) map[string]consensusValue {
+	votes := make(map[string]map[string]*valueVotes)
+	totals := make(map[string]float64)
+	samples := make(map[string]int)
+
+	for _, obs := range observations {
+		value, key, err := normalizeAttributeValue(obs.Attribute, obs.Value)
+		if err != nil {
+			continue
+		}
+
+		weight := obs.Weight * postObservationWeight
+		if obs.Source == "ai" {
+			weight = obs.Weight * aiObservationWeight
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		byKey, ok := votes[obs.Attribute]
+		if !ok {
+			byKey = make(map[string]*valueVotes)
+			votes[obs.Attribute] = byKey
+		}
+		v, ok := byKey[key]
+		if !ok {
+			v = &valueVotes{spellings: make(map[string]int)}
+			byKey[key] = v
+		}
+		v.spellings[value]++
+		v.weight += weight
+		v.count++
+
+		totals[obs.Attribute] += weight
+		samples[obs.Attribute]++
+	}
+
+	results := make(map[string]consensusValue, len(votes))
+	for attr, byKey := range votes {
+		keys := make([]string, 0, len(byKey))
+		for k := range byKey {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := byKey[keys[i]], byKey[keys[j]]
+			if a.weight != b.weight {
+				return a.weight > b.weight
+			}
+			if a.count != b.count {
+				return a.count > b.count
+			}
+			return keys[i] < keys[j]
+		})
+
+		winner := byKey[keys[0]]
+		support := math.Min(1.0, float64(winner.count)/consensusFullSupport)
+		results[attr] = consensusValue{
+			value:      mostCommonSpelling(winner.spellings),
+			confidence: winner.weight / totals[attr] * support,
+			samples:    samples[attr],
+		}
+	}
+
+	return results
+}
+
+// consensusChanges decides what a recompute writes: consensus values to
+// store, and stored consensus rows that no longer have any support.
+// Attributes a curator has set are left alone either way.
+func consensusChanges(current []sqlc.BeverageAttribute, results map[string]consensusValue) (map[string]consensusValue, []string) {
+	source := make(map[string]string, len(current))
+	for _, row := range current {
+		source[row.Attribute] = row.Source
+	}
+
+	upserts := make(map[string]consensusValue, len(results))
+	var deletes []string
+	for _, attr := range attributeNames {
+		if source[attr] == "curator" {
+			continue
+		}
+		if c, ok := results[attr]; ok {
+			upserts[attr] = c
+		} else if source[attr] == "consensus" {
+			deletes = append(deletes, attr)
+		}
+	}
+	return upserts, deletes
+}
+
+// normalizeAttributeValue validates a value and returns its display form and
+// comparison key. Numeric attributes are rounded so 5 and 5.0 agree.
+func normalizeAttributeValue(attribute, raw string) (string, string, error) {
+	raw = strings.Join(strings.Fields(raw), " ")
+
+	switch attribute {
+	case AttributeABV:
+		abv, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+		if err != nil || abv <= 0 || abv > 80 {
+			return "", "", fmt.Errorf("%w: abv %q", ErrInvalidAttribute, raw)
+		}
+		v := strconv.FormatFloat(math.Round(abv*10)/10, 'f', 1, 64)
+		return v, v, nil
+
+	case AttributeIBU:
+		ibu, err := strconv.ParseFloat(raw, 64)
+		if err != nil || ibu < 0 || ibu > 200 {
+			return "", "", fmt.Errorf("%w: ibu %q", ErrInvalidAttribute, raw)
+		}
+		v := strconv.Itoa(int(math.Round(ibu)))
+		return v, v, nil
+
+	case AttributeStyle, AttributeVarietal, AttributeRegion:
+		if raw == "" || strings.EqualFold(raw, "null") {
+			return "", "", fmt.Errorf("%w: empty %s", ErrInvalidAttribute, attribute)
+		}
+		return raw, strings.ToLower(raw), nil
+	}
+
+	return "", "", ErrUnknownAttribute
+}
+
+func isKnownAttribute(attribute string) bool {
+	for _, a := range attributeNames {
+		if a == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+// mostCommonSpelling prefers the most frequent spelling, then the alphabetically first
+func mostCommonSpelling(spellings map[string]int) string {
+	best := ""
+	for s, n := range spellings {
+		if best == "" || n > spellings[best] || (n == spellings[best] && s < best) {
+			best = s
+		}
+	}
+	return best
+}
+
+func attributeFromRow(row sqlc.BeverageAttribute) BeverageAttribute {
+	confidence, _ := row.Confidence.Float64Value()
+	return BeverageAttribute{
+		Attribute:   row.Attribute,
+		Value:       row.Value,
+		Source:      row.Source,
+		Confidence:  confidence.Float64,
+		SampleCount: int(row.SampleCount),
+		UpdatedBy:   uuidString(row.UpdatedBy),
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+}
+
+func confidenceNumeric(c float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	_ = n.Scan(strconv.FormatFloat(math.Max(0, math.Min(1, c)), 'f', 2, 64))
+	return n
+}
@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+)
+
+func observation(attribute, value, source string, weight float64) sqlc.ListBeverageAttributeObservationsRow {
+	return sqlc.ListBeverageAttributeObservationsRow{Attribute: attribute, Value: value, Source: source, Weight: weight}
+}
+
+func TestComputeConsensusWeighting(t *testing.T) {
+	cases := []struct {
+		name         string
+		observations []sqlc.ListBeverageAttributeObservationsRow
+		attribute    string
+		want         consensusValue
+	}{
+		{
+			name: "a typed value outweighs two extracted ones",
+			observations: []sqlc.ListBeverageAttributeObservationsRow{
+				observation("style", "IPA", "post", 1.0),
+				observation("style", "Pale Ale", "ai", 0.8),
+				observation("style", "Pale Ale", "ai", 0.8),
+			},
+			attribute: "style",
+			// 1.0 against 2 * 0.8 * 0.6, with one of three agreeing observations
+			want: consensusValue{value: "IPA", confidence: 1.0 / 1.96 / 3, samples: 3},
+		},
+		{
+			name: "extracted values win on numbers and confidence",
+			observations: []sqlc.ListBeverageAttributeObservationsRow{
+				observation("style", "IPA", "post", 1.0),
+				observation("style", "Pale Ale", "ai", 1.0),
+				observation("style", "Pale Ale", "ai", 1.0),
+			},
+			attribute: "style",
+			want:      consensusValue{value: "Pale Ale", confidence: 1.2 / 2.2 * 2 / 3, samples: 3},
+		},
+		{
+			name: "spellings and numeric forms agree",
+			observations: []sqlc.ListBeverageAttributeObservationsRow{
+				observation("abv", "6.5", "post", 1.0),
+				observation("abv", "6.50%", "post", 1.0),
+				observation("abv", "6.5", "post", 1.0),
+			},
+			attribute: "abv",
+			want:      consensusValue{value: "6.5", confidence: 1, samples: 3},
+		},
+		{
+			name: "case-insensitive text keeps the most common spelling",
+			observations: []sqlc.ListBeverageAttributeObservationsRow{
+				observation("region", "napa valley", "post", 1.0),
+				observation("region", "Napa Valley", "post", 1.0),
+				observation("region", "Napa Valley", "post", 1.0),
+			},
+			attribute: "region",
+			want:      consensusValue{value: "Napa Valley", confidence: 1, samples: 3},
+		},
+		{
+			name: "invalid and zero-weight values don't vote",
+			observations: []sqlc.ListBeverageAttributeObservationsRow{
+				observation("ibu", "45", "post", 1.0),
+				observation("ibu", "900", "post", 1.0),
+				observation("ibu", "lots", "post", 1.0),
+				observation("ibu", "60", "ai", 0),
+			},
+			attribute: "ibu",
+			want:      consensusValue{value: "45", confidence: 1.0 / 3, samples: 1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := computeConsensus(c.observations)[c.attribute]
+			if !ok {
+				t.Fatalf("no consensus for %s", c.attribute)
+			}
+			if got.value != c.want.value || got.samples != c.want.samples || math.Abs(got.confidence-c.want.confidence) > 1e-9 {
+				t.Errorf("consensus = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConsensusChangesKeepCuratorValues(t *testing.T) {
+	current := []sqlc.BeverageAttribute{
+		{Attribute: AttributeStyle, Value: "Hazy IPA", Source: "curator"},
+		{Attribute: AttributeIBU, Value: "40", Source: "curator"},
+		{Attribute: AttributeABV, Value: "6.5", Source: "consensus"},
+		{Attribute: AttributeRegion, Value: "Napa", Source: "consensus"},
+	}
+	results := map[string]consensusValue{
+		AttributeStyle:    {value: "IPA", confidence: 0.9, samples: 5},
+		AttributeABV:      {value: "6.8", confidence: 0.7, samples: 4},
+		AttributeVarietal: {value: "Cabernet", confidence: 0.5, samples: 2},
+	}
+
+	upserts, deletes := consensusChanges(current, results)
+	if _, ok := upserts[AttributeStyle]; ok {
+		t.Error("consensus replaced the curator's style")
+	}
+	if upserts[AttributeABV].value != "6.8" || upserts[AttributeVarietal].value != "Cabernet" || len(upserts) != 2 {
+		t.Errorf("upserts = %+v, want abv and varietal", upserts)
+	}
+	// The curator's ibu has no consensus behind it but stays
+	if want := []string{AttributeRegion}; !reflect.DeepEqual(deletes, want) {
+		t.Errorf("deletes = %v, want %v", deletes, want)
+	}
+
+	// Once the override is cleared, consensus takes the attribute back
+	upserts, _ = consensusChanges(current[2:], results)
+	if upserts[AttributeStyle].value != "IPA" {
+		t.Errorf("upserts = %+v, want consensus style after the override is cleared", upserts)
+	}
+}
@@ -0,0 +1,516 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrReasonRequired  = errors.New("a reason is required for catalog changes")
+	ErrNoChanges       = errors.New("change leaves the beverage as it is")
+	ErrAliasTaken      = errors.New("alias already belongs to a beverage")
+	ErrAliasNotFound   = errors.New("alias not found on beverage")
+	ErrInvalidBeverage = errors.New("invalid beverage details")
+)
+
+// Revision actions
+const (
+	RevisionBaseline    = "baseline"
+	RevisionEdit        = "edit"
+	RevisionImage       = "image"
+	RevisionAliasAdd    = "alias_add"
+	RevisionAliasRemove = "alias_remove"
+	RevisionRevert      = "revert"
+)
+
+var beverageCategories = []string{"wine", "beer", "cocktail", "other"}
+
+// CurationDB is a connection pool that can also start transactions;
+// *pgxpool.Pool satisfies it
+type CurationDB interface {
+	sqlc.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// curationStore is the part of sqlc.Querier a curation change runs against
+type curationStore interface {
+	GetBeverageForUpdate(ctx context.Context, id pgtype.UUID) (sqlc.Beverage, error)
+	UpdateBeverageDetails(ctx context.Context, arg sqlc.UpdateBeverageDetailsParams) (sqlc.Beverage, error)
+	ListBeverageAliases(ctx context.Context, beverageID pgtype.UUID) ([]sqlc.BeverageAlias, error)
+	CreateBeverageAlias(ctx context.Context, arg sqlc.CreateBeverageAliasParams) (sqlc.BeverageAlias, error)
+	DeleteBeverageAlias(ctx context.Context, arg sqlc.DeleteBeverageAliasParams) (int64, error)
+	CountBeverageRevisions(ctx context.Context, beverageID pgtype.UUID) (int64, error)
+	CreateBeverageRevision(ctx context.Context, arg sqlc.CreateBeverageRevisionParams) (sqlc.BeverageRevision, error)
+	GetBeverageRevision(ctx context.Context, arg sqlc.GetBeverageRevisionParams) (sqlc.BeverageRevision, error)
+}
+
+var _ curationStore = (*sqlc.Queries)(nil)
+
+// BeverageSnapshot is the curator-editable state of a beverage
+type BeverageSnapshot struct {
+	Name     string   `json:"name"`
+	Brand    string   `json:"brand"`
+	Category string   `json:"category"`
+	Vintage  string   `json:"vintage"`
+	ImageURL string   `json:"image_url"`
+	Aliases  []string `json:"aliases"`
+}
+
+// FieldChange is a field's value before and after a revision
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// BeverageRevision is one entry in a beverage's edit history
+type BeverageRevision struct {
+	Revision   int                    `json:"revision"`
+	Action     string                 `json:"action"`
+	ChangedBy  string                 `json:"changed_by,omitempty"`
+	Reason     string                 `json:"reason"`
+	Diff       map[string]FieldChange `json:"diff"`
+	Snapshot   BeverageSnapshot       `json:"snapshot"`
+	RevertedTo int                    `json:"reverted_to,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// BeverageEdit backs PATCH /v1/catalog/beverages/:id. Nil fields are left
+// unchanged; empty strings clear optional fields.
+type BeverageEdit struct {
+	Name     *string `json:"name"`
+	Brand    *string `json:"brand"`
+	Category *string `json:"category"`
+	Vintage  *string `json:"vintage"`
+	ImageURL *string `json:"image_url"`
+	Reason   string  `json:"reason"`
+}
+
+// CurationService applies curator changes to catalog beverages. Every change
+// is recorded in beverage_revisions with who made it, why, the diff and the
+// resulting state, so any revision can be restored.
+type CurationService struct {
+	DB CurationDB
+	Q  *sqlc.Queries
+}
+
+func NewCurationService(db CurationDB) *CurationService {
+	return &CurationService{DB: db, Q: sqlc.New(db)}
+}
+
+// History backs GET /v1/catalog/beverages/:id/revisions, newest first
+func (s *CurationService) History(ctx context.Context, beverageID pgtype.UUID, limit, offset int32) ([]BeverageRevision, int64, error) {
+	rows, err := s.Q.ListBeverageRevisions(ctx, sqlc.ListBeverageRevisionsParams{
+		BeverageID: beverageID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.Q.CountBeverageRevisions(ctx, beverageID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	revisions := make([]BeverageRevision, 0, len(rows))
+	for _, row := range rows {
+		rev, err := revisionFromRow(row)
+		if err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, total, nil
+}
+
+// Edit updates name, brand, category, vintage and image
+func (s *CurationService) Edit(ctx context.Context, beverageID pgtype.UUID, edit BeverageEdit, curatorID pgtype.UUID) (*BeverageRevision, error) {
+	return s.change(ctx, beverageID, curatorID, edit.Reason, editAction(edit), 0, applyEdit(edit))
+}
+
+// SetImage backs PUT /v1/catalog/beverages/:id/image. An empty URL clears it.
+func (s *CurationService) SetImage(ctx context.Context, beverageID pgtype.UUID, imageURL, reason string, curatorID pgtype.UUID) (*BeverageRevision, error) {
+	return s.Edit(ctx, beverageID, BeverageEdit{ImageURL: &imageURL, Reason: reason}, curatorID)
+}
+
+// AddAlias backs POST /v1/catalog/beverages/:id/aliases
+func (s *CurationService) AddAlias(ctx context.Context, beverageID pgtype.UUID, alias, reason string, curatorID pgtype.UUID) (*BeverageRevision, error) {
+	return s.change(ctx, beverageID, curatorID, reason, RevisionAliasAdd, 0, addAlias(alias))
+}
+
+// RemoveAlias backs DELETE /v1/catalog/beverages/:id/aliases/:alias
+func (s *CurationService) RemoveAlias(ctx context.Context, beverageID pgtype.UUID, alias, reason string, curatorID pgtype.UUID) (*BeverageRevision, error) {
+	return s.change(ctx, beverageID, curatorID, reason, RevisionAliasRemove, 0, removeAlias(alias))
+}
+
+// Revert backs POST /v1/catalog/beverages/:id/revisions/:revision/revert.
+// The beverage is restored to the state recorded by that revision, and the
+// restore is itself recorded as a new revision.
+func (s *CurationService) Revert(ctx context.Context, beverageID pgtype.UUID, revision int32, reason string, curatorID pgtype.UUID) (*BeverageRevision, error) {
+	return s.change(ctx, beverageID, curatorID, reason, RevisionRevert, revision, revertTo(ctx, beverageID, revision))
+}
+
+// snapshotChange edits next, the beverage's state after the change
+type snapshotChange func(q curationStore, next *BeverageSnapshot) error
+
+func editAction(edit BeverageEdit) string {
+	if edit.ImageURL != nil && edit.Name == nil && edit.Brand == nil && edit.Category == nil && edit.Vintage == nil {
+		return RevisionImage
+	}
+	return RevisionEdit
+}
+
+func applyEdit(edit BeverageEdit) snapshotChange {
+	return func(_ curationStore, next *BeverageSnapshot) error {
+		if edit.Name != nil {
+			next.Name = strings.TrimSpace(*edit.Name)
+		}
+		if edit.Brand != nil {
+			next.Brand = strings.TrimSpace(*edit.Brand)
+		}
+		if edit.Category != nil {
+			next.Category = strings.TrimSpace(*edit.Category)
+		}
+		if edit.Vintage != nil {
+			next.Vintage = strings.TrimSpace(*edit.Vintage)
+		}
+		if edit.ImageURL != nil {
+			next.ImageURL = strings.TrimSpace(*edit.ImageURL)
+		}
+		return nil
+	}
+}
+
+func addAlias(alias string) snapshotChange {
+	alias = strings.TrimSpace(alias)
+	return func(_ curationStore, next *BeverageSnapshot) error {
+		if alias == "" {
+			return ErrInvalidBeverage
+		}
+		if !slices.ContainsFunc(next.Aliases, sameAlias(alias)) {
+			next.Aliases = append(next.Aliases, alias)
+		}
+		return nil
+	}
+}
+
+func removeAlias(alias string) snapshotChange {
+	return func(_ curationStore, next *BeverageSnapshot) error {
+		kept := slices.DeleteFunc(slices.Clone(next.Aliases), sameAlias(alias))
+		if len(kept) == len(next.Aliases) {
+			return ErrAliasNotFound
+		}
+		next.Aliases = kept
+		return nil
+	}
+}
+
+func revertTo(ctx context.Context, beverageID pgtype.UUID, revision int32) snapshotChange {
+	return func(q curationStore, next *BeverageSnapshot) error {
+		target, err := q.GetBeverageRevision(ctx, sqlc.GetBeverageRevisionParams{
+			BeverageID: beverageID,
+			Revision:   revision,
+		})
+		if err != nil {
+			return err
+		}
+
+		var snapshot BeverageSnapshot
+		if err := json.Unmarshal(target.Snapshot, &snapshot); err != nil {
+			return err
+		}
+		*next = snapshot
+		return nil
+	}
+}
+
+// change runs applyChange in one transaction
+func (s *CurationService) change(
+	ctx context.Context,
+	beverageID, curatorID pgtype.UUID,
+	reason, action string,
+	revertedTo int32,
+	mutate snapshotChange,
+) (*BeverageRevision, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	row, err := applyChange(ctx, s.Q.WithTx(tx), beverageID, curatorID, reason, action, revertedTo, mutate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return revisionFromRow(row)
+}
+
+// applyChange locks the beverage, applies mutate to a copy of its current
+// state, writes the difference and records a revision
+func applyChange(
+	ctx context.Context,
+	q curationStore,
+	beverageID, curatorID pgtype.UUID,
+	reason, action string,
+	revertedTo int32,
+	mutate snapshotChange,
+) (sqlc.BeverageRevision, error) {
+	bev, err := q.GetBeverageForUpdate(ctx, beverageID)
+	if err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+	aliases, err := q.ListBeverageAliases(ctx, beverageID)
+	if err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+	current := snapshotOf(bev, aliases)
+
+	// The first curation change also records the state it started from, so
+	// the original can always be restored
+	count, err := q.CountBeverageRevisions(ctx, beverageID)
+	if err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+	if count == 0 {
+		if _, err := createRevision(ctx, q, beverageID, pgtype.UUID{}, RevisionBaseline,
+			"state before first curation change", map[string]FieldChange{}, current, 0); err != nil {
+			return sqlc.BeverageRevision{}, err
+		}
+	}
+
+	next := current
+	next.Aliases = slices.Clone(current.Aliases)
+	if err := mutate(q, &next); err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+	if err := validateSnapshot(next); err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+
+	diff := diffSnapshots(current, next)
+	if len(diff) == 0 {
+		return sqlc.BeverageRevision{}, ErrNoChanges
+	}
+
+	if detailsChanged(diff) {
+		if _, err := q.UpdateBeverageDetails(ctx, sqlc.UpdateBeverageDetailsParams{
+			ID:              beverageID,
+			Name:            next.Name,
+			Brand:           optionalText(next.Brand),
+			Category:        next.Category,
+			Vintage:         optionalText(next.Vintage),
+			ImageUrl:        optionalText(next.ImageURL),
+			NameNormalized:  NormalizeBeverageName(next.Name),
+			BrandNormalized: optionalText(NormalizeBeverageName(next.Brand)),
+		}); err != nil {
+			return sqlc.BeverageRevision{}, err
+		}
+	}
+
+	if _, ok := diff["aliases"]; ok {
+		if err := syncAliases(ctx, q, beverageID, current.Aliases, next.Aliases, curatorID); err != nil {
+			return sqlc.BeverageRevision{}, err
+		}
+	}
+
+	return createRevision(ctx, q, beverageID, curatorID, action, reason, diff, next, revertedTo)
+}
+
+func createRevision(
+	ctx context.Context,
+	q curationStore,
+	beverageID, changedBy pgtype.UUID,
+	action, reason string,
+	diff map[string]FieldChange,
+	snapshot BeverageSnapshot,
+	revertedTo int32,
+) (sqlc.BeverageRevision, error) {
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return sqlc.BeverageRevision{}, err
+	}
+
+	return q.CreateBeverageRevision(ctx, sqlc.CreateBeverageRevisionParams{
+		BeverageID: beverageID,
+		Action:     action,
+		ChangedBy:  changedBy,
+		Reason:     reason,
+		Diff:       diffJSON,
+		Snapshot:   snapshotJSON,
+		RevertedTo: pgtype.Int4{Int32: revertedTo, Valid: revertedTo > 0},
+	})
+}
+
+// syncAliases adds and removes aliases so the beverage ends up with want
+func syncAliases(ctx context.Context, q curationStore, beverageID pgtype.UUID, have, want []string, curatorID pgtype.UUID) error {
+	for _, alias := range have {
+		if slices.ContainsFunc(want, sameAlias(alias)) {
+			continue
+		}
+		if _, err := q.DeleteBeverageAlias(ctx, sqlc.DeleteBeverageAliasParams{
+			BeverageID:      beverageID,
+			AliasNormalized: NormalizeBeverageName(alias),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, alias := range want {
+		if slices.ContainsFunc(have, sameAlias(alias)) {
+			continue
+		}
+		_, err := q.CreateBeverageAlias(ctx, sqlc.CreateBeverageAliasParams{
+			BeverageID:      beverageID,
+			Alias:           alias,
+			AliasNormalized: NormalizeBeverageName(alias),
+			CreatedBy:       curatorID,
+		})
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAliasTaken
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func snapshotOf(b sqlc.Beverage, aliases []sqlc.BeverageAlias) BeverageSnapshot {
+	snap := BeverageSnapshot{
+		Name:     b.Name,
+		Brand:    b.Brand.String,
+		Category: b.Category,
+		Vintage:  b.Vintage.String,
+		ImageURL: b.ImageUrl.String,
+		Aliases:  make([]string, 0, len(aliases)),
+	}
+	for _, a := range aliases {
+		snap.Aliases = append(snap.Aliases, a.Alias)
+	}
+	return snap
+}
+
+func validateSnapshot(s BeverageSnapshot) error {
+	if s.Name == "" {
+		return ErrInvalidBeverage
+	}
+	if !slices.Contains(beverageCategories, s.Category) {
+		return ErrInvalidBeverage
+	}
+	if s.ImageURL != "" {
+		u, err := url.Parse(s.ImageURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return ErrInvalidBeverage
+		}
+	}
+	return nil
+}
+
+func diffSnapshots(before, after BeverageSnapshot) map[string]FieldChange {
+	diff := make(map[string]FieldChange)
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"name", before.Name, after.Name},
+		{"brand", before.Brand, after.Brand},
+		{"category", before.Category, after.Category},
+		{"vintage", before.Vintage, after.Vintage},
+		{"image_url", before.ImageURL, after.ImageURL},
+	}
+	for _, f := range fields {
+		if f.old != f.new {
+			diff[f.name] = FieldChange{Old: f.old, New: f.new}
+		}
+	}
+
+	if !sameAliasSet(before.Aliases, after.Aliases) {
+		diff["aliases"] = FieldChange{Old: before.Aliases, New: after.Aliases}
+	}
+	return diff
+}
+
+func detailsChanged(diff map[string]FieldChange) bool {
+	for field := range diff {
+		if field != "aliases" {
+			return true
+		}
+	}
+	return false
+}
+
+func sameAliasSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, alias := range a {
+		if !slices.ContainsFunc(b, sameAlias(alias)) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameAlias(alias string) func(string) bool {
+	key := NormalizeBeverageName(alias)
+	return func(other string) bool {
+		return NormalizeBeverageName(other) == key
+	}
+}
+
+func revisionFromRow(row sqlc.BeverageRevision) (*BeverageRevision, error) {
+	rev := &BeverageRevision{
+		Revision:   int(row.Revision),
+		Action:     row.Action,
+		ChangedBy:  uuidString(row.ChangedBy),
+		Reason:     row.Reason,
+		RevertedTo: int(row.RevertedTo.Int32),
+		CreatedAt:  row.CreatedAt.Time,
+	}
+	if err := json.Unmarshal(row.Diff, &rev.Diff); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(row.Snapshot, &rev.Snapshot); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// NormalizeBeverageName lowercases and collapses whitespace for name
+// matching. It is the one normalizer for beverages: name_normalized,
+// brand_normalized and alias_normalized are all written with it, so lookups
+// against those columns must normalize their query with it too.
+func NormalizeBeverageName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
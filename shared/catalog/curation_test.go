@@ -0,0 +1,241 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeCurationStore keeps beverages, aliases and revisions in memory and
+// enforces the unique alias_normalized index like Postgres does
+type fakeCurationStore struct {
+	beverages map[pgtype.UUID]sqlc.Beverage
+	aliases   []sqlc.BeverageAlias
+	revisions map[pgtype.UUID][]sqlc.BeverageRevision
+}
+
+var _ curationStore = (*fakeCurationStore)(nil)
+
+func newFakeCurationStore(bevs ...sqlc.Beverage) *fakeCurationStore {
+	f := &fakeCurationStore{
+		beverages: make(map[pgtype.UUID]sqlc.Beverage),
+		revisions: make(map[pgtype.UUID][]sqlc.BeverageRevision),
+	}
+	for _, b := range bevs {
+		f.beverages[b.ID] = b
+	}
+	return f
+}
+
+func (f *fakeCurationStore) GetBeverageForUpdate(ctx context.Context, id pgtype.UUID) (sqlc.Beverage, error) {
+	b, ok := f.beverages[id]
+	if !ok {
+		return sqlc.Beverage{}, pgx.ErrNoRows
+	}
+	return b, nil
+}
+
+func (f *fakeCurationStore) UpdateBeverageDetails(ctx context.Context, arg sqlc.UpdateBeverageDetailsParams) (sqlc.Beverage, error) {
+	b := f.beverages[arg.ID]
+	b.Name = arg.Name
+	b.Brand = arg.Brand
+	b.Category = arg.Category
+	b.Vintage = arg.Vintage
+	b.ImageUrl = arg.ImageUrl
+	b.NameNormalized = arg.NameNormalized
+	b.BrandNormalized = arg.BrandNormalized
+	f.beverages[arg.ID] = b
+	return b, nil
+}
+
+func (f *fakeCurationStore) ListBeverageAliases(ctx context.Context, beverageID pgtype.UUID) ([]sqlc.BeverageAlias, error) {
+	var out []sqlc.BeverageAlias
+	for _, a := range f.aliases {
+		if a.BeverageID == beverageID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCurationStore) CreateBeverageAlias(ctx context.Context, arg sqlc.CreateBeverageAliasParams) (sqlc.BeverageAlias, error) {
+	for _, a := range f.aliases {
+		if a.AliasNormalized == arg.AliasNormalized {
+			return sqlc.BeverageAlias{}, &pgconn.PgError{Code: "23505"}
+		}
+	}
+	a := sqlc.BeverageAlias{
+		BeverageID:      arg.BeverageID,
+		Alias:           arg.Alias,
+		AliasNormalized: arg.AliasNormalized,
+		CreatedBy:       arg.CreatedBy,
+	}
+	f.aliases = append(f.aliases, a)
+	return a, nil
+}
+
+func (f *fakeCurationStore) DeleteBeverageAlias(ctx context.Context, arg sqlc.DeleteBeverageAliasParams) (int64, error) {
+	before := len(f.aliases)
+	f.aliases = slices.DeleteFunc(f.aliases, func(a sqlc.BeverageAlias) bool {
+		return a.BeverageID == arg.BeverageID && a.AliasNormalized == arg.AliasNormalized
+	})
+	return int64(before - len(f.aliases)), nil
+}
+
+func (f *fakeCurationStore) CountBeverageRevisions(ctx context.Context, beverageID pgtype.UUID) (int64, error) {
+	return int64(len(f.revisions[beverageID])), nil
+}
+
+func (f *fakeCurationStore) CreateBeverageRevision(ctx context.Context, arg sqlc.CreateBeverageRevisionParams) (sqlc.BeverageRevision, error) {
+	rev := sqlc.BeverageRevision{
+		BeverageID: arg.BeverageID,
+		Revision:   int32(len(f.revisions[arg.BeverageID]) + 1),
+		Action:     arg.Action,
+		ChangedBy:  arg.ChangedBy,
+		Reason:     arg.Reason,
+		Diff:       arg.Diff,
+		Snapshot:   arg.Snapshot,
+		RevertedTo: arg.RevertedTo,
+	}
+	f.revisions[arg.BeverageID] = append(f.revisions[arg.BeverageID], rev)
+	return rev, nil
+}
+
+func (f *fakeCurationStore) GetBeverageRevision(ctx context.Context, arg sqlc.GetBeverageRevisionParams) (sqlc.BeverageRevision, error) {
+	for _, rev := range f.revisions[arg.BeverageID] {
+		if rev.Revision == arg.Revision {
+			return rev, nil
+		}
+	}
+	return sqlc.BeverageRevision{}, pgx.ErrNoRows
+}
+
+func (f *fakeCurationStore) snapshot(t *testing.T, id pgtype.UUID) BeverageSnapshot {
+	t.Helper()
+	aliases, _ := f.ListBeverageAliases(context.Background(), id)
+	return snapshotOf(f.beverages[id], aliases)
+}
+
+func testBeverage(n byte, name, brand, category string) sqlc.Beverage {
+	return sqlc.Beverage{
+		ID:              testUUID(n),
+		Name:            name,
+		Brand:           optionalText(brand),
+		Category:        category,
+		NameNormalized:  NormalizeBeverageName(name),
+		BrandNormalized: optionalText(NormalizeBeverageName(brand)),
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestEditRevertRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	bev := testBeverage(1, "Cabernet Sauvginon", "Silver Oak", "wine")
+	store := newFakeCurationStore(bev)
+	curator := testUUID(9)
+	original := store.snapshot(t, bev.ID)
+
+	rev, err := applyChange(ctx, store, bev.ID, curator, "typo", RevisionEdit, 0,
+		applyEdit(BeverageEdit{Name: ptr("  Cabernet   Sauvignon "), Vintage: ptr("2018")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first change records the untouched state as revision 1
+	if rev.Revision != 2 || store.revisions[bev.ID][0].Action != RevisionBaseline {
+		t.Fatalf("edit recorded as revision %d after %q", rev.Revision, store.revisions[bev.ID][0].Action)
+	}
+	if got := store.beverages[bev.ID]; got.Name != "Cabernet   Sauvignon" || got.NameNormalized != "cabernet sauvignon" {
+		t.Fatalf("edited name %q normalized %q", got.Name, got.NameNormalized)
+	}
+
+	if _, err := applyChange(ctx, store, bev.ID, curator, "nickname", RevisionAliasAdd, 0, addAlias("Silver Oak Cab")); err != nil {
+		t.Fatal(err)
+	}
+	edited := store.snapshot(t, bev.ID)
+
+	rev, err = applyChange(ctx, store, bev.ID, curator, "undo", RevisionRevert, 1, revertTo(ctx, bev.ID, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Revision != 4 || rev.RevertedTo.Int32 != 1 {
+		t.Fatalf("revert recorded as revision %d reverting to %d", rev.Revision, rev.RevertedTo.Int32)
+	}
+	if got := store.snapshot(t, bev.ID); !sameSnapshot(got, original) {
+		t.Fatalf("reverted to %+v, want %+v", got, original)
+	}
+
+	// Reverting the revert brings the edits and the alias back
+	if _, err := applyChange(ctx, store, bev.ID, curator, "redo", RevisionRevert, 3, revertTo(ctx, bev.ID, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.snapshot(t, bev.ID); !sameSnapshot(got, edited) {
+		t.Fatalf("re-applied %+v, want %+v", got, edited)
+	}
+
+	// Reverting to the state the beverage is already in changes nothing
+	if _, err := applyChange(ctx, store, bev.ID, curator, "again", RevisionRevert, 3, revertTo(ctx, bev.ID, 3)); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("no-op revert error = %v, want ErrNoChanges", err)
+	}
+	if n := len(store.revisions[bev.ID]); n != 5 {
+		t.Fatalf("%d revisions recorded, want 5", n)
+	}
+}
+
+func TestAliasConflicts(t *testing.T) {
+	ctx := context.Background()
+	a := testBeverage(1, "Pliny the Elder", "Russian River", "beer")
+	b := testBeverage(2, "Heady Topper", "The Alchemist", "beer")
+	store := newFakeCurationStore(a, b)
+	curator := testUUID(9)
+
+	if _, err := applyChange(ctx, store, a.ID, curator, "nickname", RevisionAliasAdd, 0, addAlias("Pliny")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another spelling of the same alias on the same beverage is no change
+	if _, err := applyChange(ctx, store, a.ID, curator, "again", RevisionAliasAdd, 0, addAlias("  PLINY ")); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("duplicate alias error = %v, want ErrNoChanges", err)
+	}
+
+	// An alias can only name one beverage
+	if _, err := applyChange(ctx, store, b.ID, curator, "mistake", RevisionAliasAdd, 0, addAlias("pliny")); !errors.Is(err, ErrAliasTaken) {
+		t.Fatalf("taken alias error = %v, want ErrAliasTaken", err)
+	}
+
+	if _, err := applyChange(ctx, store, b.ID, curator, "missing", RevisionAliasRemove, 0, removeAlias("Pliny")); !errors.Is(err, ErrAliasNotFound) {
+		t.Fatalf("foreign alias removal error = %v, want ErrAliasNotFound", err)
+	}
+
+	// Once removed, the alias is free for another beverage
+	if _, err := applyChange(ctx, store, a.ID, curator, "wrong beer", RevisionAliasRemove, 0, removeAlias("pliny")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyChange(ctx, store, b.ID, curator, "moved", RevisionAliasAdd, 0, addAlias("Pliny")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reverting a to when it held the alias now collides with b
+	if _, err := applyChange(ctx, store, a.ID, curator, "undo", RevisionRevert, 2, revertTo(ctx, a.ID, 2)); !errors.Is(err, ErrAliasTaken) {
+		t.Fatalf("conflicting revert error = %v, want ErrAliasTaken", err)
+	}
+}
+
+func TestEditAction(t *testing.T) {
+	if got := editAction(BeverageEdit{ImageURL: ptr("")}); got != RevisionImage {
+		t.Errorf("image-only edit action = %q", got)
+	}
+	if got := editAction(BeverageEdit{ImageURL: ptr(""), Name: ptr("x")}); got != RevisionEdit {
+		t.Errorf("mixed edit action = %q", got)
+	}
+}
+
+func sameSnapshot(a, b BeverageSnapshot) bool {
+	return len(diffSnapshots(a, b)) == 0
+}
@@ -0,0 +1,300 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ProducerBackfillReport summarizes a backfill run
+type ProducerBackfillReport struct {
+	ProducersCreated int `json:"producers_created"`
+	ProducersReused  int `json:"producers_reused"`
+	AliasesAdded     int `json:"aliases_added"`
+	BeveragesLinked  int `json:"beverages_linked"`
+}
+
+// producerKey identifies a cluster. Normalizing strips type words like
+// "brewing" and "estate", so the type keeps "Stone Brewing" and "Stone
+// Estate" apart.
+type producerKey struct {
+	producerType string
+	name         string
+}
+
+// producerCluster groups raw producer strings that refer to the same producer
+type producerCluster struct {
+	key        producerKey
+	rawCounts  map[string]int
+	beverages  map[pgtype.UUID]int
+	totalCount int
+}
+
+// ProducerBackfiller creates producers from the free-text brand, winery and
+// brewery strings and links beverages to them. Safe to re-run: beverages
+// that already have a producer are skipped and known aliases are reused.
+type ProducerBackfiller struct {
+	DB CurationDB
+	Q  *sqlc.Queries
+}
+
+func NewProducerBackfiller(db CurationDB) *ProducerBackfiller {
+	return &ProducerBackfiller{DB: db, Q: sqlc.New(db)}
+}
+
+// Run clusters unlinked producer strings and links beverages to producers.
+// Each cluster is applied in its own transaction, so a failed run leaves
+// every cluster either fully linked or untouched.
+func (b *ProducerBackfiller) Run(ctx context.Context) (*ProducerBackfillReport, error) {
+	rows, err := b.Q.ListUnlinkedProducerNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := clusterProducerNames(rows)
+	report := &ProducerBackfillReport{}
+
+	// Each beverage goes to the cluster with the most mentions of it
+	bestCluster := make(map[pgtype.UUID]*producerCluster)
+	for _, c := range clusters {
+		for bevID, votes := range c.beverages {
+			current, ok := bestCluster[bevID]
+			if !ok || votes > current.beverages[bevID] {
+				bestCluster[bevID] = c
+			}
+		}
+	}
+
+	for _, c := range clusters {
+		var linked []pgtype.UUID
+		for bevID := range c.beverages {
+			if bestCluster[bevID] == c {
+				linked = append(linked, bevID)
+			}
+		}
+		if err := b.applyCluster(ctx, c, linked, report); err != nil {
+			return report, err
+		}
+	}
+
+	log.Printf("Producer backfill: %d created, %d reused, %d aliases, %d beverages linked",
+		report.ProducersCreated, report.ProducersReused, report.AliasesAdded, report.BeveragesLinked)
+
+	return report, nil
+}
+
+// applyCluster finds or creates the cluster's producer, records its
+// spellings as aliases and links its beverages in one transaction. The
+// report is only updated once the transaction commits.
+func (b *ProducerBackfiller) applyCluster(ctx context.Context, c *producerCluster, beverages []pgtype.UUID, report *ProducerBackfillReport) error {
+	tx, err := b.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := b.Q.WithTx(tx)
+
+	producer, created, err := findOrCreateProducer(ctx, q, c)
+	if err != nil {
+		return err
+	}
+
+	aliases := 0
+	for raw := range c.rawCounts {
+		n, err := q.CreateProducerAlias(ctx, sqlc.CreateProducerAliasParams{
+			ProducerID:      producer.ID,
+			ProducerType:    producer.ProducerType,
+			Alias:           raw,
+			AliasNormalized: NormalizeProducerName(raw),
+		})
+		if err != nil {
+			return err
+		}
+		aliases += int(n)
+	}
+
+	for _, bevID := range beverages {
+		if err := q.SetBeverageProducer(ctx, sqlc.SetBeverageProducerParams{
+			ID:         bevID,
+			ProducerID: producer.ID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if created {
+		report.ProducersCreated++
+	} else {
+		report.ProducersReused++
+	}
+	report.AliasesAdded += aliases
+	report.BeveragesLinked += len(beverages)
+	return nil
+}
+
+func findOrCreateProducer(ctx context.Context, q *sqlc.Queries, c *producerCluster) (sqlc.Producer, bool, error) {
+	// Reuse a producer of the same type that already owns any of the
+	// cluster's spellings
+	for raw := range c.rawCounts {
+		existing, err := q.GetProducerByAlias(ctx, sqlc.GetProducerByAliasParams{
+			ProducerType:    c.key.producerType,
+			AliasNormalized: NormalizeProducerName(raw),
+		})
+		if err == nil {
+			return existing, false, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.Producer{}, false, err
+		}
+	}
+
+	producer, err := q.CreateProducer(ctx, sqlc.CreateProducerParams{
+		Name:           c.canonicalName(),
+		NameNormalized: c.key.name,
+		ProducerType:   c.key.producerType,
+	})
+	if err != nil {
+		return sqlc.Producer{}, false, err
+	}
+	return producer, true, nil
+}
+
+// clusterProducerNames groups raw strings by producer type and normalized
+// name, then folds names of the same type that are one typo apart into the
+// more common spelling.
+func clusterProducerNames(rows []sqlc.ListUnlinkedProducerNamesRow) []*producerCluster {
+	byKey := make(map[producerKey]*producerCluster)
+	for _, row := range rows {
+		name := NormalizeProducerName(row.RawName)
+		if name == "" {
+			continue
+		}
+		key := producerKey{producerType: producerTypeFor(row.Source, row.Category), name: name}
+		c, ok := byKey[key]
+		if !ok {
+			c = &producerCluster{
+				key:       key,
+				rawCounts: make(map[string]int),
+				beverages: make(map[pgtype.UUID]int),
+			}
+			byKey[key] = c
+		}
+		c.rawCounts[row.RawName]++
+		if row.BeverageID.Valid {
+			c.beverages[row.BeverageID]++
+		}
+		c.totalCount++
+	}
+
+	keyed := make([]*producerCluster, 0, len(byKey))
+	for _, c := range byKey {
+		keyed = append(keyed, c)
+	}
+	sort.Slice(keyed, func(i, j int) bool {
+		if keyed[i].totalCount != keyed[j].totalCount {
+			return keyed[i].totalCount > keyed[j].totalCount
+		}
+		if keyed[i].key.producerType != keyed[j].key.producerType {
+			return keyed[i].key.producerType < keyed[j].key.producerType
+		}
+		return keyed[i].key.name < keyed[j].key.name
+	})
+
+	merged := []*producerCluster{}
+	for _, c := range keyed {
+		var target *producerCluster
+		for _, m := range merged {
+			if m.key.producerType == c.key.producerType && isTypoOf(c.key.name, m.key.name) {
+				target = m
+				break
+			}
+		}
+		if target == nil {
+			merged = append(merged, c)
+			continue
+		}
+		for raw, n := range c.rawCounts {
+			target.rawCounts[raw] += n
+		}
+		for bevID, n := range c.beverages {
+			target.beverages[bevID] += n
+		}
+		target.totalCount += c.totalCount
+	}
+
+	return merged
+}
+
+// canonicalName picks the most common raw spelling, preferring shorter ones on ties
+func (c *producerCluster) canonicalName() string {
+	best := ""
+	for raw, n := range c.rawCounts {
+		switch {
+		case best == "":
+			best = raw
+		case n > c.rawCounts[best]:
+			best = raw
+		case n == c.rawCounts[best] && (len(raw) < len(best) || (len(raw) == len(best) && raw < best)):
+			best = raw
+		}
+	}
+	return best
+}
+
+// producerTypeFor infers the producer type from where the string came from
+func producerTypeFor(source, category string) string {
+	switch source {
+	case "winery":
+		return "winery"
+	case "brewery":
+		return "brewery"
+	}
+
+	switch category {
+	case "wine":
+		return "winery"
+	case "beer":
+		return "brewery"
+	case "cocktail":
+		return "distillery"
+	}
+	return "other"
+}
+
+// isTypoOf reports whether a and b differ by a single edit. Short keys must
+// match exactly since one edit changes them too much.
+func isTypoOf(a, b string) bool {
+	if len(a) < 6 || len(b) < 6 {
+		return false
+	}
+	return levenshtein(a, b) <= 1
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testUUID(n byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{15: n}, Valid: true}
+}
+
+func unlinked(bev byte, category, source, raw string) sqlc.ListUnlinkedProducerNamesRow {
+	return sqlc.ListUnlinkedProducerNamesRow{BeverageID: testUUID(bev), Category: category, RawName: raw, Source: source}
+}
+
+func TestClusterProducerNames(t *testing.T) {
+	type cluster struct {
+		producerType, name, canonical string
+		beverages                     int
+	}
+	cases := []struct {
+		name string
+		rows []sqlc.ListUnlinkedProducerNamesRow
+		want []cluster
+	}{
+		{
+			name: "spellings of one producer",
+			rows: []sqlc.ListUnlinkedProducerNamesRow{
+				unlinked(1, "beer", "brand", "Sierra Nevada"),
+				unlinked(1, "beer", "brewery", "Sierra Nevada Brewing Co."),
+				unlinked(2, "beer", "brand", "Sierra Nevada"),
+			},
+			want: []cluster{{"brewery", "sierra nevada", "Sierra Nevada", 2}},
+		},
+		{
+			name: "same stem in different categories",
+			rows: []sqlc.ListUnlinkedProducerNamesRow{
+				unlinked(1, "beer", "brand", "Stone Brewing"),
+				unlinked(2, "wine", "winery", "Stone Estate"),
+			},
+			want: []cluster{
+				{"brewery", "stone", "Stone Brewing", 1},
+				{"winery", "stone", "Stone Estate", 1},
+			},
+		},
+		{
+			name: "typo folds into the common spelling",
+			rows: []sqlc.ListUnlinkedProducerNamesRow{
+				unlinked(1, "wine", "winery", "Montelena"),
+				unlinked(2, "wine", "winery", "Montelena"),
+				unlinked(3, "wine", "winery", "Montelina"),
+			},
+			want: []cluster{{"winery", "montelena", "Montelena", 3}},
+		},
+		{
+			name: "typo across categories stays apart",
+			rows: []sqlc.ListUnlinkedProducerNamesRow{
+				unlinked(1, "wine", "winery", "Montelena"),
+				unlinked(2, "wine", "winery", "Montelena"),
+				unlinked(3, "beer", "brewery", "Montelina"),
+			},
+			want: []cluster{
+				{"brewery", "montelina", "Montelina", 1},
+				{"winery", "montelena", "Montelena", 2},
+			},
+		},
+		{
+			name: "short names need an exact match",
+			rows: []sqlc.ListUnlinkedProducerNamesRow{
+				unlinked(1, "beer", "brewery", "Alesmith"),
+				unlinked(2, "beer", "brewery", "Ale"),
+				unlinked(3, "beer", "brewery", "Alf"),
+			},
+			want: []cluster{
+				{"brewery", "ale", "Ale", 1},
+				{"brewery", "alesmith", "Alesmith", 1},
+				{"brewery", "alf", "Alf", 1},
+			},
+		},
+		{
+			name: "blank names are skipped",
+			rows: []sqlc.ListUnlinkedProducerNamesRow{unlinked(1, "cocktail", "brand", "--")},
+			want: []cluster{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusters := clusterProducerNames(c.rows)
+			got := make([]cluster, 0, len(clusters))
+			for _, cl := range clusters {
+				got = append(got, cluster{cl.key.producerType, cl.key.name, cl.canonicalName(), len(cl.beverages)})
+			}
+			sort.Slice(got, func(i, j int) bool {
+				if got[i].producerType != got[j].producerType {
+					return got[i].producerType < got[j].producerType
+				}
+				return got[i].name < got[j].name
+			})
+			if len(got) != len(c.want) {
+				t.Fatalf("clusters = %+v, want %+v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("cluster %d = %+v, want %+v", i, got[i], c.want[i])
+				}
+			}
+		})
+	}
+}
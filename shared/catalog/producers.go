@@ -0,0 +1,224 @@
+package catalog
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Producer is the API representation of a winery, brewery or distillery
+type Producer struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	ProducerType string  `json:"producer_type"`
+	City         string  `json:"city,omitempty"`
+	Region       string  `json:"region,omitempty"`
+	Country      string  `json:"country,omitempty"`
+	Lat          float64 `json:"lat,omitempty"`
+	Lng          float64 `json:"lng,omitempty"`
+}
+
+// ProducerBeverage is a beverage listed on a producer profile
+type ProducerBeverage struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Category     string  `json:"category"`
+	Vintage      string  `json:"vintage,omitempty"`
+	ImageURL     string  `json:"image_url,omitempty"`
+	AvgRating    float64 `json:"avg_rating"`
+	TotalReviews int     `json:"total_reviews"`
+}
+
+// ProducerRatings aggregates ratings across all of a producer's beverages
+type ProducerRatings struct {
+	BeverageCount int     `json:"beverage_count"`
+	TotalReviews  int     `json:"total_reviews"`
+	RatingCount   int     `json:"rating_count"`
+	AvgRating     float64 `json:"avg_rating"`
+}
+
+// TagCount is a tag with how often it was extracted
+type TagCount struct {
+	Tag     string `json:"tag"`
+	TagType string `json:"tag_type"`
+	Count   int    `json:"count"`
+}
+
+// ProducerProfile backs GET /v1/producers/:id
+type ProducerProfile struct {
+	Producer  Producer           `json:"producer"`
+	Aliases   []string           `json:"aliases"`
+	Ratings   ProducerRatings    `json:"ratings"`
+	TopTags   []TagCount         `json:"top_tags"`
+	Beverages []ProducerBeverage `json:"beverages"`
+}
+
+const (
+	profileBeverageLimit = 50
+	profileTagLimit      = 10
+)
+
+// ProducerService serves producer profiles and search
+type ProducerService struct {
+	Q *sqlc.Queries
+}
+
+func NewProducerService(q *sqlc.Queries) *ProducerService {
+	return &ProducerService{Q: q}
+}
+
+// GetProfile loads a producer with its beverages, aggregate ratings and top tags
+func (s *ProducerService) GetProfile(ctx context.Context, producerID pgtype.UUID) (*ProducerProfile, error) {
+	producer, err := s.Q.GetProducerByID(ctx, producerID)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := s.Q.GetProducerAliases(ctx, producerID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.Q.GetProducerRatingSummary(ctx, producerID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.Q.GetProducerTopTags(ctx, sqlc.GetProducerTopTagsParams{
+		ProducerID: producerID,
+		Limit:      profileTagLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	beverages, err := s.Q.ListProducerBeverages(ctx, sqlc.ListProducerBeveragesParams{
+		ProducerID: producerID,
+		Limit:      profileBeverageLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &ProducerProfile{
+		Producer: producerFromRow(producer),
+		Aliases:  aliases,
+		Ratings: ProducerRatings{
+			BeverageCount: int(summary.BeverageCount),
+			TotalReviews:  int(summary.TotalReviews),
+			RatingCount:   int(summary.RatingCount),
+			AvgRating:     ratingValue(summary.AvgRating),
+		},
+		TopTags:   make([]TagCount, 0, len(tags)),
+		Beverages: make([]ProducerBeverage, 0, len(beverages)),
+	}
+	if profile.Aliases == nil {
+		profile.Aliases = []string{}
+	}
+
+	for _, t := range tags {
+		profile.TopTags = append(profile.TopTags, TagCount{
+			Tag:     t.Tag,
+			TagType: t.TagType,
+			Count:   int(t.Count),
+		})
+	}
+
+	for _, b := range beverages {
+		profile.Beverages = append(profile.Beverages, ProducerBeverage{
+			ID:           uuidString(b.ID),
+			Name:         b.Name,
+			Category:     b.Category,
+			Vintage:      b.Vintage.String,
+			ImageURL:     b.ImageUrl.String,
+			AvgRating:    ratingValue(b.AvgRating),
+			TotalReviews: int(b.TotalReviews.Int32),
+		})
+	}
+
+	return profile, nil
+}
+
+// Search finds producers by name or alias. producerType may be empty to search all types.
+func (s *ProducerService) Search(ctx context.Context, query, producerType string, limit int32) ([]Producer, error) {
+	normalized := NormalizeProducerName(query)
+	if normalized == "" {
+		return []Producer{}, nil
+	}
+
+	rows, err := s.Q.SearchProducers(ctx, sqlc.SearchProducersParams{
+		Query:        normalized,
+		ProducerType: producerType,
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Producer, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, producerFromRow(sqlc.Producer{
+			ID:           row.ID,
+			Name:         row.Name,
+			ProducerType: row.ProducerType,
+			City:         row.City,
+			Region:       row.Region,
+			Country:      row.Country,
+			Lat:          row.Lat,
+			Lng:          row.Lng,
+		}))
+	}
+
+	return results, nil
+}
+
+func producerFromRow(p sqlc.Producer) Producer {
+	return Producer{
+		ID:           uuidString(p.ID),
+		Name:         p.Name,
+		ProducerType: p.ProducerType,
+		City:         p.City.String,
+		Region:       p.Region.String,
+		Country:      p.Country.String,
+		Lat:          p.Lat.Float64,
+		Lng:          p.Lng.Float64,
+	}
+}
+
+var (
+	nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+	// Words that say what kind of business it is rather than which one
+	producerNoiseWords = map[string]bool{
+		"the": true, "inc": true, "llc": true, "ltd": true, "co": true, "company": true, "corp": true,
+		"winery": true, "wines": true, "wine": true, "vineyard": true, "vineyards": true, "cellars": true,
+		"cellar": true, "estate": true, "estates": true, "domaine": true, "chateau": true,
+		"brewery": true, "brewing": true, "brewers": true, "brewhouse": true, "beer": true,
+		"distillery": true, "distilling": true, "distillers": true, "spirits": true,
+	}
+)
+
+// NormalizeProducerName reduces a producer string to a comparison key:
+// lowercased, punctuation stripped and business-type words removed, so
+// "Sierra Nevada Brewing Co." and "sierra nevada" share a key.
+func NormalizeProducerName(name string) string {
+	lowered := strings.ToLower(strings.ReplaceAll(name, "&", " and "))
+	tokens := strings.Fields(nonAlphanumeric.ReplaceAllString(lowered, " "))
+
+	kept := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if !producerNoiseWords[t] {
+			kept = append(kept, t)
+		}
+	}
+
+	// A name made only of noise words ("The Winery") is still a name
+	if len(kept) == 0 {
+		kept = tokens
+	}
+
+	return strings.Join(kept, " ")
+}
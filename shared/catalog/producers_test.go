@@ -0,0 +1,29 @@
+package catalog
+
+import "testing"
+
+func TestNormalizeProducerName(t *testing.T) {
+	cases := []struct {
+		name, want string
+	}{
+		{"Sierra Nevada Brewing Co.", "sierra nevada"},
+		{"sierra nevada", "sierra nevada"},
+		{"  SIERRA   NEVADA  ", "sierra nevada"},
+		{"Stone Brewing", "stone"},
+		{"Stone Estate", "stone"},
+		{"Chateau Montelena Winery", "montelena"},
+		{"Ridge Vineyards, Inc.", "ridge"},
+		{"Smith & Hook", "smith and hook"},
+		{"Buffalo Trace Distillery", "buffalo trace"},
+		{"Dogfish Head Craft Brewery", "dogfish head craft"},
+		// Only noise words: keep them rather than return nothing
+		{"The Winery", "the winery"},
+		{"", ""},
+		{"!!!", ""},
+	}
+	for _, c := range cases {
+		if got := NormalizeProducerName(c.name); got != c.want {
+			t.Errorf("NormalizeProducerName(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
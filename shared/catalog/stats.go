@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"context"
+	"log"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// StatsDrift describes a beverage whose stored stats disagreed with its posts
+type StatsDrift struct {
+	BeverageID         string  `json:"beverage_id"`
+	Name               string  `json:"name"`
+	StoredTotalReviews int     `json:"stored_total_reviews"`
+	StoredAvgRating    float64 `json:"stored_avg_rating"`
+	TotalReviews       int     `json:"total_reviews"`
+	AvgRating          float64 `json:"avg_rating"`
+}
+
+// StatsReconciler repairs beverage stats that drifted from the posts table.
+// Day-to-day maintenance happens in the posts triggers (migration 0015); this
+// is the safety net for anything that bypassed them.
+type StatsReconciler struct {
+	Q *sqlc.Queries
+}
+
+func NewStatsReconciler(q *sqlc.Queries) *StatsReconciler {
+	return &StatsReconciler{Q: q}
+}
+
+// Reconcile recomputes stats for drifted beverages and reports what was fixed
+func (r *StatsReconciler) Reconcile(ctx context.Context) ([]StatsDrift, error) {
+	rows, err := r.Q.ReconcileBeverageStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := make([]StatsDrift, 0, len(rows))
+	for _, row := range rows {
+		d := StatsDrift{
+			BeverageID:         uuidString(row.ID),
+			Name:               row.Name,
+			StoredTotalReviews: int(row.StoredTotalReviews.Int32),
+			StoredAvgRating:    ratingValue(row.StoredAvgRating),
+			TotalReviews:       int(row.TotalReviews.Int32),
+			AvgRating:          ratingValue(row.AvgRating),
+		}
+		log.Printf("Beverage stats drift fixed for %s (%s): reviews %d -> %d, avg %.2f -> %.2f",
+			d.BeverageID, d.Name, d.StoredTotalReviews, d.TotalReviews, d.StoredAvgRating, d.AvgRating)
+		drift = append(drift, d)
+	}
+
+	return drift, nil
+}
+
+// Run reconciles once; the job runner schedules it
+func (r *StatsReconciler) Run(ctx context.Context) error {
+	drift, err := r.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Beverage stats reconciliation fixed %d beverages", len(drift))
+	return nil
+}
+
+// Helper functions
+
+func uuidString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return uuid.UUID(id.Bytes).String()
+}
+
+func ratingValue(n pgtype.Numeric) float64 {
+	avg, _ := rating.Average(n)
+	return avg
+}
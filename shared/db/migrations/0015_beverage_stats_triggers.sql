@@ -0,0 +1,123 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Keep beverages.total_reviews / avg_rating in sync with posts.
+-- rating_count and rating_sum are the running totals the trigger maintains;
+-- avg_rating is derived from them so increments never accumulate rounding drift.
+ALTER TABLE beverages
+  ALTER COLUMN avg_rating TYPE NUMERIC(4,2),
+  ADD COLUMN rating_count INT NOT NULL DEFAULT 0,
+  ADD COLUMN rating_sum NUMERIC(12,1) NOT NULL DEFAULT 0;
+
+-- Single definition of a post's rating on the 0-10 scale:
+-- score when present and non-zero, otherwise stars doubled, otherwise unrated.
+CREATE OR REPLACE FUNCTION post_rating(score NUMERIC, stars INT)
+RETURNS NUMERIC AS $$
+  SELECT CASE
+    WHEN score IS NOT NULL AND score != 0 THEN score
+    WHEN stars IS NOT NULL THEN stars * 2.0
+    ELSE NULL
+  END;
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION apply_beverage_stats_delta(
+  bev_id UUID, review_delta INT, rating_delta INT, sum_delta NUMERIC
+)
+RETURNS VOID AS $$
+BEGIN
+  UPDATE beverages
+  SET
+    total_reviews = COALESCE(total_reviews, 0) + review_delta,
+    rating_count = rating_count + rating_delta,
+    rating_sum = rating_sum + sum_delta,
+    avg_rating = CASE
+      WHEN rating_count + rating_delta > 0
+        THEN ROUND((rating_sum + sum_delta) / (rating_count + rating_delta), 2)
+      ELSE 0
+    END
+  WHERE id = bev_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sync_beverage_stats()
+RETURNS TRIGGER AS $$
+DECLARE
+  old_rating NUMERIC;
+  new_rating NUMERIC;
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.beverage_id IS NOT NULL THEN
+    old_rating := post_rating(OLD.score, OLD.stars);
+    PERFORM apply_beverage_stats_delta(
+      OLD.beverage_id,
+      -1,
+      CASE WHEN old_rating IS NULL THEN 0 ELSE -1 END,
+      -COALESCE(old_rating, 0)
+    );
+  END IF;
+
+  IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.beverage_id IS NOT NULL THEN
+    new_rating := post_rating(NEW.score, NEW.stars);
+    PERFORM apply_beverage_stats_delta(
+      NEW.beverage_id,
+      1,
+      CASE WHEN new_rating IS NULL THEN 0 ELSE 1 END,
+      COALESCE(new_rating, 0)
+    );
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_posts_beverage_stats_insert
+AFTER INSERT ON posts
+FOR EACH ROW EXECUTE FUNCTION sync_beverage_stats();
+
+CREATE TRIGGER trg_posts_beverage_stats_update
+AFTER UPDATE OF beverage_id, score, stars ON posts
+FOR EACH ROW
+WHEN (OLD.beverage_id IS DISTINCT FROM NEW.beverage_id
+   OR OLD.score IS DISTINCT FROM NEW.score
+   OR OLD.stars IS DISTINCT FROM NEW.stars)
+EXECUTE FUNCTION sync_beverage_stats();
+
+CREATE TRIGGER trg_posts_beverage_stats_delete
+AFTER DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION sync_beverage_stats();
+
+-- Backfill running totals from existing posts
+UPDATE beverages b
+SET
+  total_reviews = s.total_reviews,
+  rating_count = s.rating_count,
+  rating_sum = s.rating_sum,
+  avg_rating = CASE WHEN s.rating_count > 0 THEN ROUND(s.rating_sum / s.rating_count, 2) ELSE 0 END
+FROM (
+  SELECT
+    bb.id,
+    COUNT(p.id)::INT AS total_reviews,
+    COUNT(post_rating(p.score, p.stars))::INT AS rating_count,
+    COALESCE(SUM(post_rating(p.score, p.stars)), 0) AS rating_sum
+  FROM beverages bb
+  LEFT JOIN posts p ON p.beverage_id = bb.id
+  GROUP BY bb.id
+) s
+WHERE b.id = s.id;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_posts_beverage_stats_delete ON posts;
+DROP TRIGGER IF EXISTS trg_posts_beverage_stats_update ON posts;
+DROP TRIGGER IF EXISTS trg_posts_beverage_stats_insert ON posts;
+DROP FUNCTION IF EXISTS sync_beverage_stats;
+DROP FUNCTION IF EXISTS apply_beverage_stats_delta;
+DROP FUNCTION IF EXISTS post_rating;
+
+-- NUMERIC(3,2) tops out at 9.99, so a perfect 10 average is clamped
+ALTER TABLE beverages
+  ALTER COLUMN avg_rating TYPE NUMERIC(3,2) USING LEAST(avg_rating, 9.99),
+  DROP COLUMN IF EXISTS rating_sum,
+  DROP COLUMN IF EXISTS rating_count;
+-- +goose StatementEnd
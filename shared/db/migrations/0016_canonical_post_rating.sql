@@ -0,0 +1,142 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Canonical rating on the 0-10 scale. stars and score remain as input columns
+-- for older clients, but everything that reads a rating should read this one.
+ALTER TABLE posts ADD COLUMN rating NUMERIC(3,1);
+ALTER TABLE posts ADD CONSTRAINT chk_rating CHECK (rating IS NULL OR (rating >= 0.0 AND rating <= 10.0));
+
+-- A score of 0 is a real rating, not a missing one: score wins whenever it is
+-- set, otherwise stars doubled. rating.FromPost is the Go side of this.
+CREATE OR REPLACE FUNCTION post_rating(score NUMERIC, stars INT)
+RETURNS NUMERIC AS $$
+  SELECT CASE
+    WHEN score IS NOT NULL THEN score
+    WHEN stars IS NOT NULL THEN stars * 2.0
+    ELSE NULL
+  END;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Backfill without touching updated_at
+ALTER TABLE posts DISABLE TRIGGER trg_posts_updated_at;
+UPDATE posts SET rating = post_rating(score, stars);
+ALTER TABLE posts ENABLE TRIGGER trg_posts_updated_at;
+
+CREATE INDEX idx_posts_rating ON posts(beverage_id, rating DESC) WHERE rating IS NOT NULL;
+
+-- Derive rating from stars/score unless the writer supplied it directly. A
+-- NULL rating is always derived. On UPDATE, a changed stars or score with the
+-- row's old rating sent back unchanged (a read-modify-write) is re-derived
+-- too; a rating that changed along with them is taken as given.
+CREATE OR REPLACE FUNCTION set_post_rating()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.rating IS NULL THEN
+    NEW.rating := post_rating(NEW.score, NEW.stars);
+  ELSIF TG_OP = 'UPDATE'
+    AND (NEW.score IS DISTINCT FROM OLD.score OR NEW.stars IS DISTINCT FROM OLD.stars)
+    AND NEW.rating IS NOT DISTINCT FROM OLD.rating THEN
+    NEW.rating := post_rating(NEW.score, NEW.stars);
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_posts_set_rating
+BEFORE INSERT OR UPDATE ON posts
+FOR EACH ROW EXECUTE FUNCTION set_post_rating();
+
+-- Beverage stats now aggregate the canonical rating
+CREATE OR REPLACE FUNCTION sync_beverage_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.beverage_id IS NOT NULL THEN
+    PERFORM apply_beverage_stats_delta(
+      OLD.beverage_id,
+      -1,
+      CASE WHEN OLD.rating IS NULL THEN 0 ELSE -1 END,
+      -COALESCE(OLD.rating, 0)
+    );
+  END IF;
+
+  IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.beverage_id IS NOT NULL THEN
+    PERFORM apply_beverage_stats_delta(
+      NEW.beverage_id,
+      1,
+      CASE WHEN NEW.rating IS NULL THEN 0 ELSE 1 END,
+      COALESCE(NEW.rating, 0)
+    );
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- rating is usually changed by set_post_rating rather than the SET list,
+-- so fire on any of the source columns and compare the final values
+DROP TRIGGER IF EXISTS trg_posts_beverage_stats_update ON posts;
+CREATE TRIGGER trg_posts_beverage_stats_update
+AFTER UPDATE OF beverage_id, score, stars, rating ON posts
+FOR EACH ROW
+WHEN (OLD.beverage_id IS DISTINCT FROM NEW.beverage_id
+   OR OLD.rating IS DISTINCT FROM NEW.rating)
+EXECUTE FUNCTION sync_beverage_stats();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_posts_beverage_stats_update ON posts;
+CREATE TRIGGER trg_posts_beverage_stats_update
+AFTER UPDATE OF beverage_id, score, stars ON posts
+FOR EACH ROW
+WHEN (OLD.beverage_id IS DISTINCT FROM NEW.beverage_id
+   OR OLD.score IS DISTINCT FROM NEW.score
+   OR OLD.stars IS DISTINCT FROM NEW.stars)
+EXECUTE FUNCTION sync_beverage_stats();
+
+CREATE OR REPLACE FUNCTION sync_beverage_stats()
+RETURNS TRIGGER AS $$
+DECLARE
+  old_rating NUMERIC;
+  new_rating NUMERIC;
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.beverage_id IS NOT NULL THEN
+    old_rating := post_rating(OLD.score, OLD.stars);
+    PERFORM apply_beverage_stats_delta(
+      OLD.beverage_id,
+      -1,
+      CASE WHEN old_rating IS NULL THEN 0 ELSE -1 END,
+      -COALESCE(old_rating, 0)
+    );
+  END IF;
+
+  IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.beverage_id IS NOT NULL THEN
+    new_rating := post_rating(NEW.score, NEW.stars);
+    PERFORM apply_beverage_stats_delta(
+      NEW.beverage_id,
+      1,
+      CASE WHEN new_rating IS NULL THEN 0 ELSE 1 END,
+      COALESCE(new_rating, 0)
+    );
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_posts_set_rating ON posts;
+DROP FUNCTION IF EXISTS set_post_rating;
+
+CREATE OR REPLACE FUNCTION post_rating(score NUMERIC, stars INT)
+RETURNS NUMERIC AS $$
+  SELECT CASE
+    WHEN score IS NOT NULL AND score != 0 THEN score
+    WHEN stars IS NOT NULL THEN stars * 2.0
+    ELSE NULL
+  END;
+$$ LANGUAGE sql IMMUTABLE;
+DROP INDEX IF EXISTS idx_posts_rating;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS chk_rating;
+ALTER TABLE posts DROP COLUMN IF EXISTS rating;
+-- +goose StatementEnd
@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Producers: wineries, breweries and distilleries behind catalog beverages
+CREATE TABLE producers (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL,
+  name_normalized TEXT NOT NULL,
+  producer_type TEXT NOT NULL DEFAULT 'other' CHECK (producer_type IN ('winery', 'brewery', 'distillery', 'other')),
+
+  -- Location
+  city TEXT,
+  region TEXT,
+  country TEXT,
+  lat DOUBLE PRECISION,
+  lng DOUBLE PRECISION,
+
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+  CONSTRAINT chk_producer_name_len CHECK (char_length(name) > 0),
+  CONSTRAINT chk_producer_lat CHECK (lat IS NULL OR lat BETWEEN -90 AND 90),
+  CONSTRAINT chk_producer_lng CHECK (lng IS NULL OR lng BETWEEN -180 AND 180)
+);
+
+CREATE INDEX idx_producers_name_normalized ON producers(name_normalized);
+CREATE INDEX idx_producers_type ON producers(producer_type);
+
+CREATE TRIGGER trg_producers_updated_at
+BEFORE UPDATE ON producers
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Alternate spellings seen in beverages.brand, wine_post_details.winery and
+-- beer_post_details.brewery. Each normalized alias resolves to one producer
+-- of each type, so "Stone Brewing" and "Stone Estate" stay apart; the type
+-- is copied from the producer to enforce that.
+CREATE TABLE producer_aliases (
+  producer_id UUID NOT NULL REFERENCES producers(id) ON DELETE CASCADE,
+  producer_type TEXT NOT NULL,
+  alias TEXT NOT NULL,
+  alias_normalized TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (producer_id, alias)
+);
+
+CREATE UNIQUE INDEX uniq_producer_aliases_normalized ON producer_aliases(producer_type, alias_normalized);
+
+ALTER TABLE beverages ADD COLUMN producer_id UUID REFERENCES producers(id) ON DELETE SET NULL;
+CREATE INDEX idx_beverages_producer_id ON beverages(producer_id) WHERE producer_id IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_beverages_producer_id;
+ALTER TABLE beverages DROP COLUMN IF EXISTS producer_id;
+
+DROP INDEX IF EXISTS uniq_producer_aliases_normalized;
+DROP TABLE IF EXISTS producer_aliases;
+
+DROP TRIGGER IF EXISTS trg_producers_updated_at ON producers;
+DROP TABLE IF EXISTS producers;
@@ -0,0 +1,82 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Canonical attributes on the catalog row. These mirror the winning value in
+-- beverage_attributes so they can be filtered on directly.
+ALTER TABLE beverages
+  ADD COLUMN abv NUMERIC(4,2),
+  ADD COLUMN ibu INT,
+  ADD COLUMN style TEXT,
+  ADD COLUMN varietal TEXT,
+  ADD COLUMN region TEXT,
+  ADD COLUMN attributes_computed_at TIMESTAMPTZ;
+
+CREATE INDEX idx_beverages_style ON beverages(category, style) WHERE style IS NOT NULL;
+
+-- Per-field provenance for the canonical attributes
+CREATE TABLE beverage_attributes (
+  beverage_id UUID NOT NULL REFERENCES beverages(id) ON DELETE CASCADE,
+  attribute TEXT NOT NULL CHECK (attribute IN ('abv', 'ibu', 'style', 'varietal', 'region')),
+  value TEXT NOT NULL,
+  source TEXT NOT NULL CHECK (source IN ('consensus', 'curator')),
+  confidence NUMERIC(3,2) NOT NULL DEFAULT 0.0 CHECK (confidence >= 0.0 AND confidence <= 1.0),
+  sample_count INT NOT NULL DEFAULT 0,
+  updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (beverage_id, attribute)
+);
+
+CREATE TRIGGER trg_beverage_attributes_updated_at
+BEFORE UPDATE ON beverage_attributes
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- A post leaving a beverage takes its observations with it, but leaves no
+-- newer post or tag behind to flag the beverage for recompute, so the
+-- triggers below record it here
+CREATE TABLE beverage_attribute_invalidations (
+  beverage_id UUID PRIMARY KEY REFERENCES beverages(id) ON DELETE CASCADE,
+  invalidated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION invalidate_beverage_attributes()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF OLD.beverage_id IS NOT NULL THEN
+    INSERT INTO beverage_attribute_invalidations (beverage_id)
+    SELECT OLD.beverage_id
+    WHERE EXISTS (SELECT 1 FROM beverages WHERE id = OLD.beverage_id)
+    ON CONFLICT (beverage_id) DO UPDATE SET invalidated_at = now();
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_posts_attributes_delete
+AFTER DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION invalidate_beverage_attributes();
+
+CREATE TRIGGER trg_posts_attributes_move
+AFTER UPDATE OF beverage_id ON posts
+FOR EACH ROW
+WHEN (OLD.beverage_id IS DISTINCT FROM NEW.beverage_id)
+EXECUTE FUNCTION invalidate_beverage_attributes();
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS trg_posts_attributes_move ON posts;
+DROP TRIGGER IF EXISTS trg_posts_attributes_delete ON posts;
+DROP FUNCTION IF EXISTS invalidate_beverage_attributes;
+DROP TABLE IF EXISTS beverage_attribute_invalidations;
+
+DROP TRIGGER IF EXISTS trg_beverage_attributes_updated_at ON beverage_attributes;
+DROP TABLE IF EXISTS beverage_attributes;
+
+DROP INDEX IF EXISTS idx_beverages_style;
+ALTER TABLE beverages
+  DROP COLUMN IF EXISTS attributes_computed_at,
+  DROP COLUMN IF EXISTS region,
+  DROP COLUMN IF EXISTS varietal,
+  DROP COLUMN IF EXISTS style,
+  DROP COLUMN IF EXISTS ibu,
+  DROP COLUMN IF EXISTS abv;
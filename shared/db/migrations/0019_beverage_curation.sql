@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Alternate names curators attach to a beverage so posts and searches using
+-- them resolve to the canonical entry. Each normalized alias names one beverage.
+CREATE TABLE beverage_aliases (
+  beverage_id UUID NOT NULL REFERENCES beverages(id) ON DELETE CASCADE,
+  alias TEXT NOT NULL,
+  alias_normalized TEXT NOT NULL,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (beverage_id, alias_normalized),
+
+  CONSTRAINT chk_beverage_alias_len CHECK (char_length(alias) > 0)
+);
+
+CREATE UNIQUE INDEX uniq_beverage_aliases_normalized ON beverage_aliases(alias_normalized);
+
+-- Every curator change to a beverage. snapshot holds the full editable state
+-- after the change so any revision can be restored; diff holds old/new per field.
+CREATE TABLE beverage_revisions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  beverage_id UUID NOT NULL REFERENCES beverages(id) ON DELETE CASCADE,
+  revision INT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('baseline', 'edit', 'image', 'alias_add', 'alias_remove', 'revert')),
+  changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  reason TEXT NOT NULL,
+  diff JSONB NOT NULL DEFAULT '{}'::jsonb,
+  snapshot JSONB NOT NULL,
+  reverted_to INT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+  CONSTRAINT uniq_beverage_revision UNIQUE (beverage_id, revision)
+);
+
+CREATE INDEX idx_beverage_revisions_changed_by ON beverage_revisions(changed_by, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS beverage_revisions;
+DROP TABLE IF EXISTS beverage_aliases;
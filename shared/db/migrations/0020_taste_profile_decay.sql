@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Half-life for time decay of taste profiles, per category. A post this many
+-- days old counts half as much as one from today. NULL disables decay.
+CREATE TABLE taste_decay_settings (
+  category TEXT PRIMARY KEY CHECK (category IN ('wine', 'beer', 'cocktail')),
+  half_life_days INT CHECK (half_life_days IS NULL OR half_life_days > 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TRIGGER trg_taste_decay_settings_updated_at
+BEFORE UPDATE ON taste_decay_settings
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Beer tastes drift fastest; wine preferences are the most stable
+INSERT INTO taste_decay_settings (category, half_life_days) VALUES
+  ('beer', 180),
+  ('cocktail', 270),
+  ('wine', 540);
+
+-- liked/disliked tags and mean/std rating now hold the decayed ("recent")
+-- profile the ranker uses; the all_time_ columns keep the undecayed view.
+ALTER TABLE user_taste_profiles
+  ADD COLUMN all_time_liked_tags_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  ADD COLUMN all_time_disliked_tags_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  ADD COLUMN all_time_mean_rating NUMERIC(4,2),
+  ADD COLUMN all_time_std_rating NUMERIC(4,2),
+  ADD COLUMN half_life_days INT;
+
+UPDATE user_taste_profiles
+SET
+  all_time_liked_tags_json = liked_tags_json,
+  all_time_disliked_tags_json = disliked_tags_json,
+  all_time_mean_rating = mean_rating,
+  all_time_std_rating = std_rating;
+
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE user_taste_profiles
+  DROP COLUMN IF EXISTS half_life_days,
+  DROP COLUMN IF EXISTS all_time_std_rating,
+  DROP COLUMN IF EXISTS all_time_mean_rating,
+  DROP COLUMN IF EXISTS all_time_disliked_tags_json,
+  DROP COLUMN IF EXISTS all_time_liked_tags_json;
+
+DROP TRIGGER IF EXISTS trg_taste_decay_settings_updated_at ON taste_decay_settings;
+DROP TABLE IF EXISTS taste_decay_settings;
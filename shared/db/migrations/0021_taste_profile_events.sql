@@ -0,0 +1,120 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Posts whose taste profile contribution needs to be reapplied. Filled by
+-- triggers, drained by the recommendations profile updater.
+CREATE TABLE taste_profile_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  category TEXT NOT NULL,
+  post_id UUID NOT NULL,
+  reason TEXT NOT NULL CHECK (reason IN ('tagged', 'edited', 'deleted')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Sufficient statistics behind user_taste_profiles. Recent sums are decay
+-- weighted relative to as_of; all-time sums weight every post equally.
+CREATE TABLE user_taste_stats (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  category TEXT NOT NULL CHECK (category IN ('wine', 'beer', 'cocktail')),
+  half_life_days INT,
+  recent_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  all_time_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  as_of TIMESTAMPTZ NOT NULL DEFAULT now(),
+  full_recompute_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, category)
+);
+
+CREATE INDEX idx_user_taste_stats_full_recompute_at ON user_taste_stats(full_recompute_at);
+
+CREATE TRIGGER trg_user_taste_stats_updated_at
+BEFORE UPDATE ON user_taste_stats
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- What each post last added to the stats, so edits and deletes can take it back out.
+-- post_id has no foreign key: the row must outlive a deleted post.
+CREATE TABLE user_taste_contributions (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  category TEXT NOT NULL,
+  post_id UUID NOT NULL,
+  rating NUMERIC(4,2),
+  weight DOUBLE PRECISION NOT NULL,
+  tags_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  applied_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (user_id, category, post_id)
+);
+
+CREATE OR REPLACE FUNCTION queue_post_taste_event()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO taste_profile_events (user_id, category, post_id, reason)
+    VALUES (OLD.user_id, OLD.drink_category, OLD.id, 'deleted');
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO taste_profile_events (user_id, category, post_id, reason)
+  VALUES (OLD.user_id, OLD.drink_category, OLD.id, 'edited');
+
+  -- Moving a post to another user or category touches both profiles
+  IF OLD.user_id IS DISTINCT FROM NEW.user_id OR OLD.drink_category IS DISTINCT FROM NEW.drink_category THEN
+    INSERT INTO taste_profile_events (user_id, category, post_id, reason)
+    VALUES (NEW.user_id, NEW.drink_category, NEW.id, 'edited');
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- score and stars are listed because rating is set by a BEFORE trigger from them
+CREATE TRIGGER trg_posts_taste_event_update
+AFTER UPDATE OF rating, score, stars, user_id, drink_category, created_at ON posts
+FOR EACH ROW
+WHEN (OLD.rating IS DISTINCT FROM NEW.rating
+   OR OLD.user_id IS DISTINCT FROM NEW.user_id
+   OR OLD.drink_category IS DISTINCT FROM NEW.drink_category
+   OR OLD.created_at IS DISTINCT FROM NEW.created_at)
+EXECUTE FUNCTION queue_post_taste_event();
+
+CREATE TRIGGER trg_posts_taste_event_delete
+AFTER DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION queue_post_taste_event();
+
+-- Tags land when the post_tagging job finishes
+CREATE OR REPLACE FUNCTION queue_tagged_post_taste_event()
+RETURNS TRIGGER AS $$
+BEGIN
+  INSERT INTO taste_profile_events (user_id, category, post_id, reason)
+  SELECT p.user_id, p.drink_category, p.id, 'tagged'
+  FROM posts p
+  WHERE p.id = NEW.post_id;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_openai_jobs_taste_event
+AFTER UPDATE OF status ON openai_jobs
+FOR EACH ROW
+WHEN (NEW.job_type = 'post_tagging'
+  AND NEW.post_id IS NOT NULL
+  AND NEW.status = 'done'
+  AND OLD.status IS DISTINCT FROM 'done')
+EXECUTE FUNCTION queue_tagged_post_taste_event();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_openai_jobs_taste_event ON openai_jobs;
+DROP FUNCTION IF EXISTS queue_tagged_post_taste_event();
+DROP TRIGGER IF EXISTS trg_posts_taste_event_delete ON posts;
+DROP TRIGGER IF EXISTS trg_posts_taste_event_update ON posts;
+DROP FUNCTION IF EXISTS queue_post_taste_event();
+
+DROP TABLE IF EXISTS user_taste_contributions;
+DROP TRIGGER IF EXISTS trg_user_taste_stats_updated_at ON user_taste_stats;
+DROP TABLE IF EXISTS user_taste_stats;
+DROP TABLE IF EXISTS taste_profile_events;
+-- +goose StatementEnd
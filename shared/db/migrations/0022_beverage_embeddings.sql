@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Beverage embeddings (behind RECO_EMBEDDINGS_ENABLED, like user_embeddings).
+-- Vectors stay FLOAT4[]: a category holds a few thousand beverages, so
+-- similarity is computed in process rather than through pgvector.
+CREATE TABLE beverage_embeddings (
+  beverage_id UUID PRIMARY KEY REFERENCES beverages(id) ON DELETE CASCADE,
+  embedding_text TEXT NOT NULL,
+  embedding_vector FLOAT4[] NOT NULL,
+  model TEXT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_beverage_embeddings_model ON beverage_embeddings(model);
+
+CREATE TRIGGER trg_beverage_embeddings_updated_at
+BEFORE UPDATE ON beverage_embeddings
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS trg_beverage_embeddings_updated_at ON beverage_embeddings;
+DROP TABLE IF EXISTS beverage_embeddings;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Item-to-item neighbors from co-rating patterns in posts, rebuilt offline
+-- per category by recommendations.NeighborBuilder. similarity is the shrunk
+-- adjusted cosine of the two beverages' user-centered ratings.
+CREATE TABLE beverage_neighbors (
+  beverage_id UUID NOT NULL REFERENCES beverages(id) ON DELETE CASCADE,
+  neighbor_id UUID NOT NULL REFERENCES beverages(id) ON DELETE CASCADE,
+  similarity DOUBLE PRECISION NOT NULL,
+  co_raters INT NOT NULL,
+  computed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (beverage_id, neighbor_id)
+);
+
+CREATE INDEX idx_beverage_neighbors_similarity ON beverage_neighbors(beverage_id, similarity DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS beverage_neighbors;
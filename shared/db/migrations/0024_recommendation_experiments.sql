@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Every recommendation list served through an experiment, with the variant
+-- the user was bucketed into and the strategy that ranked it. beverage_ids
+-- is the list in served order; engagement is attributed by joining feedback
+-- and posts on it.
+CREATE TABLE recommendation_lists (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  category TEXT NOT NULL CHECK (category IN ('wine', 'beer', 'cocktail')),
+  experiment TEXT NOT NULL,
+  variant TEXT NOT NULL,
+  strategy TEXT NOT NULL,
+  beverage_ids UUID[] NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_recommendation_lists_experiment ON recommendation_lists(experiment, created_at);
+CREATE INDEX idx_recommendation_lists_user_id ON recommendation_lists(user_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS recommendation_lists;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Every beverage shown to a user by a recommendation surface. rank is the
+-- 1-based position in a recommendation list and NULL for single-beverage
+-- surfaces such as scan results. converted_post_id is the user's first post
+-- on the beverage within the attribution window after the impression.
+CREATE TABLE recommendation_impressions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  beverage_id UUID NOT NULL REFERENCES beverages(id) ON DELETE CASCADE,
+  surface TEXT NOT NULL CHECK (surface IN ('recommendations', 'scan')),
+  rank INT,
+  match_score INT NOT NULL,
+  list_id UUID REFERENCES recommendation_lists(id) ON DELETE SET NULL,
+  clicked_at TIMESTAMPTZ,
+  converted_post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+  converted_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_recommendation_impressions_user_beverage ON recommendation_impressions(user_id, beverage_id, created_at);
+CREATE INDEX idx_recommendation_impressions_created_at ON recommendation_impressions(surface, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS recommendation_impressions;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Cached taste match between two users, one row per pair and category with
+-- user_id < other_user_id. The profiles' last_computed_at at the time of
+-- computing is kept so a row is recomputed once either profile changes.
+-- rating_agreement is NULL when the users co-rated too few beverages.
+CREATE TABLE taste_compatibility (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  other_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  category TEXT NOT NULL CHECK (category IN ('wine', 'beer', 'cocktail')),
+  score INT NOT NULL,
+  tag_similarity DOUBLE PRECISION NOT NULL,
+  rating_agreement DOUBLE PRECISION,
+  co_rated INT NOT NULL DEFAULT 0,
+  shared_tags_json JSONB NOT NULL DEFAULT '[]'::jsonb,
+  clashing_tags_json JSONB NOT NULL DEFAULT '[]'::jsonb,
+  profile_computed_at TIMESTAMPTZ NOT NULL,
+  other_profile_computed_at TIMESTAMPTZ NOT NULL,
+  computed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, other_user_id, category),
+  CHECK (user_id < other_user_id)
+);
+
+CREATE INDEX idx_taste_compatibility_other_user_id ON taste_compatibility(other_user_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS taste_compatibility;
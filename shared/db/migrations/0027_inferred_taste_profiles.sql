@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A user with no posts in a category gets a provisional profile transferred
+-- from their profiles in other categories. inferred_from lists the source
+-- categories; it is NULL on profiles built from the category's own posts.
+ALTER TABLE user_taste_profiles
+  ADD COLUMN inferred_from TEXT[];
+
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE user_taste_profiles
+  DROP COLUMN IF EXISTS inferred_from;
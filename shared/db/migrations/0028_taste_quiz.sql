@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Onboarding quiz answers. item_id is a beverage ID or a quiz statement ID;
+-- tags are what the item stood for when answered, so a beverage's tags
+-- changing later doesn't change the answer.
+CREATE TABLE taste_quiz_answers (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  category TEXT NOT NULL CHECK (category IN ('wine', 'beer', 'cocktail')),
+  item_id TEXT NOT NULL,
+  liked BOOLEAN NOT NULL,
+  tags TEXT[] NOT NULL,
+  answered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, category, item_id)
+);
+
+-- The share of a profile's recent tag weights that comes from quiz answers.
+-- It starts at 1 and fades to 0 as the user posts in the category.
+ALTER TABLE user_taste_profiles
+  ADD COLUMN quiz_weight DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE user_taste_profiles
+  DROP COLUMN IF EXISTS quiz_weight;
+DROP TABLE IF EXISTS taste_quiz_answers;
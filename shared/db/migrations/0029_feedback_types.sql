@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- already_tried: the user has had the beverage. It drops out of their
+-- recommendations like a posted beverage, without touching their taste,
+-- since there is no rating to learn from.
+-- not_available: the user can't get the beverage near them. It drops out of
+-- their recommendations for 30 days, except at venues where it has been
+-- posted, and doesn't touch their taste.
+ALTER TABLE recommendation_feedback
+  DROP CONSTRAINT recommendation_feedback_feedback_type_check;
+ALTER TABLE recommendation_feedback
+  ADD CONSTRAINT recommendation_feedback_feedback_type_check CHECK (feedback_type IN (
+    'more_like_this', 'less_like_this', 'hide', 'already_tried', 'not_available'
+  ));
+
+-- Profiles replay a user's feedback per category in order
+CREATE INDEX idx_recommendation_feedback_user_created ON recommendation_feedback(user_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_recommendation_feedback_user_created;
+DELETE FROM recommendation_feedback WHERE feedback_type IN ('already_tried', 'not_available');
+ALTER TABLE recommendation_feedback
+  DROP CONSTRAINT recommendation_feedback_feedback_type_check;
+ALTER TABLE recommendation_feedback
+  ADD CONSTRAINT recommendation_feedback_feedback_type_check CHECK (feedback_type IN ('more_like_this', 'less_like_this', 'hide'));
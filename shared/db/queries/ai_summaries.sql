@@ -58,6 +58,34 @@ ORDER BY count DESC, tag;
 -- name: DeleteBeverageTagAggregates :exec
 DELETE FROM beverage_tag_aggregates WHERE beverage_id = $1;
 
+-- name: RebuildBeverageTagAggregates :execrows
+-- Recounts every beverage's tags from post_tags, one per post tag like
+-- GetBeverageTags counts them for UpsertBeverageTagAggregate. Tags that no
+-- post has any more are dropped.
+WITH counted AS (
+  SELECT beverage_id, tag, tag_type, COUNT(*)::INT AS count
+  FROM post_tags
+  WHERE beverage_id IS NOT NULL
+  GROUP BY beverage_id, tag, tag_type
+),
+removed AS (
+  DELETE FROM beverage_tag_aggregates bta
+  WHERE NOT EXISTS (
+    SELECT 1 FROM counted c
+    WHERE c.beverage_id = bta.beverage_id
+      AND c.tag = bta.tag
+      AND c.tag_type = bta.tag_type
+  )
+)
+INSERT INTO beverage_tag_aggregates (beverage_id, tag, tag_type, count)
+SELECT beverage_id, tag, tag_type, count
+FROM counted
+ON CONFLICT (beverage_id, tag, tag_type)
+DO UPDATE SET
+  count = EXCLUDED.count,
+  updated_at = NOW()
+WHERE beverage_tag_aggregates.count <> EXCLUDED.count;
+
 -- name: CreateOpenAIJob :one
 INSERT INTO openai_jobs (job_type, beverage_id, post_id, status)
 VALUES ($1, $2, $3, 'queued')
@@ -0,0 +1,104 @@
+-- name: ListBeverageAttributeObservations :many
+-- Every per-post value for a beverage's attributes: the detail tables the
+-- user filled in, plus style/region/varietal tags extracted by OpenAI.
+SELECT 'abv'::TEXT AS attribute, bd.abv::TEXT AS value, 'post'::TEXT AS source, 1.0::FLOAT8 AS weight
+FROM posts p JOIN beer_post_details bd ON bd.id = p.beer_post_details_id
+WHERE p.beverage_id = $1 AND bd.abv IS NOT NULL
+UNION ALL
+SELECT 'ibu'::TEXT, bd.ibu::TEXT, 'post'::TEXT, 1.0::FLOAT8
+FROM posts p JOIN beer_post_details bd ON bd.id = p.beer_post_details_id
+WHERE p.beverage_id = $1 AND bd.ibu IS NOT NULL
+UNION ALL
+SELECT 'style'::TEXT, bd.beer_style, 'post'::TEXT, 1.0::FLOAT8
+FROM posts p JOIN beer_post_details bd ON bd.id = p.beer_post_details_id
+WHERE p.beverage_id = $1 AND bd.beer_style IS NOT NULL AND bd.beer_style != ''
+UNION ALL
+SELECT 'style'::TEXT, wd.wine_style, 'post'::TEXT, 1.0::FLOAT8
+FROM posts p JOIN wine_post_details wd ON wd.id = p.wine_post_details_id
+WHERE p.beverage_id = $1 AND wd.wine_style IS NOT NULL AND wd.wine_style != ''
+UNION ALL
+SELECT 'varietal'::TEXT, wd.varietal, 'post'::TEXT, 1.0::FLOAT8
+FROM posts p JOIN wine_post_details wd ON wd.id = p.wine_post_details_id
+WHERE p.beverage_id = $1 AND wd.varietal IS NOT NULL AND wd.varietal != ''
+UNION ALL
+SELECT 'region'::TEXT, wd.region, 'post'::TEXT, 1.0::FLOAT8
+FROM posts p JOIN wine_post_details wd ON wd.id = p.wine_post_details_id
+WHERE p.beverage_id = $1 AND wd.region IS NOT NULL AND wd.region != ''
+UNION ALL
+SELECT 'style'::TEXT, cd.cocktail_family, 'post'::TEXT, 1.0::FLOAT8
+FROM posts p JOIN cocktail_post_details cd ON cd.id = p.cocktail_post_details_id
+WHERE p.beverage_id = $1 AND cd.cocktail_family IS NOT NULL AND cd.cocktail_family != ''
+UNION ALL
+SELECT pt.tag_type, pt.tag, 'ai'::TEXT, COALESCE(pt.confidence, 1.0)::FLOAT8
+FROM post_tags pt JOIN posts p ON p.id = pt.post_id
+WHERE p.beverage_id = $1 AND pt.tag_type IN ('style', 'region', 'varietal');
+
+-- name: GetBeverageAttributes :many
+SELECT * FROM beverage_attributes
+WHERE beverage_id = $1
+ORDER BY attribute;
+
+-- name: UpsertConsensusAttribute :exec
+-- Curator overrides always win, so consensus never replaces them
+INSERT INTO beverage_attributes (beverage_id, attribute, value, source, confidence, sample_count)
+VALUES ($1, $2, $3, 'consensus', $4, $5)
+ON CONFLICT (beverage_id, attribute)
+DO UPDATE SET
+  value = EXCLUDED.value,
+  confidence = EXCLUDED.confidence,
+  sample_count = EXCLUDED.sample_count,
+  updated_by = NULL
+WHERE beverage_attributes.source = 'consensus';
+
+-- name: DeleteConsensusAttribute :exec
+DELETE FROM beverage_attributes
+WHERE beverage_id = $1 AND attribute = $2 AND source = 'consensus';
+
+-- name: SetCuratorAttribute :one
+INSERT INTO beverage_attributes (beverage_id, attribute, value, source, confidence, sample_count, updated_by)
+VALUES ($1, $2, $3, 'curator', 1.0, 0, $4)
+ON CONFLICT (beverage_id, attribute)
+DO UPDATE SET
+  value = EXCLUDED.value,
+  source = 'curator',
+  confidence = 1.0,
+  sample_count = 0,
+  updated_by = EXCLUDED.updated_by
+RETURNING *;
+
+-- name: DeleteCuratorAttribute :exec
+DELETE FROM beverage_attributes
+WHERE beverage_id = $1 AND attribute = $2 AND source = 'curator';
+
+-- name: SyncBeverageAttributeColumns :exec
+-- Copies the winning attribute values onto the beverages row
+UPDATE beverages b
+SET
+  abv = (SELECT ba.value::NUMERIC(4,2) FROM beverage_attributes ba WHERE ba.beverage_id = b.id AND ba.attribute = 'abv'),
+  ibu = (SELECT ba.value::INT FROM beverage_attributes ba WHERE ba.beverage_id = b.id AND ba.attribute = 'ibu'),
+  style = (SELECT ba.value FROM beverage_attributes ba WHERE ba.beverage_id = b.id AND ba.attribute = 'style'),
+  varietal = (SELECT ba.value FROM beverage_attributes ba WHERE ba.beverage_id = b.id AND ba.attribute = 'varietal'),
+  region = (SELECT ba.value FROM beverage_attributes ba WHERE ba.beverage_id = b.id AND ba.attribute = 'region'),
+  attributes_computed_at = NOW()
+WHERE b.id = $1;
+
+-- name: ListBeveragesNeedingAttributes :many
+-- Beverages with posts or extracted tags newer than their last attribute
+-- computation, or that lost a post since
+SELECT b.id FROM beverages b
+WHERE EXISTS (
+  SELECT 1 FROM beverage_attribute_invalidations i
+  WHERE i.beverage_id = b.id
+    AND (b.attributes_computed_at IS NULL OR i.invalidated_at > b.attributes_computed_at)
+) OR EXISTS (
+  SELECT 1 FROM posts p
+  WHERE p.beverage_id = b.id
+    AND (b.attributes_computed_at IS NULL OR p.updated_at > b.attributes_computed_at)
+) OR EXISTS (
+  SELECT 1 FROM post_tags pt
+  WHERE pt.beverage_id = b.id
+    AND pt.tag_type IN ('style', 'region', 'varietal')
+    AND pt.created_at > b.attributes_computed_at
+)
+ORDER BY b.attributes_computed_at NULLS FIRST
+LIMIT $1;
@@ -13,6 +13,10 @@ SELECT
   (
     -- Exact name match (highest priority)
     CASE WHEN b.name_normalized = $1 THEN 100 ELSE 0 END +
+    -- Exact curator alias match
+    CASE WHEN EXISTS (
+      SELECT 1 FROM beverage_aliases ba WHERE ba.beverage_id = b.id AND ba.alias_normalized = $1
+    ) THEN 90 ELSE 0 END +
     -- Name prefix match
     CASE WHEN b.name_normalized LIKE $1 || '%' THEN 50 ELSE 0 END +
     -- Name contains match
@@ -33,6 +37,9 @@ WHERE
   (
     b.name_normalized LIKE '%' || $1 || '%' OR
     b.brand_normalized LIKE '%' || $2 || '%' OR
+    EXISTS (
+      SELECT 1 FROM beverage_aliases ba WHERE ba.beverage_id = b.id AND ba.alias_normalized LIKE '%' || $1 || '%'
+    ) OR
     ($3::TEXT IS NOT NULL AND b.vintage = $3::TEXT)
   )
 ORDER BY match_score DESC, b.avg_rating DESC
@@ -51,14 +58,47 @@ SELECT p.* FROM posts p
 WHERE
   (p.drink_name = $1 OR LOWER(p.drink_name) = $2) AND
   ($3::TEXT IS NULL OR p.drink_category = $3) AND
-  p.score IS NOT NULL
-ORDER BY p.score DESC, p.created_at DESC
+  p.rating IS NOT NULL
+ORDER BY p.rating DESC, p.created_at DESC
 LIMIT $4;
 
--- name: UpdateBeverageStats :exec
+-- name: ReconcileBeverageStats :many
+-- Recomputes stats from posts for every beverage whose stored totals have
+-- drifted and returns the stored values alongside the corrected ones.
+WITH actual AS (
+  SELECT
+    b.id,
+    COUNT(p.id)::INT AS total_reviews,
+    COUNT(p.rating)::INT AS rating_count,
+    COALESCE(SUM(p.rating), 0)::NUMERIC(12,1) AS rating_sum
+  FROM beverages b
+  LEFT JOIN posts p ON p.beverage_id = b.id
+  GROUP BY b.id
+),
+drifted AS (
+  SELECT
+    b.id,
+    b.total_reviews AS stored_total_reviews,
+    b.avg_rating AS stored_avg_rating,
+    a.total_reviews,
+    a.rating_count,
+    a.rating_sum,
+    CASE WHEN a.rating_count > 0 THEN ROUND(a.rating_sum / a.rating_count, 2) ELSE 0 END AS avg_rating
+  FROM beverages b
+  JOIN actual a ON a.id = b.id
+)
 UPDATE beverages
 SET
-  total_reviews = $2,
-  avg_rating = $3,
-  updated_at = NOW()
-WHERE id = $1;
+  total_reviews = d.total_reviews,
+  rating_count = d.rating_count,
+  rating_sum = d.rating_sum,
+  avg_rating = d.avg_rating
+FROM drifted d
+WHERE beverages.id = d.id
+  AND (
+    beverages.total_reviews IS DISTINCT FROM d.total_reviews OR
+    beverages.rating_count != d.rating_count OR
+    beverages.rating_sum != d.rating_sum OR
+    beverages.avg_rating IS DISTINCT FROM d.avg_rating
+  )
+RETURNING beverages.id, beverages.name, d.stored_total_reviews, d.stored_avg_rating, beverages.total_reviews, beverages.avg_rating;
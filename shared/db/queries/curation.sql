@@ -0,0 +1,54 @@
+-- name: GetBeverageForUpdate :one
+SELECT * FROM beverages WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateBeverageDetails :one
+UPDATE beverages
+SET
+  name = $2,
+  brand = $3,
+  category = $4,
+  vintage = $5,
+  image_url = $6,
+  name_normalized = $7,
+  brand_normalized = $8
+WHERE id = $1
+RETURNING *;
+
+-- name: ListBeverageAliases :many
+SELECT * FROM beverage_aliases
+WHERE beverage_id = $1
+ORDER BY alias;
+
+-- name: CreateBeverageAlias :one
+INSERT INTO beverage_aliases (beverage_id, alias, alias_normalized, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: DeleteBeverageAlias :execrows
+DELETE FROM beverage_aliases
+WHERE beverage_id = $1 AND alias_normalized = $2;
+
+-- name: DeleteBeverageAliases :exec
+DELETE FROM beverage_aliases WHERE beverage_id = $1;
+
+-- name: CreateBeverageRevision :one
+INSERT INTO beverage_revisions (beverage_id, revision, action, changed_by, reason, diff, snapshot, reverted_to)
+VALUES (
+  $1,
+  (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM beverage_revisions r WHERE r.beverage_id = $1),
+  $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetBeverageRevision :one
+SELECT * FROM beverage_revisions
+WHERE beverage_id = $1 AND revision = $2;
+
+-- name: ListBeverageRevisions :many
+SELECT * FROM beverage_revisions
+WHERE beverage_id = $1
+ORDER BY revision DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountBeverageRevisions :one
+SELECT COUNT(*) FROM beverage_revisions WHERE beverage_id = $1;
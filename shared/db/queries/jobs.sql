@@ -0,0 +1,18 @@
+-- name: TryAdvisoryLock :one
+-- Takes a session-level advisory lock without waiting; false when another
+-- session holds it
+SELECT pg_try_advisory_lock(sqlc.arg(key)::BIGINT);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock(sqlc.arg(key)::BIGINT);
+
+-- name: HoldsAdvisoryLock :one
+-- Whether this session still holds the advisory lock on a non-negative key
+SELECT EXISTS (
+  SELECT 1 FROM pg_locks
+  WHERE locktype = 'advisory'
+    AND pid = pg_backend_pid()
+    AND granted
+    AND objsubid = 1
+    AND ((classid::BIGINT << 32) | objid::BIGINT) = sqlc.arg(key)::BIGINT
+) AS held;
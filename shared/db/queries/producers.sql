@@ -0,0 +1,95 @@
+-- name: CreateProducer :one
+INSERT INTO producers (name, name_normalized, producer_type, city, region, country, lat, lng)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetProducerByID :one
+SELECT * FROM producers WHERE id = $1;
+
+-- name: GetProducerByAlias :one
+SELECT p.* FROM producers p
+JOIN producer_aliases pa ON pa.producer_id = p.id
+WHERE pa.producer_type = $1 AND pa.alias_normalized = $2;
+
+-- name: CreateProducerAlias :execrows
+-- Adds an alias unless it is already known; returns 0 when it was
+INSERT INTO producer_aliases (producer_id, producer_type, alias, alias_normalized)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+
+-- name: GetProducerAliases :many
+SELECT alias FROM producer_aliases
+WHERE producer_id = $1
+ORDER BY alias;
+
+-- name: SearchProducers :many
+SELECT
+  p.*,
+  (
+    CASE WHEN p.name_normalized = sqlc.arg(query)::TEXT THEN 100 ELSE 0 END +
+    CASE WHEN p.name_normalized LIKE sqlc.arg(query)::TEXT || '%' THEN 50 ELSE 0 END +
+    CASE WHEN p.name_normalized LIKE '%' || sqlc.arg(query)::TEXT || '%' THEN 25 ELSE 0 END +
+    CASE WHEN EXISTS (
+      SELECT 1 FROM producer_aliases pa
+      WHERE pa.producer_id = p.id AND pa.alias_normalized LIKE '%' || sqlc.arg(query)::TEXT || '%'
+    ) THEN 20 ELSE 0 END
+  ) AS match_score
+FROM producers p
+WHERE
+  (sqlc.arg(producer_type)::TEXT = '' OR p.producer_type = sqlc.arg(producer_type)::TEXT) AND
+  (
+    p.name_normalized LIKE '%' || sqlc.arg(query)::TEXT || '%' OR
+    EXISTS (
+      SELECT 1 FROM producer_aliases pa
+      WHERE pa.producer_id = p.id AND pa.alias_normalized LIKE '%' || sqlc.arg(query)::TEXT || '%'
+    )
+  )
+ORDER BY match_score DESC, p.name
+LIMIT sqlc.arg('limit');
+
+-- name: ListProducerBeverages :many
+SELECT * FROM beverages
+WHERE producer_id = $1
+ORDER BY avg_rating DESC, total_reviews DESC
+LIMIT $2;
+
+-- name: GetProducerRatingSummary :one
+SELECT
+  COUNT(*)::INT AS beverage_count,
+  COALESCE(SUM(total_reviews), 0)::INT AS total_reviews,
+  COALESCE(SUM(rating_count), 0)::INT AS rating_count,
+  (CASE WHEN SUM(rating_count) > 0 THEN ROUND(SUM(rating_sum) / SUM(rating_count), 2) ELSE 0 END)::NUMERIC(4,2) AS avg_rating
+FROM beverages
+WHERE producer_id = $1;
+
+-- name: GetProducerTopTags :many
+SELECT bta.tag, bta.tag_type, SUM(bta.count)::INT AS count
+FROM beverage_tag_aggregates bta
+JOIN beverages b ON b.id = bta.beverage_id
+WHERE b.producer_id = $1
+GROUP BY bta.tag, bta.tag_type
+ORDER BY count DESC, bta.tag
+LIMIT $2;
+
+-- name: ListUnlinkedProducerNames :many
+-- Raw producer strings for beverages that have no producer yet
+SELECT b.id AS beverage_id, b.category, b.brand::TEXT AS raw_name, 'brand'::TEXT AS source
+FROM beverages b
+WHERE b.producer_id IS NULL AND b.brand IS NOT NULL AND b.brand != ''
+UNION ALL
+SELECT b.id AS beverage_id, b.category, w.winery::TEXT AS raw_name, 'winery'::TEXT AS source
+FROM posts p
+JOIN beverages b ON b.id = p.beverage_id
+JOIN wine_post_details w ON w.id = p.wine_post_details_id
+WHERE b.producer_id IS NULL AND w.winery IS NOT NULL AND w.winery != ''
+UNION ALL
+SELECT b.id AS beverage_id, b.category, bd.brewery::TEXT AS raw_name, 'brewery'::TEXT AS source
+FROM posts p
+JOIN beverages b ON b.id = p.beverage_id
+JOIN beer_post_details bd ON bd.id = p.beer_post_details_id
+WHERE b.producer_id IS NULL AND bd.brewery IS NOT NULL AND bd.brewery != '';
+
+-- name: SetBeverageProducer :exec
+UPDATE beverages
+SET producer_id = $2, updated_at = NOW()
+WHERE id = $1;
@@ -7,9 +7,12 @@ WHERE user_id = $1 AND category = $2;
 -- name: UpsertUserTasteProfile :one
 INSERT INTO user_taste_profiles (
   user_id, category, liked_tags_json, disliked_tags_json,
-  mean_rating, std_rating, post_count, last_computed_at
+  mean_rating, std_rating, post_count, last_computed_at,
+  all_time_liked_tags_json, all_time_disliked_tags_json,
+  all_time_mean_rating, all_time_std_rating, half_life_days, inferred_from,
+  quiz_weight
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+VALUES ($1, $2, $3, $4, $5, $6, $7, now(), $8, $9, $10, $11, $12, $13, $14)
 ON CONFLICT (user_id, category)
 DO UPDATE SET
   liked_tags_json = EXCLUDED.liked_tags_json,
@@ -17,7 +20,29 @@ DO UPDATE SET
   mean_rating = EXCLUDED.mean_rating,
   std_rating = EXCLUDED.std_rating,
   post_count = EXCLUDED.post_count,
-  last_computed_at = EXCLUDED.last_computed_at
+  last_computed_at = EXCLUDED.last_computed_at,
+  all_time_liked_tags_json = EXCLUDED.all_time_liked_tags_json,
+  all_time_disliked_tags_json = EXCLUDED.all_time_disliked_tags_json,
+  all_time_mean_rating = EXCLUDED.all_time_mean_rating,
+  all_time_std_rating = EXCLUDED.all_time_std_rating,
+  half_life_days = EXCLUDED.half_life_days,
+  inferred_from = EXCLUDED.inferred_from,
+  quiz_weight = EXCLUDED.quiz_weight
+RETURNING *;
+
+-- name: GetTasteDecaySetting :one
+SELECT * FROM taste_decay_settings
+WHERE category = $1;
+
+-- name: ListTasteDecaySettings :many
+SELECT * FROM taste_decay_settings
+ORDER BY category;
+
+-- name: UpsertTasteDecaySetting :one
+INSERT INTO taste_decay_settings (category, half_life_days)
+VALUES ($1, $2)
+ON CONFLICT (category)
+DO UPDATE SET half_life_days = EXCLUDED.half_life_days
 RETURNING *;
 
 -- name: GetUserPostsForCategory :many
@@ -58,38 +83,54 @@ WHERE user_id = $1 AND feedback_type = 'hide';
 DELETE FROM recommendation_feedback
 WHERE user_id = $1 AND beverage_id = $2 AND feedback_type = $3;
 
+-- name: GetUserFeedbackForCategory :many
+-- A user's feedback on beverages in a category, oldest first, for profiles to replay
+SELECT rf.* FROM recommendation_feedback rf
+JOIN beverages b ON b.id = rf.beverage_id
+WHERE rf.user_id = $1 AND b.category = $2
+ORDER BY rf.created_at, rf.id;
+
 -- Recommendation Candidates
 
 -- name: GetRecommendationCandidates :many
 SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
-       COUNT(DISTINCT p.id) AS review_count,
-       COALESCE(AVG(
-         CASE
-           WHEN p.score IS NOT NULL AND p.score != 0 THEN p.score
-           WHEN p.stars IS NOT NULL THEN p.stars * 2.0
-           ELSE NULL
-         END
-       ), 0) AS avg_rating
+       b.total_reviews AS review_count,
+       b.avg_rating, b.producer_id, b.style
 FROM beverages b
-LEFT JOIN posts p ON b.id = p.beverage_id
-WHERE b.category = $1
+WHERE b.category = sqlc.arg(category)
   AND b.id NOT IN (
     SELECT rf.beverage_id FROM recommendation_feedback rf
-    WHERE rf.user_id = $2 AND rf.feedback_type = 'hide'
+    WHERE rf.user_id = sqlc.arg(user_id)
+      AND (rf.feedback_type IN ('hide', 'already_tried')
+        OR (rf.feedback_type = 'not_available' AND rf.created_at > now() - interval '30 days'))
   )
   AND b.id NOT IN (
     SELECT p2.beverage_id FROM posts p2
-    WHERE p2.user_id = $2 AND p2.beverage_id IS NOT NULL
+    WHERE p2.user_id = sqlc.arg(user_id) AND p2.beverage_id IS NOT NULL
     ORDER BY p2.created_at DESC
     LIMIT 20
   )
-GROUP BY b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at
-HAVING COUNT(DISTINCT p.id) >= 2 OR EXISTS (
-  SELECT 1 FROM beverage_tag_aggregates bta
-  WHERE bta.beverage_id = b.id
-)
-ORDER BY review_count DESC, avg_rating DESC
-LIMIT $3;
+  AND (b.total_reviews >= 2 OR EXISTS (
+    SELECT 1 FROM beverage_tag_aggregates bta
+    WHERE bta.beverage_id = b.id
+  ))
+  AND COALESCE(b.total_reviews, 0) >= sqlc.arg(min_reviews)::int
+  AND (sqlc.narg(min_abv)::numeric IS NULL OR b.abv >= sqlc.narg(min_abv)::numeric)
+  AND (sqlc.narg(max_abv)::numeric IS NULL OR b.abv <= sqlc.narg(max_abv)::numeric)
+  AND (cardinality(sqlc.arg(styles)::text[]) = 0 OR lower(b.style) = ANY(sqlc.arg(styles)::text[]))
+  AND (cardinality(sqlc.arg(regions)::text[]) = 0 OR lower(b.region) = ANY(sqlc.arg(regions)::text[]))
+  AND (cardinality(sqlc.arg(varietals)::text[]) = 0 OR lower(b.varietal) = ANY(sqlc.arg(varietals)::text[]))
+  AND (NOT sqlc.arg(exclude_tried)::bool OR NOT EXISTS (
+    SELECT 1 FROM posts pt
+    WHERE pt.user_id = sqlc.arg(user_id) AND pt.beverage_id = b.id
+  ))
+  AND ((sqlc.narg(min_price_cents)::int IS NULL AND sqlc.narg(max_price_cents)::int IS NULL) OR (
+    SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY pp.price_cents)
+    FROM posts pp
+    WHERE pp.beverage_id = b.id AND pp.price_cents IS NOT NULL
+  ) BETWEEN COALESCE(sqlc.narg(min_price_cents)::int, 0) AND COALESCE(sqlc.narg(max_price_cents)::int, 2147483647))
+ORDER BY b.total_reviews DESC, b.avg_rating DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetBeverageWithTags :one
 SELECT b.*,
@@ -108,8 +149,51 @@ LEFT JOIN beverage_tag_aggregates bta ON b.id = bta.beverage_id
 WHERE b.id = $1
 GROUP BY b.id;
 
+-- name: GetTagsForBeverages :many
+-- Tag aggregates for a batch of beverages, so candidates can be scored in one round trip
+SELECT beverage_id, tag, tag_type, count
+FROM beverage_tag_aggregates
+WHERE beverage_id = ANY($1::UUID[])
+ORDER BY beverage_id, count DESC, tag;
+
 -- User Embeddings (optional)
 
+-- name: GetRecommendationCandidatesByIDs :many
+-- Candidates found outside the popularity query, with the same exclusions
+SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
+       b.total_reviews AS review_count,
+       b.avg_rating, b.producer_id, b.style
+FROM beverages b
+WHERE b.category = sqlc.arg(category)
+  AND b.id = ANY(sqlc.arg(ids)::UUID[])
+  AND b.id NOT IN (
+    SELECT rf.beverage_id FROM recommendation_feedback rf
+    WHERE rf.user_id = sqlc.arg(user_id)
+      AND (rf.feedback_type IN ('hide', 'already_tried')
+        OR (rf.feedback_type = 'not_available' AND rf.created_at > now() - interval '30 days'))
+  )
+  AND b.id NOT IN (
+    SELECT p2.beverage_id FROM posts p2
+    WHERE p2.user_id = sqlc.arg(user_id) AND p2.beverage_id IS NOT NULL
+    ORDER BY p2.created_at DESC
+    LIMIT 20
+  )
+  AND COALESCE(b.total_reviews, 0) >= sqlc.arg(min_reviews)::int
+  AND (sqlc.narg(min_abv)::numeric IS NULL OR b.abv >= sqlc.narg(min_abv)::numeric)
+  AND (sqlc.narg(max_abv)::numeric IS NULL OR b.abv <= sqlc.narg(max_abv)::numeric)
+  AND (cardinality(sqlc.arg(styles)::text[]) = 0 OR lower(b.style) = ANY(sqlc.arg(styles)::text[]))
+  AND (cardinality(sqlc.arg(regions)::text[]) = 0 OR lower(b.region) = ANY(sqlc.arg(regions)::text[]))
+  AND (cardinality(sqlc.arg(varietals)::text[]) = 0 OR lower(b.varietal) = ANY(sqlc.arg(varietals)::text[]))
+  AND (NOT sqlc.arg(exclude_tried)::bool OR NOT EXISTS (
+    SELECT 1 FROM posts pt
+    WHERE pt.user_id = sqlc.arg(user_id) AND pt.beverage_id = b.id
+  ))
+  AND ((sqlc.narg(min_price_cents)::int IS NULL AND sqlc.narg(max_price_cents)::int IS NULL) OR (
+    SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY pp.price_cents)
+    FROM posts pp
+    WHERE pp.beverage_id = b.id AND pp.price_cents IS NOT NULL
+  ) BETWEEN COALESCE(sqlc.narg(min_price_cents)::int, 0) AND COALESCE(sqlc.narg(max_price_cents)::int, 2147483647));
+
 -- name: GetUserEmbedding :one
 SELECT * FROM user_embeddings
 WHERE user_id = $1 AND category = $2;
@@ -123,3 +207,446 @@ DO UPDATE SET
   embedding_vector = EXCLUDED.embedding_vector,
   model = EXCLUDED.model
 RETURNING *;
+
+-- name: ListTasteProfileEvents :many
+-- The oldest queued events. They stay queued until DeleteTasteProfileEvents
+-- removes them in the transaction that applies them.
+SELECT * FROM taste_profile_events
+ORDER BY id
+LIMIT $1;
+
+-- name: DeleteTasteProfileEvents :execrows
+-- Claims a post's queued events up to up_to; concurrent workers skip each
+-- other's rows, so none claimed means another worker has them
+DELETE FROM taste_profile_events
+WHERE id IN (
+  SELECT id FROM taste_profile_events e
+  WHERE e.user_id = $1 AND e.category = $2 AND e.post_id = $3 AND e.id <= sqlc.arg(up_to)
+  FOR UPDATE SKIP LOCKED
+);
+
+-- name: GetPostForTasteProfile :many
+SELECT p.*, pt.tag, pt.tag_type, pt.confidence
+FROM posts p
+LEFT JOIN post_tags pt ON p.id = pt.post_id
+WHERE p.id = $1;
+
+-- name: GetUserTasteStatsForUpdate :one
+SELECT * FROM user_taste_stats
+WHERE user_id = $1 AND category = $2
+FOR UPDATE;
+
+-- name: UpsertUserTasteStats :exec
+INSERT INTO user_taste_stats (
+  user_id, category, half_life_days, recent_json, all_time_json, as_of, full_recompute_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, category)
+DO UPDATE SET
+  half_life_days = EXCLUDED.half_life_days,
+  recent_json = EXCLUDED.recent_json,
+  all_time_json = EXCLUDED.all_time_json,
+  as_of = EXCLUDED.as_of,
+  full_recompute_at = EXCLUDED.full_recompute_at;
+
+-- name: GetTasteContribution :one
+SELECT * FROM user_taste_contributions
+WHERE user_id = $1 AND category = $2 AND post_id = $3;
+
+-- name: UpsertTasteContribution :exec
+INSERT INTO user_taste_contributions (
+  user_id, category, post_id, rating, weight, tags_json, applied_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, category, post_id)
+DO UPDATE SET
+  rating = EXCLUDED.rating,
+  weight = EXCLUDED.weight,
+  tags_json = EXCLUDED.tags_json,
+  applied_at = EXCLUDED.applied_at;
+
+-- name: DeleteTasteContribution :exec
+DELETE FROM user_taste_contributions
+WHERE user_id = $1 AND category = $2 AND post_id = $3;
+
+-- name: DeleteTasteContributions :exec
+DELETE FROM user_taste_contributions
+WHERE user_id = $1 AND category = $2;
+
+-- name: ListTasteProfilesDueForRecompute :many
+-- Profiles whose stats are missing or were last rebuilt before the cutoff
+SELECT utp.user_id, utp.category
+FROM user_taste_profiles utp
+LEFT JOIN user_taste_stats s ON s.user_id = utp.user_id AND s.category = utp.category
+WHERE s.user_id IS NULL OR s.full_recompute_at < $1
+ORDER BY s.full_recompute_at NULLS FIRST
+LIMIT $2;
+
+-- name: ListTasteProfilesUpdatedBefore :many
+-- Profiles nothing has touched since the cutoff, oldest first
+SELECT user_id, category
+FROM user_taste_profiles
+WHERE updated_at < $1
+ORDER BY updated_at
+LIMIT $2;
+
+-- name: GetBeverageEmbeddingsByCategory :many
+SELECT be.beverage_id, be.embedding_vector
+FROM beverage_embeddings be
+JOIN beverages b ON b.id = be.beverage_id
+WHERE b.category = $1 AND be.model = $2;
+
+-- name: UpsertBeverageEmbedding :exec
+INSERT INTO beverage_embeddings (beverage_id, embedding_text, embedding_vector, model)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (beverage_id)
+DO UPDATE SET
+  embedding_text = EXCLUDED.embedding_text,
+  embedding_vector = EXCLUDED.embedding_vector,
+  model = EXCLUDED.model;
+
+-- name: ListBeveragesNeedingEmbeddings :many
+-- Beverages with no embedding for the model, or whose details or tags changed since
+SELECT b.id, b.name, b.brand, b.category, b.style, b.varietal, b.region,
+       be.embedding_text AS current_text, be.model AS current_model
+FROM beverages b
+LEFT JOIN beverage_embeddings be ON be.beverage_id = b.id
+WHERE be.beverage_id IS NULL
+   OR be.model <> $1
+   OR be.updated_at < b.updated_at
+   OR EXISTS (
+     SELECT 1 FROM beverage_tag_aggregates bta
+     WHERE bta.beverage_id = b.id AND bta.updated_at > be.updated_at
+   )
+ORDER BY b.total_reviews DESC NULLS LAST
+LIMIT $2;
+
+-- name: ListUserEmbeddingsToRefresh :many
+-- Taste profiles with no embedding for the model, or updated since it was built
+SELECT utp.user_id, utp.category, utp.liked_tags_json,
+       ue.embedding_text AS current_text, ue.model AS current_model
+FROM user_taste_profiles utp
+LEFT JOIN user_embeddings ue ON ue.user_id = utp.user_id AND ue.category = utp.category
+WHERE ue.user_id IS NULL
+   OR ue.model IS DISTINCT FROM $1
+   OR ue.updated_at < utp.updated_at
+ORDER BY utp.updated_at
+LIMIT $2;
+
+-- name: TouchBeverageEmbedding :exec
+-- Marks an embedding current when its source text has not changed
+UPDATE beverage_embeddings SET updated_at = now()
+WHERE beverage_id = $1;
+
+-- name: TouchUserEmbedding :exec
+UPDATE user_embeddings SET updated_at = now()
+WHERE user_id = $1 AND category = $2;
+
+-- Beverage Neighbors
+
+-- name: ListCoRatingInputs :many
+-- One averaged rating per user and beverage in a category
+SELECT p.user_id, p.beverage_id, AVG(p.rating)::float8 AS rating
+FROM posts p
+WHERE p.drink_category = $1
+  AND p.beverage_id IS NOT NULL
+  AND p.rating IS NOT NULL
+GROUP BY p.user_id, p.beverage_id
+ORDER BY p.user_id;
+
+-- name: DeleteBeverageNeighborsForCategory :exec
+DELETE FROM beverage_neighbors
+WHERE beverage_id IN (SELECT id FROM beverages WHERE category = $1);
+
+-- name: InsertBeverageNeighbor :exec
+INSERT INTO beverage_neighbors (beverage_id, neighbor_id, similarity, co_raters)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetBeverageNeighbors :many
+SELECT b.id, b.name, b.brand, b.category,
+       b.total_reviews AS review_count,
+       b.avg_rating,
+       bn.similarity, bn.co_raters
+FROM beverage_neighbors bn
+JOIN beverages b ON b.id = bn.neighbor_id
+WHERE bn.beverage_id = $1
+ORDER BY bn.similarity DESC
+LIMIT $2;
+
+-- name: GetUserNeighborScores :many
+-- Neighbors of the beverages a user rated at or above their own average,
+-- with the liked beverage that contributes most to each
+WITH rated AS (
+  SELECT p.beverage_id, AVG(p.rating) AS rating
+  FROM posts p
+  WHERE p.user_id = $1
+    AND p.drink_category = $2
+    AND p.beverage_id IS NOT NULL
+    AND p.rating IS NOT NULL
+  GROUP BY p.beverage_id
+),
+liked AS (
+  SELECT beverage_id FROM rated
+  WHERE rating >= (SELECT AVG(rating) FROM rated)
+)
+SELECT bn.neighbor_id,
+       SUM(bn.similarity)::float8 AS score,
+       (array_agg(b.name ORDER BY bn.similarity DESC))[1]::text AS because_name
+FROM liked l
+JOIN beverage_neighbors bn ON bn.beverage_id = l.beverage_id
+JOIN beverages b ON b.id = l.beverage_id
+GROUP BY bn.neighbor_id
+ORDER BY score DESC
+LIMIT $3;
+
+-- Similar Beverages
+
+-- name: GetCategoryTagProfiles :many
+-- Every tagged beverage in a category with its tag counts, style and median
+-- post price (0 when unknown), so TF-IDF similarity and its filters run in
+-- one round trip
+SELECT b.id, b.name, b.brand, b.style,
+       b.total_reviews AS review_count,
+       b.avg_rating,
+       COALESCE(pr.median_price_cents, 0)::int AS median_price_cents,
+       json_agg(json_build_object('tag', bta.tag, 'count', bta.count)) AS tags_json
+FROM beverages b
+JOIN beverage_tag_aggregates bta ON bta.beverage_id = b.id
+LEFT JOIN (
+  SELECT beverage_id, percentile_cont(0.5) WITHIN GROUP (ORDER BY price_cents) AS median_price_cents
+  FROM posts
+  WHERE beverage_id IS NOT NULL AND price_cents IS NOT NULL
+  GROUP BY beverage_id
+) pr ON pr.beverage_id = b.id
+WHERE b.category = $1
+GROUP BY b.id, pr.median_price_cents;
+
+-- Offline Evaluation
+
+-- name: ListEvaluationPosts :many
+-- Every post linked to a beverage, with its tags, oldest first per user
+SELECT p.*, pt.tag, pt.tag_type, pt.confidence
+FROM posts p
+LEFT JOIN post_tags pt ON p.id = pt.post_id
+WHERE p.beverage_id IS NOT NULL
+ORDER BY p.user_id, p.created_at, p.id;
+
+-- name: ListEvaluationBeverages :many
+SELECT id, name, brand, category, producer_id, style
+FROM beverages;
+
+-- Experiments
+
+-- name: InsertRecommendationList :one
+INSERT INTO recommendation_lists (user_id, category, experiment, variant, strategy, beverage_ids)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetExperimentEngagement :many
+-- Per variant: lists served since $2, and the feedback and posts the same
+-- user left on listed beverages within window_days days of the list being served
+SELECT rl.variant,
+       COUNT(*)::int AS lists,
+       COUNT(DISTINCT rl.user_id)::int AS users,
+       COALESCE(SUM(fb.positive), 0)::int AS positive_feedback,
+       COALESCE(SUM(fb.negative), 0)::int AS negative_feedback,
+       COALESCE(SUM(ps.posts), 0)::int AS posts,
+       COUNT(*) FILTER (WHERE ps.posts > 0)::int AS converted_lists
+FROM recommendation_lists rl
+LEFT JOIN LATERAL (
+  SELECT COUNT(*) FILTER (WHERE rf.feedback_type = 'more_like_this') AS positive,
+         COUNT(*) FILTER (WHERE rf.feedback_type IN ('less_like_this', 'hide')) AS negative
+  FROM recommendation_feedback rf
+  WHERE rf.user_id = rl.user_id
+    AND rf.beverage_id = ANY(rl.beverage_ids)
+    AND rf.created_at >= rl.created_at
+    AND rf.created_at < rl.created_at + sqlc.arg(window_days)::int * INTERVAL '1 day'
+) fb ON true
+LEFT JOIN LATERAL (
+  SELECT COUNT(*) AS posts
+  FROM posts p
+  WHERE p.user_id = rl.user_id
+    AND p.beverage_id = ANY(rl.beverage_ids)
+    AND p.created_at >= rl.created_at
+    AND p.created_at < rl.created_at + sqlc.arg(window_days)::int * INTERVAL '1 day'
+) ps ON true
+WHERE rl.experiment = $1
+  AND rl.created_at >= $2
+GROUP BY rl.variant
+ORDER BY rl.variant;
+
+-- Impressions
+
+-- name: InsertRecommendationImpressions :many
+-- Logs one impression per beverage from parallel arrays; a rank of 0 is stored as NULL
+INSERT INTO recommendation_impressions (user_id, surface, list_id, beverage_id, rank, match_score)
+SELECT sqlc.arg(user_id)::UUID, sqlc.arg(surface)::TEXT, sqlc.narg(list_id)::UUID,
+       i.beverage_id, NULLIF(i.rank, 0), i.match_score
+FROM unnest(sqlc.arg(beverage_ids)::UUID[], sqlc.arg(ranks)::INT[], sqlc.arg(match_scores)::INT[]) AS i(beverage_id, rank, match_score)
+RETURNING id, beverage_id;
+
+-- name: RecordImpressionClick :exec
+UPDATE recommendation_impressions
+SET clicked_at = COALESCE(clicked_at, now())
+WHERE id = $1 AND user_id = $2;
+
+-- name: AttributeConversions :execrows
+-- Marks unconverted impressions with the user's first post on the beverage
+-- within attribution_days days of the impression, considering posts created since $1
+UPDATE recommendation_impressions ri
+SET converted_post_id = c.post_id, converted_at = c.created_at
+FROM (
+  SELECT DISTINCT ON (ri2.id) ri2.id AS impression_id, p.id AS post_id, p.created_at
+  FROM recommendation_impressions ri2
+  JOIN posts p ON p.user_id = ri2.user_id AND p.beverage_id = ri2.beverage_id
+  WHERE ri2.converted_post_id IS NULL
+    AND p.created_at >= $1
+    AND p.created_at >= ri2.created_at
+    AND p.created_at < ri2.created_at + sqlc.arg(attribution_days)::int * INTERVAL '1 day'
+  ORDER BY ri2.id, p.created_at
+) c
+WHERE ri.id = c.impression_id;
+
+-- name: GetImpressionStatsBySurface :many
+SELECT surface,
+       COUNT(*)::int AS impressions,
+       COUNT(clicked_at)::int AS clicks,
+       COUNT(converted_at)::int AS conversions
+FROM recommendation_impressions
+WHERE created_at >= $1
+GROUP BY surface
+ORDER BY surface;
+
+-- name: GetImpressionStatsByRank :many
+SELECT rank,
+       COUNT(*)::int AS impressions,
+       COUNT(clicked_at)::int AS clicks,
+       COUNT(converted_at)::int AS conversions
+FROM recommendation_impressions
+WHERE surface = $1
+  AND created_at >= $2
+  AND rank IS NOT NULL
+GROUP BY rank
+ORDER BY rank;
+
+-- Venue Recommendations
+
+-- name: GetVenueCandidates :many
+-- Beverages posted at a venue, matched by venue ID or map provider place ID,
+-- with when they were last seen there and the last price posted (0 if none)
+SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
+       b.total_reviews AS review_count,
+       b.avg_rating, b.producer_id, b.style,
+       s.sightings::int AS sightings,
+       s.last_seen_at::timestamptz AS last_seen_at,
+       COALESCE(s.last_price_cents, 0)::int AS last_price_cents
+FROM (
+  SELECT p.beverage_id,
+         COUNT(*) AS sightings,
+         MAX(p.created_at) AS last_seen_at,
+         (ARRAY_AGG(p.price_cents ORDER BY p.created_at DESC) FILTER (WHERE p.price_cents IS NOT NULL))[1] AS last_price_cents
+  FROM posts p
+  JOIN venues v ON p.venue_id = v.id
+  WHERE (v.id = sqlc.narg(venue_id) OR v.external_place_id = sqlc.narg(external_place_id))
+    AND p.beverage_id IS NOT NULL
+  GROUP BY p.beverage_id
+) s
+JOIN beverages b ON b.id = s.beverage_id
+WHERE b.category = sqlc.arg(category)
+  AND b.id NOT IN (
+    SELECT rf.beverage_id FROM recommendation_feedback rf
+    WHERE rf.user_id = sqlc.arg(user_id)
+      AND rf.feedback_type IN ('hide', 'already_tried')
+  )
+  AND COALESCE(b.total_reviews, 0) >= sqlc.arg(min_reviews)::int
+  AND (sqlc.narg(min_abv)::numeric IS NULL OR b.abv >= sqlc.narg(min_abv)::numeric)
+  AND (sqlc.narg(max_abv)::numeric IS NULL OR b.abv <= sqlc.narg(max_abv)::numeric)
+  AND (cardinality(sqlc.arg(styles)::text[]) = 0 OR lower(b.style) = ANY(sqlc.arg(styles)::text[]))
+  AND (cardinality(sqlc.arg(regions)::text[]) = 0 OR lower(b.region) = ANY(sqlc.arg(regions)::text[]))
+  AND (cardinality(sqlc.arg(varietals)::text[]) = 0 OR lower(b.varietal) = ANY(sqlc.arg(varietals)::text[]))
+  AND (NOT sqlc.arg(exclude_tried)::bool OR NOT EXISTS (
+    SELECT 1 FROM posts pt
+    WHERE pt.user_id = sqlc.arg(user_id) AND pt.beverage_id = b.id
+  ))
+  AND ((sqlc.narg(min_price_cents)::int IS NULL AND sqlc.narg(max_price_cents)::int IS NULL) OR (
+    SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY pp.price_cents)
+    FROM posts pp
+    WHERE pp.beverage_id = b.id AND pp.price_cents IS NOT NULL
+  ) BETWEEN COALESCE(sqlc.narg(min_price_cents)::int, 0) AND COALESCE(sqlc.narg(max_price_cents)::int, 2147483647))
+ORDER BY s.last_seen_at DESC
+LIMIT sqlc.arg('limit');
+
+-- Taste Compatibility
+
+-- name: ListTasteProfilesForUsers :many
+SELECT * FROM user_taste_profiles
+WHERE user_id = ANY(sqlc.arg(user_ids)::uuid[]);
+
+-- name: GetCoRatedBeverages :many
+-- Both users' averaged ratings on every beverage each of them has rated
+SELECT a.drink_category AS category, a.beverage_id,
+       a.rating::float8 AS rating,
+       b.rating::float8 AS other_rating
+FROM (
+  SELECT drink_category, beverage_id, AVG(rating) AS rating
+  FROM posts
+  WHERE user_id = sqlc.arg(user_id) AND beverage_id IS NOT NULL AND rating IS NOT NULL
+  GROUP BY drink_category, beverage_id
+) a
+JOIN (
+  SELECT beverage_id, AVG(rating) AS rating
+  FROM posts
+  WHERE user_id = sqlc.arg(other_user_id) AND beverage_id IS NOT NULL AND rating IS NOT NULL
+  GROUP BY beverage_id
+) b ON b.beverage_id = a.beverage_id
+ORDER BY a.drink_category, a.beverage_id;
+
+-- name: GetTasteCompatibility :many
+SELECT * FROM taste_compatibility
+WHERE user_id = $1 AND other_user_id = $2;
+
+-- name: UpsertTasteCompatibility :exec
+INSERT INTO taste_compatibility (
+  user_id, other_user_id, category, score, tag_similarity, rating_agreement, co_rated,
+  shared_tags_json, clashing_tags_json, profile_computed_at, other_profile_computed_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (user_id, other_user_id, category)
+DO UPDATE SET
+  score = EXCLUDED.score,
+  tag_similarity = EXCLUDED.tag_similarity,
+  rating_agreement = EXCLUDED.rating_agreement,
+  co_rated = EXCLUDED.co_rated,
+  shared_tags_json = EXCLUDED.shared_tags_json,
+  clashing_tags_json = EXCLUDED.clashing_tags_json,
+  profile_computed_at = EXCLUDED.profile_computed_at,
+  other_profile_computed_at = EXCLUDED.other_profile_computed_at,
+  computed_at = now();
+
+-- Onboarding Quiz
+
+-- name: ListQuizBeverages :many
+-- The most reviewed tagged beverages in a category, to pick quiz items from
+SELECT b.id, b.name, b.brand, b.image_url, b.total_reviews AS review_count
+FROM beverages b
+WHERE b.category = $1
+  AND EXISTS (
+    SELECT 1 FROM beverage_tag_aggregates bta
+    WHERE bta.beverage_id = b.id
+  )
+ORDER BY b.total_reviews DESC, b.avg_rating DESC
+LIMIT $2;
+
+-- name: ListTasteQuizAnswers :many
+SELECT * FROM taste_quiz_answers
+WHERE user_id = $1 AND category = $2
+ORDER BY answered_at, item_id;
+
+-- name: UpsertTasteQuizAnswer :exec
+INSERT INTO taste_quiz_answers (user_id, category, item_id, liked, tags)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, category, item_id)
+DO UPDATE SET
+  liked = EXCLUDED.liked,
+  tags = EXCLUDED.tags,
+  answered_at = now();
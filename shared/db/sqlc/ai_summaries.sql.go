@@ -315,6 +315,43 @@ func (q *Queries) GetQueuedJobs(ctx context.Context, limit int32) ([]OpenaiJob,
 	return items, nil
 }
 
+const rebuildBeverageTagAggregates = `-- name: RebuildBeverageTagAggregates :execrows
+WITH counted AS (
+  SELECT beverage_id, tag, tag_type, COUNT(*)::INT AS count
+  FROM post_tags
+  WHERE beverage_id IS NOT NULL
+  GROUP BY beverage_id, tag, tag_type
+),
+removed AS (
+  DELETE FROM beverage_tag_aggregates bta
+  WHERE NOT EXISTS (
+    SELECT 1 FROM counted c
+    WHERE c.beverage_id = bta.beverage_id
+      AND c.tag = bta.tag
+      AND c.tag_type = bta.tag_type
+  )
+)
+INSERT INTO beverage_tag_aggregates (beverage_id, tag, tag_type, count)
+SELECT beverage_id, tag, tag_type, count
+FROM counted
+ON CONFLICT (beverage_id, tag, tag_type)
+DO UPDATE SET
+  count = EXCLUDED.count,
+  updated_at = NOW()
+WHERE beverage_tag_aggregates.count <> EXCLUDED.count
+`
+
+// Recounts every beverage's tags from post_tags, one per post tag like
+// GetBeverageTags counts them for UpsertBeverageTagAggregate. Tags that no
+// post has any more are dropped.
+func (q *Queries) RebuildBeverageTagAggregates(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, rebuildBeverageTagAggregates)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateOpenAIJobStatus = `-- name: UpdateOpenAIJobStatus :exec
 UPDATE openai_jobs
 SET status = $2, attempts = $3, last_error = $4, updated_at = NOW()
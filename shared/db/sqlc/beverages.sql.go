@@ -14,7 +14,7 @@ import (
 const createBeverage = `-- name: CreateBeverage :one
 INSERT INTO beverages (name, brand, category, vintage, image_url, name_normalized, brand_normalized)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, name, brand, category, vintage, image_url, name_normalized, brand_normalized, total_reviews, avg_rating, created_at, updated_at
+RETURNING id, name, brand, category, vintage, image_url, name_normalized, brand_normalized, total_reviews, avg_rating, created_at, updated_at, rating_count, rating_sum, producer_id, abv, ibu, style, varietal, region, attributes_computed_at
 `
 
 type CreateBeverageParams struct {
@@ -51,12 +51,21 @@ func (q *Queries) CreateBeverage(ctx context.Context, arg CreateBeverageParams)
 		&i.AvgRating,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingCount,
+		&i.RatingSum,
+		&i.ProducerID,
+		&i.Abv,
+		&i.Ibu,
+		&i.Style,
+		&i.Varietal,
+		&i.Region,
+		&i.AttributesComputedAt,
 	)
 	return i, err
 }
 
 const getBeverageByID = `-- name: GetBeverageByID :one
-SELECT id, name, brand, category, vintage, image_url, name_normalized, brand_normalized, total_reviews, avg_rating, created_at, updated_at FROM beverages WHERE id = $1
+SELECT id, name, brand, category, vintage, image_url, name_normalized, brand_normalized, total_reviews, avg_rating, created_at, updated_at, rating_count, rating_sum, producer_id, abv, ibu, style, varietal, region, attributes_computed_at FROM beverages WHERE id = $1
 `
 
 func (q *Queries) GetBeverageByID(ctx context.Context, id pgtype.UUID) (Beverage, error) {
@@ -75,12 +84,21 @@ func (q *Queries) GetBeverageByID(ctx context.Context, id pgtype.UUID) (Beverage
 		&i.AvgRating,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingCount,
+		&i.RatingSum,
+		&i.ProducerID,
+		&i.Abv,
+		&i.Ibu,
+		&i.Style,
+		&i.Varietal,
+		&i.Region,
+		&i.AttributesComputedAt,
 	)
 	return i, err
 }
 
 const getPostsForBeverage = `-- name: GetPostsForBeverage :many
-SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id FROM posts p
+SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, p.rating FROM posts p
 WHERE
   (p.drink_name = $1 OR LOWER(p.drink_name) = $2) AND
   ($3::TEXT IS NULL OR p.drink_category = $3)
@@ -126,6 +144,7 @@ func (q *Queries) GetPostsForBeverage(ctx context.Context, arg GetPostsForBevera
 			&i.UpdatedAt,
 			&i.Score,
 			&i.BeverageID,
+			&i.Rating,
 		); err != nil {
 			return nil, err
 		}
@@ -138,12 +157,12 @@ func (q *Queries) GetPostsForBeverage(ctx context.Context, arg GetPostsForBevera
 }
 
 const getTopReviewsForBeverage = `-- name: GetTopReviewsForBeverage :many
-SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id FROM posts p
+SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, p.rating FROM posts p
 WHERE
   (p.drink_name = $1 OR LOWER(p.drink_name) = $2) AND
   ($3::TEXT IS NULL OR p.drink_category = $3) AND
-  p.score IS NOT NULL
-ORDER BY p.score DESC, p.created_at DESC
+  p.rating IS NOT NULL
+ORDER BY p.rating DESC, p.created_at DESC
 LIMIT $4
 `
 
@@ -185,6 +204,85 @@ func (q *Queries) GetTopReviewsForBeverage(ctx context.Context, arg GetTopReview
 			&i.UpdatedAt,
 			&i.Score,
 			&i.BeverageID,
+			&i.Rating,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reconcileBeverageStats = `-- name: ReconcileBeverageStats :many
+WITH actual AS (
+  SELECT
+    b.id,
+    COUNT(p.id)::INT AS total_reviews,
+    COUNT(p.rating)::INT AS rating_count,
+    COALESCE(SUM(p.rating), 0)::NUMERIC(12,1) AS rating_sum
+  FROM beverages b
+  LEFT JOIN posts p ON p.beverage_id = b.id
+  GROUP BY b.id
+),
+drifted AS (
+  SELECT
+    b.id,
+    b.total_reviews AS stored_total_reviews,
+    b.avg_rating AS stored_avg_rating,
+    a.total_reviews,
+    a.rating_count,
+    a.rating_sum,
+    CASE WHEN a.rating_count > 0 THEN ROUND(a.rating_sum / a.rating_count, 2) ELSE 0 END AS avg_rating
+  FROM beverages b
+  JOIN actual a ON a.id = b.id
+)
+UPDATE beverages
+SET
+  total_reviews = d.total_reviews,
+  rating_count = d.rating_count,
+  rating_sum = d.rating_sum,
+  avg_rating = d.avg_rating
+FROM drifted d
+WHERE beverages.id = d.id
+  AND (
+    beverages.total_reviews IS DISTINCT FROM d.total_reviews OR
+    beverages.rating_count != d.rating_count OR
+    beverages.rating_sum != d.rating_sum OR
+    beverages.avg_rating IS DISTINCT FROM d.avg_rating
+  )
+RETURNING beverages.id, beverages.name, d.stored_total_reviews, d.stored_avg_rating, beverages.total_reviews, beverages.avg_rating
+`
+
+type ReconcileBeverageStatsRow struct {
+	ID                 pgtype.UUID    `json:"id"`
+	Name               string         `json:"name"`
+	StoredTotalReviews pgtype.Int4    `json:"stored_total_reviews"`
+	StoredAvgRating    pgtype.Numeric `json:"stored_avg_rating"`
+	TotalReviews       pgtype.Int4    `json:"total_reviews"`
+	AvgRating          pgtype.Numeric `json:"avg_rating"`
+}
+
+// Recomputes stats from posts for every beverage whose stored totals have
+// drifted and returns the stored values alongside the corrected ones.
+func (q *Queries) ReconcileBeverageStats(ctx context.Context) ([]ReconcileBeverageStatsRow, error) {
+	rows, err := q.db.Query(ctx, reconcileBeverageStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReconcileBeverageStatsRow
+	for rows.Next() {
+		var i ReconcileBeverageStatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.StoredTotalReviews,
+			&i.StoredAvgRating,
+			&i.TotalReviews,
+			&i.AvgRating,
 		); err != nil {
 			return nil, err
 		}
@@ -198,11 +296,15 @@ func (q *Queries) GetTopReviewsForBeverage(ctx context.Context, arg GetTopReview
 
 const searchBeveragesByTokens = `-- name: SearchBeveragesByTokens :many
 SELECT
-  b.id, b.name, b.brand, b.category, b.vintage, b.image_url, b.name_normalized, b.brand_normalized, b.total_reviews, b.avg_rating, b.created_at, b.updated_at,
+  b.id, b.name, b.brand, b.category, b.vintage, b.image_url, b.name_normalized, b.brand_normalized, b.total_reviews, b.avg_rating, b.created_at, b.updated_at, b.rating_count, b.rating_sum, b.producer_id, b.abv, b.ibu, b.style, b.varietal, b.region, b.attributes_computed_at,
   -- Calculate match score
   (
     -- Exact name match (highest priority)
     CASE WHEN b.name_normalized = $1 THEN 100 ELSE 0 END +
+    -- Exact curator alias match
+    CASE WHEN EXISTS (
+      SELECT 1 FROM beverage_aliases ba WHERE ba.beverage_id = b.id AND ba.alias_normalized = $1
+    ) THEN 90 ELSE 0 END +
     -- Name prefix match
     CASE WHEN b.name_normalized LIKE $1 || '%' THEN 50 ELSE 0 END +
     -- Name contains match
@@ -223,6 +325,9 @@ WHERE
   (
     b.name_normalized LIKE '%' || $1 || '%' OR
     b.brand_normalized LIKE '%' || $2 || '%' OR
+    EXISTS (
+      SELECT 1 FROM beverage_aliases ba WHERE ba.beverage_id = b.id AND ba.alias_normalized LIKE '%' || $1 || '%'
+    ) OR
     ($3::TEXT IS NOT NULL AND b.vintage = $3::TEXT)
   )
 ORDER BY match_score DESC, b.avg_rating DESC
@@ -238,19 +343,28 @@ type SearchBeveragesByTokensParams struct {
 }
 
 type SearchBeveragesByTokensRow struct {
-	ID              pgtype.UUID        `json:"id"`
-	Name            string             `json:"name"`
-	Brand           pgtype.Text        `json:"brand"`
-	Category        string             `json:"category"`
-	Vintage         pgtype.Text        `json:"vintage"`
-	ImageUrl        pgtype.Text        `json:"image_url"`
-	NameNormalized  string             `json:"name_normalized"`
-	BrandNormalized pgtype.Text        `json:"brand_normalized"`
-	TotalReviews    pgtype.Int4        `json:"total_reviews"`
-	AvgRating       pgtype.Numeric     `json:"avg_rating"`
-	CreatedAt       pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
-	MatchScore      int32              `json:"match_score"`
+	ID                   pgtype.UUID        `json:"id"`
+	Name                 string             `json:"name"`
+	Brand                pgtype.Text        `json:"brand"`
+	Category             string             `json:"category"`
+	Vintage              pgtype.Text        `json:"vintage"`
+	ImageUrl             pgtype.Text        `json:"image_url"`
+	NameNormalized       string             `json:"name_normalized"`
+	BrandNormalized      pgtype.Text        `json:"brand_normalized"`
+	TotalReviews         pgtype.Int4        `json:"total_reviews"`
+	AvgRating            pgtype.Numeric     `json:"avg_rating"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	RatingCount          int32              `json:"rating_count"`
+	RatingSum            pgtype.Numeric     `json:"rating_sum"`
+	ProducerID           pgtype.UUID        `json:"producer_id"`
+	Abv                  pgtype.Numeric     `json:"abv"`
+	Ibu                  pgtype.Int4        `json:"ibu"`
+	Style                pgtype.Text        `json:"style"`
+	Varietal             pgtype.Text        `json:"varietal"`
+	Region               pgtype.Text        `json:"region"`
+	AttributesComputedAt pgtype.Timestamptz `json:"attributes_computed_at"`
+	MatchScore           int32              `json:"match_score"`
 }
 
 func (q *Queries) SearchBeveragesByTokens(ctx context.Context, arg SearchBeveragesByTokensParams) ([]SearchBeveragesByTokensRow, error) {
@@ -281,6 +395,15 @@ func (q *Queries) SearchBeveragesByTokens(ctx context.Context, arg SearchBeverag
 			&i.AvgRating,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RatingCount,
+			&i.RatingSum,
+			&i.ProducerID,
+			&i.Abv,
+			&i.Ibu,
+			&i.Style,
+			&i.Varietal,
+			&i.Region,
+			&i.AttributesComputedAt,
 			&i.MatchScore,
 		); err != nil {
 			return nil, err
@@ -292,23 +415,3 @@ func (q *Queries) SearchBeveragesByTokens(ctx context.Context, arg SearchBeverag
 	}
 	return items, nil
 }
-
-const updateBeverageStats = `-- name: UpdateBeverageStats :exec
-UPDATE beverages
-SET
-  total_reviews = $2,
-  avg_rating = $3,
-  updated_at = NOW()
-WHERE id = $1
-`
-
-type UpdateBeverageStatsParams struct {
-	ID           pgtype.UUID    `json:"id"`
-	TotalReviews pgtype.Int4    `json:"total_reviews"`
-	AvgRating    pgtype.Numeric `json:"avg_rating"`
-}
-
-func (q *Queries) UpdateBeverageStats(ctx context.Context, arg UpdateBeverageStatsParams) error {
-	_, err := q.db.Exec(ctx, updateBeverageStats, arg.ID, arg.TotalReviews, arg.AvgRating)
-	return err
-}
@@ -21,20 +21,82 @@ type BeerPostDetail struct {
 }
 
 type Beverage struct {
-	ID              pgtype.UUID        `json:"id"`
-	Name            string             `json:"name"`
-	Brand           pgtype.Text        `json:"brand"`
-	Category        string             `json:"category"`
-	Vintage         pgtype.Text        `json:"vintage"`
-	ImageUrl        pgtype.Text        `json:"image_url"`
-	NameNormalized  string             `json:"name_normalized"`
-	BrandNormalized pgtype.Text        `json:"brand_normalized"`
-	TotalReviews    pgtype.Int4        `json:"total_reviews"`
-	AvgRating       pgtype.Numeric     `json:"avg_rating"`
+	ID                   pgtype.UUID        `json:"id"`
+	Name                 string             `json:"name"`
+	Brand                pgtype.Text        `json:"brand"`
+	Category             string             `json:"category"`
+	Vintage              pgtype.Text        `json:"vintage"`
+	ImageUrl             pgtype.Text        `json:"image_url"`
+	NameNormalized       string             `json:"name_normalized"`
+	BrandNormalized      pgtype.Text        `json:"brand_normalized"`
+	TotalReviews         pgtype.Int4        `json:"total_reviews"`
+	AvgRating            pgtype.Numeric     `json:"avg_rating"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	RatingCount          int32              `json:"rating_count"`
+	RatingSum            pgtype.Numeric     `json:"rating_sum"`
+	ProducerID           pgtype.UUID        `json:"producer_id"`
+	Abv                  pgtype.Numeric     `json:"abv"`
+	Ibu                  pgtype.Int4        `json:"ibu"`
+	Style                pgtype.Text        `json:"style"`
+	Varietal             pgtype.Text        `json:"varietal"`
+	Region               pgtype.Text        `json:"region"`
+	AttributesComputedAt pgtype.Timestamptz `json:"attributes_computed_at"`
+}
+
+type BeverageAlias struct {
+	BeverageID      pgtype.UUID        `json:"beverage_id"`
+	Alias           string             `json:"alias"`
+	AliasNormalized string             `json:"alias_normalized"`
+	CreatedBy       pgtype.UUID        `json:"created_by"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type BeverageAttribute struct {
+	BeverageID  pgtype.UUID        `json:"beverage_id"`
+	Attribute   string             `json:"attribute"`
+	Value       string             `json:"value"`
+	Source      string             `json:"source"`
+	Confidence  pgtype.Numeric     `json:"confidence"`
+	SampleCount int32              `json:"sample_count"`
+	UpdatedBy   pgtype.UUID        `json:"updated_by"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type BeverageAttributeInvalidation struct {
+	BeverageID    pgtype.UUID        `json:"beverage_id"`
+	InvalidatedAt pgtype.Timestamptz `json:"invalidated_at"`
+}
+
+type BeverageEmbedding struct {
+	BeverageID      pgtype.UUID        `json:"beverage_id"`
+	EmbeddingText   string             `json:"embedding_text"`
+	EmbeddingVector []float32          `json:"embedding_vector"`
+	Model           string             `json:"model"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
+type BeverageNeighbor struct {
+	BeverageID pgtype.UUID        `json:"beverage_id"`
+	NeighborID pgtype.UUID        `json:"neighbor_id"`
+	Similarity float64            `json:"similarity"`
+	CoRaters   int32              `json:"co_raters"`
+	ComputedAt pgtype.Timestamptz `json:"computed_at"`
+}
+
+type BeverageRevision struct {
+	ID         pgtype.UUID        `json:"id"`
+	BeverageID pgtype.UUID        `json:"beverage_id"`
+	Revision   int32              `json:"revision"`
+	Action     string             `json:"action"`
+	ChangedBy  pgtype.UUID        `json:"changed_by"`
+	Reason     string             `json:"reason"`
+	Diff       []byte             `json:"diff"`
+	Snapshot   []byte             `json:"snapshot"`
+	RevertedTo pgtype.Int4        `json:"reverted_to"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
 type BeverageSummary struct {
 	BeverageID        pgtype.UUID        `json:"beverage_id"`
 	SummaryText       string             `json:"summary_text"`
@@ -117,6 +179,7 @@ type Post struct {
 	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
 	Score                 pgtype.Numeric     `json:"score"`
 	BeverageID            pgtype.UUID        `json:"beverage_id"`
+	Rating                pgtype.Numeric     `json:"rating"`
 }
 
 type PostMedium struct {
@@ -137,6 +200,28 @@ type PostTag struct {
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
 
+type Producer struct {
+	ID             pgtype.UUID        `json:"id"`
+	Name           string             `json:"name"`
+	NameNormalized string             `json:"name_normalized"`
+	ProducerType   string             `json:"producer_type"`
+	City           pgtype.Text        `json:"city"`
+	Region         pgtype.Text        `json:"region"`
+	Country        pgtype.Text        `json:"country"`
+	Lat            pgtype.Float8      `json:"lat"`
+	Lng            pgtype.Float8      `json:"lng"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ProducerAlias struct {
+	ProducerID      pgtype.UUID        `json:"producer_id"`
+	ProducerType    string             `json:"producer_type"`
+	Alias           string             `json:"alias"`
+	AliasNormalized string             `json:"alias_normalized"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
 type RecommendationFeedback struct {
 	ID           pgtype.UUID        `json:"id"`
 	UserID       pgtype.UUID        `json:"user_id"`
@@ -145,6 +230,31 @@ type RecommendationFeedback struct {
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
 
+type RecommendationImpression struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	BeverageID      pgtype.UUID        `json:"beverage_id"`
+	Surface         string             `json:"surface"`
+	Rank            pgtype.Int4        `json:"rank"`
+	MatchScore      int32              `json:"match_score"`
+	ListID          pgtype.UUID        `json:"list_id"`
+	ClickedAt       pgtype.Timestamptz `json:"clicked_at"`
+	ConvertedPostID pgtype.UUID        `json:"converted_post_id"`
+	ConvertedAt     pgtype.Timestamptz `json:"converted_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type RecommendationList struct {
+	ID          pgtype.UUID        `json:"id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	Category    string             `json:"category"`
+	Experiment  string             `json:"experiment"`
+	Variant     string             `json:"variant"`
+	Strategy    string             `json:"strategy"`
+	BeverageIds []pgtype.UUID      `json:"beverage_ids"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
 type RefreshToken struct {
 	ID        pgtype.UUID        `json:"id"`
 	UserID    pgtype.UUID        `json:"user_id"`
@@ -154,6 +264,45 @@ type RefreshToken struct {
 	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
 }
 
+type TasteCompatibility struct {
+	UserID                 pgtype.UUID        `json:"user_id"`
+	OtherUserID            pgtype.UUID        `json:"other_user_id"`
+	Category               string             `json:"category"`
+	Score                  int32              `json:"score"`
+	TagSimilarity          float64            `json:"tag_similarity"`
+	RatingAgreement        pgtype.Float8      `json:"rating_agreement"`
+	CoRated                int32              `json:"co_rated"`
+	SharedTagsJson         []byte             `json:"shared_tags_json"`
+	ClashingTagsJson       []byte             `json:"clashing_tags_json"`
+	ProfileComputedAt      pgtype.Timestamptz `json:"profile_computed_at"`
+	OtherProfileComputedAt pgtype.Timestamptz `json:"other_profile_computed_at"`
+	ComputedAt             pgtype.Timestamptz `json:"computed_at"`
+}
+
+type TasteQuizAnswer struct {
+	UserID     pgtype.UUID        `json:"user_id"`
+	Category   string             `json:"category"`
+	ItemID     string             `json:"item_id"`
+	Liked      bool               `json:"liked"`
+	Tags       []string           `json:"tags"`
+	AnsweredAt pgtype.Timestamptz `json:"answered_at"`
+}
+
+type TasteDecaySetting struct {
+	Category     string             `json:"category"`
+	HalfLifeDays pgtype.Int4        `json:"half_life_days"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+type TasteProfileEvent struct {
+	ID        int64              `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Category  string             `json:"category"`
+	PostID    pgtype.UUID        `json:"post_id"`
+	Reason    string             `json:"reason"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type User struct {
 	ID            pgtype.UUID        `json:"id"`
 	Email         string             `json:"email"`
@@ -176,16 +325,44 @@ type UserEmbedding struct {
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
+type UserTasteContribution struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	Category  string             `json:"category"`
+	PostID    pgtype.UUID        `json:"post_id"`
+	Rating    pgtype.Numeric     `json:"rating"`
+	Weight    float64            `json:"weight"`
+	TagsJson  []byte             `json:"tags_json"`
+	AppliedAt pgtype.Timestamptz `json:"applied_at"`
+}
+
 type UserTasteProfile struct {
-	UserID           pgtype.UUID        `json:"user_id"`
-	Category         string             `json:"category"`
-	LikedTagsJson    []byte             `json:"liked_tags_json"`
-	DislikedTagsJson []byte             `json:"disliked_tags_json"`
-	MeanRating       pgtype.Numeric     `json:"mean_rating"`
-	StdRating        pgtype.Numeric     `json:"std_rating"`
-	PostCount        pgtype.Int4        `json:"post_count"`
-	LastComputedAt   pgtype.Timestamptz `json:"last_computed_at"`
-	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	UserID                  pgtype.UUID        `json:"user_id"`
+	Category                string             `json:"category"`
+	LikedTagsJson           []byte             `json:"liked_tags_json"`
+	DislikedTagsJson        []byte             `json:"disliked_tags_json"`
+	MeanRating              pgtype.Numeric     `json:"mean_rating"`
+	StdRating               pgtype.Numeric     `json:"std_rating"`
+	PostCount               pgtype.Int4        `json:"post_count"`
+	LastComputedAt          pgtype.Timestamptz `json:"last_computed_at"`
+	UpdatedAt               pgtype.Timestamptz `json:"updated_at"`
+	AllTimeLikedTagsJson    []byte             `json:"all_time_liked_tags_json"`
+	AllTimeDislikedTagsJson []byte             `json:"all_time_disliked_tags_json"`
+	AllTimeMeanRating       pgtype.Numeric     `json:"all_time_mean_rating"`
+	AllTimeStdRating        pgtype.Numeric     `json:"all_time_std_rating"`
+	HalfLifeDays            pgtype.Int4        `json:"half_life_days"`
+	InferredFrom            []string           `json:"inferred_from"`
+	QuizWeight              float64            `json:"quiz_weight"`
+}
+
+type UserTasteStat struct {
+	UserID          pgtype.UUID        `json:"user_id"`
+	Category        string             `json:"category"`
+	HalfLifeDays    pgtype.Int4        `json:"half_life_days"`
+	RecentJson      []byte             `json:"recent_json"`
+	AllTimeJson     []byte             `json:"all_time_json"`
+	AsOf            pgtype.Timestamptz `json:"as_of"`
+	FullRecomputeAt pgtype.Timestamptz `json:"full_recompute_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
 type Venue struct {
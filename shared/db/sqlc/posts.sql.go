@@ -96,9 +96,9 @@ func (q *Queries) CreateCocktailPostDetails(ctx context.Context, arg CreateCockt
 }
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (user_id, venue_id, drink_name, drink_category, stars, score, notes, beer_post_details_id, wine_post_details_id, cocktail_post_details_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-RETURNING id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id
+INSERT INTO posts (user_id, venue_id, drink_name, drink_category, stars, score, notes, beer_post_details_id, wine_post_details_id, cocktail_post_details_id, rating)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id, rating
 `
 
 type CreatePostParams struct {
@@ -112,6 +112,7 @@ type CreatePostParams struct {
 	BeerPostDetailsID     pgtype.UUID    `json:"beer_post_details_id"`
 	WinePostDetailsID     pgtype.UUID    `json:"wine_post_details_id"`
 	CocktailPostDetailsID pgtype.UUID    `json:"cocktail_post_details_id"`
+	Rating                pgtype.Numeric `json:"rating"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -126,6 +127,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.BeerPostDetailsID,
 		arg.WinePostDetailsID,
 		arg.CocktailPostDetailsID,
+		arg.Rating,
 	)
 	var i Post
 	err := row.Scan(
@@ -145,6 +147,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.UpdatedAt,
 		&i.Score,
 		&i.BeverageID,
+		&i.Rating,
 	)
 	return i, err
 }
@@ -251,7 +254,7 @@ func (q *Queries) GetCocktailPostDetails(ctx context.Context, id pgtype.UUID) (C
 }
 
 const getPostByID = `-- name: GetPostByID :one
-SELECT id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id FROM posts WHERE id = $1
+SELECT id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id, rating FROM posts WHERE id = $1
 `
 
 func (q *Queries) GetPostByID(ctx context.Context, id pgtype.UUID) (Post, error) {
@@ -274,6 +277,7 @@ func (q *Queries) GetPostByID(ctx context.Context, id pgtype.UUID) (Post, error)
 		&i.UpdatedAt,
 		&i.Score,
 		&i.BeverageID,
+		&i.Rating,
 	)
 	return i, err
 }
@@ -303,7 +307,7 @@ func (q *Queries) GetWinePostDetails(ctx context.Context, id pgtype.UUID) (WineP
 }
 
 const listPosts = `-- name: ListPosts :many
-SELECT id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id FROM posts ORDER BY created_at DESC LIMIT $1
+SELECT id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id, rating FROM posts ORDER BY created_at DESC LIMIT $1
 `
 
 func (q *Queries) ListPosts(ctx context.Context, limit int32) ([]Post, error) {
@@ -332,6 +336,7 @@ func (q *Queries) ListPosts(ctx context.Context, limit int32) ([]Post, error) {
 			&i.UpdatedAt,
 			&i.Score,
 			&i.BeverageID,
+			&i.Rating,
 		); err != nil {
 			return nil, err
 		}
@@ -344,7 +349,7 @@ func (q *Queries) ListPosts(ctx context.Context, limit int32) ([]Post, error) {
 }
 
 const listPostsByExternalPlaceID = `-- name: ListPostsByExternalPlaceID :many
-SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id FROM posts p
+SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, p.rating FROM posts p
 JOIN venues v ON p.venue_id = v.id
 WHERE v.external_place_id = $1
 ORDER BY p.created_at DESC
@@ -376,6 +381,7 @@ func (q *Queries) ListPostsByExternalPlaceID(ctx context.Context, externalPlaceI
 			&i.UpdatedAt,
 			&i.Score,
 			&i.BeverageID,
+			&i.Rating,
 		); err != nil {
 			return nil, err
 		}
@@ -479,9 +485,9 @@ func (q *Queries) UpdateCocktailPostDetails(ctx context.Context, arg UpdateCockt
 
 const updatePost = `-- name: UpdatePost :one
 UPDATE posts
-SET drink_name = $2, stars = $3, score = $4, notes = $5, updated_at = NOW()
+SET drink_name = $2, stars = $3, score = $4, notes = $5, rating = $6, updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id
+RETURNING id, user_id, venue_id, drink_name, drink_category, stars, notes, wine_post_details_id, beer_post_details_id, cocktail_post_details_id, price_cents, photo_url, created_at, updated_at, score, beverage_id, rating
 `
 
 type UpdatePostParams struct {
@@ -490,6 +496,7 @@ type UpdatePostParams struct {
 	Stars     pgtype.Int4    `json:"stars"`
 	Score     pgtype.Numeric `json:"score"`
 	Notes     pgtype.Text    `json:"notes"`
+	Rating    pgtype.Numeric `json:"rating"`
 }
 
 func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
@@ -499,6 +506,7 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		arg.Stars,
 		arg.Score,
 		arg.Notes,
+		arg.Rating,
 	)
 	var i Post
 	err := row.Scan(
@@ -518,6 +526,7 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.UpdatedAt,
 		&i.Score,
 		&i.BeverageID,
+		&i.Rating,
 	)
 	return i, err
 }
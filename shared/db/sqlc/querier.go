@@ -11,46 +11,95 @@ import (
 )
 
 type Querier interface {
+	AdvisoryUnlock(ctx context.Context, key int64) (bool, error)
 	AttachMediaToPost(ctx context.Context, arg AttachMediaToPostParams) (PostMedium, error)
+	// Marks unconverted impressions with the user's first post on the beverage
+	// within attribution_days days of the impression, considering posts created since $1
+	AttributeConversions(ctx context.Context, arg AttributeConversionsParams) (int64, error)
+	CountBeverageRevisions(ctx context.Context, beverageID pgtype.UUID) (int64, error)
 	CreateBeerPostDetails(ctx context.Context, arg CreateBeerPostDetailsParams) (BeerPostDetail, error)
 	CreateBeverage(ctx context.Context, arg CreateBeverageParams) (Beverage, error)
+	CreateBeverageAlias(ctx context.Context, arg CreateBeverageAliasParams) (BeverageAlias, error)
+	CreateBeverageRevision(ctx context.Context, arg CreateBeverageRevisionParams) (BeverageRevision, error)
 	CreateCocktailPostDetails(ctx context.Context, arg CreateCocktailPostDetailsParams) (CocktailPostDetail, error)
 	CreateMedia(ctx context.Context, arg CreateMediaParams) (Medium, error)
 	CreateOpenAIJob(ctx context.Context, arg CreateOpenAIJobParams) (OpenaiJob, error)
 	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
 	CreatePostTag(ctx context.Context, arg CreatePostTagParams) (PostTag, error)
+	CreateProducer(ctx context.Context, arg CreateProducerParams) (Producer, error)
+	// Adds an alias unless it is already known; returns 0 when it was
+	CreateProducerAlias(ctx context.Context, arg CreateProducerAliasParams) (int64, error)
 	// Recommendation Feedback
 	CreateRecommendationFeedback(ctx context.Context, arg CreateRecommendationFeedbackParams) (RecommendationFeedback, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVenue(ctx context.Context, arg CreateVenueParams) (Venue, error)
 	CreateWinePostDetails(ctx context.Context, arg CreateWinePostDetailsParams) (WinePostDetail, error)
+	DeleteBeverageAlias(ctx context.Context, arg DeleteBeverageAliasParams) (int64, error)
+	DeleteBeverageAliases(ctx context.Context, beverageID pgtype.UUID) error
+	DeleteBeverageNeighborsForCategory(ctx context.Context, category string) error
 	DeleteBeverageSummary(ctx context.Context, beverageID pgtype.UUID) error
 	DeleteBeverageTagAggregates(ctx context.Context, beverageID pgtype.UUID) error
+	DeleteConsensusAttribute(ctx context.Context, arg DeleteConsensusAttributeParams) error
+	DeleteCuratorAttribute(ctx context.Context, arg DeleteCuratorAttributeParams) error
 	DeleteFeedback(ctx context.Context, arg DeleteFeedbackParams) error
 	DeleteOpenAIJob(ctx context.Context, id pgtype.UUID) error
 	DeletePost(ctx context.Context, id pgtype.UUID) error
 	DeletePostTags(ctx context.Context, postID pgtype.UUID) error
 	DeleteStagedMediaOlderThan(ctx context.Context, createdAt pgtype.Timestamptz) error
+	DeleteTasteContribution(ctx context.Context, arg DeleteTasteContributionParams) error
+	DeleteTasteContributions(ctx context.Context, arg DeleteTasteContributionsParams) error
+	// Claims a post's queued events up to up_to; concurrent workers skip each
+	// other's rows, so none claimed means another worker has them
+	DeleteTasteProfileEvents(ctx context.Context, arg DeleteTasteProfileEventsParams) (int64, error)
 	GetBeerPostDetails(ctx context.Context, id pgtype.UUID) (BeerPostDetail, error)
+	GetBeverageAttributes(ctx context.Context, beverageID pgtype.UUID) ([]BeverageAttribute, error)
 	GetBeverageByID(ctx context.Context, id pgtype.UUID) (Beverage, error)
+	GetBeverageEmbeddingsByCategory(ctx context.Context, arg GetBeverageEmbeddingsByCategoryParams) ([]GetBeverageEmbeddingsByCategoryRow, error)
+	GetBeverageForUpdate(ctx context.Context, id pgtype.UUID) (Beverage, error)
+	GetBeverageNeighbors(ctx context.Context, arg GetBeverageNeighborsParams) ([]GetBeverageNeighborsRow, error)
+	GetBeverageRevision(ctx context.Context, arg GetBeverageRevisionParams) (BeverageRevision, error)
 	GetBeverageSummary(ctx context.Context, beverageID pgtype.UUID) (BeverageSummary, error)
 	GetBeverageTagAggregates(ctx context.Context, beverageID pgtype.UUID) ([]BeverageTagAggregate, error)
 	GetBeverageTags(ctx context.Context, beverageID pgtype.UUID) ([]GetBeverageTagsRow, error)
 	GetBeverageWithTags(ctx context.Context, id pgtype.UUID) (GetBeverageWithTagsRow, error)
+	// Every tagged beverage in a category with its tag counts, style and median
+	// post price (0 when unknown), so TF-IDF similarity and its filters run in
+	// one round trip
+	GetCategoryTagProfiles(ctx context.Context, category string) ([]GetCategoryTagProfilesRow, error)
+	// Both users' averaged ratings on every beverage each of them has rated
+	GetCoRatedBeverages(ctx context.Context, arg GetCoRatedBeveragesParams) ([]GetCoRatedBeveragesRow, error)
 	GetCocktailPostDetails(ctx context.Context, id pgtype.UUID) (CocktailPostDetail, error)
+	// Per variant: lists served since $2, and the feedback and posts the same
+	// user left on listed beverages within window_days days of the list being served
+	GetExperimentEngagement(ctx context.Context, arg GetExperimentEngagementParams) ([]GetExperimentEngagementRow, error)
 	GetHiddenBeveragesForUser(ctx context.Context, userID pgtype.UUID) ([]pgtype.UUID, error)
+	GetImpressionStatsByRank(ctx context.Context, arg GetImpressionStatsByRankParams) ([]GetImpressionStatsByRankRow, error)
+	GetImpressionStatsBySurface(ctx context.Context, createdAt pgtype.Timestamptz) ([]GetImpressionStatsBySurfaceRow, error)
 	GetMediaByID(ctx context.Context, id pgtype.UUID) (Medium, error)
 	GetMediaByObjectKey(ctx context.Context, objectKey string) (Medium, error)
 	GetMediaForPost(ctx context.Context, postID pgtype.UUID) ([]Medium, error)
 	GetOpenAIJob(ctx context.Context, id pgtype.UUID) (OpenaiJob, error)
 	GetPendingSummaryJob(ctx context.Context, beverageID pgtype.UUID) (OpenaiJob, error)
 	GetPostByID(ctx context.Context, id pgtype.UUID) (Post, error)
+	GetPostForTasteProfile(ctx context.Context, id pgtype.UUID) ([]GetPostForTasteProfileRow, error)
 	GetPostTags(ctx context.Context, postID pgtype.UUID) ([]PostTag, error)
 	GetPostsForBeverage(ctx context.Context, arg GetPostsForBeverageParams) ([]Post, error)
+	GetProducerAliases(ctx context.Context, producerID pgtype.UUID) ([]string, error)
+	GetProducerByAlias(ctx context.Context, arg GetProducerByAliasParams) (Producer, error)
+	GetProducerByID(ctx context.Context, id pgtype.UUID) (Producer, error)
+	GetProducerRatingSummary(ctx context.Context, producerID pgtype.UUID) (GetProducerRatingSummaryRow, error)
+	GetProducerTopTags(ctx context.Context, arg GetProducerTopTagsParams) ([]GetProducerTopTagsRow, error)
 	GetQueuedJobs(ctx context.Context, limit int32) ([]OpenaiJob, error)
 	// Recommendation Candidates
 	GetRecommendationCandidates(ctx context.Context, arg GetRecommendationCandidatesParams) ([]GetRecommendationCandidatesRow, error)
+	// Candidates found outside the popularity query, with the same exclusions
+	GetRecommendationCandidatesByIDs(ctx context.Context, arg GetRecommendationCandidatesByIDsParams) ([]GetRecommendationCandidatesByIDsRow, error)
 	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// Tag aggregates for a batch of beverages, so candidates can be scored in one round trip
+	GetTagsForBeverages(ctx context.Context, dollar_1 []pgtype.UUID) ([]GetTagsForBeveragesRow, error)
+	GetTasteCompatibility(ctx context.Context, arg GetTasteCompatibilityParams) ([]TasteCompatibility, error)
+	GetTasteContribution(ctx context.Context, arg GetTasteContributionParams) (UserTasteContribution, error)
+	GetTasteDecaySetting(ctx context.Context, category string) (TasteDecaySetting, error)
 	GetThumbnailForPost(ctx context.Context, dollar_1 pgtype.Text) (string, error)
 	GetTopReviewsForBeverage(ctx context.Context, arg GetTopReviewsForBeverageParams) ([]Post, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
@@ -60,34 +109,108 @@ type Querier interface {
 	GetUserEmbedding(ctx context.Context, arg GetUserEmbeddingParams) (UserEmbedding, error)
 	GetUserFeedback(ctx context.Context, userID pgtype.UUID) ([]RecommendationFeedback, error)
 	GetUserFeedbackForBeverage(ctx context.Context, arg GetUserFeedbackForBeverageParams) ([]RecommendationFeedback, error)
+	// A user's feedback on beverages in a category, oldest first, for profiles to replay
+	GetUserFeedbackForCategory(ctx context.Context, arg GetUserFeedbackForCategoryParams) ([]RecommendationFeedback, error)
+	// Neighbors of the beverages a user rated at or above their own average,
+	// with the liked beverage that contributes most to each
+	GetUserNeighborScores(ctx context.Context, arg GetUserNeighborScoresParams) ([]GetUserNeighborScoresRow, error)
 	GetUserPostCountByCategory(ctx context.Context, arg GetUserPostCountByCategoryParams) (int64, error)
 	GetUserPostsForCategory(ctx context.Context, arg GetUserPostsForCategoryParams) ([]GetUserPostsForCategoryRow, error)
 	// User Taste Profiles
 	GetUserTasteProfile(ctx context.Context, arg GetUserTasteProfileParams) (UserTasteProfile, error)
+	GetUserTasteStatsForUpdate(ctx context.Context, arg GetUserTasteStatsForUpdateParams) (UserTasteStat, error)
 	GetVenueByExternalPlaceID(ctx context.Context, externalPlaceID pgtype.Text) (Venue, error)
 	GetVenueByID(ctx context.Context, id pgtype.UUID) (Venue, error)
+	// Beverages posted at a venue, matched by venue ID or map provider place ID,
+	// with when they were last seen there and the last price posted (0 if none)
+	GetVenueCandidates(ctx context.Context, arg GetVenueCandidatesParams) ([]GetVenueCandidatesRow, error)
 	GetWinePostDetails(ctx context.Context, id pgtype.UUID) (WinePostDetail, error)
+	// Whether this session still holds the advisory lock on a non-negative key
+	HoldsAdvisoryLock(ctx context.Context, key int64) (bool, error)
+	InsertBeverageNeighbor(ctx context.Context, arg InsertBeverageNeighborParams) error
+	// Logs one impression per beverage from parallel arrays; a rank of 0 is stored as NULL
+	InsertRecommendationImpressions(ctx context.Context, arg InsertRecommendationImpressionsParams) ([]InsertRecommendationImpressionsRow, error)
+	InsertRecommendationList(ctx context.Context, arg InsertRecommendationListParams) (RecommendationList, error)
 	InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) (RefreshToken, error)
+	ListBeverageAliases(ctx context.Context, beverageID pgtype.UUID) ([]BeverageAlias, error)
+	// Every per-post value for a beverage's attributes: the detail tables the
+	// user filled in, plus style/region/varietal tags extracted by OpenAI.
+	ListBeverageAttributeObservations(ctx context.Context, beverageID pgtype.UUID) ([]ListBeverageAttributeObservationsRow, error)
+	ListBeverageRevisions(ctx context.Context, arg ListBeverageRevisionsParams) ([]BeverageRevision, error)
+	// Beverages with posts or extracted tags newer than their last attribute
+	// computation, or that lost a post since
+	ListBeveragesNeedingAttributes(ctx context.Context, limit int32) ([]pgtype.UUID, error)
+	// Beverages with no embedding for the model, or whose details or tags changed since
+	ListBeveragesNeedingEmbeddings(ctx context.Context, arg ListBeveragesNeedingEmbeddingsParams) ([]ListBeveragesNeedingEmbeddingsRow, error)
+	// One averaged rating per user and beverage in a category
+	ListCoRatingInputs(ctx context.Context, drinkCategory string) ([]ListCoRatingInputsRow, error)
+	ListEvaluationBeverages(ctx context.Context) ([]ListEvaluationBeveragesRow, error)
+	// Every post linked to a beverage, with its tags, oldest first per user
+	ListEvaluationPosts(ctx context.Context) ([]ListEvaluationPostsRow, error)
 	ListPosts(ctx context.Context, limit int32) ([]Post, error)
 	ListPostsByExternalPlaceID(ctx context.Context, externalPlaceID pgtype.Text) ([]Post, error)
+	ListProducerBeverages(ctx context.Context, arg ListProducerBeveragesParams) ([]Beverage, error)
+	// The most reviewed tagged beverages in a category, to pick quiz items from
+	ListQuizBeverages(ctx context.Context, arg ListQuizBeveragesParams) ([]ListQuizBeveragesRow, error)
+	ListTasteDecaySettings(ctx context.Context) ([]TasteDecaySetting, error)
+	// The oldest queued events. They stay queued until DeleteTasteProfileEvents
+	// removes them in the transaction that applies them.
+	ListTasteProfileEvents(ctx context.Context, limit int32) ([]TasteProfileEvent, error)
+	// Profiles whose stats are missing or were last rebuilt before the cutoff
+	ListTasteProfilesDueForRecompute(ctx context.Context, arg ListTasteProfilesDueForRecomputeParams) ([]ListTasteProfilesDueForRecomputeRow, error)
+	ListTasteProfilesForUsers(ctx context.Context, userIds []pgtype.UUID) ([]UserTasteProfile, error)
+	// Profiles nothing has touched since the cutoff, oldest first
+	ListTasteProfilesUpdatedBefore(ctx context.Context, arg ListTasteProfilesUpdatedBeforeParams) ([]ListTasteProfilesUpdatedBeforeRow, error)
+	ListTasteQuizAnswers(ctx context.Context, arg ListTasteQuizAnswersParams) ([]TasteQuizAnswer, error)
+	// Raw producer strings for beverages that have no producer yet
+	ListUnlinkedProducerNames(ctx context.Context) ([]ListUnlinkedProducerNamesRow, error)
+	// Taste profiles with no embedding for the model, or updated since it was built
+	ListUserEmbeddingsToRefresh(ctx context.Context, arg ListUserEmbeddingsToRefreshParams) ([]ListUserEmbeddingsToRefreshRow, error)
 	ListUsers(ctx context.Context, limit int32) ([]User, error)
 	ListVenues(ctx context.Context, arg ListVenuesParams) ([]Venue, error)
+	// Recounts every beverage's tags from post_tags, one per post tag like
+	// GetBeverageTags counts them for UpsertBeverageTagAggregate. Tags that no
+	// post has any more are dropped.
+	RebuildBeverageTagAggregates(ctx context.Context) (int64, error)
+	// Recomputes stats from posts for every beverage whose stored totals have
+	// drifted and returns the stored values alongside the corrected ones.
+	ReconcileBeverageStats(ctx context.Context) ([]ReconcileBeverageStatsRow, error)
+	RecordImpressionClick(ctx context.Context, arg RecordImpressionClickParams) error
 	RevokeAllRefreshTokensForUser(ctx context.Context, userID pgtype.UUID) error
 	RevokeRefreshToken(ctx context.Context, id pgtype.UUID) error
 	SearchBeveragesByTokens(ctx context.Context, arg SearchBeveragesByTokensParams) ([]SearchBeveragesByTokensRow, error)
+	SearchProducers(ctx context.Context, arg SearchProducersParams) ([]SearchProducersRow, error)
 	SearchVenues(ctx context.Context, arg SearchVenuesParams) ([]Venue, error)
+	SetBeverageProducer(ctx context.Context, arg SetBeverageProducerParams) error
+	SetCuratorAttribute(ctx context.Context, arg SetCuratorAttributeParams) (BeverageAttribute, error)
+	// Copies the winning attribute values onto the beverages row
+	SyncBeverageAttributeColumns(ctx context.Context, id pgtype.UUID) error
+	// Marks an embedding current when its source text has not changed
+	TouchBeverageEmbedding(ctx context.Context, beverageID pgtype.UUID) error
+	TouchUserEmbedding(ctx context.Context, arg TouchUserEmbeddingParams) error
+	// Takes a session-level advisory lock without waiting; false when another
+	// session holds it
+	TryAdvisoryLock(ctx context.Context, key int64) (bool, error)
 	UpdateBeerPostDetails(ctx context.Context, arg UpdateBeerPostDetailsParams) (BeerPostDetail, error)
-	UpdateBeverageStats(ctx context.Context, arg UpdateBeverageStatsParams) error
+	UpdateBeverageDetails(ctx context.Context, arg UpdateBeverageDetailsParams) (Beverage, error)
 	UpdateCocktailPostDetails(ctx context.Context, arg UpdateCocktailPostDetailsParams) (CocktailPostDetail, error)
 	UpdateMediaMetadata(ctx context.Context, arg UpdateMediaMetadataParams) (Medium, error)
 	UpdateMediaStatus(ctx context.Context, arg UpdateMediaStatusParams) (Medium, error)
 	UpdateOpenAIJobStatus(ctx context.Context, arg UpdateOpenAIJobStatusParams) error
 	UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error)
 	UpdateWinePostDetails(ctx context.Context, arg UpdateWinePostDetailsParams) (WinePostDetail, error)
+	UpsertBeverageEmbedding(ctx context.Context, arg UpsertBeverageEmbeddingParams) error
 	UpsertBeverageSummary(ctx context.Context, arg UpsertBeverageSummaryParams) (BeverageSummary, error)
 	UpsertBeverageTagAggregate(ctx context.Context, arg UpsertBeverageTagAggregateParams) error
+	// Curator overrides always win, so consensus never replaces them
+	UpsertConsensusAttribute(ctx context.Context, arg UpsertConsensusAttributeParams) error
+	UpsertTasteCompatibility(ctx context.Context, arg UpsertTasteCompatibilityParams) error
+	UpsertTasteContribution(ctx context.Context, arg UpsertTasteContributionParams) error
+	UpsertTasteDecaySetting(ctx context.Context, arg UpsertTasteDecaySettingParams) (TasteDecaySetting, error)
+	UpsertTasteQuizAnswer(ctx context.Context, arg UpsertTasteQuizAnswerParams) error
 	UpsertUserEmbedding(ctx context.Context, arg UpsertUserEmbeddingParams) (UserEmbedding, error)
 	UpsertUserTasteProfile(ctx context.Context, arg UpsertUserTasteProfileParams) (UserTasteProfile, error)
+	UpsertUserTasteStats(ctx context.Context, arg UpsertUserTasteStatsParams) error
 }
 
 var _ Querier = (*Queries)(nil)
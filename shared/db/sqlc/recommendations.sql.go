@@ -11,6 +11,37 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const attributeConversions = `-- name: AttributeConversions :execrows
+UPDATE recommendation_impressions ri
+SET converted_post_id = c.post_id, converted_at = c.created_at
+FROM (
+  SELECT DISTINCT ON (ri2.id) ri2.id AS impression_id, p.id AS post_id, p.created_at
+  FROM recommendation_impressions ri2
+  JOIN posts p ON p.user_id = ri2.user_id AND p.beverage_id = ri2.beverage_id
+  WHERE ri2.converted_post_id IS NULL
+    AND p.created_at >= $1
+    AND p.created_at >= ri2.created_at
+    AND p.created_at < ri2.created_at + $2::int * INTERVAL '1 day'
+  ORDER BY ri2.id, p.created_at
+) c
+WHERE ri.id = c.impression_id
+`
+
+type AttributeConversionsParams struct {
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	AttributionDays int32              `json:"attribution_days"`
+}
+
+// Marks unconverted impressions with the user's first post on the beverage
+// within attribution_days days of the impression, considering posts created since $1
+func (q *Queries) AttributeConversions(ctx context.Context, arg AttributeConversionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, attributeConversions, arg.CreatedAt, arg.AttributionDays)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createRecommendationFeedback = `-- name: CreateRecommendationFeedback :one
 
 INSERT INTO recommendation_feedback (user_id, beverage_id, feedback_type)
@@ -39,6 +70,16 @@ func (q *Queries) CreateRecommendationFeedback(ctx context.Context, arg CreateRe
 	return i, err
 }
 
+const deleteBeverageNeighborsForCategory = `-- name: DeleteBeverageNeighborsForCategory :exec
+DELETE FROM beverage_neighbors
+WHERE beverage_id IN (SELECT id FROM beverages WHERE category = $1)
+`
+
+func (q *Queries) DeleteBeverageNeighborsForCategory(ctx context.Context, category string) error {
+	_, err := q.db.Exec(ctx, deleteBeverageNeighborsForCategory, category)
+	return err
+}
+
 const deleteFeedback = `-- name: DeleteFeedback :exec
 DELETE FROM recommendation_feedback
 WHERE user_id = $1 AND beverage_id = $2 AND feedback_type = $3
@@ -55,8 +96,164 @@ func (q *Queries) DeleteFeedback(ctx context.Context, arg DeleteFeedbackParams)
 	return err
 }
 
+const deleteTasteContribution = `-- name: DeleteTasteContribution :exec
+DELETE FROM user_taste_contributions
+WHERE user_id = $1 AND category = $2 AND post_id = $3
+`
+
+type DeleteTasteContributionParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+	PostID   pgtype.UUID `json:"post_id"`
+}
+
+func (q *Queries) DeleteTasteContribution(ctx context.Context, arg DeleteTasteContributionParams) error {
+	_, err := q.db.Exec(ctx, deleteTasteContribution, arg.UserID, arg.Category, arg.PostID)
+	return err
+}
+
+const deleteTasteContributions = `-- name: DeleteTasteContributions :exec
+DELETE FROM user_taste_contributions
+WHERE user_id = $1 AND category = $2
+`
+
+type DeleteTasteContributionsParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+}
+
+func (q *Queries) DeleteTasteContributions(ctx context.Context, arg DeleteTasteContributionsParams) error {
+	_, err := q.db.Exec(ctx, deleteTasteContributions, arg.UserID, arg.Category)
+	return err
+}
+
+const deleteTasteProfileEvents = `-- name: DeleteTasteProfileEvents :execrows
+DELETE FROM taste_profile_events
+WHERE id IN (
+  SELECT id FROM taste_profile_events e
+  WHERE e.user_id = $1 AND e.category = $2 AND e.post_id = $3 AND e.id <= $4
+  FOR UPDATE SKIP LOCKED
+)
+`
+
+type DeleteTasteProfileEventsParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+	PostID   pgtype.UUID `json:"post_id"`
+	UpTo     int64       `json:"up_to"`
+}
+
+// Claims a post's queued events up to up_to; concurrent workers skip each
+// other's rows, so none claimed means another worker has them
+func (q *Queries) DeleteTasteProfileEvents(ctx context.Context, arg DeleteTasteProfileEventsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTasteProfileEvents,
+		arg.UserID,
+		arg.Category,
+		arg.PostID,
+		arg.UpTo,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getBeverageEmbeddingsByCategory = `-- name: GetBeverageEmbeddingsByCategory :many
+SELECT be.beverage_id, be.embedding_vector
+FROM beverage_embeddings be
+JOIN beverages b ON b.id = be.beverage_id
+WHERE b.category = $1 AND be.model = $2
+`
+
+type GetBeverageEmbeddingsByCategoryParams struct {
+	Category string `json:"category"`
+	Model    string `json:"model"`
+}
+
+type GetBeverageEmbeddingsByCategoryRow struct {
+	BeverageID      pgtype.UUID `json:"beverage_id"`
+	EmbeddingVector []float32   `json:"embedding_vector"`
+}
+
+func (q *Queries) GetBeverageEmbeddingsByCategory(ctx context.Context, arg GetBeverageEmbeddingsByCategoryParams) ([]GetBeverageEmbeddingsByCategoryRow, error) {
+	rows, err := q.db.Query(ctx, getBeverageEmbeddingsByCategory, arg.Category, arg.Model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBeverageEmbeddingsByCategoryRow
+	for rows.Next() {
+		var i GetBeverageEmbeddingsByCategoryRow
+		if err := rows.Scan(&i.BeverageID, &i.EmbeddingVector); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBeverageNeighbors = `-- name: GetBeverageNeighbors :many
+SELECT b.id, b.name, b.brand, b.category,
+       b.total_reviews AS review_count,
+       b.avg_rating,
+       bn.similarity, bn.co_raters
+FROM beverage_neighbors bn
+JOIN beverages b ON b.id = bn.neighbor_id
+WHERE bn.beverage_id = $1
+ORDER BY bn.similarity DESC
+LIMIT $2
+`
+
+type GetBeverageNeighborsParams struct {
+	BeverageID pgtype.UUID `json:"beverage_id"`
+	Limit      int32       `json:"limit"`
+}
+
+type GetBeverageNeighborsRow struct {
+	ID          pgtype.UUID    `json:"id"`
+	Name        string         `json:"name"`
+	Brand       pgtype.Text    `json:"brand"`
+	Category    string         `json:"category"`
+	ReviewCount pgtype.Int4    `json:"review_count"`
+	AvgRating   pgtype.Numeric `json:"avg_rating"`
+	Similarity  float64        `json:"similarity"`
+	CoRaters    int32          `json:"co_raters"`
+}
+
+func (q *Queries) GetBeverageNeighbors(ctx context.Context, arg GetBeverageNeighborsParams) ([]GetBeverageNeighborsRow, error) {
+	rows, err := q.db.Query(ctx, getBeverageNeighbors, arg.BeverageID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBeverageNeighborsRow
+	for rows.Next() {
+		var i GetBeverageNeighborsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Category,
+			&i.ReviewCount,
+			&i.AvgRating,
+			&i.Similarity,
+			&i.CoRaters,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getBeverageWithTags = `-- name: GetBeverageWithTags :one
-SELECT b.id, b.name, b.brand, b.category, b.vintage, b.image_url, b.name_normalized, b.brand_normalized, b.total_reviews, b.avg_rating, b.created_at, b.updated_at,
+SELECT b.id, b.name, b.brand, b.category, b.vintage, b.image_url, b.name_normalized, b.brand_normalized, b.total_reviews, b.avg_rating, b.created_at, b.updated_at, b.rating_count, b.rating_sum, b.producer_id, b.abv, b.ibu, b.style, b.varietal, b.region, b.attributes_computed_at,
        COALESCE(
          json_agg(
            json_build_object(
@@ -74,19 +271,28 @@ GROUP BY b.id
 `
 
 type GetBeverageWithTagsRow struct {
-	ID              pgtype.UUID        `json:"id"`
-	Name            string             `json:"name"`
-	Brand           pgtype.Text        `json:"brand"`
-	Category        string             `json:"category"`
-	Vintage         pgtype.Text        `json:"vintage"`
-	ImageUrl        pgtype.Text        `json:"image_url"`
-	NameNormalized  string             `json:"name_normalized"`
-	BrandNormalized pgtype.Text        `json:"brand_normalized"`
-	TotalReviews    pgtype.Int4        `json:"total_reviews"`
-	AvgRating       pgtype.Numeric     `json:"avg_rating"`
-	CreatedAt       pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
-	TagsJson        interface{}        `json:"tags_json"`
+	ID                   pgtype.UUID        `json:"id"`
+	Name                 string             `json:"name"`
+	Brand                pgtype.Text        `json:"brand"`
+	Category             string             `json:"category"`
+	Vintage              pgtype.Text        `json:"vintage"`
+	ImageUrl             pgtype.Text        `json:"image_url"`
+	NameNormalized       string             `json:"name_normalized"`
+	BrandNormalized      pgtype.Text        `json:"brand_normalized"`
+	TotalReviews         pgtype.Int4        `json:"total_reviews"`
+	AvgRating            pgtype.Numeric     `json:"avg_rating"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	RatingCount          int32              `json:"rating_count"`
+	RatingSum            pgtype.Numeric     `json:"rating_sum"`
+	ProducerID           pgtype.UUID        `json:"producer_id"`
+	Abv                  pgtype.Numeric     `json:"abv"`
+	Ibu                  pgtype.Int4        `json:"ibu"`
+	Style                pgtype.Text        `json:"style"`
+	Varietal             pgtype.Text        `json:"varietal"`
+	Region               pgtype.Text        `json:"region"`
+	AttributesComputedAt pgtype.Timestamptz `json:"attributes_computed_at"`
+	TagsJson             interface{}        `json:"tags_json"`
 }
 
 func (q *Queries) GetBeverageWithTags(ctx context.Context, id pgtype.UUID) (GetBeverageWithTagsRow, error) {
@@ -105,11 +311,216 @@ func (q *Queries) GetBeverageWithTags(ctx context.Context, id pgtype.UUID) (GetB
 		&i.AvgRating,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingCount,
+		&i.RatingSum,
+		&i.ProducerID,
+		&i.Abv,
+		&i.Ibu,
+		&i.Style,
+		&i.Varietal,
+		&i.Region,
+		&i.AttributesComputedAt,
 		&i.TagsJson,
 	)
 	return i, err
 }
 
+const getCategoryTagProfiles = `-- name: GetCategoryTagProfiles :many
+SELECT b.id, b.name, b.brand, b.style,
+       b.total_reviews AS review_count,
+       b.avg_rating,
+       COALESCE(pr.median_price_cents, 0)::int AS median_price_cents,
+       json_agg(json_build_object('tag', bta.tag, 'count', bta.count)) AS tags_json
+FROM beverages b
+JOIN beverage_tag_aggregates bta ON bta.beverage_id = b.id
+LEFT JOIN (
+  SELECT beverage_id, percentile_cont(0.5) WITHIN GROUP (ORDER BY price_cents) AS median_price_cents
+  FROM posts
+  WHERE beverage_id IS NOT NULL AND price_cents IS NOT NULL
+  GROUP BY beverage_id
+) pr ON pr.beverage_id = b.id
+WHERE b.category = $1
+GROUP BY b.id, pr.median_price_cents
+`
+
+type GetCategoryTagProfilesRow struct {
+	ID               pgtype.UUID    `json:"id"`
+	Name             string         `json:"name"`
+	Brand            pgtype.Text    `json:"brand"`
+	Style            pgtype.Text    `json:"style"`
+	ReviewCount      pgtype.Int4    `json:"review_count"`
+	AvgRating        pgtype.Numeric `json:"avg_rating"`
+	MedianPriceCents int32          `json:"median_price_cents"`
+	TagsJson         []byte         `json:"tags_json"`
+}
+
+// Every tagged beverage in a category with its tag counts, style and median
+// post price (0 when unknown), so TF-IDF similarity and its filters run in
+// one round trip
+func (q *Queries) GetCategoryTagProfiles(ctx context.Context, category string) ([]GetCategoryTagProfilesRow, error) {
+	rows, err := q.db.Query(ctx, getCategoryTagProfiles, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCategoryTagProfilesRow
+	for rows.Next() {
+		var i GetCategoryTagProfilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Style,
+			&i.ReviewCount,
+			&i.AvgRating,
+			&i.MedianPriceCents,
+			&i.TagsJson,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCoRatedBeverages = `-- name: GetCoRatedBeverages :many
+SELECT a.drink_category AS category, a.beverage_id,
+       a.rating::float8 AS rating,
+       b.rating::float8 AS other_rating
+FROM (
+  SELECT drink_category, beverage_id, AVG(rating) AS rating
+  FROM posts
+  WHERE user_id = $1 AND beverage_id IS NOT NULL AND rating IS NOT NULL
+  GROUP BY drink_category, beverage_id
+) a
+JOIN (
+  SELECT beverage_id, AVG(rating) AS rating
+  FROM posts
+  WHERE user_id = $2 AND beverage_id IS NOT NULL AND rating IS NOT NULL
+  GROUP BY beverage_id
+) b ON b.beverage_id = a.beverage_id
+ORDER BY a.drink_category, a.beverage_id
+`
+
+type GetCoRatedBeveragesParams struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	OtherUserID pgtype.UUID `json:"other_user_id"`
+}
+
+type GetCoRatedBeveragesRow struct {
+	Category    string      `json:"category"`
+	BeverageID  pgtype.UUID `json:"beverage_id"`
+	Rating      float64     `json:"rating"`
+	OtherRating float64     `json:"other_rating"`
+}
+
+// Both users' averaged ratings on every beverage each of them has rated
+func (q *Queries) GetCoRatedBeverages(ctx context.Context, arg GetCoRatedBeveragesParams) ([]GetCoRatedBeveragesRow, error) {
+	rows, err := q.db.Query(ctx, getCoRatedBeverages, arg.UserID, arg.OtherUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCoRatedBeveragesRow
+	for rows.Next() {
+		var i GetCoRatedBeveragesRow
+		if err := rows.Scan(
+			&i.Category,
+			&i.BeverageID,
+			&i.Rating,
+			&i.OtherRating,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExperimentEngagement = `-- name: GetExperimentEngagement :many
+SELECT rl.variant,
+       COUNT(*)::int AS lists,
+       COUNT(DISTINCT rl.user_id)::int AS users,
+       COALESCE(SUM(fb.positive), 0)::int AS positive_feedback,
+       COALESCE(SUM(fb.negative), 0)::int AS negative_feedback,
+       COALESCE(SUM(ps.posts), 0)::int AS posts,
+       COUNT(*) FILTER (WHERE ps.posts > 0)::int AS converted_lists
+FROM recommendation_lists rl
+LEFT JOIN LATERAL (
+  SELECT COUNT(*) FILTER (WHERE rf.feedback_type = 'more_like_this') AS positive,
+         COUNT(*) FILTER (WHERE rf.feedback_type IN ('less_like_this', 'hide')) AS negative
+  FROM recommendation_feedback rf
+  WHERE rf.user_id = rl.user_id
+    AND rf.beverage_id = ANY(rl.beverage_ids)
+    AND rf.created_at >= rl.created_at
+    AND rf.created_at < rl.created_at + $3::int * INTERVAL '1 day'
+) fb ON true
+LEFT JOIN LATERAL (
+  SELECT COUNT(*) AS posts
+  FROM posts p
+  WHERE p.user_id = rl.user_id
+    AND p.beverage_id = ANY(rl.beverage_ids)
+    AND p.created_at >= rl.created_at
+    AND p.created_at < rl.created_at + $3::int * INTERVAL '1 day'
+) ps ON true
+WHERE rl.experiment = $1
+  AND rl.created_at >= $2
+GROUP BY rl.variant
+ORDER BY rl.variant
+`
+
+type GetExperimentEngagementParams struct {
+	Experiment string             `json:"experiment"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	WindowDays int32              `json:"window_days"`
+}
+
+type GetExperimentEngagementRow struct {
+	Variant          string `json:"variant"`
+	Lists            int32  `json:"lists"`
+	Users            int32  `json:"users"`
+	PositiveFeedback int32  `json:"positive_feedback"`
+	NegativeFeedback int32  `json:"negative_feedback"`
+	Posts            int32  `json:"posts"`
+	ConvertedLists   int32  `json:"converted_lists"`
+}
+
+// Per variant: lists served since $2, and the feedback and posts the same
+// user left on listed beverages within window_days days of the list being served
+func (q *Queries) GetExperimentEngagement(ctx context.Context, arg GetExperimentEngagementParams) ([]GetExperimentEngagementRow, error) {
+	rows, err := q.db.Query(ctx, getExperimentEngagement, arg.Experiment, arg.CreatedAt, arg.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExperimentEngagementRow
+	for rows.Next() {
+		var i GetExperimentEngagementRow
+		if err := rows.Scan(
+			&i.Variant,
+			&i.Lists,
+			&i.Users,
+			&i.PositiveFeedback,
+			&i.NegativeFeedback,
+			&i.Posts,
+			&i.ConvertedLists,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getHiddenBeveragesForUser = `-- name: GetHiddenBeveragesForUser :many
 SELECT beverage_id FROM recommendation_feedback
 WHERE user_id = $1 AND feedback_type = 'hide'
@@ -135,77 +546,1508 @@ func (q *Queries) GetHiddenBeveragesForUser(ctx context.Context, userID pgtype.U
 	return items, nil
 }
 
-const getRecommendationCandidates = `-- name: GetRecommendationCandidates :many
+const getImpressionStatsByRank = `-- name: GetImpressionStatsByRank :many
+SELECT rank,
+       COUNT(*)::int AS impressions,
+       COUNT(clicked_at)::int AS clicks,
+       COUNT(converted_at)::int AS conversions
+FROM recommendation_impressions
+WHERE surface = $1
+  AND created_at >= $2
+  AND rank IS NOT NULL
+GROUP BY rank
+ORDER BY rank
+`
 
-SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
-       COUNT(DISTINCT p.id) AS review_count,
-       COALESCE(AVG(
-         CASE
-           WHEN p.score IS NOT NULL AND p.score != 0 THEN p.score
-           WHEN p.stars IS NOT NULL THEN p.stars * 2.0
-           ELSE NULL
-         END
-       ), 0) AS avg_rating
-FROM beverages b
-LEFT JOIN posts p ON b.id = p.beverage_id
-WHERE b.category = $1
-  AND b.id NOT IN (
-    SELECT rf.beverage_id FROM recommendation_feedback rf
-    WHERE rf.user_id = $2 AND rf.feedback_type = 'hide'
-  )
-  AND b.id NOT IN (
-    SELECT p2.beverage_id FROM posts p2
-    WHERE p2.user_id = $2 AND p2.beverage_id IS NOT NULL
-    ORDER BY p2.created_at DESC
-    LIMIT 20
-  )
-GROUP BY b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at
-HAVING COUNT(DISTINCT p.id) >= 2 OR EXISTS (
-  SELECT 1 FROM beverage_tag_aggregates bta
-  WHERE bta.beverage_id = b.id
-)
-ORDER BY review_count DESC, avg_rating DESC
-LIMIT $3
+type GetImpressionStatsByRankParams struct {
+	Surface   string             `json:"surface"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type GetImpressionStatsByRankRow struct {
+	Rank        pgtype.Int4 `json:"rank"`
+	Impressions int32       `json:"impressions"`
+	Clicks      int32       `json:"clicks"`
+	Conversions int32       `json:"conversions"`
+}
+
+func (q *Queries) GetImpressionStatsByRank(ctx context.Context, arg GetImpressionStatsByRankParams) ([]GetImpressionStatsByRankRow, error) {
+	rows, err := q.db.Query(ctx, getImpressionStatsByRank, arg.Surface, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetImpressionStatsByRankRow
+	for rows.Next() {
+		var i GetImpressionStatsByRankRow
+		if err := rows.Scan(
+			&i.Rank,
+			&i.Impressions,
+			&i.Clicks,
+			&i.Conversions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getImpressionStatsBySurface = `-- name: GetImpressionStatsBySurface :many
+SELECT surface,
+       COUNT(*)::int AS impressions,
+       COUNT(clicked_at)::int AS clicks,
+       COUNT(converted_at)::int AS conversions
+FROM recommendation_impressions
+WHERE created_at >= $1
+GROUP BY surface
+ORDER BY surface
 `
 
-type GetRecommendationCandidatesParams struct {
-	Category string      `json:"category"`
-	UserID   pgtype.UUID `json:"user_id"`
-	Limit    int32       `json:"limit"`
+type GetImpressionStatsBySurfaceRow struct {
+	Surface     string `json:"surface"`
+	Impressions int32  `json:"impressions"`
+	Clicks      int32  `json:"clicks"`
+	Conversions int32  `json:"conversions"`
+}
+
+func (q *Queries) GetImpressionStatsBySurface(ctx context.Context, createdAt pgtype.Timestamptz) ([]GetImpressionStatsBySurfaceRow, error) {
+	rows, err := q.db.Query(ctx, getImpressionStatsBySurface, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetImpressionStatsBySurfaceRow
+	for rows.Next() {
+		var i GetImpressionStatsBySurfaceRow
+		if err := rows.Scan(
+			&i.Surface,
+			&i.Impressions,
+			&i.Clicks,
+			&i.Conversions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostForTasteProfile = `-- name: GetPostForTasteProfile :many
+SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, p.rating, pt.tag, pt.tag_type, pt.confidence
+FROM posts p
+LEFT JOIN post_tags pt ON p.id = pt.post_id
+WHERE p.id = $1
+`
+
+type GetPostForTasteProfileRow struct {
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	VenueID               pgtype.UUID        `json:"venue_id"`
+	DrinkName             string             `json:"drink_name"`
+	DrinkCategory         string             `json:"drink_category"`
+	Stars                 pgtype.Int4        `json:"stars"`
+	Notes                 pgtype.Text        `json:"notes"`
+	WinePostDetailsID     pgtype.UUID        `json:"wine_post_details_id"`
+	BeerPostDetailsID     pgtype.UUID        `json:"beer_post_details_id"`
+	CocktailPostDetailsID pgtype.UUID        `json:"cocktail_post_details_id"`
+	PriceCents            pgtype.Int4        `json:"price_cents"`
+	PhotoUrl              pgtype.Text        `json:"photo_url"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+	Score                 pgtype.Numeric     `json:"score"`
+	BeverageID            pgtype.UUID        `json:"beverage_id"`
+	Rating                pgtype.Numeric     `json:"rating"`
+	Tag                   pgtype.Text        `json:"tag"`
+	TagType               pgtype.Text        `json:"tag_type"`
+	Confidence            pgtype.Numeric     `json:"confidence"`
+}
+
+func (q *Queries) GetPostForTasteProfile(ctx context.Context, id pgtype.UUID) ([]GetPostForTasteProfileRow, error) {
+	rows, err := q.db.Query(ctx, getPostForTasteProfile, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostForTasteProfileRow
+	for rows.Next() {
+		var i GetPostForTasteProfileRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.VenueID,
+			&i.DrinkName,
+			&i.DrinkCategory,
+			&i.Stars,
+			&i.Notes,
+			&i.WinePostDetailsID,
+			&i.BeerPostDetailsID,
+			&i.CocktailPostDetailsID,
+			&i.PriceCents,
+			&i.PhotoUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Score,
+			&i.BeverageID,
+			&i.Rating,
+			&i.Tag,
+			&i.TagType,
+			&i.Confidence,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecommendationCandidates = `-- name: GetRecommendationCandidates :many
+
+SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
+       b.total_reviews AS review_count,
+       b.avg_rating, b.producer_id, b.style
+FROM beverages b
+WHERE b.category = $1
+  AND b.id NOT IN (
+    SELECT rf.beverage_id FROM recommendation_feedback rf
+    WHERE rf.user_id = $2
+      AND (rf.feedback_type IN ('hide', 'already_tried')
+        OR (rf.feedback_type = 'not_available' AND rf.created_at > now() - interval '30 days'))
+  )
+  AND b.id NOT IN (
+    SELECT p2.beverage_id FROM posts p2
+    WHERE p2.user_id = $2 AND p2.beverage_id IS NOT NULL
+    ORDER BY p2.created_at DESC
+    LIMIT 20
+  )
+  AND (b.total_reviews >= 2 OR EXISTS (
+    SELECT 1 FROM beverage_tag_aggregates bta
+    WHERE bta.beverage_id = b.id
+  ))
+  AND COALESCE(b.total_reviews, 0) >= $3::int
+  AND ($4::numeric IS NULL OR b.abv >= $4::numeric)
+  AND ($5::numeric IS NULL OR b.abv <= $5::numeric)
+  AND (cardinality($6::text[]) = 0 OR lower(b.style) = ANY($6::text[]))
+  AND (cardinality($7::text[]) = 0 OR lower(b.region) = ANY($7::text[]))
+  AND (cardinality($8::text[]) = 0 OR lower(b.varietal) = ANY($8::text[]))
+  AND (NOT $9::bool OR NOT EXISTS (
+    SELECT 1 FROM posts pt
+    WHERE pt.user_id = $2 AND pt.beverage_id = b.id
+  ))
+  AND (($10::int IS NULL AND $11::int IS NULL) OR (
+    SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY pp.price_cents)
+    FROM posts pp
+    WHERE pp.beverage_id = b.id AND pp.price_cents IS NOT NULL
+  ) BETWEEN COALESCE($10::int, 0) AND COALESCE($11::int, 2147483647))
+ORDER BY b.total_reviews DESC, b.avg_rating DESC
+LIMIT $12
+`
+
+type GetRecommendationCandidatesParams struct {
+	Category      string         `json:"category"`
+	UserID        pgtype.UUID    `json:"user_id"`
+	MinReviews    int32          `json:"min_reviews"`
+	MinAbv        pgtype.Numeric `json:"min_abv"`
+	MaxAbv        pgtype.Numeric `json:"max_abv"`
+	Styles        []string       `json:"styles"`
+	Regions       []string       `json:"regions"`
+	Varietals     []string       `json:"varietals"`
+	ExcludeTried  bool           `json:"exclude_tried"`
+	MinPriceCents pgtype.Int4    `json:"min_price_cents"`
+	MaxPriceCents pgtype.Int4    `json:"max_price_cents"`
+	Limit         int32          `json:"limit"`
+}
+
+type GetRecommendationCandidatesRow struct {
+	ID          pgtype.UUID        `json:"id"`
+	Name        string             `json:"name"`
+	Brand       pgtype.Text        `json:"brand"`
+	Category    string             `json:"category"`
+	ImageUrl    pgtype.Text        `json:"image_url"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ReviewCount pgtype.Int4        `json:"review_count"`
+	AvgRating   pgtype.Numeric     `json:"avg_rating"`
+	ProducerID  pgtype.UUID        `json:"producer_id"`
+	Style       pgtype.Text        `json:"style"`
+}
+
+// Recommendation Candidates
+func (q *Queries) GetRecommendationCandidates(ctx context.Context, arg GetRecommendationCandidatesParams) ([]GetRecommendationCandidatesRow, error) {
+	rows, err := q.db.Query(ctx, getRecommendationCandidates,
+		arg.Category,
+		arg.UserID,
+		arg.MinReviews,
+		arg.MinAbv,
+		arg.MaxAbv,
+		arg.Styles,
+		arg.Regions,
+		arg.Varietals,
+		arg.ExcludeTried,
+		arg.MinPriceCents,
+		arg.MaxPriceCents,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecommendationCandidatesRow
+	for rows.Next() {
+		var i GetRecommendationCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Category,
+			&i.ImageUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReviewCount,
+			&i.AvgRating,
+			&i.ProducerID,
+			&i.Style,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecommendationCandidatesByIDs = `-- name: GetRecommendationCandidatesByIDs :many
+SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
+       b.total_reviews AS review_count,
+       b.avg_rating, b.producer_id, b.style
+FROM beverages b
+WHERE b.category = $1
+  AND b.id = ANY($2::UUID[])
+  AND b.id NOT IN (
+    SELECT rf.beverage_id FROM recommendation_feedback rf
+    WHERE rf.user_id = $3
+      AND (rf.feedback_type IN ('hide', 'already_tried')
+        OR (rf.feedback_type = 'not_available' AND rf.created_at > now() - interval '30 days'))
+  )
+  AND b.id NOT IN (
+    SELECT p2.beverage_id FROM posts p2
+    WHERE p2.user_id = $3 AND p2.beverage_id IS NOT NULL
+    ORDER BY p2.created_at DESC
+    LIMIT 20
+  )
+  AND COALESCE(b.total_reviews, 0) >= $4::int
+  AND ($5::numeric IS NULL OR b.abv >= $5::numeric)
+  AND ($6::numeric IS NULL OR b.abv <= $6::numeric)
+  AND (cardinality($7::text[]) = 0 OR lower(b.style) = ANY($7::text[]))
+  AND (cardinality($8::text[]) = 0 OR lower(b.region) = ANY($8::text[]))
+  AND (cardinality($9::text[]) = 0 OR lower(b.varietal) = ANY($9::text[]))
+  AND (NOT $10::bool OR NOT EXISTS (
+    SELECT 1 FROM posts pt
+    WHERE pt.user_id = $3 AND pt.beverage_id = b.id
+  ))
+  AND (($11::int IS NULL AND $12::int IS NULL) OR (
+    SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY pp.price_cents)
+    FROM posts pp
+    WHERE pp.beverage_id = b.id AND pp.price_cents IS NOT NULL
+  ) BETWEEN COALESCE($11::int, 0) AND COALESCE($12::int, 2147483647))
+`
+
+type GetRecommendationCandidatesByIDsParams struct {
+	Category      string         `json:"category"`
+	Ids           []pgtype.UUID  `json:"ids"`
+	UserID        pgtype.UUID    `json:"user_id"`
+	MinReviews    int32          `json:"min_reviews"`
+	MinAbv        pgtype.Numeric `json:"min_abv"`
+	MaxAbv        pgtype.Numeric `json:"max_abv"`
+	Styles        []string       `json:"styles"`
+	Regions       []string       `json:"regions"`
+	Varietals     []string       `json:"varietals"`
+	ExcludeTried  bool           `json:"exclude_tried"`
+	MinPriceCents pgtype.Int4    `json:"min_price_cents"`
+	MaxPriceCents pgtype.Int4    `json:"max_price_cents"`
+}
+
+type GetRecommendationCandidatesByIDsRow struct {
+	ID          pgtype.UUID        `json:"id"`
+	Name        string             `json:"name"`
+	Brand       pgtype.Text        `json:"brand"`
+	Category    string             `json:"category"`
+	ImageUrl    pgtype.Text        `json:"image_url"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ReviewCount pgtype.Int4        `json:"review_count"`
+	AvgRating   pgtype.Numeric     `json:"avg_rating"`
+	ProducerID  pgtype.UUID        `json:"producer_id"`
+	Style       pgtype.Text        `json:"style"`
+}
+
+// Candidates found outside the popularity query, with the same exclusions
+func (q *Queries) GetRecommendationCandidatesByIDs(ctx context.Context, arg GetRecommendationCandidatesByIDsParams) ([]GetRecommendationCandidatesByIDsRow, error) {
+	rows, err := q.db.Query(ctx, getRecommendationCandidatesByIDs,
+		arg.Category,
+		arg.Ids,
+		arg.UserID,
+		arg.MinReviews,
+		arg.MinAbv,
+		arg.MaxAbv,
+		arg.Styles,
+		arg.Regions,
+		arg.Varietals,
+		arg.ExcludeTried,
+		arg.MinPriceCents,
+		arg.MaxPriceCents,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecommendationCandidatesByIDsRow
+	for rows.Next() {
+		var i GetRecommendationCandidatesByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Category,
+			&i.ImageUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReviewCount,
+			&i.AvgRating,
+			&i.ProducerID,
+			&i.Style,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagsForBeverages = `-- name: GetTagsForBeverages :many
+SELECT beverage_id, tag, tag_type, count
+FROM beverage_tag_aggregates
+WHERE beverage_id = ANY($1::UUID[])
+ORDER BY beverage_id, count DESC, tag
+`
+
+type GetTagsForBeveragesRow struct {
+	BeverageID pgtype.UUID `json:"beverage_id"`
+	Tag        string      `json:"tag"`
+	TagType    string      `json:"tag_type"`
+	Count      int32       `json:"count"`
+}
+
+// Tag aggregates for a batch of beverages, so candidates can be scored in one round trip
+func (q *Queries) GetTagsForBeverages(ctx context.Context, dollar_1 []pgtype.UUID) ([]GetTagsForBeveragesRow, error) {
+	rows, err := q.db.Query(ctx, getTagsForBeverages, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForBeveragesRow
+	for rows.Next() {
+		var i GetTagsForBeveragesRow
+		if err := rows.Scan(
+			&i.BeverageID,
+			&i.Tag,
+			&i.TagType,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTasteCompatibility = `-- name: GetTasteCompatibility :many
+SELECT user_id, other_user_id, category, score, tag_similarity, rating_agreement, co_rated, shared_tags_json, clashing_tags_json, profile_computed_at, other_profile_computed_at, computed_at FROM taste_compatibility
+WHERE user_id = $1 AND other_user_id = $2
+`
+
+type GetTasteCompatibilityParams struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	OtherUserID pgtype.UUID `json:"other_user_id"`
+}
+
+func (q *Queries) GetTasteCompatibility(ctx context.Context, arg GetTasteCompatibilityParams) ([]TasteCompatibility, error) {
+	rows, err := q.db.Query(ctx, getTasteCompatibility, arg.UserID, arg.OtherUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TasteCompatibility
+	for rows.Next() {
+		var i TasteCompatibility
+		if err := rows.Scan(
+			&i.UserID,
+			&i.OtherUserID,
+			&i.Category,
+			&i.Score,
+			&i.TagSimilarity,
+			&i.RatingAgreement,
+			&i.CoRated,
+			&i.SharedTagsJson,
+			&i.ClashingTagsJson,
+			&i.ProfileComputedAt,
+			&i.OtherProfileComputedAt,
+			&i.ComputedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTasteContribution = `-- name: GetTasteContribution :one
+SELECT user_id, category, post_id, rating, weight, tags_json, applied_at FROM user_taste_contributions
+WHERE user_id = $1 AND category = $2 AND post_id = $3
+`
+
+type GetTasteContributionParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+	PostID   pgtype.UUID `json:"post_id"`
+}
+
+func (q *Queries) GetTasteContribution(ctx context.Context, arg GetTasteContributionParams) (UserTasteContribution, error) {
+	row := q.db.QueryRow(ctx, getTasteContribution, arg.UserID, arg.Category, arg.PostID)
+	var i UserTasteContribution
+	err := row.Scan(
+		&i.UserID,
+		&i.Category,
+		&i.PostID,
+		&i.Rating,
+		&i.Weight,
+		&i.TagsJson,
+		&i.AppliedAt,
+	)
+	return i, err
+}
+
+const getTasteDecaySetting = `-- name: GetTasteDecaySetting :one
+SELECT category, half_life_days, updated_at FROM taste_decay_settings
+WHERE category = $1
+`
+
+func (q *Queries) GetTasteDecaySetting(ctx context.Context, category string) (TasteDecaySetting, error) {
+	row := q.db.QueryRow(ctx, getTasteDecaySetting, category)
+	var i TasteDecaySetting
+	err := row.Scan(&i.Category, &i.HalfLifeDays, &i.UpdatedAt)
+	return i, err
+}
+
+const getUserEmbedding = `-- name: GetUserEmbedding :one
+
+SELECT user_id, category, embedding_text, embedding_vector, model, updated_at FROM user_embeddings
+WHERE user_id = $1 AND category = $2
+`
+
+type GetUserEmbeddingParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+}
+
+// User Embeddings (optional)
+func (q *Queries) GetUserEmbedding(ctx context.Context, arg GetUserEmbeddingParams) (UserEmbedding, error) {
+	row := q.db.QueryRow(ctx, getUserEmbedding, arg.UserID, arg.Category)
+	var i UserEmbedding
+	err := row.Scan(
+		&i.UserID,
+		&i.Category,
+		&i.EmbeddingText,
+		&i.EmbeddingVector,
+		&i.Model,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserFeedback = `-- name: GetUserFeedback :many
+SELECT id, user_id, beverage_id, feedback_type, created_at FROM recommendation_feedback
+WHERE user_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserFeedback(ctx context.Context, userID pgtype.UUID) ([]RecommendationFeedback, error) {
+	rows, err := q.db.Query(ctx, getUserFeedback, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecommendationFeedback
+	for rows.Next() {
+		var i RecommendationFeedback
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.BeverageID,
+			&i.FeedbackType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFeedbackForBeverage = `-- name: GetUserFeedbackForBeverage :many
+SELECT id, user_id, beverage_id, feedback_type, created_at FROM recommendation_feedback
+WHERE user_id = $1 AND beverage_id = $2
+`
+
+type GetUserFeedbackForBeverageParams struct {
+	UserID     pgtype.UUID `json:"user_id"`
+	BeverageID pgtype.UUID `json:"beverage_id"`
+}
+
+func (q *Queries) GetUserFeedbackForBeverage(ctx context.Context, arg GetUserFeedbackForBeverageParams) ([]RecommendationFeedback, error) {
+	rows, err := q.db.Query(ctx, getUserFeedbackForBeverage, arg.UserID, arg.BeverageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecommendationFeedback
+	for rows.Next() {
+		var i RecommendationFeedback
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.BeverageID,
+			&i.FeedbackType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFeedbackForCategory = `-- name: GetUserFeedbackForCategory :many
+SELECT rf.id, rf.user_id, rf.beverage_id, rf.feedback_type, rf.created_at FROM recommendation_feedback rf
+JOIN beverages b ON b.id = rf.beverage_id
+WHERE rf.user_id = $1 AND b.category = $2
+ORDER BY rf.created_at, rf.id
+`
+
+type GetUserFeedbackForCategoryParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+}
+
+// A user's feedback on beverages in a category, oldest first, for profiles to replay
+func (q *Queries) GetUserFeedbackForCategory(ctx context.Context, arg GetUserFeedbackForCategoryParams) ([]RecommendationFeedback, error) {
+	rows, err := q.db.Query(ctx, getUserFeedbackForCategory, arg.UserID, arg.Category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecommendationFeedback
+	for rows.Next() {
+		var i RecommendationFeedback
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.BeverageID,
+			&i.FeedbackType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserNeighborScores = `-- name: GetUserNeighborScores :many
+WITH rated AS (
+  SELECT p.beverage_id, AVG(p.rating) AS rating
+  FROM posts p
+  WHERE p.user_id = $1
+    AND p.drink_category = $2
+    AND p.beverage_id IS NOT NULL
+    AND p.rating IS NOT NULL
+  GROUP BY p.beverage_id
+),
+liked AS (
+  SELECT beverage_id FROM rated
+  WHERE rating >= (SELECT AVG(rating) FROM rated)
+)
+SELECT bn.neighbor_id,
+       SUM(bn.similarity)::float8 AS score,
+       (array_agg(b.name ORDER BY bn.similarity DESC))[1]::text AS because_name
+FROM liked l
+JOIN beverage_neighbors bn ON bn.beverage_id = l.beverage_id
+JOIN beverages b ON b.id = l.beverage_id
+GROUP BY bn.neighbor_id
+ORDER BY score DESC
+LIMIT $3
+`
+
+type GetUserNeighborScoresParams struct {
+	UserID        pgtype.UUID `json:"user_id"`
+	DrinkCategory string      `json:"drink_category"`
+	Limit         int32       `json:"limit"`
+}
+
+type GetUserNeighborScoresRow struct {
+	NeighborID  pgtype.UUID `json:"neighbor_id"`
+	Score       float64     `json:"score"`
+	BecauseName string      `json:"because_name"`
+}
+
+// Neighbors of the beverages a user rated at or above their own average,
+// with the liked beverage that contributes most to each
+func (q *Queries) GetUserNeighborScores(ctx context.Context, arg GetUserNeighborScoresParams) ([]GetUserNeighborScoresRow, error) {
+	rows, err := q.db.Query(ctx, getUserNeighborScores, arg.UserID, arg.DrinkCategory, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserNeighborScoresRow
+	for rows.Next() {
+		var i GetUserNeighborScoresRow
+		if err := rows.Scan(&i.NeighborID, &i.Score, &i.BecauseName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserPostCountByCategory = `-- name: GetUserPostCountByCategory :one
+SELECT COUNT(*) AS count
+FROM posts
+WHERE user_id = $1 AND drink_category = $2
+`
+
+type GetUserPostCountByCategoryParams struct {
+	UserID        pgtype.UUID `json:"user_id"`
+	DrinkCategory string      `json:"drink_category"`
+}
+
+func (q *Queries) GetUserPostCountByCategory(ctx context.Context, arg GetUserPostCountByCategoryParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getUserPostCountByCategory, arg.UserID, arg.DrinkCategory)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getUserPostsForCategory = `-- name: GetUserPostsForCategory :many
+SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, p.rating, pt.tag, pt.tag_type, pt.confidence
+FROM posts p
+LEFT JOIN post_tags pt ON p.id = pt.post_id
+WHERE p.user_id = $1
+  AND p.drink_category = $2
+ORDER BY p.created_at DESC
+`
+
+type GetUserPostsForCategoryParams struct {
+	UserID        pgtype.UUID `json:"user_id"`
+	DrinkCategory string      `json:"drink_category"`
+}
+
+type GetUserPostsForCategoryRow struct {
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	VenueID               pgtype.UUID        `json:"venue_id"`
+	DrinkName             string             `json:"drink_name"`
+	DrinkCategory         string             `json:"drink_category"`
+	Stars                 pgtype.Int4        `json:"stars"`
+	Notes                 pgtype.Text        `json:"notes"`
+	WinePostDetailsID     pgtype.UUID        `json:"wine_post_details_id"`
+	BeerPostDetailsID     pgtype.UUID        `json:"beer_post_details_id"`
+	CocktailPostDetailsID pgtype.UUID        `json:"cocktail_post_details_id"`
+	PriceCents            pgtype.Int4        `json:"price_cents"`
+	PhotoUrl              pgtype.Text        `json:"photo_url"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+	Score                 pgtype.Numeric     `json:"score"`
+	BeverageID            pgtype.UUID        `json:"beverage_id"`
+	Rating                pgtype.Numeric     `json:"rating"`
+	Tag                   pgtype.Text        `json:"tag"`
+	TagType               pgtype.Text        `json:"tag_type"`
+	Confidence            pgtype.Numeric     `json:"confidence"`
+}
+
+func (q *Queries) GetUserPostsForCategory(ctx context.Context, arg GetUserPostsForCategoryParams) ([]GetUserPostsForCategoryRow, error) {
+	rows, err := q.db.Query(ctx, getUserPostsForCategory, arg.UserID, arg.DrinkCategory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserPostsForCategoryRow
+	for rows.Next() {
+		var i GetUserPostsForCategoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.VenueID,
+			&i.DrinkName,
+			&i.DrinkCategory,
+			&i.Stars,
+			&i.Notes,
+			&i.WinePostDetailsID,
+			&i.BeerPostDetailsID,
+			&i.CocktailPostDetailsID,
+			&i.PriceCents,
+			&i.PhotoUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Score,
+			&i.BeverageID,
+			&i.Rating,
+			&i.Tag,
+			&i.TagType,
+			&i.Confidence,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserTasteProfile = `-- name: GetUserTasteProfile :one
+
+SELECT user_id, category, liked_tags_json, disliked_tags_json, mean_rating, std_rating, post_count, last_computed_at, updated_at, all_time_liked_tags_json, all_time_disliked_tags_json, all_time_mean_rating, all_time_std_rating, half_life_days, inferred_from, quiz_weight FROM user_taste_profiles
+WHERE user_id = $1 AND category = $2
+`
+
+type GetUserTasteProfileParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+}
+
+// User Taste Profiles
+func (q *Queries) GetUserTasteProfile(ctx context.Context, arg GetUserTasteProfileParams) (UserTasteProfile, error) {
+	row := q.db.QueryRow(ctx, getUserTasteProfile, arg.UserID, arg.Category)
+	var i UserTasteProfile
+	err := row.Scan(
+		&i.UserID,
+		&i.Category,
+		&i.LikedTagsJson,
+		&i.DislikedTagsJson,
+		&i.MeanRating,
+		&i.StdRating,
+		&i.PostCount,
+		&i.LastComputedAt,
+		&i.UpdatedAt,
+		&i.AllTimeLikedTagsJson,
+		&i.AllTimeDislikedTagsJson,
+		&i.AllTimeMeanRating,
+		&i.AllTimeStdRating,
+		&i.HalfLifeDays,
+		&i.InferredFrom,
+		&i.QuizWeight,
+	)
+	return i, err
+}
+
+const getUserTasteStatsForUpdate = `-- name: GetUserTasteStatsForUpdate :one
+SELECT user_id, category, half_life_days, recent_json, all_time_json, as_of, full_recompute_at, updated_at FROM user_taste_stats
+WHERE user_id = $1 AND category = $2
+FOR UPDATE
+`
+
+type GetUserTasteStatsForUpdateParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+}
+
+func (q *Queries) GetUserTasteStatsForUpdate(ctx context.Context, arg GetUserTasteStatsForUpdateParams) (UserTasteStat, error) {
+	row := q.db.QueryRow(ctx, getUserTasteStatsForUpdate, arg.UserID, arg.Category)
+	var i UserTasteStat
+	err := row.Scan(
+		&i.UserID,
+		&i.Category,
+		&i.HalfLifeDays,
+		&i.RecentJson,
+		&i.AllTimeJson,
+		&i.AsOf,
+		&i.FullRecomputeAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getVenueCandidates = `-- name: GetVenueCandidates :many
+SELECT b.id, b.name, b.brand, b.category, b.image_url, b.created_at, b.updated_at,
+       b.total_reviews AS review_count,
+       b.avg_rating, b.producer_id, b.style,
+       s.sightings::int AS sightings,
+       s.last_seen_at::timestamptz AS last_seen_at,
+       COALESCE(s.last_price_cents, 0)::int AS last_price_cents
+FROM (
+  SELECT p.beverage_id,
+         COUNT(*) AS sightings,
+         MAX(p.created_at) AS last_seen_at,
+         (ARRAY_AGG(p.price_cents ORDER BY p.created_at DESC) FILTER (WHERE p.price_cents IS NOT NULL))[1] AS last_price_cents
+  FROM posts p
+  JOIN venues v ON p.venue_id = v.id
+  WHERE (v.id = $1 OR v.external_place_id = $2)
+    AND p.beverage_id IS NOT NULL
+  GROUP BY p.beverage_id
+) s
+JOIN beverages b ON b.id = s.beverage_id
+WHERE b.category = $3
+  AND b.id NOT IN (
+    SELECT rf.beverage_id FROM recommendation_feedback rf
+    WHERE rf.user_id = $4
+      AND rf.feedback_type IN ('hide', 'already_tried')
+  )
+  AND COALESCE(b.total_reviews, 0) >= $5::int
+  AND ($6::numeric IS NULL OR b.abv >= $6::numeric)
+  AND ($7::numeric IS NULL OR b.abv <= $7::numeric)
+  AND (cardinality($8::text[]) = 0 OR lower(b.style) = ANY($8::text[]))
+  AND (cardinality($9::text[]) = 0 OR lower(b.region) = ANY($9::text[]))
+  AND (cardinality($10::text[]) = 0 OR lower(b.varietal) = ANY($10::text[]))
+  AND (NOT $11::bool OR NOT EXISTS (
+    SELECT 1 FROM posts pt
+    WHERE pt.user_id = $4 AND pt.beverage_id = b.id
+  ))
+  AND (($12::int IS NULL AND $13::int IS NULL) OR (
+    SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY pp.price_cents)
+    FROM posts pp
+    WHERE pp.beverage_id = b.id AND pp.price_cents IS NOT NULL
+  ) BETWEEN COALESCE($12::int, 0) AND COALESCE($13::int, 2147483647))
+ORDER BY s.last_seen_at DESC
+LIMIT $14
+`
+
+type GetVenueCandidatesParams struct {
+	VenueID         pgtype.UUID    `json:"venue_id"`
+	ExternalPlaceID pgtype.Text    `json:"external_place_id"`
+	Category        string         `json:"category"`
+	UserID          pgtype.UUID    `json:"user_id"`
+	MinReviews      int32          `json:"min_reviews"`
+	MinAbv          pgtype.Numeric `json:"min_abv"`
+	MaxAbv          pgtype.Numeric `json:"max_abv"`
+	Styles          []string       `json:"styles"`
+	Regions         []string       `json:"regions"`
+	Varietals       []string       `json:"varietals"`
+	ExcludeTried    bool           `json:"exclude_tried"`
+	MinPriceCents   pgtype.Int4    `json:"min_price_cents"`
+	MaxPriceCents   pgtype.Int4    `json:"max_price_cents"`
+	Limit           int32          `json:"limit"`
+}
+
+type GetVenueCandidatesRow struct {
+	ID             pgtype.UUID        `json:"id"`
+	Name           string             `json:"name"`
+	Brand          pgtype.Text        `json:"brand"`
+	Category       string             `json:"category"`
+	ImageUrl       pgtype.Text        `json:"image_url"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	ReviewCount    pgtype.Int4        `json:"review_count"`
+	AvgRating      pgtype.Numeric     `json:"avg_rating"`
+	ProducerID     pgtype.UUID        `json:"producer_id"`
+	Style          pgtype.Text        `json:"style"`
+	Sightings      int32              `json:"sightings"`
+	LastSeenAt     pgtype.Timestamptz `json:"last_seen_at"`
+	LastPriceCents int32              `json:"last_price_cents"`
+}
+
+// Beverages posted at a venue, matched by venue ID or map provider place ID,
+// with when they were last seen there and the last price posted (0 if none)
+func (q *Queries) GetVenueCandidates(ctx context.Context, arg GetVenueCandidatesParams) ([]GetVenueCandidatesRow, error) {
+	rows, err := q.db.Query(ctx, getVenueCandidates,
+		arg.VenueID,
+		arg.ExternalPlaceID,
+		arg.Category,
+		arg.UserID,
+		arg.MinReviews,
+		arg.MinAbv,
+		arg.MaxAbv,
+		arg.Styles,
+		arg.Regions,
+		arg.Varietals,
+		arg.ExcludeTried,
+		arg.MinPriceCents,
+		arg.MaxPriceCents,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVenueCandidatesRow
+	for rows.Next() {
+		var i GetVenueCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Category,
+			&i.ImageUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReviewCount,
+			&i.AvgRating,
+			&i.ProducerID,
+			&i.Style,
+			&i.Sightings,
+			&i.LastSeenAt,
+			&i.LastPriceCents,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertBeverageNeighbor = `-- name: InsertBeverageNeighbor :exec
+INSERT INTO beverage_neighbors (beverage_id, neighbor_id, similarity, co_raters)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertBeverageNeighborParams struct {
+	BeverageID pgtype.UUID `json:"beverage_id"`
+	NeighborID pgtype.UUID `json:"neighbor_id"`
+	Similarity float64     `json:"similarity"`
+	CoRaters   int32       `json:"co_raters"`
+}
+
+func (q *Queries) InsertBeverageNeighbor(ctx context.Context, arg InsertBeverageNeighborParams) error {
+	_, err := q.db.Exec(ctx, insertBeverageNeighbor,
+		arg.BeverageID,
+		arg.NeighborID,
+		arg.Similarity,
+		arg.CoRaters,
+	)
+	return err
+}
+
+const insertRecommendationImpressions = `-- name: InsertRecommendationImpressions :many
+INSERT INTO recommendation_impressions (user_id, surface, list_id, beverage_id, rank, match_score)
+SELECT $1::UUID, $2::TEXT, $3::UUID,
+       i.beverage_id, NULLIF(i.rank, 0), i.match_score
+FROM unnest($4::UUID[], $5::INT[], $6::INT[]) AS i(beverage_id, rank, match_score)
+RETURNING id, beverage_id
+`
+
+type InsertRecommendationImpressionsParams struct {
+	UserID      pgtype.UUID   `json:"user_id"`
+	Surface     string        `json:"surface"`
+	ListID      pgtype.UUID   `json:"list_id"`
+	BeverageIds []pgtype.UUID `json:"beverage_ids"`
+	Ranks       []int32       `json:"ranks"`
+	MatchScores []int32       `json:"match_scores"`
+}
+
+type InsertRecommendationImpressionsRow struct {
+	ID         pgtype.UUID `json:"id"`
+	BeverageID pgtype.UUID `json:"beverage_id"`
+}
+
+// Logs one impression per beverage from parallel arrays; a rank of 0 is stored as NULL
+func (q *Queries) InsertRecommendationImpressions(ctx context.Context, arg InsertRecommendationImpressionsParams) ([]InsertRecommendationImpressionsRow, error) {
+	rows, err := q.db.Query(ctx, insertRecommendationImpressions,
+		arg.UserID,
+		arg.Surface,
+		arg.ListID,
+		arg.BeverageIds,
+		arg.Ranks,
+		arg.MatchScores,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []InsertRecommendationImpressionsRow
+	for rows.Next() {
+		var i InsertRecommendationImpressionsRow
+		if err := rows.Scan(&i.ID, &i.BeverageID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertRecommendationList = `-- name: InsertRecommendationList :one
+INSERT INTO recommendation_lists (user_id, category, experiment, variant, strategy, beverage_ids)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, category, experiment, variant, strategy, beverage_ids, created_at
+`
+
+type InsertRecommendationListParams struct {
+	UserID      pgtype.UUID   `json:"user_id"`
+	Category    string        `json:"category"`
+	Experiment  string        `json:"experiment"`
+	Variant     string        `json:"variant"`
+	Strategy    string        `json:"strategy"`
+	BeverageIds []pgtype.UUID `json:"beverage_ids"`
+}
+
+func (q *Queries) InsertRecommendationList(ctx context.Context, arg InsertRecommendationListParams) (RecommendationList, error) {
+	row := q.db.QueryRow(ctx, insertRecommendationList,
+		arg.UserID,
+		arg.Category,
+		arg.Experiment,
+		arg.Variant,
+		arg.Strategy,
+		arg.BeverageIds,
+	)
+	var i RecommendationList
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Category,
+		&i.Experiment,
+		&i.Variant,
+		&i.Strategy,
+		&i.BeverageIds,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listBeveragesNeedingEmbeddings = `-- name: ListBeveragesNeedingEmbeddings :many
+SELECT b.id, b.name, b.brand, b.category, b.style, b.varietal, b.region,
+       be.embedding_text AS current_text, be.model AS current_model
+FROM beverages b
+LEFT JOIN beverage_embeddings be ON be.beverage_id = b.id
+WHERE be.beverage_id IS NULL
+   OR be.model <> $1
+   OR be.updated_at < b.updated_at
+   OR EXISTS (
+     SELECT 1 FROM beverage_tag_aggregates bta
+     WHERE bta.beverage_id = b.id AND bta.updated_at > be.updated_at
+   )
+ORDER BY b.total_reviews DESC NULLS LAST
+LIMIT $2
+`
+
+type ListBeveragesNeedingEmbeddingsParams struct {
+	Model string `json:"model"`
+	Limit int32  `json:"limit"`
+}
+
+type ListBeveragesNeedingEmbeddingsRow struct {
+	ID           pgtype.UUID `json:"id"`
+	Name         string      `json:"name"`
+	Brand        pgtype.Text `json:"brand"`
+	Category     string      `json:"category"`
+	Style        pgtype.Text `json:"style"`
+	Varietal     pgtype.Text `json:"varietal"`
+	Region       pgtype.Text `json:"region"`
+	CurrentText  pgtype.Text `json:"current_text"`
+	CurrentModel pgtype.Text `json:"current_model"`
+}
+
+// Beverages with no embedding for the model, or whose details or tags changed since
+func (q *Queries) ListBeveragesNeedingEmbeddings(ctx context.Context, arg ListBeveragesNeedingEmbeddingsParams) ([]ListBeveragesNeedingEmbeddingsRow, error) {
+	rows, err := q.db.Query(ctx, listBeveragesNeedingEmbeddings, arg.Model, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBeveragesNeedingEmbeddingsRow
+	for rows.Next() {
+		var i ListBeveragesNeedingEmbeddingsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Category,
+			&i.Style,
+			&i.Varietal,
+			&i.Region,
+			&i.CurrentText,
+			&i.CurrentModel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoRatingInputs = `-- name: ListCoRatingInputs :many
+SELECT p.user_id, p.beverage_id, AVG(p.rating)::float8 AS rating
+FROM posts p
+WHERE p.drink_category = $1
+  AND p.beverage_id IS NOT NULL
+  AND p.rating IS NOT NULL
+GROUP BY p.user_id, p.beverage_id
+ORDER BY p.user_id
+`
+
+type ListCoRatingInputsRow struct {
+	UserID     pgtype.UUID `json:"user_id"`
+	BeverageID pgtype.UUID `json:"beverage_id"`
+	Rating     float64     `json:"rating"`
+}
+
+// One averaged rating per user and beverage in a category
+func (q *Queries) ListCoRatingInputs(ctx context.Context, drinkCategory string) ([]ListCoRatingInputsRow, error) {
+	rows, err := q.db.Query(ctx, listCoRatingInputs, drinkCategory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCoRatingInputsRow
+	for rows.Next() {
+		var i ListCoRatingInputsRow
+		if err := rows.Scan(&i.UserID, &i.BeverageID, &i.Rating); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEvaluationBeverages = `-- name: ListEvaluationBeverages :many
+SELECT id, name, brand, category, producer_id, style
+FROM beverages
+`
+
+type ListEvaluationBeveragesRow struct {
+	ID         pgtype.UUID `json:"id"`
+	Name       string      `json:"name"`
+	Brand      pgtype.Text `json:"brand"`
+	Category   string      `json:"category"`
+	ProducerID pgtype.UUID `json:"producer_id"`
+	Style      pgtype.Text `json:"style"`
+}
+
+func (q *Queries) ListEvaluationBeverages(ctx context.Context) ([]ListEvaluationBeveragesRow, error) {
+	rows, err := q.db.Query(ctx, listEvaluationBeverages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEvaluationBeveragesRow
+	for rows.Next() {
+		var i ListEvaluationBeveragesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.Category,
+			&i.ProducerID,
+			&i.Style,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEvaluationPosts = `-- name: ListEvaluationPosts :many
+SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, p.rating, pt.tag, pt.tag_type, pt.confidence
+FROM posts p
+LEFT JOIN post_tags pt ON p.id = pt.post_id
+WHERE p.beverage_id IS NOT NULL
+ORDER BY p.user_id, p.created_at, p.id
+`
+
+type ListEvaluationPostsRow struct {
+	ID                    pgtype.UUID        `json:"id"`
+	UserID                pgtype.UUID        `json:"user_id"`
+	VenueID               pgtype.UUID        `json:"venue_id"`
+	DrinkName             string             `json:"drink_name"`
+	DrinkCategory         string             `json:"drink_category"`
+	Stars                 pgtype.Int4        `json:"stars"`
+	Notes                 pgtype.Text        `json:"notes"`
+	WinePostDetailsID     pgtype.UUID        `json:"wine_post_details_id"`
+	BeerPostDetailsID     pgtype.UUID        `json:"beer_post_details_id"`
+	CocktailPostDetailsID pgtype.UUID        `json:"cocktail_post_details_id"`
+	PriceCents            pgtype.Int4        `json:"price_cents"`
+	PhotoUrl              pgtype.Text        `json:"photo_url"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+	Score                 pgtype.Numeric     `json:"score"`
+	BeverageID            pgtype.UUID        `json:"beverage_id"`
+	Rating                pgtype.Numeric     `json:"rating"`
+	Tag                   pgtype.Text        `json:"tag"`
+	TagType               pgtype.Text        `json:"tag_type"`
+	Confidence            pgtype.Numeric     `json:"confidence"`
+}
+
+// Every post linked to a beverage, with its tags, oldest first per user
+func (q *Queries) ListEvaluationPosts(ctx context.Context) ([]ListEvaluationPostsRow, error) {
+	rows, err := q.db.Query(ctx, listEvaluationPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEvaluationPostsRow
+	for rows.Next() {
+		var i ListEvaluationPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.VenueID,
+			&i.DrinkName,
+			&i.DrinkCategory,
+			&i.Stars,
+			&i.Notes,
+			&i.WinePostDetailsID,
+			&i.BeerPostDetailsID,
+			&i.CocktailPostDetailsID,
+			&i.PriceCents,
+			&i.PhotoUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Score,
+			&i.BeverageID,
+			&i.Rating,
+			&i.Tag,
+			&i.TagType,
+			&i.Confidence,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuizBeverages = `-- name: ListQuizBeverages :many
+SELECT b.id, b.name, b.brand, b.image_url, b.total_reviews AS review_count
+FROM beverages b
+WHERE b.category = $1
+  AND EXISTS (
+    SELECT 1 FROM beverage_tag_aggregates bta
+    WHERE bta.beverage_id = b.id
+  )
+ORDER BY b.total_reviews DESC, b.avg_rating DESC
+LIMIT $2
+`
+
+type ListQuizBeveragesParams struct {
+	Category string `json:"category"`
+	Limit    int32  `json:"limit"`
+}
+
+type ListQuizBeveragesRow struct {
+	ID          pgtype.UUID `json:"id"`
+	Name        string      `json:"name"`
+	Brand       pgtype.Text `json:"brand"`
+	ImageUrl    pgtype.Text `json:"image_url"`
+	ReviewCount pgtype.Int4 `json:"review_count"`
+}
+
+// The most reviewed tagged beverages in a category, to pick quiz items from
+func (q *Queries) ListQuizBeverages(ctx context.Context, arg ListQuizBeveragesParams) ([]ListQuizBeveragesRow, error) {
+	rows, err := q.db.Query(ctx, listQuizBeverages, arg.Category, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListQuizBeveragesRow
+	for rows.Next() {
+		var i ListQuizBeveragesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Brand,
+			&i.ImageUrl,
+			&i.ReviewCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasteDecaySettings = `-- name: ListTasteDecaySettings :many
+SELECT category, half_life_days, updated_at FROM taste_decay_settings
+ORDER BY category
+`
+
+func (q *Queries) ListTasteDecaySettings(ctx context.Context) ([]TasteDecaySetting, error) {
+	rows, err := q.db.Query(ctx, listTasteDecaySettings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TasteDecaySetting
+	for rows.Next() {
+		var i TasteDecaySetting
+		if err := rows.Scan(&i.Category, &i.HalfLifeDays, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasteProfileEvents = `-- name: ListTasteProfileEvents :many
+SELECT id, user_id, category, post_id, reason, created_at FROM taste_profile_events
+ORDER BY id
+LIMIT $1
+`
+
+// The oldest queued events. They stay queued until DeleteTasteProfileEvents
+// removes them in the transaction that applies them.
+func (q *Queries) ListTasteProfileEvents(ctx context.Context, limit int32) ([]TasteProfileEvent, error) {
+	rows, err := q.db.Query(ctx, listTasteProfileEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TasteProfileEvent
+	for rows.Next() {
+		var i TasteProfileEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Category,
+			&i.PostID,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasteProfilesDueForRecompute = `-- name: ListTasteProfilesDueForRecompute :many
+SELECT utp.user_id, utp.category
+FROM user_taste_profiles utp
+LEFT JOIN user_taste_stats s ON s.user_id = utp.user_id AND s.category = utp.category
+WHERE s.user_id IS NULL OR s.full_recompute_at < $1
+ORDER BY s.full_recompute_at NULLS FIRST
+LIMIT $2
+`
+
+type ListTasteProfilesDueForRecomputeParams struct {
+	FullRecomputeAt pgtype.Timestamptz `json:"full_recompute_at"`
+	Limit           int32              `json:"limit"`
+}
+
+type ListTasteProfilesDueForRecomputeRow struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+}
+
+// Profiles whose stats are missing or were last rebuilt before the cutoff
+func (q *Queries) ListTasteProfilesDueForRecompute(ctx context.Context, arg ListTasteProfilesDueForRecomputeParams) ([]ListTasteProfilesDueForRecomputeRow, error) {
+	rows, err := q.db.Query(ctx, listTasteProfilesDueForRecompute, arg.FullRecomputeAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTasteProfilesDueForRecomputeRow
+	for rows.Next() {
+		var i ListTasteProfilesDueForRecomputeRow
+		if err := rows.Scan(&i.UserID, &i.Category); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-type GetRecommendationCandidatesRow struct {
-	ID          pgtype.UUID        `json:"id"`
-	Name        string             `json:"name"`
-	Brand       pgtype.Text        `json:"brand"`
-	Category    string             `json:"category"`
-	ImageUrl    pgtype.Text        `json:"image_url"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	ReviewCount int64              `json:"review_count"`
-	AvgRating   interface{}        `json:"avg_rating"`
-}
+const listTasteProfilesForUsers = `-- name: ListTasteProfilesForUsers :many
+SELECT user_id, category, liked_tags_json, disliked_tags_json, mean_rating, std_rating, post_count, last_computed_at, updated_at, all_time_liked_tags_json, all_time_disliked_tags_json, all_time_mean_rating, all_time_std_rating, half_life_days, inferred_from, quiz_weight FROM user_taste_profiles
+WHERE user_id = ANY($1::uuid[])
+`
 
-// Recommendation Candidates
-func (q *Queries) GetRecommendationCandidates(ctx context.Context, arg GetRecommendationCandidatesParams) ([]GetRecommendationCandidatesRow, error) {
-	rows, err := q.db.Query(ctx, getRecommendationCandidates, arg.Category, arg.UserID, arg.Limit)
+func (q *Queries) ListTasteProfilesForUsers(ctx context.Context, userIds []pgtype.UUID) ([]UserTasteProfile, error) {
+	rows, err := q.db.Query(ctx, listTasteProfilesForUsers, userIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetRecommendationCandidatesRow
+	var items []UserTasteProfile
 	for rows.Next() {
-		var i GetRecommendationCandidatesRow
+		var i UserTasteProfile
 		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Brand,
+			&i.UserID,
 			&i.Category,
-			&i.ImageUrl,
-			&i.CreatedAt,
+			&i.LikedTagsJson,
+			&i.DislikedTagsJson,
+			&i.MeanRating,
+			&i.StdRating,
+			&i.PostCount,
+			&i.LastComputedAt,
 			&i.UpdatedAt,
-			&i.ReviewCount,
-			&i.AvgRating,
+			&i.AllTimeLikedTagsJson,
+			&i.AllTimeDislikedTagsJson,
+			&i.AllTimeMeanRating,
+			&i.AllTimeStdRating,
+			&i.HalfLifeDays,
+			&i.InferredFrom,
+			&i.QuizWeight,
 		); err != nil {
 			return nil, err
 		}
@@ -217,54 +2059,35 @@ func (q *Queries) GetRecommendationCandidates(ctx context.Context, arg GetRecomm
 	return items, nil
 }
 
-const getUserEmbedding = `-- name: GetUserEmbedding :one
-
-SELECT user_id, category, embedding_text, embedding_vector, model, updated_at FROM user_embeddings
-WHERE user_id = $1 AND category = $2
+const listTasteProfilesUpdatedBefore = `-- name: ListTasteProfilesUpdatedBefore :many
+SELECT user_id, category
+FROM user_taste_profiles
+WHERE updated_at < $1
+ORDER BY updated_at
+LIMIT $2
 `
 
-type GetUserEmbeddingParams struct {
-	UserID   pgtype.UUID `json:"user_id"`
-	Category string      `json:"category"`
+type ListTasteProfilesUpdatedBeforeParams struct {
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Limit     int32              `json:"limit"`
 }
 
-// User Embeddings (optional)
-func (q *Queries) GetUserEmbedding(ctx context.Context, arg GetUserEmbeddingParams) (UserEmbedding, error) {
-	row := q.db.QueryRow(ctx, getUserEmbedding, arg.UserID, arg.Category)
-	var i UserEmbedding
-	err := row.Scan(
-		&i.UserID,
-		&i.Category,
-		&i.EmbeddingText,
-		&i.EmbeddingVector,
-		&i.Model,
-		&i.UpdatedAt,
-	)
-	return i, err
+type ListTasteProfilesUpdatedBeforeRow struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
 }
 
-const getUserFeedback = `-- name: GetUserFeedback :many
-SELECT id, user_id, beverage_id, feedback_type, created_at FROM recommendation_feedback
-WHERE user_id = $1
-ORDER BY created_at DESC
-`
-
-func (q *Queries) GetUserFeedback(ctx context.Context, userID pgtype.UUID) ([]RecommendationFeedback, error) {
-	rows, err := q.db.Query(ctx, getUserFeedback, userID)
+// Profiles nothing has touched since the cutoff, oldest first
+func (q *Queries) ListTasteProfilesUpdatedBefore(ctx context.Context, arg ListTasteProfilesUpdatedBeforeParams) ([]ListTasteProfilesUpdatedBeforeRow, error) {
+	rows, err := q.db.Query(ctx, listTasteProfilesUpdatedBefore, arg.UpdatedAt, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []RecommendationFeedback
+	var items []ListTasteProfilesUpdatedBeforeRow
 	for rows.Next() {
-		var i RecommendationFeedback
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.BeverageID,
-			&i.FeedbackType,
-			&i.CreatedAt,
-		); err != nil {
+		var i ListTasteProfilesUpdatedBeforeRow
+		if err := rows.Scan(&i.UserID, &i.Category); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -275,31 +2098,33 @@ func (q *Queries) GetUserFeedback(ctx context.Context, userID pgtype.UUID) ([]Re
 	return items, nil
 }
 
-const getUserFeedbackForBeverage = `-- name: GetUserFeedbackForBeverage :many
-SELECT id, user_id, beverage_id, feedback_type, created_at FROM recommendation_feedback
-WHERE user_id = $1 AND beverage_id = $2
+const listTasteQuizAnswers = `-- name: ListTasteQuizAnswers :many
+SELECT user_id, category, item_id, liked, tags, answered_at FROM taste_quiz_answers
+WHERE user_id = $1 AND category = $2
+ORDER BY answered_at, item_id
 `
 
-type GetUserFeedbackForBeverageParams struct {
-	UserID     pgtype.UUID `json:"user_id"`
-	BeverageID pgtype.UUID `json:"beverage_id"`
+type ListTasteQuizAnswersParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
 }
 
-func (q *Queries) GetUserFeedbackForBeverage(ctx context.Context, arg GetUserFeedbackForBeverageParams) ([]RecommendationFeedback, error) {
-	rows, err := q.db.Query(ctx, getUserFeedbackForBeverage, arg.UserID, arg.BeverageID)
+func (q *Queries) ListTasteQuizAnswers(ctx context.Context, arg ListTasteQuizAnswersParams) ([]TasteQuizAnswer, error) {
+	rows, err := q.db.Query(ctx, listTasteQuizAnswers, arg.UserID, arg.Category)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []RecommendationFeedback
+	var items []TasteQuizAnswer
 	for rows.Next() {
-		var i RecommendationFeedback
+		var i TasteQuizAnswer
 		if err := rows.Scan(
-			&i.ID,
 			&i.UserID,
-			&i.BeverageID,
-			&i.FeedbackType,
-			&i.CreatedAt,
+			&i.Category,
+			&i.ItemID,
+			&i.Liked,
+			&i.Tags,
+			&i.AnsweredAt,
 		); err != nil {
 			return nil, err
 		}
@@ -311,89 +2136,47 @@ func (q *Queries) GetUserFeedbackForBeverage(ctx context.Context, arg GetUserFee
 	return items, nil
 }
 
-const getUserPostCountByCategory = `-- name: GetUserPostCountByCategory :one
-SELECT COUNT(*) AS count
-FROM posts
-WHERE user_id = $1 AND drink_category = $2
+const listUserEmbeddingsToRefresh = `-- name: ListUserEmbeddingsToRefresh :many
+SELECT utp.user_id, utp.category, utp.liked_tags_json,
+       ue.embedding_text AS current_text, ue.model AS current_model
+FROM user_taste_profiles utp
+LEFT JOIN user_embeddings ue ON ue.user_id = utp.user_id AND ue.category = utp.category
+WHERE ue.user_id IS NULL
+   OR ue.model IS DISTINCT FROM $1
+   OR ue.updated_at < utp.updated_at
+ORDER BY utp.updated_at
+LIMIT $2
 `
 
-type GetUserPostCountByCategoryParams struct {
-	UserID        pgtype.UUID `json:"user_id"`
-	DrinkCategory string      `json:"drink_category"`
-}
-
-func (q *Queries) GetUserPostCountByCategory(ctx context.Context, arg GetUserPostCountByCategoryParams) (int64, error) {
-	row := q.db.QueryRow(ctx, getUserPostCountByCategory, arg.UserID, arg.DrinkCategory)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+type ListUserEmbeddingsToRefreshParams struct {
+	Model pgtype.Text `json:"model"`
+	Limit int32       `json:"limit"`
 }
 
-const getUserPostsForCategory = `-- name: GetUserPostsForCategory :many
-SELECT p.id, p.user_id, p.venue_id, p.drink_name, p.drink_category, p.stars, p.notes, p.wine_post_details_id, p.beer_post_details_id, p.cocktail_post_details_id, p.price_cents, p.photo_url, p.created_at, p.updated_at, p.score, p.beverage_id, pt.tag, pt.tag_type, pt.confidence
-FROM posts p
-LEFT JOIN post_tags pt ON p.id = pt.post_id
-WHERE p.user_id = $1
-  AND p.drink_category = $2
-ORDER BY p.created_at DESC
-`
-
-type GetUserPostsForCategoryParams struct {
+type ListUserEmbeddingsToRefreshRow struct {
 	UserID        pgtype.UUID `json:"user_id"`
-	DrinkCategory string      `json:"drink_category"`
-}
-
-type GetUserPostsForCategoryRow struct {
-	ID                    pgtype.UUID        `json:"id"`
-	UserID                pgtype.UUID        `json:"user_id"`
-	VenueID               pgtype.UUID        `json:"venue_id"`
-	DrinkName             string             `json:"drink_name"`
-	DrinkCategory         string             `json:"drink_category"`
-	Stars                 pgtype.Int4        `json:"stars"`
-	Notes                 pgtype.Text        `json:"notes"`
-	WinePostDetailsID     pgtype.UUID        `json:"wine_post_details_id"`
-	BeerPostDetailsID     pgtype.UUID        `json:"beer_post_details_id"`
-	CocktailPostDetailsID pgtype.UUID        `json:"cocktail_post_details_id"`
-	PriceCents            pgtype.Int4        `json:"price_cents"`
-	PhotoUrl              pgtype.Text        `json:"photo_url"`
-	CreatedAt             pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
-	Score                 pgtype.Numeric     `json:"score"`
-	BeverageID            pgtype.UUID        `json:"beverage_id"`
-	Tag                   pgtype.Text        `json:"tag"`
-	TagType               pgtype.Text        `json:"tag_type"`
-	Confidence            pgtype.Numeric     `json:"confidence"`
+	Category      string      `json:"category"`
+	LikedTagsJson []byte      `json:"liked_tags_json"`
+	CurrentText   pgtype.Text `json:"current_text"`
+	CurrentModel  pgtype.Text `json:"current_model"`
 }
 
-func (q *Queries) GetUserPostsForCategory(ctx context.Context, arg GetUserPostsForCategoryParams) ([]GetUserPostsForCategoryRow, error) {
-	rows, err := q.db.Query(ctx, getUserPostsForCategory, arg.UserID, arg.DrinkCategory)
+// Taste profiles with no embedding for the model, or updated since it was built
+func (q *Queries) ListUserEmbeddingsToRefresh(ctx context.Context, arg ListUserEmbeddingsToRefreshParams) ([]ListUserEmbeddingsToRefreshRow, error) {
+	rows, err := q.db.Query(ctx, listUserEmbeddingsToRefresh, arg.Model, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetUserPostsForCategoryRow
+	var items []ListUserEmbeddingsToRefreshRow
 	for rows.Next() {
-		var i GetUserPostsForCategoryRow
+		var i ListUserEmbeddingsToRefreshRow
 		if err := rows.Scan(
-			&i.ID,
 			&i.UserID,
-			&i.VenueID,
-			&i.DrinkName,
-			&i.DrinkCategory,
-			&i.Stars,
-			&i.Notes,
-			&i.WinePostDetailsID,
-			&i.BeerPostDetailsID,
-			&i.CocktailPostDetailsID,
-			&i.PriceCents,
-			&i.PhotoUrl,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Score,
-			&i.BeverageID,
-			&i.Tag,
-			&i.TagType,
-			&i.Confidence,
+			&i.Category,
+			&i.LikedTagsJson,
+			&i.CurrentText,
+			&i.CurrentModel,
 		); err != nil {
 			return nil, err
 		}
@@ -405,35 +2188,210 @@ func (q *Queries) GetUserPostsForCategory(ctx context.Context, arg GetUserPostsF
 	return items, nil
 }
 
-const getUserTasteProfile = `-- name: GetUserTasteProfile :one
+const recordImpressionClick = `-- name: RecordImpressionClick :exec
+UPDATE recommendation_impressions
+SET clicked_at = COALESCE(clicked_at, now())
+WHERE id = $1 AND user_id = $2
+`
+
+type RecordImpressionClickParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) RecordImpressionClick(ctx context.Context, arg RecordImpressionClickParams) error {
+	_, err := q.db.Exec(ctx, recordImpressionClick, arg.ID, arg.UserID)
+	return err
+}
+
+const touchBeverageEmbedding = `-- name: TouchBeverageEmbedding :exec
+UPDATE beverage_embeddings SET updated_at = now()
+WHERE beverage_id = $1
+`
+
+// Marks an embedding current when its source text has not changed
+func (q *Queries) TouchBeverageEmbedding(ctx context.Context, beverageID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchBeverageEmbedding, beverageID)
+	return err
+}
 
-SELECT user_id, category, liked_tags_json, disliked_tags_json, mean_rating, std_rating, post_count, last_computed_at, updated_at FROM user_taste_profiles
+const touchUserEmbedding = `-- name: TouchUserEmbedding :exec
+UPDATE user_embeddings SET updated_at = now()
 WHERE user_id = $1 AND category = $2
 `
 
-type GetUserTasteProfileParams struct {
+type TouchUserEmbeddingParams struct {
 	UserID   pgtype.UUID `json:"user_id"`
 	Category string      `json:"category"`
 }
 
-// User Taste Profiles
-func (q *Queries) GetUserTasteProfile(ctx context.Context, arg GetUserTasteProfileParams) (UserTasteProfile, error) {
-	row := q.db.QueryRow(ctx, getUserTasteProfile, arg.UserID, arg.Category)
-	var i UserTasteProfile
-	err := row.Scan(
-		&i.UserID,
-		&i.Category,
-		&i.LikedTagsJson,
-		&i.DislikedTagsJson,
-		&i.MeanRating,
-		&i.StdRating,
-		&i.PostCount,
-		&i.LastComputedAt,
-		&i.UpdatedAt,
+func (q *Queries) TouchUserEmbedding(ctx context.Context, arg TouchUserEmbeddingParams) error {
+	_, err := q.db.Exec(ctx, touchUserEmbedding, arg.UserID, arg.Category)
+	return err
+}
+
+const upsertBeverageEmbedding = `-- name: UpsertBeverageEmbedding :exec
+INSERT INTO beverage_embeddings (beverage_id, embedding_text, embedding_vector, model)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (beverage_id)
+DO UPDATE SET
+  embedding_text = EXCLUDED.embedding_text,
+  embedding_vector = EXCLUDED.embedding_vector,
+  model = EXCLUDED.model
+`
+
+type UpsertBeverageEmbeddingParams struct {
+	BeverageID      pgtype.UUID `json:"beverage_id"`
+	EmbeddingText   string      `json:"embedding_text"`
+	EmbeddingVector []float32   `json:"embedding_vector"`
+	Model           string      `json:"model"`
+}
+
+func (q *Queries) UpsertBeverageEmbedding(ctx context.Context, arg UpsertBeverageEmbeddingParams) error {
+	_, err := q.db.Exec(ctx, upsertBeverageEmbedding,
+		arg.BeverageID,
+		arg.EmbeddingText,
+		arg.EmbeddingVector,
+		arg.Model,
+	)
+	return err
+}
+
+const upsertTasteCompatibility = `-- name: UpsertTasteCompatibility :exec
+INSERT INTO taste_compatibility (
+  user_id, other_user_id, category, score, tag_similarity, rating_agreement, co_rated,
+  shared_tags_json, clashing_tags_json, profile_computed_at, other_profile_computed_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (user_id, other_user_id, category)
+DO UPDATE SET
+  score = EXCLUDED.score,
+  tag_similarity = EXCLUDED.tag_similarity,
+  rating_agreement = EXCLUDED.rating_agreement,
+  co_rated = EXCLUDED.co_rated,
+  shared_tags_json = EXCLUDED.shared_tags_json,
+  clashing_tags_json = EXCLUDED.clashing_tags_json,
+  profile_computed_at = EXCLUDED.profile_computed_at,
+  other_profile_computed_at = EXCLUDED.other_profile_computed_at,
+  computed_at = now()
+`
+
+type UpsertTasteCompatibilityParams struct {
+	UserID                 pgtype.UUID        `json:"user_id"`
+	OtherUserID            pgtype.UUID        `json:"other_user_id"`
+	Category               string             `json:"category"`
+	Score                  int32              `json:"score"`
+	TagSimilarity          float64            `json:"tag_similarity"`
+	RatingAgreement        pgtype.Float8      `json:"rating_agreement"`
+	CoRated                int32              `json:"co_rated"`
+	SharedTagsJson         []byte             `json:"shared_tags_json"`
+	ClashingTagsJson       []byte             `json:"clashing_tags_json"`
+	ProfileComputedAt      pgtype.Timestamptz `json:"profile_computed_at"`
+	OtherProfileComputedAt pgtype.Timestamptz `json:"other_profile_computed_at"`
+}
+
+func (q *Queries) UpsertTasteCompatibility(ctx context.Context, arg UpsertTasteCompatibilityParams) error {
+	_, err := q.db.Exec(ctx, upsertTasteCompatibility,
+		arg.UserID,
+		arg.OtherUserID,
+		arg.Category,
+		arg.Score,
+		arg.TagSimilarity,
+		arg.RatingAgreement,
+		arg.CoRated,
+		arg.SharedTagsJson,
+		arg.ClashingTagsJson,
+		arg.ProfileComputedAt,
+		arg.OtherProfileComputedAt,
+	)
+	return err
+}
+
+const upsertTasteContribution = `-- name: UpsertTasteContribution :exec
+INSERT INTO user_taste_contributions (
+  user_id, category, post_id, rating, weight, tags_json, applied_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, category, post_id)
+DO UPDATE SET
+  rating = EXCLUDED.rating,
+  weight = EXCLUDED.weight,
+  tags_json = EXCLUDED.tags_json,
+  applied_at = EXCLUDED.applied_at
+`
+
+type UpsertTasteContributionParams struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	Category  string             `json:"category"`
+	PostID    pgtype.UUID        `json:"post_id"`
+	Rating    pgtype.Numeric     `json:"rating"`
+	Weight    float64            `json:"weight"`
+	TagsJson  []byte             `json:"tags_json"`
+	AppliedAt pgtype.Timestamptz `json:"applied_at"`
+}
+
+func (q *Queries) UpsertTasteContribution(ctx context.Context, arg UpsertTasteContributionParams) error {
+	_, err := q.db.Exec(ctx, upsertTasteContribution,
+		arg.UserID,
+		arg.Category,
+		arg.PostID,
+		arg.Rating,
+		arg.Weight,
+		arg.TagsJson,
+		arg.AppliedAt,
 	)
+	return err
+}
+
+const upsertTasteDecaySetting = `-- name: UpsertTasteDecaySetting :one
+INSERT INTO taste_decay_settings (category, half_life_days)
+VALUES ($1, $2)
+ON CONFLICT (category)
+DO UPDATE SET half_life_days = EXCLUDED.half_life_days
+RETURNING category, half_life_days, updated_at
+`
+
+type UpsertTasteDecaySettingParams struct {
+	Category     string      `json:"category"`
+	HalfLifeDays pgtype.Int4 `json:"half_life_days"`
+}
+
+func (q *Queries) UpsertTasteDecaySetting(ctx context.Context, arg UpsertTasteDecaySettingParams) (TasteDecaySetting, error) {
+	row := q.db.QueryRow(ctx, upsertTasteDecaySetting, arg.Category, arg.HalfLifeDays)
+	var i TasteDecaySetting
+	err := row.Scan(&i.Category, &i.HalfLifeDays, &i.UpdatedAt)
 	return i, err
 }
 
+const upsertTasteQuizAnswer = `-- name: UpsertTasteQuizAnswer :exec
+INSERT INTO taste_quiz_answers (user_id, category, item_id, liked, tags)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, category, item_id)
+DO UPDATE SET
+  liked = EXCLUDED.liked,
+  tags = EXCLUDED.tags,
+  answered_at = now()
+`
+
+type UpsertTasteQuizAnswerParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Category string      `json:"category"`
+	ItemID   string      `json:"item_id"`
+	Liked    bool        `json:"liked"`
+	Tags     []string    `json:"tags"`
+}
+
+func (q *Queries) UpsertTasteQuizAnswer(ctx context.Context, arg UpsertTasteQuizAnswerParams) error {
+	_, err := q.db.Exec(ctx, upsertTasteQuizAnswer,
+		arg.UserID,
+		arg.Category,
+		arg.ItemID,
+		arg.Liked,
+		arg.Tags,
+	)
+	return err
+}
+
 const upsertUserEmbedding = `-- name: UpsertUserEmbedding :one
 INSERT INTO user_embeddings (user_id, category, embedding_text, embedding_vector, model)
 VALUES ($1, $2, $3, $4, $5)
@@ -476,9 +2434,12 @@ func (q *Queries) UpsertUserEmbedding(ctx context.Context, arg UpsertUserEmbeddi
 const upsertUserTasteProfile = `-- name: UpsertUserTasteProfile :one
 INSERT INTO user_taste_profiles (
   user_id, category, liked_tags_json, disliked_tags_json,
-  mean_rating, std_rating, post_count, last_computed_at
+  mean_rating, std_rating, post_count, last_computed_at,
+  all_time_liked_tags_json, all_time_disliked_tags_json,
+  all_time_mean_rating, all_time_std_rating, half_life_days, inferred_from,
+  quiz_weight
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+VALUES ($1, $2, $3, $4, $5, $6, $7, now(), $8, $9, $10, $11, $12, $13, $14)
 ON CONFLICT (user_id, category)
 DO UPDATE SET
   liked_tags_json = EXCLUDED.liked_tags_json,
@@ -486,18 +2447,32 @@ DO UPDATE SET
   mean_rating = EXCLUDED.mean_rating,
   std_rating = EXCLUDED.std_rating,
   post_count = EXCLUDED.post_count,
-  last_computed_at = EXCLUDED.last_computed_at
-RETURNING user_id, category, liked_tags_json, disliked_tags_json, mean_rating, std_rating, post_count, last_computed_at, updated_at
+  last_computed_at = EXCLUDED.last_computed_at,
+  all_time_liked_tags_json = EXCLUDED.all_time_liked_tags_json,
+  all_time_disliked_tags_json = EXCLUDED.all_time_disliked_tags_json,
+  all_time_mean_rating = EXCLUDED.all_time_mean_rating,
+  all_time_std_rating = EXCLUDED.all_time_std_rating,
+  half_life_days = EXCLUDED.half_life_days,
+  inferred_from = EXCLUDED.inferred_from,
+  quiz_weight = EXCLUDED.quiz_weight
+RETURNING user_id, category, liked_tags_json, disliked_tags_json, mean_rating, std_rating, post_count, last_computed_at, updated_at, all_time_liked_tags_json, all_time_disliked_tags_json, all_time_mean_rating, all_time_std_rating, half_life_days, inferred_from, quiz_weight
 `
 
 type UpsertUserTasteProfileParams struct {
-	UserID           pgtype.UUID    `json:"user_id"`
-	Category         string         `json:"category"`
-	LikedTagsJson    []byte         `json:"liked_tags_json"`
-	DislikedTagsJson []byte         `json:"disliked_tags_json"`
-	MeanRating       pgtype.Numeric `json:"mean_rating"`
-	StdRating        pgtype.Numeric `json:"std_rating"`
-	PostCount        pgtype.Int4    `json:"post_count"`
+	UserID                  pgtype.UUID    `json:"user_id"`
+	Category                string         `json:"category"`
+	LikedTagsJson           []byte         `json:"liked_tags_json"`
+	DislikedTagsJson        []byte         `json:"disliked_tags_json"`
+	MeanRating              pgtype.Numeric `json:"mean_rating"`
+	StdRating               pgtype.Numeric `json:"std_rating"`
+	PostCount               pgtype.Int4    `json:"post_count"`
+	AllTimeLikedTagsJson    []byte         `json:"all_time_liked_tags_json"`
+	AllTimeDislikedTagsJson []byte         `json:"all_time_disliked_tags_json"`
+	AllTimeMeanRating       pgtype.Numeric `json:"all_time_mean_rating"`
+	AllTimeStdRating        pgtype.Numeric `json:"all_time_std_rating"`
+	HalfLifeDays            pgtype.Int4    `json:"half_life_days"`
+	InferredFrom            []string       `json:"inferred_from"`
+	QuizWeight              float64        `json:"quiz_weight"`
 }
 
 func (q *Queries) UpsertUserTasteProfile(ctx context.Context, arg UpsertUserTasteProfileParams) (UserTasteProfile, error) {
@@ -509,6 +2484,13 @@ func (q *Queries) UpsertUserTasteProfile(ctx context.Context, arg UpsertUserTast
 		arg.MeanRating,
 		arg.StdRating,
 		arg.PostCount,
+		arg.AllTimeLikedTagsJson,
+		arg.AllTimeDislikedTagsJson,
+		arg.AllTimeMeanRating,
+		arg.AllTimeStdRating,
+		arg.HalfLifeDays,
+		arg.InferredFrom,
+		arg.QuizWeight,
 	)
 	var i UserTasteProfile
 	err := row.Scan(
@@ -521,6 +2503,50 @@ func (q *Queries) UpsertUserTasteProfile(ctx context.Context, arg UpsertUserTast
 		&i.PostCount,
 		&i.LastComputedAt,
 		&i.UpdatedAt,
+		&i.AllTimeLikedTagsJson,
+		&i.AllTimeDislikedTagsJson,
+		&i.AllTimeMeanRating,
+		&i.AllTimeStdRating,
+		&i.HalfLifeDays,
+		&i.InferredFrom,
+		&i.QuizWeight,
 	)
 	return i, err
 }
+
+const upsertUserTasteStats = `-- name: UpsertUserTasteStats :exec
+INSERT INTO user_taste_stats (
+  user_id, category, half_life_days, recent_json, all_time_json, as_of, full_recompute_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, category)
+DO UPDATE SET
+  half_life_days = EXCLUDED.half_life_days,
+  recent_json = EXCLUDED.recent_json,
+  all_time_json = EXCLUDED.all_time_json,
+  as_of = EXCLUDED.as_of,
+  full_recompute_at = EXCLUDED.full_recompute_at
+`
+
+type UpsertUserTasteStatsParams struct {
+	UserID          pgtype.UUID        `json:"user_id"`
+	Category        string             `json:"category"`
+	HalfLifeDays    pgtype.Int4        `json:"half_life_days"`
+	RecentJson      []byte             `json:"recent_json"`
+	AllTimeJson     []byte             `json:"all_time_json"`
+	AsOf            pgtype.Timestamptz `json:"as_of"`
+	FullRecomputeAt pgtype.Timestamptz `json:"full_recompute_at"`
+}
+
+func (q *Queries) UpsertUserTasteStats(ctx context.Context, arg UpsertUserTasteStatsParams) error {
+	_, err := q.db.Exec(ctx, upsertUserTasteStats,
+		arg.UserID,
+		arg.Category,
+		arg.HalfLifeDays,
+		arg.RecentJson,
+		arg.AllTimeJson,
+		arg.AsOf,
+		arg.FullRecomputeAt,
+	)
+	return err
+}
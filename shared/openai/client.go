@@ -8,7 +8,10 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/burkebarcode/backend/shared/rating"
 )
 
 const (
@@ -16,26 +19,33 @@ const (
 	defaultModel   = "gpt-4o-mini"
 	defaultTimeout = 60 * time.Second
 	maxRetries     = 2
+
+	defaultEmbeddingModel = "text-embedding-3-small"
 )
 
 type Client struct {
-	apiKey     string
-	model      string
-	baseURL    string
-	httpClient *http.Client
+	apiKey         string
+	model          string
+	embeddingModel string
+	baseURL        string
+	httpClient     *http.Client
 }
 
 type Config struct {
-	APIKey  string
-	Model   string
-	BaseURL string
-	Timeout time.Duration
+	APIKey         string
+	Model          string
+	EmbeddingModel string
+	BaseURL        string
+	Timeout        time.Duration
 }
 
 func NewClient(cfg Config) *Client {
 	if cfg.Model == "" {
 		cfg.Model = defaultModel
 	}
+	if cfg.EmbeddingModel == "" {
+		cfg.EmbeddingModel = defaultEmbeddingModel
+	}
 	if cfg.BaseURL == "" {
 		cfg.BaseURL = defaultBaseURL
 	}
@@ -44,9 +54,10 @@ func NewClient(cfg Config) *Client {
 	}
 
 	return &Client{
-		apiKey:  cfg.APIKey,
-		model:   cfg.Model,
-		baseURL: cfg.BaseURL,
+		apiKey:         cfg.APIKey,
+		model:          cfg.Model,
+		embeddingModel: cfg.EmbeddingModel,
+		baseURL:        cfg.BaseURL,
 		httpClient: &http.Client{
 			Timeout: cfg.Timeout,
 		},
@@ -61,7 +72,7 @@ type BeverageSummaryInput struct {
 }
 
 type ReviewForSummary struct {
-	Rating float64
+	Rating rating.Rating
 	Notes  string
 }
 
@@ -79,7 +90,7 @@ type PostTaggingInput struct {
 	DrinkName string
 	Category  string
 	Notes     string
-	Rating    float64
+	Rating    rating.Rating
 	// Optional structured details
 	Winery   string
 	Vintage  string
@@ -123,6 +134,51 @@ type CocktailStructured struct {
 	Family     *string `json:"family,omitempty"`
 }
 
+// structuredTagConfidence is assigned to tags derived from the structured
+// block, which carries no confidence of its own
+const structuredTagConfidence = 0.8
+
+// attributeTags returns the extracted tags plus the style, region and
+// varietal from the structured block, so both reach post_tags and feed
+// beverage attribute consensus. Structured values already present as tags
+// are not repeated.
+func (r *PostTaggingResponse) attributeTags() []Tag {
+	tags := append([]Tag{}, r.Tags...)
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		seen[t.TagType+":"+strings.ToLower(strings.TrimSpace(t.Tag))] = true
+	}
+
+	add := func(tagType string, value *string) {
+		if value == nil {
+			return
+		}
+		v := strings.TrimSpace(*value)
+		if v == "" || strings.EqualFold(v, "null") {
+			return
+		}
+		key := tagType + ":" + strings.ToLower(v)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		tags = append(tags, Tag{Tag: v, TagType: tagType, Confidence: structuredTagConfidence})
+	}
+
+	if w := r.Structured.Wine; w != nil {
+		add("varietal", w.Varietal)
+		add("region", w.Region)
+	}
+	if b := r.Structured.Beer; b != nil {
+		add("style", b.Style)
+	}
+	if c := r.Structured.Cocktail; c != nil {
+		add("style", c.Family)
+	}
+
+	return tags
+}
+
 // GenerateBeverageSummary creates an AI summary from reviews
 func (c *Client) GenerateBeverageSummary(ctx context.Context, input BeverageSummaryInput) (*BeverageSummaryResponse, error) {
 	if len(input.Reviews) == 0 {
@@ -139,7 +195,7 @@ func (c *Client) GenerateBeverageSummary(ctx context.Context, input BeverageSumm
 	reviewsText := ""
 	for i, r := range reviews {
 		notes := truncateText(r.Notes, 500)
-		reviewsText += fmt.Sprintf("%d. Rating: %.1f/10, Notes: %s\n", i+1, r.Rating, notes)
+		reviewsText += fmt.Sprintf("%d. Rating: %s, Notes: %s\n", i+1, r.Rating, notes)
 	}
 
 	systemPrompt := `You are a sommelier and beverage expert. Generate a concise summary of what people say about this beverage based ONLY on the provided reviews. Be honest and grounded - only mention flavors, characteristics, and opinions explicitly stated in the reviews. Do not hallucinate tasting notes.
@@ -174,7 +230,8 @@ Output format (JSON):
 	return &summary, nil
 }
 
-// ExtractPostTags extracts tags from a single post
+// ExtractPostTags extracts tags from a single post. Style, region and
+// varietal from the structured block are included as tags too.
 func (c *Client) ExtractPostTags(ctx context.Context, input PostTaggingInput) (*PostTaggingResponse, error) {
 	if input.Notes == "" {
 		// No notes, return empty tags
@@ -220,7 +277,7 @@ Output format (JSON):
   }
 }`
 
-	userPrompt := fmt.Sprintf("Beverage: %s (%s)\nRating: %.1f/10\nNotes: %s",
+	userPrompt := fmt.Sprintf("Beverage: %s (%s)\nRating: %s\nNotes: %s",
 		input.DrinkName, input.Category, input.Rating, notes)
 
 	if input.Winery != "" {
@@ -245,10 +302,100 @@ Output format (JSON):
 	if err := json.Unmarshal([]byte(response), &tagging); err != nil {
 		return nil, fmt.Errorf("failed to parse tagging response: %w", err)
 	}
+	tagging.Tags = tagging.attributeTags()
 
 	return &tagging, nil
 }
 
+// EmbeddingModel is the model Embed uses; stored alongside each vector
+func (c *Client) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+// Embed returns one embedding per input text, in input order
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(attempt) * time.Second
+			log.Printf("OpenAI embedding retry %d/%d after %v", attempt, maxRetries, backoff)
+			time.Sleep(backoff)
+		}
+
+		vectors, err := c.doEmbeddingRequest(ctx, texts)
+		if err == nil {
+			return vectors, nil
+		}
+
+		lastErr = err
+		log.Printf("OpenAI embedding request failed (attempt %d/%d): %v", attempt+1, maxRetries+1, err)
+	}
+
+	return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
+}
+
+func (c *Client) doEmbeddingRequest(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": c.embeddingModel,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/embeddings", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+
+	return vectors, nil
+}
+
 func (c *Client) chatCompletion(ctx context.Context, systemPrompt, userPrompt, responseFormat string) (string, error) {
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
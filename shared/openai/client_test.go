@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractPostTagsIncludesStructuredAttributes(t *testing.T) {
+	content := `{
+  "tags": [
+    {"tag": "oaky", "tag_type": "descriptor", "confidence": 0.9},
+    {"tag": "Napa", "tag_type": "region", "confidence": 0.95}
+  ],
+  "structured": {
+    "wine": {"varietal": "Cabernet Sauvignon", "region": "napa", "body": "full"}
+  }
+}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": content}}},
+		})
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{APIKey: "test", BaseURL: srv.URL})
+	resp, err := c.ExtractPostTags(context.Background(), PostTaggingInput{
+		DrinkName: "Silver Oak Cabernet",
+		Category:  "wine",
+		Notes:     "Oaky Napa cab",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Tag{
+		{Tag: "oaky", TagType: "descriptor", Confidence: 0.9},
+		{Tag: "Napa", TagType: "region", Confidence: 0.95},
+		// The structured region repeats a tag and is dropped
+		{Tag: "Cabernet Sauvignon", TagType: "varietal", Confidence: structuredTagConfidence},
+	}
+	if len(resp.Tags) != len(want) {
+		t.Fatalf("tags = %+v, want %+v", resp.Tags, want)
+	}
+	for i := range want {
+		if resp.Tags[i] != want[i] {
+			t.Errorf("tag %d = %+v, want %+v", i, resp.Tags[i], want[i])
+		}
+	}
+}
+
+func TestAttributeTagsSkipsEmptyValues(t *testing.T) {
+	null, blank, family := "null", " ", "Tiki"
+	r := PostTaggingResponse{Structured: PostTaggingStructured{
+		Wine:     &WineStructured{Varietal: &null, Region: &blank},
+		Cocktail: &CocktailStructured{Family: &family},
+	}}
+	tags := r.attributeTags()
+	if len(tags) != 1 || tags[0] != (Tag{Tag: "Tiki", TagType: "style", Confidence: structuredTagConfidence}) {
+		t.Errorf("tags = %+v", tags)
+	}
+}
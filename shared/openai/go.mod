@@ -1,3 +1,9 @@
 module github.com/burkebarcode/backend/shared/openai
 
 go 1.25.3
+
+require github.com/burkebarcode/backend/shared/rating v0.0.0-00010101000000-000000000000
+
+require github.com/jackc/pgx/v5 v5.7.6 // indirect
+
+replace github.com/burkebarcode/backend/shared/rating => ../rating
@@ -0,0 +1,5 @@
+module github.com/burkebarcode/backend/shared/rating
+
+go 1.25.3
+
+require github.com/jackc/pgx/v5 v5.7.6
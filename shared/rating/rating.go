@@ -0,0 +1,128 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Rating is a drink rating on the canonical 0-10 scale with one decimal of
+// precision. It is what posts.rating stores and what every consumer (taste
+// profiles, ranking, summaries) should read instead of stars or score.
+type Rating float64
+
+const (
+	Min Rating = 0
+	Max Rating = 10
+
+	// starsMultiplier converts legacy 1-5 stars onto the 0-10 scale
+	starsMultiplier = 2
+	minStars        = 1
+	maxStars        = 5
+)
+
+// FromScore validates a 0-10 score and rounds it to one decimal
+func FromScore(score float64) (Rating, error) {
+	r := Rating(math.Round(score*10) / 10)
+	if err := r.Validate(); err != nil {
+		return 0, err
+	}
+	return r, nil
+}
+
+// FromStars converts legacy 1-5 stars onto the 0-10 scale
+func FromStars(stars int) (Rating, error) {
+	if stars < minStars || stars > maxStars {
+		return 0, fmt.Errorf("stars must be between %d and %d, got %d", minStars, maxStars, stars)
+	}
+	return Rating(stars * starsMultiplier), nil
+}
+
+// FromPost derives a post's rating from its score and stars columns the same
+// way the post_rating SQL function does: the score whenever it is set, 0
+// included, otherwise stars doubled. ok is false for an unrated post or
+// out-of-range input.
+func FromPost(score pgtype.Numeric, stars pgtype.Int4) (Rating, bool) {
+	if score.Valid {
+		return FromNumeric(score)
+	}
+	if stars.Valid {
+		r, err := FromStars(int(stars.Int32))
+		return r, err == nil
+	}
+	return 0, false
+}
+
+// FromNumeric reads a per-post rating column; ok is false for NULL or
+// out-of-range values
+func FromNumeric(n pgtype.Numeric) (Rating, bool) {
+	if !n.Valid {
+		return 0, false
+	}
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return 0, false
+	}
+	r, err := FromScore(f.Float64)
+	if err != nil {
+		return 0, false
+	}
+	return r, true
+}
+
+// Average reads an averaged rating column such as beverages.avg_rating at
+// its full precision; ok is false for NULL. Averages stay plain floats since
+// rounding them to a Rating's one decimal would blur their ordering.
+func Average(n pgtype.Numeric) (float64, bool) {
+	if !n.Valid {
+		return 0, false
+	}
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return 0, false
+	}
+	return f.Float64, true
+}
+
+// AverageNumeric converts an average for storage in a NUMERIC(4,2) column
+func AverageNumeric(avg float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	n.Scan(fmt.Sprintf("%.2f", avg))
+	return n
+}
+
+// Validate reports whether the rating is within the canonical scale
+func (r Rating) Validate() error {
+	if math.IsNaN(float64(r)) || r < Min || r > Max {
+		return fmt.Errorf("rating must be between %v and %v, got %v", float64(Min), float64(Max), float64(r))
+	}
+	return nil
+}
+
+// Numeric converts the rating for storage in a NUMERIC(3,1) column
+func (r Rating) Numeric() pgtype.Numeric {
+	var n pgtype.Numeric
+	n.Scan(fmt.Sprintf("%.1f", float64(r)))
+	return n
+}
+
+// Float64 returns the rating on the 0-10 scale
+func (r Rating) Float64() float64 {
+	return float64(r)
+}
+
+// Normalized returns the rating on a 0-1 scale
+func (r Rating) Normalized() float64 {
+	return float64(r) / float64(Max)
+}
+
+// Stars returns the rating on the legacy 5-star scale, rounded to half stars
+func (r Rating) Stars() float64 {
+	return math.Round(float64(r)/starsMultiplier*2) / 2
+}
+
+// String formats the rating for display, e.g. "8.5/10"
+func (r Rating) String() string {
+	return fmt.Sprintf("%.1f/%d", float64(r), int(Max))
+}
@@ -0,0 +1,125 @@
+package rating
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func numeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestFromScore(t *testing.T) {
+	cases := []struct {
+		score float64
+		want  Rating
+		ok    bool
+	}{
+		{0, 0, true},
+		{10, 10, true},
+		{7.25, 7.3, true},
+		{7.24, 7.2, true},
+		// Rounds onto the scale before the bounds check
+		{10.04, 10, true},
+		{-0.01, 0, true},
+		{10.05, 0, false},
+		{-0.5, 0, false},
+		{math.NaN(), 0, false},
+	}
+	for _, c := range cases {
+		got, err := FromScore(c.score)
+		if (err == nil) != c.ok {
+			t.Errorf("FromScore(%v) error = %v, want ok %v", c.score, err, c.ok)
+			continue
+		}
+		if c.ok && got != c.want {
+			t.Errorf("FromScore(%v) = %v, want %v", c.score, got, c.want)
+		}
+	}
+}
+
+func TestFromNumeric(t *testing.T) {
+	if _, ok := FromNumeric(pgtype.Numeric{}); ok {
+		t.Error("NULL read as a rating")
+	}
+	if _, ok := FromNumeric(numeric(t, "11.0")); ok {
+		t.Error("11.0 read as a rating")
+	}
+	if r, ok := FromNumeric(numeric(t, "8.5")); !ok || r != 8.5 {
+		t.Errorf("8.5 read as %v, %v", r, ok)
+	}
+}
+
+func TestFromPost(t *testing.T) {
+	stars := func(n int32) pgtype.Int4 { return pgtype.Int4{Int32: n, Valid: true} }
+	cases := []struct {
+		name  string
+		score pgtype.Numeric
+		stars pgtype.Int4
+		want  Rating
+		ok    bool
+	}{
+		{"score wins", numeric(t, "7.5"), stars(5), 7.5, true},
+		{"zero score is a rating", numeric(t, "0"), stars(4), 0, true},
+		{"stars without score", pgtype.Numeric{}, stars(4), 8, true},
+		{"stars out of range", pgtype.Numeric{}, stars(6), 0, false},
+		{"score out of range", numeric(t, "11"), stars(3), 0, false},
+		{"unrated", pgtype.Numeric{}, pgtype.Int4{}, 0, false},
+	}
+	for _, c := range cases {
+		got, ok := FromPost(c.score, c.stars)
+		if ok != c.ok || got != c.want {
+			t.Errorf("%s: FromPost = %v, %v; want %v, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestAverageKeepsPrecision(t *testing.T) {
+	if _, ok := Average(pgtype.Numeric{}); ok {
+		t.Error("NULL read as an average")
+	}
+	a, _ := Average(numeric(t, "8.46"))
+	b, _ := Average(numeric(t, "8.44"))
+	if a != 8.46 || b != 8.44 {
+		t.Errorf("averages = %v, %v; want 8.46, 8.44", a, b)
+	}
+	if got, _ := Average(AverageNumeric(7.456)); got != 7.46 {
+		t.Errorf("stored average = %v, want 7.46", got)
+	}
+}
+
+func TestScaleConversions(t *testing.T) {
+	cases := []struct {
+		r          Rating
+		normalized float64
+		stars      float64
+		str        string
+	}{
+		{0, 0, 0, "0.0/10"},
+		{10, 1, 5, "10.0/10"},
+		{7, 0.7, 3.5, "7.0/10"},
+		{7.4, 0.74, 3.5, "7.4/10"},
+		{8.6, 0.86, 4.5, "8.6/10"},
+	}
+	for _, c := range cases {
+		if got := c.r.Normalized(); math.Abs(got-c.normalized) > 1e-9 {
+			t.Errorf("%v normalized = %v, want %v", c.r, got, c.normalized)
+		}
+		if got := c.r.Stars(); got != c.stars {
+			t.Errorf("%v stars = %v, want %v", c.r, got, c.stars)
+		}
+		if got := c.r.String(); got != c.str {
+			t.Errorf("%v string = %q, want %q", float64(c.r), got, c.str)
+		}
+		if back, ok := FromNumeric(c.r.Numeric()); !ok || back != c.r {
+			t.Errorf("%v round-tripped through NUMERIC as %v, %v", c.r, back, ok)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Command recoeval replays post history through the Ranker and reports
+// precision, recall, NDCG and coverage at k. Save a run with -out and pass it
+// as -baseline on a later run to print the change each metric saw.
+//
+//	DATABASE_URL=... go run ./cmd/recoeval -out before.json
+//	DATABASE_URL=... go run ./cmd/recoeval -baseline before.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/recommendations"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	defaults := recommendations.DefaultEvalConfig()
+	k := flag.Int("k", defaults.K, "recommendations per user to score")
+	holdOut := flag.Int("holdout", defaults.HoldOut, "most recent posts per user and category to hold out")
+	relevant := flag.Float64("relevant", defaults.RelevantRating, "rating a held-out post needs to count as relevant")
+	diversity := flag.Float64("diversity", defaults.Diversity, "MMR diversity passed to the ranker")
+	serendipity := flag.Bool("serendipity", defaults.Serendipity, "reserve a slot for an exploratory pick")
+	out := flag.String("out", "", "write the report as JSON to this file")
+	baselinePath := flag.String("baseline", "", "JSON report from an earlier run to compare against")
+	flag.Parse()
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	data, err := recommendations.LoadEvalData(ctx, sqlc.New(pool))
+	if err != nil {
+		log.Fatalf("Failed to load history: %v", err)
+	}
+
+	report, err := recommendations.Evaluate(ctx, data, recommendations.EvalConfig{
+		K:              *k,
+		HoldOut:        *holdOut,
+		RelevantRating: *relevant,
+		Diversity:      *diversity,
+		Serendipity:    *serendipity,
+	})
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	var baseline *recommendations.EvalReport
+	if *baselinePath != "" {
+		raw, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			log.Fatalf("Failed to read baseline: %v", err)
+		}
+		baseline = &recommendations.EvalReport{}
+		if err := json.Unmarshal(raw, baseline); err != nil {
+			log.Fatalf("Failed to parse baseline: %v", err)
+		}
+	}
+
+	if err := recommendations.WriteReport(os.Stdout, report, baseline); err != nil {
+		log.Fatal(err)
+	}
+
+	if *out != "" {
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*out, append(raw, '\n'), 0o644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+}
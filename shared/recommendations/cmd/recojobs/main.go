@@ -0,0 +1,50 @@
+// Command recojobs runs the scheduled recommendation jobs. Run it on as many
+// instances as you like; they elect a leader through a Postgres advisory
+// lock and only the leader runs jobs. With RECO_EMBEDDINGS_ENABLED set, the
+// nightly batch also refreshes embeddings through OpenAI using OPENAI_API_KEY.
+//
+//	DATABASE_URL=... go run ./cmd/recojobs -schedule "0 3 * * *" -frequent "* * * * *"
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/burkebarcode/backend/shared/openai"
+	"github.com/burkebarcode/backend/shared/recommendations"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	schedule := flag.String("schedule", recommendations.DefaultNightlySchedule, "cron schedule for the nightly batch")
+	frequent := flag.String("frequent", recommendations.DefaultFrequentSchedule, "cron schedule for attribute, profile and conversion catch-up")
+	staleAfter := flag.Duration("stale-after", recommendations.DefaultProfileStaleAfter, "recompute profiles not updated for this long")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	var embedder recommendations.Embedder
+	if recommendations.EmbeddingsEnabled() {
+		embedder = openai.NewClient(openai.Config{APIKey: os.Getenv("OPENAI_API_KEY")})
+	}
+
+	runner := recommendations.NewJobRunner(recommendations.NewAdvisoryLeader(pool))
+	if err := recommendations.AddNightlyJobs(runner, pool, *schedule, *staleAfter, embedder); err != nil {
+		log.Fatalf("Invalid schedule: %v", err)
+	}
+	if err := recommendations.AddFrequentJobs(runner, pool, *frequent); err != nil {
+		log.Fatalf("Invalid frequent schedule: %v", err)
+	}
+	runner.Run(ctx)
+}
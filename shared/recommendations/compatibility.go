@@ -0,0 +1,314 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// minCoRated is how many beverages both users must have rated before
+	// their ratings count toward compatibility
+	minCoRated = 3
+	// compatibilityShrinkage sets how fast co-rating agreement takes over
+	// from tag similarity: it gets weight n / (n + shrinkage)
+	compatibilityShrinkage = 5.0
+	// compatibilityTagLimit is how many shared and clashing tags are listed
+	compatibilityTagLimit = 5
+)
+
+// CompatibilityCategories are the categories a taste match is broken down by
+var CompatibilityCategories = []string{"wine", "beer", "cocktail"}
+
+// TagMatch is a tag two users feel the same or opposite ways about. Weight
+// is the weaker of the two feelings; LikedBy is set on clashing tags.
+type TagMatch struct {
+	Tag     string  `json:"tag"`
+	Weight  float64 `json:"weight"`
+	LikedBy string  `json:"liked_by,omitempty"`
+}
+
+// CategoryMatch is two users' taste match within one category
+type CategoryMatch struct {
+	Category string `json:"category"`
+	Score    int    `json:"score"` // 0-100
+	// TagSimilarity is the cosine of the users' net tag weights, -1 to 1
+	TagSimilarity float64 `json:"tag_similarity"`
+	// RatingAgreement is the correlation of their ratings on co-rated
+	// beverages; nil when they have co-rated fewer than minCoRated
+	RatingAgreement *float64   `json:"rating_agreement,omitempty"`
+	CoRated         int        `json:"co_rated"`
+	SharedTags      []TagMatch `json:"shared_tags"`
+	ClashingTags    []TagMatch `json:"clashing_tags"`
+	ComputedAt      time.Time  `json:"computed_at"`
+}
+
+// TasteMatch is the "taste match" shown on a user's profile. Score is the
+// categories' scores weighted by how much both users have posted in each;
+// it is nil when they share no category with enough posts.
+type TasteMatch struct {
+	UserID      string          `json:"user_id"`
+	OtherUserID string          `json:"other_user_id"`
+	Score       *int            `json:"score"`
+	Categories  []CategoryMatch `json:"categories"`
+}
+
+// TasteMatcher computes taste compatibility between users, caching it in
+// taste_compatibility until either user's profile is recomputed
+type TasteMatcher struct {
+	Q Store
+}
+
+func NewTasteMatcher(q Store) *TasteMatcher {
+	return &TasteMatcher{Q: q}
+}
+
+// Match returns userID's taste match with otherID. Categories where either
+// user is still cold-start are left out.
+func (m *TasteMatcher) Match(ctx context.Context, userID, otherID pgtype.UUID) (TasteMatch, error) {
+	if userID == otherID {
+		return TasteMatch{}, errors.New("taste match needs two different users")
+	}
+	// Pairs are cached once, lowest ID first
+	a, b := userID, otherID
+	if uuidLess(b, a) {
+		a, b = b, a
+	}
+
+	profileRows, err := m.Q.ListTasteProfilesForUsers(ctx, []pgtype.UUID{a, b})
+	if err != nil {
+		return TasteMatch{}, err
+	}
+	profilesA := make(map[string]sqlc.UserTasteProfile)
+	profilesB := make(map[string]sqlc.UserTasteProfile)
+	for _, p := range profileRows {
+		if p.UserID == a {
+			profilesA[p.Category] = p
+		} else {
+			profilesB[p.Category] = p
+		}
+	}
+
+	cachedRows, err := m.Q.GetTasteCompatibility(ctx, sqlc.GetTasteCompatibilityParams{UserID: a, OtherUserID: b})
+	if err != nil {
+		return TasteMatch{}, err
+	}
+	cached := make(map[string]sqlc.TasteCompatibility, len(cachedRows))
+	for _, row := range cachedRows {
+		cached[row.Category] = row
+	}
+
+	match := TasteMatch{
+		UserID:      uuid.UUID(userID.Bytes).String(),
+		OtherUserID: uuid.UUID(otherID.Bytes).String(),
+		Categories:  []CategoryMatch{},
+	}
+	var coRated map[string][]sqlc.GetCoRatedBeveragesRow
+	var weighted, weights float64
+	for _, category := range CompatibilityCategories {
+		pa, okA := profilesA[category]
+		pb, okB := profilesB[category]
+		if !okA || !okB || pa.PostCount.Int32 < coldStartPostCount || pb.PostCount.Int32 < coldStartPostCount {
+			continue
+		}
+
+		var cm CategoryMatch
+		if row, ok := cached[category]; ok && row.ProfileComputedAt.Time.Equal(pa.LastComputedAt.Time) &&
+			row.OtherProfileComputedAt.Time.Equal(pb.LastComputedAt.Time) {
+			cm = cachedCategoryMatch(row)
+		} else {
+			if coRated == nil {
+				coRated, err = m.loadCoRated(ctx, a, b)
+				if err != nil {
+					return TasteMatch{}, err
+				}
+			}
+			cm = computeCategoryMatch(pa, pb, coRated[category])
+			m.store(ctx, pa, pb, cm)
+		}
+
+		match.Categories = append(match.Categories, cm)
+		weight := float64(min(pa.PostCount.Int32, pb.PostCount.Int32))
+		weighted += weight * float64(cm.Score)
+		weights += weight
+	}
+	if weights > 0 {
+		score := int(math.Round(weighted / weights))
+		match.Score = &score
+	}
+	return match, nil
+}
+
+func (m *TasteMatcher) loadCoRated(ctx context.Context, a, b pgtype.UUID) (map[string][]sqlc.GetCoRatedBeveragesRow, error) {
+	rows, err := m.Q.GetCoRatedBeverages(ctx, sqlc.GetCoRatedBeveragesParams{UserID: a, OtherUserID: b})
+	if err != nil {
+		return nil, err
+	}
+	byCategory := make(map[string][]sqlc.GetCoRatedBeveragesRow)
+	for _, row := range rows {
+		byCategory[row.Category] = append(byCategory[row.Category], row)
+	}
+	return byCategory, nil
+}
+
+// store caches a computed match; failures are logged since the match is
+// still good to return
+func (m *TasteMatcher) store(ctx context.Context, pa, pb sqlc.UserTasteProfile, cm CategoryMatch) {
+	shared, _ := json.Marshal(cm.SharedTags)
+	clashing, _ := json.Marshal(cm.ClashingTags)
+	arg := sqlc.UpsertTasteCompatibilityParams{
+		UserID:                 pa.UserID,
+		OtherUserID:            pb.UserID,
+		Category:               cm.Category,
+		Score:                  int32(cm.Score),
+		TagSimilarity:          cm.TagSimilarity,
+		CoRated:                int32(cm.CoRated),
+		SharedTagsJson:         shared,
+		ClashingTagsJson:       clashing,
+		ProfileComputedAt:      pa.LastComputedAt,
+		OtherProfileComputedAt: pb.LastComputedAt,
+	}
+	if cm.RatingAgreement != nil {
+		arg.RatingAgreement = pgtype.Float8{Float64: *cm.RatingAgreement, Valid: true}
+	}
+	if err := m.Q.UpsertTasteCompatibility(ctx, arg); err != nil {
+		log.Printf("Failed to cache taste compatibility for %s: %v", cm.Category, err)
+	}
+}
+
+func cachedCategoryMatch(row sqlc.TasteCompatibility) CategoryMatch {
+	cm := CategoryMatch{
+		Category:      row.Category,
+		Score:         int(row.Score),
+		TagSimilarity: row.TagSimilarity,
+		CoRated:       int(row.CoRated),
+		SharedTags:    []TagMatch{},
+		ClashingTags:  []TagMatch{},
+		ComputedAt:    row.ComputedAt.Time,
+	}
+	if row.RatingAgreement.Valid {
+		agreement := row.RatingAgreement.Float64
+		cm.RatingAgreement = &agreement
+	}
+	json.Unmarshal(row.SharedTagsJson, &cm.SharedTags)
+	json.Unmarshal(row.ClashingTagsJson, &cm.ClashingTags)
+	return cm
+}
+
+// computeCategoryMatch blends the cosine of the users' net tag weights with
+// the correlation of their co-rated ratings, trusting the ratings more the
+// more beverages back them, and maps the result from -1..1 to 0..100
+func computeCategoryMatch(pa, pb sqlc.UserTasteProfile, coRated []sqlc.GetCoRatedBeveragesRow) CategoryMatch {
+	netA, netB := netTagWeights(pa), netTagWeights(pb)
+
+	var dot, normA, normB float64
+	for tag, w := range netA {
+		dot += w * netB[tag]
+		normA += w * w
+	}
+	for _, w := range netB {
+		normB += w * w
+	}
+	tagSim := 0.0
+	if normA > 0 && normB > 0 {
+		tagSim = dot / math.Sqrt(normA*normB)
+	}
+
+	cm := CategoryMatch{
+		Category:      pa.Category,
+		TagSimilarity: tagSim,
+		CoRated:       len(coRated),
+		ComputedAt:    time.Now(),
+	}
+	sim := tagSim
+	if agreement, ok := ratingAgreement(coRated); ok {
+		cm.RatingAgreement = &agreement
+		trust := float64(len(coRated)) / (float64(len(coRated)) + compatibilityShrinkage)
+		sim = (1-trust)*tagSim + trust*agreement
+	}
+	cm.Score = int(math.Round(50 * (1 + min(1, max(-1, sim)))))
+	cm.SharedTags, cm.ClashingTags = matchTags(netA, netB, uuid.UUID(pa.UserID.Bytes).String(), uuid.UUID(pb.UserID.Bytes).String())
+	return cm
+}
+
+// netTagWeights is liked minus disliked weight per tag
+func netTagWeights(p sqlc.UserTasteProfile) TagWeights {
+	var liked, disliked TagWeights
+	json.Unmarshal(p.LikedTagsJson, &liked)
+	json.Unmarshal(p.DislikedTagsJson, &disliked)
+
+	net := make(TagWeights, len(liked)+len(disliked))
+	for tag, w := range liked {
+		net[tag] += w
+	}
+	for tag, w := range disliked {
+		net[tag] -= w
+	}
+	return net
+}
+
+// ratingAgreement is the Pearson correlation of the two users' ratings;
+// ok is false with fewer than minCoRated beverages or no variation
+func ratingAgreement(rows []sqlc.GetCoRatedBeveragesRow) (float64, bool) {
+	if len(rows) < minCoRated {
+		return 0, false
+	}
+	var meanA, meanB float64
+	for _, row := range rows {
+		meanA += row.Rating
+		meanB += row.OtherRating
+	}
+	meanA /= float64(len(rows))
+	meanB /= float64(len(rows))
+
+	var ab, aa, bb float64
+	for _, row := range rows {
+		da, db := row.Rating-meanA, row.OtherRating-meanB
+		ab += da * db
+		aa += da * da
+		bb += db * db
+	}
+	if aa == 0 || bb == 0 {
+		return 0, false
+	}
+	return ab / math.Sqrt(aa*bb), true
+}
+
+// matchTags lists the tags both users like and the tags one likes and the
+// other dislikes, strongest first
+func matchTags(netA, netB TagWeights, userA, userB string) (shared, clashing []TagMatch) {
+	shared, clashing = []TagMatch{}, []TagMatch{}
+	for tag, a := range netA {
+		b := netB[tag]
+		switch {
+		case a > 0 && b > 0:
+			shared = append(shared, TagMatch{Tag: tag, Weight: min(a, b)})
+		case a > 0 && b < 0:
+			clashing = append(clashing, TagMatch{Tag: tag, Weight: min(a, -b), LikedBy: userA})
+		case a < 0 && b > 0:
+			clashing = append(clashing, TagMatch{Tag: tag, Weight: min(-a, b), LikedBy: userB})
+		}
+	}
+	return topTagMatches(shared), topTagMatches(clashing)
+}
+
+func topTagMatches(tags []TagMatch) []TagMatch {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Weight != tags[j].Weight {
+			return tags[i].Weight > tags[j].Weight
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	if len(tags) > compatibilityTagLimit {
+		tags = tags[:compatibilityTagLimit]
+	}
+	return tags
+}
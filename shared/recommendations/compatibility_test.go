@@ -0,0 +1,136 @@
+package recommendations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestTasteMatchByCategory(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := testUUID(100), testUUID(101)
+	// Same wine taste, opposite beer taste, and only Alice drinks cocktails
+	store.setProfile(alice, "wine", TagWeights{"oaky": 0.9, "buttery": 0.6}, TagWeights{"tannic": 0.5}, 10)
+	store.setProfile(bob, "wine", TagWeights{"oaky": 0.8, "buttery": 0.7}, TagWeights{"tannic": 0.4}, 10)
+	store.setProfile(alice, "beer", TagWeights{"hoppy": 1.0}, TagWeights{"sour": 0.8}, 10)
+	store.setProfile(bob, "beer", TagWeights{"sour": 1.0}, TagWeights{"hoppy": 0.9}, 10)
+	store.setProfile(alice, "cocktail", TagWeights{"bitter": 1.0}, TagWeights{}, 10)
+
+	match, err := NewTasteMatcher(store).Match(context.Background(), bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(match.Categories) != 2 || match.Categories[0].Category != "wine" || match.Categories[1].Category != "beer" {
+		t.Fatalf("categories = %+v", match.Categories)
+	}
+	wine, beer := match.Categories[0], match.Categories[1]
+	if wine.Score < 90 || beer.Score > 10 {
+		t.Errorf("wine %d, beer %d", wine.Score, beer.Score)
+	}
+	if match.Score == nil || *match.Score != (wine.Score+beer.Score+1)/2 {
+		t.Errorf("overall = %v", match.Score)
+	}
+	if len(wine.SharedTags) != 2 || wine.SharedTags[0].Tag != "oaky" || wine.SharedTags[0].Weight != 0.8 {
+		t.Errorf("wine shared = %+v", wine.SharedTags)
+	}
+	if len(beer.ClashingTags) != 2 || len(beer.SharedTags) != 0 {
+		t.Fatalf("beer tags = %+v / %+v", beer.SharedTags, beer.ClashingTags)
+	}
+	if hoppy := beer.ClashingTags[0]; hoppy.Tag != "hoppy" || hoppy.LikedBy != match.OtherUserID {
+		t.Errorf("clash = %+v, want hoppy liked by %s", hoppy, match.OtherUserID)
+	}
+}
+
+func TestTasteMatchBlendsCoRatings(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := testUUID(100), testUUID(101)
+	store.setProfile(alice, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 10)
+	store.setProfile(bob, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 10)
+
+	tagsOnly, err := NewTasteMatcher(store).Match(context.Background(), alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same tags, but they rate the same beers in opposite order
+	var rows []sqlc.GetCoRatedBeveragesRow
+	for i := range 6 {
+		rows = append(rows, sqlc.GetCoRatedBeveragesRow{
+			Category:    "beer",
+			BeverageID:  testUUID(i + 1),
+			Rating:      float64(4 + i),
+			OtherRating: float64(9 - i),
+		})
+	}
+	store.coRatings[[2]pgtype.UUID{alice, bob}] = rows
+	store.compat = make(map[string]sqlc.TasteCompatibility)
+
+	blended, err := NewTasteMatcher(store).Match(context.Background(), alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := blended.Categories[0]
+	if got.RatingAgreement == nil || *got.RatingAgreement > -0.99 || got.CoRated != 6 {
+		t.Fatalf("agreement = %v over %d", got.RatingAgreement, got.CoRated)
+	}
+	if got.Score >= tagsOnly.Categories[0].Score {
+		t.Errorf("disagreeing ratings left score at %d (tags only %d)", got.Score, tagsOnly.Categories[0].Score)
+	}
+}
+
+func TestTasteMatchCachesUntilProfileRecomputed(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := testUUID(100), testUUID(101)
+	store.setProfile(alice, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 10)
+	store.setProfile(bob, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 10)
+	matcher := NewTasteMatcher(store)
+
+	first, err := matcher.Match(context.Background(), alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := matcher.Match(context.Background(), bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if store.calls["UpsertTasteCompatibility"] != 1 || store.calls["GetCoRatedBeverages"] != 1 {
+		t.Fatalf("cached pair recomputed: %v", store.calls)
+	}
+
+	// Bob's profile is recomputed with a changed taste
+	store.setProfile(bob, "beer", TagWeights{"malty": 1.0}, TagWeights{"hoppy": 1.0}, 11)
+	p := store.profiles[profileKey{bob, "beer"}]
+	p.LastComputedAt.Time = p.LastComputedAt.Time.Add(time.Minute)
+	store.profiles[profileKey{bob, "beer"}] = p
+
+	second, err := matcher.Match(context.Background(), alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.calls["UpsertTasteCompatibility"] != 2 {
+		t.Error("stale match served from cache")
+	}
+	if second.Categories[0].Score >= first.Categories[0].Score {
+		t.Errorf("score %d after tastes diverged, was %d", second.Categories[0].Score, first.Categories[0].Score)
+	}
+}
+
+func TestTasteMatchSkipsColdStartCategories(t *testing.T) {
+	store := newFakeStore()
+	alice, bob := testUUID(100), testUUID(101)
+	store.setProfile(alice, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 10)
+	store.setProfile(bob, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 1)
+
+	match, err := NewTasteMatcher(store).Match(context.Background(), alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(match.Categories) != 0 || match.Score != nil {
+		t.Errorf("match = %+v", match)
+	}
+	if _, err := NewTasteMatcher(store).Match(context.Background(), alice, alice); err == nil {
+		t.Error("matched a user with themselves")
+	}
+}
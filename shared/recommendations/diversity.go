@@ -0,0 +1,186 @@
+package recommendations
+
+import (
+	"strings"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+)
+
+const (
+	// DefaultDiversity is the MMR trade-off used when a request doesn't set one
+	DefaultDiversity = 0.3
+
+	// Redundancy between two picks adds up from these; together they reach 1
+	producerRedundancy = 0.5
+	styleRedundancy    = 0.3
+	tagRedundancy      = 0.2
+
+	// serendipityMinLimit is the smallest page that gives up a slot to exploration
+	serendipityMinLimit = 3
+)
+
+// RankOptions tunes one recommendation request
+type RankOptions struct {
+	Limit int32
+	// Diversity trades relevance for variety when re-ranking: 0 keeps the
+	// pure score order, 1 picks whatever is least like what's already shown
+	Diversity float64
+	// Serendipity reserves the last slot for an exploratory pick that is
+	// unlike the rest of the page but still reasonably scored
+	Serendipity bool
+	// Venue restricts candidates to beverages posted at the venue, boosted
+	// by how recently they were seen there
+	Venue *VenueRef
+	// Filter constrains candidates by price, ABV, style and the like
+	Filter RecommendationFilter
+}
+
+// DefaultRankOptions is what RankRecommendations uses
+func DefaultRankOptions(limit int32) RankOptions {
+	return RankOptions{Limit: limit, Diversity: DefaultDiversity, Serendipity: true}
+}
+
+// scoredCandidate is a candidate with its relevance score, reasons and tags
+type scoredCandidate struct {
+	bev         sqlc.GetRecommendationCandidatesRow
+	score       float64
+	reasons     []RecommendationReason
+	tags        []beverageTag
+	exploratory bool
+	sighting    *venueSighting
+}
+
+// rerankForDiversity picks limit candidates by maximal marginal relevance:
+// each pick maximizes (1-diversity)*relevance - diversity*redundancy with the
+// picks before it. scored must be sorted by score, best first.
+func rerankForDiversity(scored []scoredCandidate, limit int, diversity float64, serendipity bool) []scoredCandidate {
+	if limit > len(scored) {
+		limit = len(scored)
+	}
+	if limit <= 0 {
+		return nil
+	}
+	diversity = min(1, max(0, diversity))
+
+	// Normalize scores to 0-1 so they trade evenly against redundancy
+	top, bottom := scored[0].score, scored[len(scored)-1].score
+	relevance := make([]float64, len(scored))
+	for i, c := range scored {
+		relevance[i] = 1
+		if top > bottom {
+			relevance[i] = (c.score - bottom) / (top - bottom)
+		}
+	}
+
+	explore := serendipity && limit >= serendipityMinLimit && len(scored) > limit
+	mmrSlots := limit
+	if explore {
+		mmrSlots--
+	}
+
+	picked := make([]int, 0, limit)
+	used := make([]bool, len(scored))
+	// redundancy[i] is candidate i's highest redundancy with any pick so far
+	redundancy := make([]float64, len(scored))
+
+	pick := func(i int) {
+		used[i] = true
+		picked = append(picked, i)
+		for j := range scored {
+			if !used[j] {
+				redundancy[j] = max(redundancy[j], candidateRedundancy(scored[i], scored[j]))
+			}
+		}
+	}
+
+	mmrPick := func() {
+		best, bestValue := -1, 0.0
+		for i := range scored {
+			if used[i] {
+				continue
+			}
+			value := (1-diversity)*relevance[i] - diversity*redundancy[i]
+			if best < 0 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		pick(best)
+	}
+	for len(picked) < mmrSlots {
+		mmrPick()
+	}
+
+	exploratory := -1
+	if explore {
+		exploratory = exploratoryPick(relevance, redundancy, used)
+		if exploratory >= 0 {
+			pick(exploratory)
+		} else {
+			// Nothing worth exploring, so the slot goes back to MMR
+			mmrPick()
+		}
+	}
+
+	out := make([]scoredCandidate, 0, limit)
+	for _, i := range picked {
+		c := scored[i]
+		c.exploratory = i == exploratory
+		out = append(out, c)
+	}
+	return out
+}
+
+// exploratoryPick is the unused candidate with the best relevance discounted
+// by its redundancy with the page, so it is unlike the picks but not simply
+// the worst-scored leftover
+func exploratoryPick(relevance, redundancy []float64, used []bool) int {
+	best, bestValue := -1, 0.0
+	for i := range relevance {
+		if used[i] {
+			continue
+		}
+		value := relevance[i] * (1 - redundancy[i])
+		if value > bestValue {
+			best, bestValue = i, value
+		}
+	}
+	return best
+}
+
+// candidateRedundancy is how much b repeats a: same producer, same style
+// and overlapping tags each add their share, up to 1
+func candidateRedundancy(a, b scoredCandidate) float64 {
+	var r float64
+	if a.bev.ProducerID.Valid && a.bev.ProducerID == b.bev.ProducerID {
+		r += producerRedundancy
+	}
+	if a.bev.Style.String != "" && strings.EqualFold(a.bev.Style.String, b.bev.Style.String) {
+		r += styleRedundancy
+	}
+	r += tagRedundancy * tagJaccard(a.tags, b.tags)
+	return r
+}
+
+func tagJaccard(a, b []beverageTag) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t.Tag] = true
+	}
+	shared, union := 0, len(set)
+	seen := make(map[string]bool, len(b))
+	for _, t := range b {
+		if seen[t.Tag] {
+			continue
+		}
+		seen[t.Tag] = true
+		if set[t.Tag] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
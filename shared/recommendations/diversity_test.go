@@ -0,0 +1,128 @@
+package recommendations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// sameWineryFixture has five near-identical Cabs from one producer that all
+// outscore two different wines
+func sameWineryFixture() (*fakeStore, pgtype.UUID) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "wine", TagWeights{"oaky": 1.0, "full-bodied": 0.8, "bright": 0.3}, TagWeights{}, 12)
+	winery := testUUID(900)
+	for i := 1; i <= 5; i++ {
+		id := testUUID(i)
+		store.addBeverage(id, "Estate Cab "+string(rune('A'+i-1)), "wine", 9.0, int32(50-i), "oaky", "full-bodied")
+		store.setStyle(id, "Cabernet Sauvignon")
+		b := store.beverages[id]
+		b.ProducerID = winery
+		store.beverages[id] = b
+	}
+	store.addBeverage(testUUID(10), "Oaked Chardonnay", "wine", 8.5, 20, "oaky", "buttery")
+	store.setStyle(testUUID(10), "Chardonnay")
+	store.addBeverage(testUUID(11), "Sparkling Rose", "wine", 8.0, 15, "bright", "fizzy")
+	store.setStyle(testUUID(11), "Rose")
+	return store, user
+}
+
+func producers(store *fakeStore, results []RankedBeverage) map[pgtype.UUID]int {
+	counts := make(map[pgtype.UUID]int)
+	for _, r := range results {
+		for _, b := range store.beverages {
+			if b.Name == r.Name {
+				counts[b.ProducerID]++
+			}
+		}
+	}
+	return counts
+}
+
+func TestRankWithOptionsZeroDiversityKeepsScoreOrder(t *testing.T) {
+	store, user := sameWineryFixture()
+
+	results, err := NewRanker(store).RankWithOptions(context.Background(), user, "wine", RankOptions{Limit: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Estate Cab A", "Estate Cab B", "Estate Cab C", "Estate Cab D"}
+	if got := names(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestRankWithOptionsDiversityBreaksUpProducer(t *testing.T) {
+	store, user := sameWineryFixture()
+
+	results, err := NewRanker(store).RankWithOptions(context.Background(), user, "wine", RankOptions{Limit: 4, Diversity: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Name != "Estate Cab A" {
+		t.Errorf("top pick = %s, diversity should not displace the best match", results[0].Name)
+	}
+	if n := producers(store, results)[testUUID(900)]; n > 2 {
+		t.Errorf("%d of 4 picks from one winery: %v", n, names(results))
+	}
+	for _, r := range results {
+		if r.Exploratory {
+			t.Errorf("%s marked exploratory without serendipity", r.Name)
+		}
+	}
+}
+
+func TestRankWithOptionsSerendipitySlot(t *testing.T) {
+	store, user := sameWineryFixture()
+
+	results, err := NewRanker(store).RankWithOptions(context.Background(), user, "wine", RankOptions{Limit: 3, Serendipity: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results", len(results))
+	}
+	last := results[2]
+	if !last.Exploratory || last.Name == "Estate Cab C" {
+		t.Errorf("last slot = %+v, want an exploratory non-Cab pick", last)
+	}
+	if last.Reasons[0] != "Something different to try" {
+		t.Errorf("exploratory reasons = %v", last.Reasons)
+	}
+	for _, r := range results[:2] {
+		if r.Exploratory {
+			t.Errorf("%s marked exploratory", r.Name)
+		}
+	}
+}
+
+func TestRankRecommendationsDefaultsToDiverse(t *testing.T) {
+	store, user := sameWineryFixture()
+
+	results, err := NewRanker(store).RankRecommendations(context.Background(), user, "wine", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := producers(store, results)[testUUID(900)]; n == 5 {
+		t.Errorf("default ranking returned five wines from one winery: %v", names(results))
+	}
+}
+
+func TestRerankForDiversityEdgeCases(t *testing.T) {
+	scored := []scoredCandidate{{score: 3}, {score: 2}, {score: 1}}
+	if got := rerankForDiversity(scored, 10, 0.5, true); len(got) != 3 {
+		t.Errorf("limit above candidates returned %d", len(got))
+	}
+	if got := rerankForDiversity(nil, 5, 0.5, true); len(got) != 0 {
+		t.Errorf("no candidates returned %d", len(got))
+	}
+	// Serendipity only applies when candidates are left over
+	for _, c := range rerankForDiversity(scored, 3, 0.5, true) {
+		if c.exploratory {
+			t.Error("exploratory pick made with nothing left to explore")
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultEmbeddingIndexTTL is how long a category's beverage vectors are
+	// cached before EmbeddingIndex reloads them
+	DefaultEmbeddingIndexTTL = 10 * time.Minute
+
+	embeddingRefreshBatch = 100
+	embeddingTextTags     = 12
+)
+
+// EmbeddingsEnabled reports whether RECO_EMBEDDINGS_ENABLED is set to a true value
+func EmbeddingsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("RECO_EMBEDDINGS_ENABLED"))
+	return enabled
+}
+
+// Embedder turns text into vectors. *openai.Client satisfies it;
+// HashEmbedder is a deterministic local stand-in.
+type Embedder interface {
+	EmbeddingModel() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HashEmbedder embeds text by hashing its words into Dims signed buckets.
+// Texts that share words land close together, which is enough to run the
+// pipeline in development and tests without an API key.
+type HashEmbedder struct {
+	Dims int
+}
+
+func NewHashEmbedder(dims int) *HashEmbedder {
+	return &HashEmbedder{Dims: dims}
+}
+
+func (h *HashEmbedder) EmbeddingModel() string {
+	return fmt.Sprintf("local-hash-%d", h.Dims)
+}
+
+func (h *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, h.Dims)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			hash := fnv.New64a()
+			hash.Write([]byte(word))
+			sum := hash.Sum64()
+			sign := float32(1)
+			if sum&(1<<63) != 0 {
+				sign = -1
+			}
+			v[sum%uint64(h.Dims)] += sign
+		}
+		vectors[i] = normalizeVector(v)
+	}
+	return vectors, nil
+}
+
+// EmbeddingService keeps beverage and user embeddings in step with the
+// catalog and taste profiles
+type EmbeddingService struct {
+	Q        Store
+	Embedder Embedder
+}
+
+func NewEmbeddingService(q Store, embedder Embedder) *EmbeddingService {
+	return &EmbeddingService{Q: q, Embedder: embedder}
+}
+
+// RefreshBeverages embeds up to limit beverages whose embedding is missing,
+// from another model, or older than the beverage's details or tags.
+// Unchanged text is only marked current, not re-embedded.
+func (s *EmbeddingService) RefreshBeverages(ctx context.Context, limit int32) (int, error) {
+	model := s.Embedder.EmbeddingModel()
+	rows, err := s.Q.ListBeveragesNeedingEmbeddings(ctx, sqlc.ListBeveragesNeedingEmbeddingsParams{
+		Model: model,
+		Limit: limit,
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	ids := make([]pgtype.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	tags, err := loadBeverageTags(ctx, s.Q, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var pending []sqlc.ListBeveragesNeedingEmbeddingsRow
+	var texts []string
+	for _, row := range rows {
+		text := beverageEmbeddingText(row, tags[row.ID])
+		if row.CurrentText.String == text && row.CurrentModel.String == model {
+			if err := s.Q.TouchBeverageEmbedding(ctx, row.ID); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		pending = append(pending, row)
+		texts = append(texts, text)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	vectors, err := s.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+	for i, row := range pending {
+		if err := s.Q.UpsertBeverageEmbedding(ctx, sqlc.UpsertBeverageEmbeddingParams{
+			BeverageID:      row.ID,
+			EmbeddingText:   texts[i],
+			EmbeddingVector: vectors[i],
+			Model:           model,
+		}); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// RefreshUsers embeds the liked tags of up to limit taste profiles changed
+// since their embedding was built
+func (s *EmbeddingService) RefreshUsers(ctx context.Context, limit int32) (int, error) {
+	model := s.Embedder.EmbeddingModel()
+	rows, err := s.Q.ListUserEmbeddingsToRefresh(ctx, sqlc.ListUserEmbeddingsToRefreshParams{
+		Model: pgtype.Text{String: model, Valid: true},
+		Limit: limit,
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	var pending []sqlc.ListUserEmbeddingsToRefreshRow
+	var texts []string
+	for _, row := range rows {
+		var liked TagWeights
+		json.Unmarshal(row.LikedTagsJson, &liked)
+		text := userEmbeddingText(row.Category, liked)
+		if row.CurrentText.String == text && row.CurrentModel.String == model {
+			if err := s.Q.TouchUserEmbedding(ctx, sqlc.TouchUserEmbeddingParams{
+				UserID:   row.UserID,
+				Category: row.Category,
+			}); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		pending = append(pending, row)
+		texts = append(texts, text)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	vectors, err := s.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+	for i, row := range pending {
+		if _, err := s.Q.UpsertUserEmbedding(ctx, sqlc.UpsertUserEmbeddingParams{
+			UserID:          row.UserID,
+			Category:        row.Category,
+			EmbeddingText:   texts[i],
+			EmbeddingVector: vectors[i],
+			Model:           pgtype.Text{String: model, Valid: true},
+		}); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// Run embeds batches of beverages, then of users, until a batch has nothing
+// left to embed
+func (s *EmbeddingService) Run(ctx context.Context) error {
+	total := 0
+	for {
+		n, err := s.RefreshBeverages(ctx, embeddingRefreshBatch)
+		if err != nil {
+			return fmt.Errorf("beverage embeddings: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+	}
+	log.Printf("Beverage embedding refresh embedded %d beverages", total)
+
+	total = 0
+	for {
+		n, err := s.RefreshUsers(ctx, embeddingRefreshBatch)
+		if err != nil {
+			return fmt.Errorf("user embeddings: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+	}
+	log.Printf("User embedding refresh embedded %d profiles", total)
+	return nil
+}
+
+// beverageEmbeddingText describes a beverage by its canonical attributes and
+// most common tags
+func beverageEmbeddingText(row sqlc.ListBeveragesNeedingEmbeddingsRow, tags []beverageTag) string {
+	parts := []string{row.Name}
+	for _, t := range []pgtype.Text{row.Brand, row.Style, row.Varietal, row.Region} {
+		if t.Valid && t.String != "" {
+			parts = append(parts, t.String)
+		}
+	}
+	parts = append(parts, row.Category)
+
+	sorted := append([]beverageTag{}, tags...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Count > sorted[j].Count })
+	var names []string
+	for i := 0; i < len(sorted) && i < embeddingTextTags; i++ {
+		names = append(names, sorted[i].Tag)
+	}
+	if len(names) > 0 {
+		parts = append(parts, "Tasting notes: "+strings.Join(names, ", "))
+	}
+
+	return strings.Join(parts, ". ")
+}
+
+// userEmbeddingText describes what a user likes in a category, strongest
+// first. Disliked tags are left out so they don't pull the vector towards
+// the beverages the user avoids.
+func userEmbeddingText(category string, liked TagWeights) string {
+	tags := make([]string, 0, len(liked))
+	for tag, w := range liked {
+		if w > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if liked[tags[i]] != liked[tags[j]] {
+			return liked[tags[i]] > liked[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > embeddingTextTags {
+		tags = tags[:embeddingTextTags]
+	}
+	return category + ". Tasting notes: " + strings.Join(tags, ", ")
+}
+
+// EmbeddingMatch is a beverage and its cosine similarity to a query vector
+type EmbeddingMatch struct {
+	BeverageID pgtype.UUID
+	Similarity float64
+}
+
+// EmbeddingIndex caches beverage embeddings per category and answers cosine
+// similarity queries in process. A category holds a few thousand beverages,
+// so a brute-force scan is cheaper than running pgvector.
+type EmbeddingIndex struct {
+	Q     Store
+	Model string
+	TTL   time.Duration
+
+	mu         sync.Mutex
+	categories map[string]*embeddingSet
+}
+
+type embeddingSet struct {
+	ids      []pgtype.UUID
+	vectors  [][]float32
+	index    map[pgtype.UUID]int
+	loadedAt time.Time
+}
+
+func NewEmbeddingIndex(q Store, model string) *EmbeddingIndex {
+	return &EmbeddingIndex{
+		Q:          q,
+		Model:      model,
+		TTL:        DefaultEmbeddingIndexTTL,
+		categories: make(map[string]*embeddingSet),
+	}
+}
+
+func (x *EmbeddingIndex) load(ctx context.Context, category string) (*embeddingSet, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if set, ok := x.categories[category]; ok && time.Since(set.loadedAt) < x.TTL {
+		return set, nil
+	}
+
+	rows, err := x.Q.GetBeverageEmbeddingsByCategory(ctx, sqlc.GetBeverageEmbeddingsByCategoryParams{
+		Category: category,
+		Model:    x.Model,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	set := &embeddingSet{
+		index:    make(map[pgtype.UUID]int, len(rows)),
+		loadedAt: time.Now(),
+	}
+	for _, row := range rows {
+		if len(row.EmbeddingVector) == 0 {
+			continue
+		}
+		set.index[row.BeverageID] = len(set.ids)
+		set.ids = append(set.ids, row.BeverageID)
+		set.vectors = append(set.vectors, normalizeVector(row.EmbeddingVector))
+	}
+	if x.categories == nil {
+		x.categories = make(map[string]*embeddingSet)
+	}
+	x.categories[category] = set
+	return set, nil
+}
+
+// Nearest returns up to k beverages in the category most similar to query
+func (x *EmbeddingIndex) Nearest(ctx context.Context, category string, query []float32, k int) ([]EmbeddingMatch, error) {
+	set, err := x.load(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	q := normalizeVector(query)
+	matches := make([]EmbeddingMatch, 0, len(set.ids))
+	for i, v := range set.vectors {
+		if len(v) != len(q) {
+			continue
+		}
+		matches = append(matches, EmbeddingMatch{BeverageID: set.ids[i], Similarity: dot(q, v)})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Similarities returns the cosine similarity to query of each beverage in ids
+// that has an embedding
+func (x *EmbeddingIndex) Similarities(ctx context.Context, category string, query []float32, ids []pgtype.UUID) (map[pgtype.UUID]float64, error) {
+	set, err := x.load(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	q := normalizeVector(query)
+	out := make(map[pgtype.UUID]float64, len(ids))
+	for _, id := range ids {
+		i, ok := set.index[id]
+		if !ok || len(set.vectors[i]) != len(q) {
+			continue
+		}
+		out[id] = dot(q, set.vectors[i])
+	}
+	return out, nil
+}
+
+// normalizeVector returns v scaled to unit length, so dot products are cosines
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+	norm := math.Sqrt(sum)
+	for i, f := range v {
+		out[i] = float32(float64(f) / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,183 @@
+package recommendations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestHashEmbedderDeterministicAndSimilar(t *testing.T) {
+	e := NewHashEmbedder(64)
+	vectors, err := e.Embed(context.Background(), []string{
+		"hoppy citrus pine",
+		"hoppy citrus pine",
+		"hoppy citrus resin",
+		"roasty chocolate coffee",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Error("same text gave different vectors")
+	}
+	if sim := dot(vectors[0], vectors[0]); sim < 0.999 || sim > 1.001 {
+		t.Errorf("vector not unit length: %f", sim)
+	}
+	close, far := dot(vectors[0], vectors[2]), dot(vectors[0], vectors[3])
+	if close <= far {
+		t.Errorf("overlapping text similarity %.2f not above disjoint %.2f", close, far)
+	}
+}
+
+func TestEmbeddingIndexNearestAndSimilarities(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "IPA", "beer", 7, 1)
+	store.addBeverage(testUUID(2), "Pale", "beer", 7, 1)
+	store.addBeverage(testUUID(3), "Stout", "beer", 7, 1)
+	store.addBeverage(testUUID(4), "Red", "wine", 7, 1)
+	store.setBeverageEmbedding(testUUID(1), "m", 1, 0)
+	store.setBeverageEmbedding(testUUID(2), "m", 1, 1)
+	store.setBeverageEmbedding(testUUID(3), "m", 0, 1)
+	store.setBeverageEmbedding(testUUID(4), "m", 1, 0)
+
+	index := NewEmbeddingIndex(store, "m")
+	nearest, err := index.Nearest(context.Background(), "beer", []float32{2, 0}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nearest) != 2 || nearest[0].BeverageID != testUUID(1) || nearest[1].BeverageID != testUUID(2) {
+		t.Fatalf("nearest = %+v", nearest)
+	}
+
+	sims, err := index.Similarities(context.Background(), "beer", []float32{0, 1}, []pgtype.UUID{testUUID(1), testUUID(3), testUUID(4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sims) != 2 || sims[testUUID(1)] > 1e-6 || sims[testUUID(3)] < 0.999 {
+		t.Errorf("similarities = %v", sims)
+	}
+	if n := store.calls["GetBeverageEmbeddingsByCategory"]; n != 1 {
+		t.Errorf("embeddings loaded %d times, want 1 cached load", n)
+	}
+}
+
+func TestEmbeddingServiceRefreshBeverages(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "IPA", "beer", 7, 1, "hoppy", "citrus")
+	store.addBeverage(testUUID(2), "Stout", "beer", 7, 1, "roasty")
+	service := NewEmbeddingService(store, NewHashEmbedder(32))
+
+	n, err := service.RefreshBeverages(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("embedded %d beverages, want 2", n)
+	}
+	if got := store.bevEmbeds[testUUID(1)].EmbeddingText; got != "IPA. beer. Tasting notes: hoppy, citrus" {
+		t.Errorf("embedding text = %q", got)
+	}
+
+	// A stale embedding whose text hasn't changed is only touched
+	e := store.bevEmbeds[testUUID(2)]
+	e.UpdatedAt = pgtype.Timestamptz{}
+	store.bevEmbeds[testUUID(2)] = e
+
+	n, err = service.RefreshBeverages(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || store.calls["TouchBeverageEmbedding"] != 1 || store.calls["UpsertBeverageEmbedding"] != 2 {
+		t.Errorf("embedded %d, touched %d, upserted %d", n, store.calls["TouchBeverageEmbedding"], store.calls["UpsertBeverageEmbedding"])
+	}
+}
+
+func TestEmbeddingServiceRefreshUsers(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"citrus": 0.5, "hoppy": 1.0}, TagWeights{"sour": 1.0}, 5)
+	service := NewEmbeddingService(store, NewHashEmbedder(32))
+
+	n, err := service.RefreshUsers(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("embedded %d users, want 1", n)
+	}
+	e := store.userEmbeds[profileKey{user, "beer"}]
+	if e.EmbeddingText != "beer. Tasting notes: hoppy, citrus" {
+		t.Errorf("embedding text = %q", e.EmbeddingText)
+	}
+	if e.Model.String != "local-hash-32" || len(e.EmbeddingVector) != 32 {
+		t.Errorf("model %q with %d dims", e.Model.String, len(e.EmbeddingVector))
+	}
+}
+
+func TestRankRecommendationsBlendsEmbeddings(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "Lager A", "beer", 8.0, 30, "crisp")
+	store.addBeverage(testUUID(2), "Lager B", "beer", 8.0, 20, "crisp")
+	store.addBeverage(testUUID(3), "Lager C", "beer", 8.0, 10, "crisp")
+	// Outside the popularity candidates for limit 1, reachable only by embedding
+	store.addBeverage(testUUID(4), "Hidden IPA", "beer", 7.0, 1, "hoppy")
+	store.setBeverageEmbedding(testUUID(1), "m", 0, 1)
+	store.setBeverageEmbedding(testUUID(2), "m", 0, 1)
+	store.setBeverageEmbedding(testUUID(3), "m", 0, 1)
+	store.setBeverageEmbedding(testUUID(4), "m", 1, 0)
+	store.userEmbeds[profileKey{user, "beer"}] = sqlc.UserEmbedding{
+		UserID:          user,
+		Category:        "beer",
+		EmbeddingVector: []float32{1, 0},
+		Model:           pgtype.Text{String: "m", Valid: true},
+	}
+
+	ranker := NewRanker(store)
+	ranker.Embeddings = NewEmbeddingIndex(store, "m")
+	results, err := ranker.RankRecommendations(context.Background(), user, "beer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(results); !reflect.DeepEqual(got, []string{"Hidden IPA"}) {
+		t.Fatalf("results = %v", got)
+	}
+	if got := results[0].Reasons; !reflect.DeepEqual(got, []string{"You rate 'hoppy' higher", "Close to your overall taste"}) {
+		t.Errorf("reasons = %v", got)
+	}
+	if n := store.calls["GetRecommendationCandidatesByIDs"]; n != 1 {
+		t.Errorf("extra candidates loaded %d times", n)
+	}
+}
+
+func TestRankRecommendationsIgnoresEmbeddingFromOtherModel(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "IPA", "beer", 8.0, 10, "hoppy")
+	store.setBeverageEmbedding(testUUID(1), "m", 1, 0)
+	store.userEmbeds[profileKey{user, "beer"}] = sqlc.UserEmbedding{
+		UserID:          user,
+		Category:        "beer",
+		EmbeddingVector: []float32{1, 0},
+		Model:           pgtype.Text{String: "old", Valid: true},
+	}
+
+	ranker := NewRanker(store)
+	ranker.Embeddings = NewEmbeddingIndex(store, "m")
+	results, err := ranker.RankRecommendations(context.Background(), user, "beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := results[0].Reasons; !reflect.DeepEqual(got, []string{"You rate 'hoppy' higher"}) {
+		t.Errorf("reasons = %v", got)
+	}
+	if n := store.calls["GetBeverageEmbeddingsByCategory"]; n != 0 {
+		t.Errorf("beverage embeddings loaded %d times for a mismatched model", n)
+	}
+}
@@ -0,0 +1,616 @@
+package recommendations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// recentPostExclusion mirrors the LIMIT in GetRecommendationCandidates: a
+// user's most recent posted beverages are never recommended back to them
+const recentPostExclusion = 20
+
+// EvalConfig controls an offline evaluation run
+type EvalConfig struct {
+	// K is the recommendation page size the metrics are computed at
+	K int `json:"k"`
+	// HoldOut is how many of each user's most recent posts per category are
+	// hidden from training and used as the ground truth
+	HoldOut int `json:"hold_out"`
+	// RelevantRating is the rating a held-out post needs to count as a hit
+	RelevantRating float64 `json:"relevant_rating"`
+	Diversity      float64 `json:"diversity"`
+	Serendipity    bool    `json:"serendipity"`
+}
+
+// DefaultEvalConfig evaluates the default ranking at a page of 10
+func DefaultEvalConfig() EvalConfig {
+	opts := DefaultRankOptions(10)
+	return EvalConfig{
+		K:              int(opts.Limit),
+		HoldOut:        2,
+		RelevantRating: 7,
+		Diversity:      opts.Diversity,
+		Serendipity:    opts.Serendipity,
+	}
+}
+
+// EvalMetrics are ranking metrics averaged over evaluated users
+type EvalMetrics struct {
+	Users        int     `json:"users"`
+	PrecisionAtK float64 `json:"precision_at_k"`
+	RecallAtK    float64 `json:"recall_at_k"`
+	NDCGAtK      float64 `json:"ndcg_at_k"`
+	// HitRate is the share of users with at least one relevant pick
+	HitRate float64 `json:"hit_rate"`
+}
+
+// CategoryReport breaks a run down for one drink category
+type CategoryReport struct {
+	Category  string      `json:"category"`
+	All       EvalMetrics `json:"all"`
+	ColdStart EvalMetrics `json:"cold_start"`
+	Warm      EvalMetrics `json:"warm"`
+	// Coverage is the share of the category's catalog recommended to anyone
+	Coverage    float64 `json:"coverage"`
+	CatalogSize int     `json:"catalog_size"`
+}
+
+// EvalReport is the result of Evaluate; its JSON form is what a ranking
+// change is compared against
+type EvalReport struct {
+	Config      EvalConfig       `json:"config"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Categories  []CategoryReport `json:"categories"`
+	Overall     EvalMetrics      `json:"overall"`
+	Coverage    float64          `json:"coverage"`
+}
+
+// EvalData is the history an evaluation replays
+type EvalData struct {
+	Posts         []sqlc.ListEvaluationPostsRow
+	Beverages     []sqlc.ListEvaluationBeveragesRow
+	DecaySettings []sqlc.TasteDecaySetting
+}
+
+// LoadEvalData reads the full post history and catalog
+func LoadEvalData(ctx context.Context, q *sqlc.Queries) (EvalData, error) {
+	posts, err := q.ListEvaluationPosts(ctx)
+	if err != nil {
+		return EvalData{}, err
+	}
+	beverages, err := q.ListEvaluationBeverages(ctx)
+	if err != nil {
+		return EvalData{}, err
+	}
+	decay, err := q.ListTasteDecaySettings(ctx)
+	if err != nil {
+		return EvalData{}, err
+	}
+	return EvalData{Posts: posts, Beverages: beverages, DecaySettings: decay}, nil
+}
+
+// evalKey identifies one user's history in one category
+type evalKey struct {
+	userID   pgtype.UUID
+	category string
+}
+
+// evalPost is one post with all of its tag rows
+type evalPost struct {
+	rows       []sqlc.GetUserPostsForCategoryRow
+	beverageID pgtype.UUID
+	rating     rating.Rating
+	rated      bool
+	createdAt  time.Time
+}
+
+// evalCase is a user whose held-out posts are scored against their recommendations
+type evalCase struct {
+	key      evalKey
+	asOf     time.Time
+	relevant map[pgtype.UUID]bool
+}
+
+// Evaluate replays history: each user's most recent posts per category are
+// held out, profiles and catalog stats are rebuilt from the remaining posts
+// only, and the Ranker's picks are scored against the held-out beverages the
+// user rated highly. Users with no such beverage are skipped.
+func Evaluate(ctx context.Context, data EvalData, cfg EvalConfig) (EvalReport, error) {
+	if cfg.K <= 0 || cfg.HoldOut <= 0 {
+		return EvalReport{}, errors.New("k and hold-out must be positive")
+	}
+
+	history := groupEvalPosts(data.Posts)
+	keys := make([]evalKey, 0, len(history))
+	for key := range history {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].category != keys[j].category {
+			return keys[i].category < keys[j].category
+		}
+		return uuidLess(keys[i].userID, keys[j].userID)
+	})
+
+	// Split every history so no held-out post leaks into training, including
+	// into other users' candidate stats
+	training := make(map[evalKey][]evalPost, len(history))
+	var cases []evalCase
+	for _, key := range keys {
+		posts := history[key]
+		split := max(0, len(posts)-cfg.HoldOut)
+		training[key] = posts[:split]
+
+		seen := make(map[pgtype.UUID]bool)
+		for _, p := range posts[:split] {
+			seen[p.beverageID] = true
+		}
+		relevant := make(map[pgtype.UUID]bool)
+		for _, p := range posts[split:] {
+			if p.rated && p.rating.Float64() >= cfg.RelevantRating && !seen[p.beverageID] {
+				relevant[p.beverageID] = true
+			}
+		}
+		if len(relevant) > 0 {
+			cases = append(cases, evalCase{key: key, asOf: posts[split].createdAt, relevant: relevant})
+		}
+	}
+
+	store := newReplayStore(data, training)
+	ranker := NewRanker(store)
+	opts := RankOptions{Limit: int32(cfg.K), Diversity: cfg.Diversity, Serendipity: cfg.Serendipity}
+
+	type categoryTotals struct {
+		all, cold, warm metricSums
+		recommended     map[string]bool
+	}
+	totals := make(map[string]*categoryTotals)
+	var overall metricSums
+	recommended := make(map[string]bool)
+
+	for _, c := range cases {
+		computer := &TasteProfileComputer{Q: store, Now: func() time.Time { return c.asOf }}
+		if err := computer.ComputeProfile(ctx, c.key.userID, c.key.category); err != nil {
+			return EvalReport{}, err
+		}
+		ranked, err := ranker.RankWithOptions(ctx, c.key.userID, c.key.category, opts)
+		if err != nil {
+			return EvalReport{}, err
+		}
+
+		ids := make([]string, len(ranked))
+		for i, r := range ranked {
+			ids[i] = r.BeverageID
+		}
+		m := scoreRanking(ids, c.relevant, cfg.K)
+
+		t := totals[c.key.category]
+		if t == nil {
+			t = &categoryTotals{recommended: make(map[string]bool)}
+			totals[c.key.category] = t
+		}
+		t.all.add(m)
+		overall.add(m)
+		if !personalized(store.profiles[c.key]) {
+			t.cold.add(m)
+		} else {
+			t.warm.add(m)
+		}
+		for _, id := range ids {
+			t.recommended[id] = true
+			recommended[id] = true
+		}
+	}
+
+	catalog := make(map[string]int)
+	for _, b := range data.Beverages {
+		catalog[b.Category]++
+	}
+
+	report := EvalReport{
+		Config:      cfg,
+		GeneratedAt: time.Now().UTC(),
+		Overall:     overall.metrics(),
+		Coverage:    ratio(len(recommended), len(data.Beverages)),
+	}
+	for category, t := range totals {
+		report.Categories = append(report.Categories, CategoryReport{
+			Category:    category,
+			All:         t.all.metrics(),
+			ColdStart:   t.cold.metrics(),
+			Warm:        t.warm.metrics(),
+			Coverage:    ratio(len(t.recommended), catalog[category]),
+			CatalogSize: catalog[category],
+		})
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+	return report, nil
+}
+
+// groupEvalPosts splits the post rows into per-user, per-category histories,
+// oldest post first
+func groupEvalPosts(rows []sqlc.ListEvaluationPostsRow) map[evalKey][]evalPost {
+	history := make(map[evalKey][]evalPost)
+	index := make(map[pgtype.UUID]int)
+	for _, row := range rows {
+		key := evalKey{row.UserID, row.DrinkCategory}
+		i, ok := index[row.ID]
+		if !ok {
+			i = len(history[key])
+			index[row.ID] = i
+			r, rated := rating.FromNumeric(row.Rating)
+			history[key] = append(history[key], evalPost{
+				beverageID: row.BeverageID,
+				rating:     r,
+				rated:      rated,
+				createdAt:  row.CreatedAt.Time,
+			})
+		}
+		history[key][i].rows = append(history[key][i].rows, sqlc.GetUserPostsForCategoryRow(row))
+	}
+	for _, posts := range history {
+		sort.SliceStable(posts, func(i, j int) bool {
+			return posts[i].createdAt.Before(posts[j].createdAt)
+		})
+	}
+	return history
+}
+
+// scoreRanking computes precision, recall and NDCG at k for one user's picks
+func scoreRanking(ids []string, relevant map[pgtype.UUID]bool, k int) EvalMetrics {
+	relevantIDs := make(map[string]bool, len(relevant))
+	for id := range relevant {
+		relevantIDs[uuid.UUID(id.Bytes).String()] = true
+	}
+
+	var hits int
+	var dcg float64
+	for i, id := range ids[:min(len(ids), k)] {
+		if relevantIDs[id] {
+			hits++
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+	var idcg float64
+	for i := range min(len(relevant), k) {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+
+	m := EvalMetrics{
+		Users:        1,
+		PrecisionAtK: float64(hits) / float64(k),
+		RecallAtK:    ratio(hits, len(relevant)),
+	}
+	if idcg > 0 {
+		m.NDCGAtK = dcg / idcg
+	}
+	if hits > 0 {
+		m.HitRate = 1
+	}
+	return m
+}
+
+// metricSums accumulates per-user metrics for averaging
+type metricSums struct {
+	users                         int
+	precision, recall, ndcg, hits float64
+}
+
+func (s *metricSums) add(m EvalMetrics) {
+	s.users++
+	s.precision += m.PrecisionAtK
+	s.recall += m.RecallAtK
+	s.ndcg += m.NDCGAtK
+	s.hits += m.HitRate
+}
+
+func (s metricSums) metrics() EvalMetrics {
+	if s.users == 0 {
+		return EvalMetrics{}
+	}
+	n := float64(s.users)
+	return EvalMetrics{
+		Users:        s.users,
+		PrecisionAtK: s.precision / n,
+		RecallAtK:    s.recall / n,
+		NDCGAtK:      s.ndcg / n,
+		HitRate:      s.hits / n,
+	}
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// WriteReport prints the report as a table. When baseline is set, each
+// metric is followed by its change from the baseline run.
+func WriteReport(w io.Writer, report EvalReport, baseline *EvalReport) error {
+	var buf bytes.Buffer
+	cfg := report.Config
+	fmt.Fprintf(&buf, "k=%d hold_out=%d relevant_rating=%.1f diversity=%.2f serendipity=%v\n",
+		cfg.K, cfg.HoldOut, cfg.RelevantRating, cfg.Diversity, cfg.Serendipity)
+	if baseline != nil && baseline.Config != cfg {
+		fmt.Fprintf(&buf, "warning: baseline was run with a different config\n")
+	}
+	fmt.Fprintf(&buf, "%-10s %-6s %6s %18s %18s %18s %18s %18s\n",
+		"category", "segment", "users", "precision@k", "recall@k", "ndcg@k", "hit_rate", "coverage")
+
+	var base map[string]CategoryReport
+	if baseline != nil {
+		base = make(map[string]CategoryReport, len(baseline.Categories))
+		for _, c := range baseline.Categories {
+			base[c.Category] = c
+		}
+	}
+
+	row := func(category, segment string, m EvalMetrics, b *EvalMetrics, coverage float64, baseCoverage *float64) {
+		cell := func(v float64, prev *float64) string {
+			if prev == nil {
+				return fmt.Sprintf("%.4f", v)
+			}
+			return fmt.Sprintf("%.4f (%+.4f)", v, v-*prev)
+		}
+		var bp, br, bn, bh *float64
+		if b != nil {
+			bp, br, bn, bh = &b.PrecisionAtK, &b.RecallAtK, &b.NDCGAtK, &b.HitRate
+		}
+		cov := ""
+		if segment == "all" {
+			cov = cell(coverage, baseCoverage)
+		}
+		fmt.Fprintf(&buf, "%-10s %-6s %6d %18s %18s %18s %18s %18s\n", category, segment, m.Users,
+			cell(m.PrecisionAtK, bp), cell(m.RecallAtK, br), cell(m.NDCGAtK, bn), cell(m.HitRate, bh), cov)
+	}
+
+	for _, c := range report.Categories {
+		prev, ok := base[c.Category]
+		if !ok {
+			row(c.Category, "all", c.All, nil, c.Coverage, nil)
+			row(c.Category, "cold", c.ColdStart, nil, 0, nil)
+			row(c.Category, "warm", c.Warm, nil, 0, nil)
+			continue
+		}
+		row(c.Category, "all", c.All, &prev.All, c.Coverage, &prev.Coverage)
+		row(c.Category, "cold", c.ColdStart, &prev.ColdStart, 0, nil)
+		row(c.Category, "warm", c.Warm, &prev.Warm, 0, nil)
+	}
+	if baseline != nil {
+		row("overall", "all", report.Overall, &baseline.Overall, report.Coverage, &baseline.Coverage)
+	} else {
+		row("overall", "all", report.Overall, nil, report.Coverage, nil)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// replayStore serves the Ranker and TasteProfileComputer from training posts
+// held in memory. Only the methods profile computation and RankWithOptions
+// use are implemented, so the Ranker must run without embeddings or
+// co-ratings, which would read live tables that include held-out posts.
+type replayStore struct {
+	Store
+
+	decay      map[string]sqlc.TasteDecaySetting
+	posts      map[evalKey][]sqlc.GetUserPostsForCategoryRow
+	candidates map[string][]sqlc.GetRecommendationCandidatesRow
+	tags       map[pgtype.UUID][]sqlc.GetTagsForBeveragesRow
+	recent     map[pgtype.UUID]map[pgtype.UUID]bool
+	profiles   map[evalKey]sqlc.UserTasteProfile
+}
+
+func newReplayStore(data EvalData, training map[evalKey][]evalPost) *replayStore {
+	s := &replayStore{
+		decay:      make(map[string]sqlc.TasteDecaySetting),
+		posts:      make(map[evalKey][]sqlc.GetUserPostsForCategoryRow),
+		candidates: make(map[string][]sqlc.GetRecommendationCandidatesRow),
+		tags:       make(map[pgtype.UUID][]sqlc.GetTagsForBeveragesRow),
+		recent:     make(map[pgtype.UUID]map[pgtype.UUID]bool),
+		profiles:   make(map[evalKey]sqlc.UserTasteProfile),
+	}
+	for _, d := range data.DecaySettings {
+		s.decay[d.Category] = d
+	}
+
+	type beverageStats struct {
+		reviews, rated int
+		ratingSum      float64
+		tags           map[string]*sqlc.GetTagsForBeveragesRow
+	}
+	stats := make(map[pgtype.UUID]*beverageStats)
+	userPosts := make(map[pgtype.UUID][]evalPost)
+
+	for key, posts := range training {
+		for i := len(posts) - 1; i >= 0; i-- {
+			s.posts[key] = append(s.posts[key], posts[i].rows...)
+		}
+		userPosts[key.userID] = append(userPosts[key.userID], posts...)
+
+		for _, p := range posts {
+			st := stats[p.beverageID]
+			if st == nil {
+				st = &beverageStats{tags: make(map[string]*sqlc.GetTagsForBeveragesRow)}
+				stats[p.beverageID] = st
+			}
+			st.reviews++
+			if p.rated {
+				st.rated++
+				st.ratingSum += p.rating.Float64()
+			}
+			for _, row := range p.rows {
+				if !row.Tag.Valid {
+					continue
+				}
+				t := st.tags[row.Tag.String]
+				if t == nil {
+					t = &sqlc.GetTagsForBeveragesRow{BeverageID: p.beverageID, Tag: row.Tag.String, TagType: row.TagType.String}
+					st.tags[row.Tag.String] = t
+				}
+				t.Count++
+			}
+		}
+	}
+
+	// Candidates exclude the user's most recent posted beverages across categories
+	for userID, posts := range userPosts {
+		sort.Slice(posts, func(i, j int) bool { return posts[i].createdAt.After(posts[j].createdAt) })
+		recent := make(map[pgtype.UUID]bool)
+		for _, p := range posts[:min(len(posts), recentPostExclusion)] {
+			recent[p.beverageID] = true
+		}
+		s.recent[userID] = recent
+	}
+
+	for _, b := range data.Beverages {
+		st := stats[b.ID]
+		row := sqlc.GetRecommendationCandidatesRow{
+			ID:          b.ID,
+			Name:        b.Name,
+			Brand:       b.Brand,
+			Category:    b.Category,
+			ReviewCount: pgtype.Int4{Valid: true},
+			AvgRating:   rating.AverageNumeric(0),
+			ProducerID:  b.ProducerID,
+			Style:       b.Style,
+		}
+		if st != nil {
+			row.ReviewCount.Int32 = int32(st.reviews)
+			if st.rated > 0 {
+				row.AvgRating = rating.AverageNumeric(st.ratingSum / float64(st.rated))
+			}
+			for _, t := range st.tags {
+				s.tags[b.ID] = append(s.tags[b.ID], *t)
+			}
+			sort.Slice(s.tags[b.ID], func(i, j int) bool {
+				a, c := s.tags[b.ID][i], s.tags[b.ID][j]
+				if a.Count != c.Count {
+					return a.Count > c.Count
+				}
+				return a.Tag < c.Tag
+			})
+		}
+		if row.ReviewCount.Int32 >= 2 || len(s.tags[b.ID]) > 0 {
+			s.candidates[b.Category] = append(s.candidates[b.Category], row)
+		}
+	}
+	for _, rows := range s.candidates {
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].ReviewCount.Int32 != rows[j].ReviewCount.Int32 {
+				return rows[i].ReviewCount.Int32 > rows[j].ReviewCount.Int32
+			}
+			a, _ := rating.Average(rows[i].AvgRating)
+			b, _ := rating.Average(rows[j].AvgRating)
+			return a > b
+		})
+	}
+	return s
+}
+
+func (s *replayStore) DeleteTasteContributions(ctx context.Context, arg sqlc.DeleteTasteContributionsParams) error {
+	return nil
+}
+
+func (s *replayStore) GetRecommendationCandidates(ctx context.Context, arg sqlc.GetRecommendationCandidatesParams) ([]sqlc.GetRecommendationCandidatesRow, error) {
+	recent := s.recent[arg.UserID]
+	var rows []sqlc.GetRecommendationCandidatesRow
+	for _, row := range s.candidates[arg.Category] {
+		if len(rows) == int(arg.Limit) {
+			break
+		}
+		if !recent[row.ID] {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (s *replayStore) GetTagsForBeverages(ctx context.Context, ids []pgtype.UUID) ([]sqlc.GetTagsForBeveragesRow, error) {
+	var rows []sqlc.GetTagsForBeveragesRow
+	for _, id := range ids {
+		rows = append(rows, s.tags[id]...)
+	}
+	return rows, nil
+}
+
+func (s *replayStore) GetTasteDecaySetting(ctx context.Context, category string) (sqlc.TasteDecaySetting, error) {
+	d, ok := s.decay[category]
+	if !ok {
+		return sqlc.TasteDecaySetting{}, pgx.ErrNoRows
+	}
+	return d, nil
+}
+
+func (s *replayStore) GetUserPostsForCategory(ctx context.Context, arg sqlc.GetUserPostsForCategoryParams) ([]sqlc.GetUserPostsForCategoryRow, error) {
+	return s.posts[evalKey{arg.UserID, arg.DrinkCategory}], nil
+}
+
+// GetUserFeedbackForCategory returns nothing: replay learns from posts alone
+func (s *replayStore) GetUserFeedbackForCategory(ctx context.Context, arg sqlc.GetUserFeedbackForCategoryParams) ([]sqlc.RecommendationFeedback, error) {
+	return nil, nil
+}
+
+func (s *replayStore) GetUserTasteProfile(ctx context.Context, arg sqlc.GetUserTasteProfileParams) (sqlc.UserTasteProfile, error) {
+	p, ok := s.profiles[evalKey{arg.UserID, arg.Category}]
+	if !ok {
+		return sqlc.UserTasteProfile{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+// ListTasteProfilesForUsers returns nothing, so replay never infers a
+// profile from other categories: profiles are rebuilt one at a time, and
+// which others exist yet would depend on replay order
+func (s *replayStore) ListTasteProfilesForUsers(ctx context.Context, userIds []pgtype.UUID) ([]sqlc.UserTasteProfile, error) {
+	return nil, nil
+}
+
+// ListTasteQuizAnswers returns nothing: historical users never took the quiz
+func (s *replayStore) ListTasteQuizAnswers(ctx context.Context, arg sqlc.ListTasteQuizAnswersParams) ([]sqlc.TasteQuizAnswer, error) {
+	return nil, nil
+}
+
+func (s *replayStore) UpsertTasteContribution(ctx context.Context, arg sqlc.UpsertTasteContributionParams) error {
+	return nil
+}
+
+func (s *replayStore) UpsertUserTasteProfile(ctx context.Context, arg sqlc.UpsertUserTasteProfileParams) (sqlc.UserTasteProfile, error) {
+	p := sqlc.UserTasteProfile{
+		UserID:                  arg.UserID,
+		Category:                arg.Category,
+		LikedTagsJson:           arg.LikedTagsJson,
+		DislikedTagsJson:        arg.DislikedTagsJson,
+		MeanRating:              arg.MeanRating,
+		StdRating:               arg.StdRating,
+		PostCount:               arg.PostCount,
+		AllTimeLikedTagsJson:    arg.AllTimeLikedTagsJson,
+		AllTimeDislikedTagsJson: arg.AllTimeDislikedTagsJson,
+		AllTimeMeanRating:       arg.AllTimeMeanRating,
+		AllTimeStdRating:        arg.AllTimeStdRating,
+		HalfLifeDays:            arg.HalfLifeDays,
+		InferredFrom:            arg.InferredFrom,
+		QuizWeight:              arg.QuizWeight,
+	}
+	s.profiles[evalKey{arg.UserID, arg.Category}] = p
+	return p, nil
+}
+
+func (s *replayStore) UpsertUserTasteStats(ctx context.Context, arg sqlc.UpsertUserTasteStatsParams) error {
+	return nil
+}
@@ -0,0 +1,173 @@
+package recommendations
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// evalHistory builds ListEvaluationPosts rows, one per tag
+type evalHistory struct {
+	rows   []sqlc.ListEvaluationPostsRow
+	nextID int
+}
+
+func (h *evalHistory) post(userID, beverageID pgtype.UUID, rating float64, hour int, tags ...string) {
+	h.nextID++
+	row := sqlc.ListEvaluationPostsRow{
+		ID:            testUUID(5000 + h.nextID),
+		UserID:        userID,
+		DrinkCategory: "beer",
+		BeverageID:    beverageID,
+		Rating:        testNumeric(rating),
+		CreatedAt:     pgtype.Timestamptz{Time: time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC), Valid: true},
+	}
+	if len(tags) == 0 {
+		h.rows = append(h.rows, row)
+	}
+	for _, tag := range tags {
+		row.Tag = pgtype.Text{String: tag, Valid: true}
+		row.TagType = pgtype.Text{String: "descriptor", Valid: true}
+		h.rows = append(h.rows, row)
+	}
+}
+
+func evalBeverage(id pgtype.UUID, name string) sqlc.ListEvaluationBeveragesRow {
+	return sqlc.ListEvaluationBeveragesRow{ID: id, Name: name, Category: "beer"}
+}
+
+func TestScoreRanking(t *testing.T) {
+	relevant := map[pgtype.UUID]bool{testUUID(1): true, testUUID(2): true}
+	ids := []string{
+		uuid.UUID(testUUID(3).Bytes).String(),
+		uuid.UUID(testUUID(1).Bytes).String(),
+		uuid.UUID(testUUID(4).Bytes).String(),
+	}
+
+	m := scoreRanking(ids, relevant, 4)
+	if m.PrecisionAtK != 0.25 || m.RecallAtK != 0.5 || m.HitRate != 1 {
+		t.Errorf("metrics = %+v", m)
+	}
+	// One hit at rank 2 against an ideal of hits at ranks 1 and 2
+	want := (1 / math.Log2(3)) / (1 + 1/math.Log2(3))
+	if math.Abs(m.NDCGAtK-want) > 1e-9 {
+		t.Errorf("ndcg = %f, want %f", m.NDCGAtK, want)
+	}
+
+	if m := scoreRanking(nil, relevant, 4); m.HitRate != 0 || m.NDCGAtK != 0 {
+		t.Errorf("empty ranking scored %+v", m)
+	}
+}
+
+func TestEvaluateReplaysWithoutLeakage(t *testing.T) {
+	hoppy, cold := testUUID(100), testUUID(104)
+	pale, pilsner, doubleIPA, ipa, lager, stout := testUUID(1), testUUID(2), testUUID(3), testUUID(4), testUUID(5), testUUID(6)
+
+	var h evalHistory
+	// A warm user who likes hoppy beer and whose last post is an IPA
+	h.post(hoppy, pale, 9, 1, "hoppy")
+	h.post(hoppy, pilsner, 5, 2, "crisp")
+	h.post(hoppy, doubleIPA, 8, 3, "hoppy")
+	h.post(hoppy, ipa, 9, 10, "hoppy")
+	// Others make the lager the most popular; their held-out stouts are rated
+	// too low to be relevant, so they are not evaluated
+	for i, user := range []pgtype.UUID{testUUID(101), testUUID(102)} {
+		h.post(user, ipa, 8, 4+i, "hoppy")
+		h.post(user, lager, 9, 6+i, "crisp")
+		h.post(user, stout, 4, 11+i, "roasty")
+	}
+	h.post(testUUID(103), lager, 9, 8, "crisp")
+	h.post(testUUID(103), stout, 4, 13, "roasty")
+	// A user with a single post is evaluated from an empty profile
+	h.post(cold, ipa, 9, 14, "hoppy")
+
+	data := EvalData{
+		Posts: h.rows,
+		Beverages: []sqlc.ListEvaluationBeveragesRow{
+			evalBeverage(pale, "Pale"), evalBeverage(pilsner, "Pilsner"), evalBeverage(doubleIPA, "Double IPA"),
+			evalBeverage(ipa, "IPA"), evalBeverage(lager, "Lager"), evalBeverage(stout, "Stout"),
+		},
+	}
+	cfg := EvalConfig{K: 1, HoldOut: 1, RelevantRating: 7}
+
+	report, err := Evaluate(context.Background(), data, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Categories) != 1 {
+		t.Fatalf("got %d categories", len(report.Categories))
+	}
+	beer := report.Categories[0]
+	if beer.All.Users != 2 || beer.Warm.Users != 1 || beer.ColdStart.Users != 1 {
+		t.Fatalf("users all/warm/cold = %d/%d/%d", beer.All.Users, beer.Warm.Users, beer.ColdStart.Users)
+	}
+	// Personalized ranking finds the IPA; popularity alone offers the lager
+	if beer.Warm.PrecisionAtK != 1 || beer.Warm.NDCGAtK != 1 {
+		t.Errorf("warm metrics = %+v", beer.Warm)
+	}
+	if beer.ColdStart.HitRate != 0 {
+		t.Errorf("cold start metrics = %+v", beer.ColdStart)
+	}
+	if beer.Coverage != 2.0/6 || beer.CatalogSize != 6 {
+		t.Errorf("coverage = %f of %d", beer.Coverage, beer.CatalogSize)
+	}
+	if report.Overall.HitRate != 0.5 {
+		t.Errorf("overall = %+v", report.Overall)
+	}
+
+	// Held-out posts never reach the replayed catalog
+	history := groupEvalPosts(data.Posts)
+	training := make(map[evalKey][]evalPost)
+	for key, posts := range history {
+		training[key] = posts[:len(posts)-1]
+	}
+	store := newReplayStore(data, training)
+	for _, row := range store.candidates["beer"] {
+		if row.ID == stout {
+			t.Error("stout is a candidate although all of its posts are held out")
+		}
+		if row.ID == ipa && row.ReviewCount.Int32 != 2 {
+			t.Errorf("ipa has %d training reviews, want 2", row.ReviewCount.Int32)
+		}
+	}
+}
+
+func TestEvaluateRejectsEmptyPage(t *testing.T) {
+	if _, err := Evaluate(context.Background(), EvalData{}, EvalConfig{HoldOut: 1}); err == nil {
+		t.Error("k of 0 accepted")
+	}
+}
+
+func TestWriteReportComparesToBaseline(t *testing.T) {
+	cfg := DefaultEvalConfig()
+	baseline := EvalReport{
+		Config:     cfg,
+		Categories: []CategoryReport{{Category: "beer", All: EvalMetrics{Users: 10, PrecisionAtK: 0.1}, Coverage: 0.2}},
+		Overall:    EvalMetrics{Users: 10, PrecisionAtK: 0.1},
+		Coverage:   0.2,
+	}
+	report := baseline
+	report.Categories = []CategoryReport{{Category: "beer", All: EvalMetrics{Users: 10, PrecisionAtK: 0.15}, Coverage: 0.25}}
+	report.Overall = EvalMetrics{Users: 10, PrecisionAtK: 0.15}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, report, &baseline); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"0.1500 (+0.0500)", "0.2500 (+0.0500)", "overall"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "warning") {
+		t.Errorf("same config flagged as different:\n%s", out)
+	}
+}
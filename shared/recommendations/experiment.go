@@ -0,0 +1,243 @@
+package recommendations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// TagPopularityStrategy is Ranker: tag affinity plus popularity, with the
+	// optional embedding and co-rating signals
+	TagPopularityStrategy = "tag_popularity"
+	// PopularityStrategy ranks on rating and review count alone
+	PopularityStrategy = "popularity"
+
+	// DefaultAttributionDays is how long after a beverage is recommended
+	// feedback and posts on it still count as engagement with the recommendation
+	DefaultAttributionDays = 14
+)
+
+// RankingStrategy produces a recommendation list for a user
+type RankingStrategy interface {
+	Name() string
+	Rank(ctx context.Context, userID pgtype.UUID, category string, opts RankOptions) ([]RankedBeverage, error)
+}
+
+var (
+	_ RankingStrategy = (*Ranker)(nil)
+	_ RankingStrategy = (*PopularityRanker)(nil)
+)
+
+func (r *Ranker) Name() string {
+	return TagPopularityStrategy
+}
+
+func (r *Ranker) Rank(ctx context.Context, userID pgtype.UUID, category string, opts RankOptions) ([]RankedBeverage, error) {
+	return r.RankWithOptions(ctx, userID, category, opts)
+}
+
+// PopularityRanker ignores taste profiles and ranks every user the way
+// Ranker ranks a cold-start user; it is the control arm for personalization
+type PopularityRanker struct {
+	Q Store
+}
+
+func NewPopularityRanker(q Store) *PopularityRanker {
+	return &PopularityRanker{Q: q}
+}
+
+func (p *PopularityRanker) Name() string {
+	return PopularityStrategy
+}
+
+func (p *PopularityRanker) Rank(ctx context.Context, userID pgtype.UUID, category string, opts RankOptions) ([]RankedBeverage, error) {
+	if err := opts.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	candidates, err := p.Q.GetRecommendationCandidates(ctx, opts.Filter.candidatesParams(category, userID, opts.Limit*3))
+	if err != nil {
+		return nil, err
+	}
+
+	scored := make([]scoredCandidate, len(candidates))
+	for i, candidate := range candidates {
+		b := breakdownScore(candidate, nil, nil, nil, true)
+		scored[i] = scoredCandidate{bev: candidate, score: b.Total, reasons: b.Reasons}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	results := []RankedBeverage{}
+	for _, item := range rerankForDiversity(scored, int(opts.Limit), opts.Diversity, opts.Serendipity) {
+		results = append(results, item.ranked())
+	}
+	return results, nil
+}
+
+// Variant is one arm of an experiment. Weight is its share of users
+// relative to the other variants.
+type Variant struct {
+	Name     string
+	Weight   int
+	Strategy RankingStrategy
+}
+
+// Experiment splits users between ranking strategies
+type Experiment struct {
+	Name     string
+	Variants []Variant
+}
+
+// Assign picks the user's variant. The bucket is a hash of the experiment
+// name and user ID, so a user stays in one variant for the life of the
+// experiment and buckets are independent across experiments.
+func (e Experiment) Assign(userID pgtype.UUID) (Variant, error) {
+	total := 0
+	for _, v := range e.Variants {
+		if v.Weight < 0 {
+			return Variant{}, errors.New("variant weights must not be negative")
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return Variant{}, errors.New("experiment has no weighted variants")
+	}
+
+	bucket := int(experimentBucket(e.Name, userID) % uint64(total))
+	for _, v := range e.Variants {
+		if bucket < v.Weight {
+			return v, nil
+		}
+		bucket -= v.Weight
+	}
+	return e.Variants[len(e.Variants)-1], nil
+}
+
+func experimentBucket(experiment string, userID pgtype.UUID) uint64 {
+	h := sha256.New()
+	h.Write([]byte(experiment))
+	h.Write([]byte{0})
+	h.Write(userID.Bytes[:])
+	return binary.BigEndian.Uint64(h.Sum(nil)[:8])
+}
+
+// ServedList is a recommendation list with the variant that produced it.
+// ListID is empty when the list could not be logged.
+type ServedList struct {
+	ListID     string           `json:"list_id,omitempty"`
+	Experiment string           `json:"experiment"`
+	Variant    string           `json:"variant"`
+	Strategy   string           `json:"strategy"`
+	Beverages  []RankedBeverage `json:"beverages"`
+}
+
+// ExperimentRanker serves recommendations through the user's assigned
+// variant and logs each list to recommendation_lists
+type ExperimentRanker struct {
+	Q          Store
+	Experiment Experiment
+	// Impressions logs each list's beverages against the list; nil disables it
+	Impressions *ImpressionLogger
+}
+
+func NewExperimentRanker(q Store, experiment Experiment) *ExperimentRanker {
+	return &ExperimentRanker{Q: q, Experiment: experiment}
+}
+
+// Recommend ranks with the user's variant and records the list served.
+// Logging failures are not returned; the user still gets their list.
+func (e *ExperimentRanker) Recommend(ctx context.Context, userID pgtype.UUID, category string, opts RankOptions) (ServedList, error) {
+	variant, err := e.Experiment.Assign(userID)
+	if err != nil {
+		return ServedList{}, err
+	}
+	beverages, err := variant.Strategy.Rank(ctx, userID, category, opts)
+	if err != nil {
+		return ServedList{}, err
+	}
+
+	served := ServedList{
+		Experiment: e.Experiment.Name,
+		Variant:    variant.Name,
+		Strategy:   variant.Strategy.Name(),
+		Beverages:  beverages,
+	}
+
+	ids := make([]pgtype.UUID, 0, len(beverages))
+	for _, b := range beverages {
+		id, err := uuid.Parse(b.BeverageID)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, pgtype.UUID{Bytes: id, Valid: true})
+	}
+	list, err := e.Q.InsertRecommendationList(ctx, sqlc.InsertRecommendationListParams{
+		UserID:      userID,
+		Category:    category,
+		Experiment:  served.Experiment,
+		Variant:     served.Variant,
+		Strategy:    served.Strategy,
+		BeverageIds: ids,
+	})
+	// Impressions are logged without a list when the list couldn't be saved
+	var listID pgtype.UUID
+	if err != nil {
+		log.Printf("Failed to log recommendation list for experiment %s: %v", served.Experiment, err)
+	} else {
+		listID = list.ID
+		served.ListID = uuid.UUID(listID.Bytes).String()
+	}
+	if e.Impressions != nil {
+		e.Impressions.LogList(ctx, userID, listID, served.Beverages)
+	}
+	return served, nil
+}
+
+// VariantEngagement is how users responded to one variant's lists
+type VariantEngagement struct {
+	Variant          string `json:"variant"`
+	Lists            int    `json:"lists"`
+	Users            int    `json:"users"`
+	PositiveFeedback int    `json:"positive_feedback"`
+	NegativeFeedback int    `json:"negative_feedback"`
+	Posts            int    `json:"posts"`
+	// ConversionRate is the share of lists with a post on a listed beverage
+	ConversionRate float64 `json:"conversion_rate"`
+}
+
+// Engagement reports per-variant engagement with lists served since the
+// given time, counting feedback and posts within attributionDays of each list
+func (e *ExperimentRanker) Engagement(ctx context.Context, since time.Time, attributionDays int32) ([]VariantEngagement, error) {
+	rows, err := e.Q.GetExperimentEngagement(ctx, sqlc.GetExperimentEngagementParams{
+		Experiment: e.Experiment.Name,
+		CreatedAt:  pgtype.Timestamptz{Time: since, Valid: true},
+		WindowDays: attributionDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]VariantEngagement, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, VariantEngagement{
+			Variant:          row.Variant,
+			Lists:            int(row.Lists),
+			Users:            int(row.Users),
+			PositiveFeedback: int(row.PositiveFeedback),
+			NegativeFeedback: int(row.NegativeFeedback),
+			Posts:            int(row.Posts),
+			ConversionRate:   ratio(int(row.ConvertedLists), int(row.Lists)),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+)
+
+func splitExperiment(store *fakeStore) Experiment {
+	return Experiment{Name: "personalization", Variants: []Variant{
+		{Name: "control", Weight: 1, Strategy: NewPopularityRanker(store)},
+		{Name: "treatment", Weight: 1, Strategy: NewRanker(store)},
+	}}
+}
+
+func TestExperimentAssignIsDeterministicAndWeighted(t *testing.T) {
+	exp := splitExperiment(newFakeStore())
+	other := Experiment{Name: "diversity", Variants: exp.Variants}
+
+	counts := make(map[string]int)
+	differs := 0
+	for i := range 2000 {
+		user := testUUID(i)
+		first, err := exp.Assign(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := exp.Assign(user)
+		if first.Name != again.Name {
+			t.Fatalf("user %d moved from %s to %s", i, first.Name, again.Name)
+		}
+		counts[first.Name]++
+		if v, _ := other.Assign(user); v.Name != first.Name {
+			differs++
+		}
+	}
+	if counts["control"] < 900 || counts["control"] > 1100 {
+		t.Errorf("50/50 split assigned %v", counts)
+	}
+	// Buckets are independent across experiments, so about half the users differ
+	if differs < 800 || differs > 1200 {
+		t.Errorf("%d of 2000 users differ between experiments", differs)
+	}
+
+	if _, err := (Experiment{Name: "empty", Variants: []Variant{{Name: "off"}}}).Assign(testUUID(1)); err == nil {
+		t.Error("experiment without weight assigned a variant")
+	}
+}
+
+func TestExperimentRankerLogsServedVariant(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "Lager", "beer", 8.0, 100, "crisp")
+	store.addBeverage(testUUID(2), "IPA", "beer", 7.0, 5, "hoppy")
+
+	exp := splitExperiment(store)
+	variant, _ := exp.Assign(user)
+	served, err := NewExperimentRanker(store, exp).Recommend(context.Background(), user, "beer", RankOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.Variant != variant.Name || served.Strategy != variant.Strategy.Name() {
+		t.Errorf("served %s/%s, assigned %s", served.Variant, served.Strategy, variant.Name)
+	}
+
+	// Popularity ignores the hoppy profile; the tag strategy puts the IPA first
+	want := []string{"Lager", "IPA"}
+	if served.Strategy == TagPopularityStrategy {
+		want = []string{"IPA", "Lager"}
+	}
+	if got := names(served.Beverages); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s ranked %v, want %v", served.Strategy, got, want)
+	}
+
+	if len(store.lists) != 1 {
+		t.Fatalf("logged %d lists", len(store.lists))
+	}
+	list := store.lists[0]
+	if served.ListID != uuid.UUID(list.ID.Bytes).String() || list.Variant != served.Variant || list.Experiment != "personalization" {
+		t.Errorf("logged list = %+v, served %+v", list, served)
+	}
+	if len(list.BeverageIds) != 2 || uuid.UUID(list.BeverageIds[0].Bytes).String() != served.Beverages[0].BeverageID {
+		t.Errorf("logged beverages = %v", list.BeverageIds)
+	}
+}
+
+func TestExperimentRankerServesWhenLoggingFails(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "Lager", "beer", 8.0, 100, "crisp")
+	store.listErr = errors.New("connection reset")
+
+	ranker := NewExperimentRanker(store, splitExperiment(store))
+	ranker.Impressions = NewImpressionLogger(store)
+	served, err := ranker.Recommend(context.Background(), testUUID(100), "beer", RankOptions{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(served.Beverages) != 1 || served.ListID != "" {
+		t.Errorf("served = %+v", served)
+	}
+	// Impressions are still logged, just not tied to a list
+	if len(store.impressions) != 1 || store.impressions[0].ListID.Valid {
+		t.Errorf("impressions = %+v", store.impressions)
+	}
+}
+
+func TestExperimentEngagement(t *testing.T) {
+	store := newFakeStore()
+	store.engagement = []sqlc.GetExperimentEngagementRow{
+		{Variant: "control", Lists: 200, Users: 90, Posts: 12, ConvertedLists: 10},
+		{Variant: "treatment", Lists: 0},
+	}
+
+	report, err := NewExperimentRanker(store, splitExperiment(store)).Engagement(context.Background(), time.Now().AddDate(0, 0, -7), DefaultAttributionDays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report[0].ConversionRate != 0.05 || report[0].Posts != 12 || report[1].ConversionRate != 0 {
+		t.Errorf("engagement = %+v", report)
+	}
+}
@@ -0,0 +1,349 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// explainNeighborLimit bounds the co-rating scores scanned when explaining
+const explainNeighborLimit = 200
+
+// TagContribution is what one of the beverage's tags adds to its score
+type TagContribution struct {
+	Tag            string  `json:"tag"`
+	TagType        string  `json:"tag_type"`
+	Count          int     `json:"count"`
+	LikedWeight    float64 `json:"liked_weight"`
+	DislikedWeight float64 `json:"disliked_weight"`
+	Contribution   float64 `json:"contribution"`
+	Points         float64 `json:"points"`
+}
+
+// ScoreBreakdown is every component of a candidate's ranking score.
+// Personalized: Total = Baseline + TagPoints + RatingPoints + PopularityPoints
+// + EmbeddingPoints + CoRatingPoints. Cold start: RatingPoints + PopularityPoints.
+type ScoreBreakdown struct {
+	ColdStart           bool                   `json:"cold_start"`
+	Baseline            float64                `json:"baseline"`
+	Tags                []TagContribution      `json:"tags"`
+	TagPoints           float64                `json:"tag_points"`
+	RatingPoints        float64                `json:"rating_points"`
+	PopularityPoints    float64                `json:"popularity_points"`
+	EmbeddingSimilarity *float64               `json:"embedding_similarity,omitempty"`
+	EmbeddingPoints     float64                `json:"embedding_points"`
+	CoRatingWeight      *float64               `json:"co_rating_weight,omitempty"`
+	CoRatingBecause     string                 `json:"co_rating_because,omitempty"`
+	CoRatingPoints      float64                `json:"co_rating_points"`
+	VenueRecency        *float64               `json:"venue_recency,omitempty"`
+	VenuePoints         float64                `json:"venue_points"`
+	Total               float64                `json:"total"`
+	MatchScore          int                    `json:"match_score"`
+	Reasons             []RecommendationReason `json:"reasons"`
+}
+
+// breakdownScore scores a candidate from its preloaded tags. Cold start ranks
+// on global popularity; otherwise liked and disliked tags move the score
+// around a baseline of 50, with popularity as a smaller bonus.
+func breakdownScore(candidate sqlc.GetRecommendationCandidatesRow, beverageTags []beverageTag, likedTags, dislikedTags TagWeights, coldStart bool) ScoreBreakdown {
+	b := ScoreBreakdown{
+		ColdStart: coldStart,
+		Tags:      []TagContribution{},
+		Reasons:   []RecommendationReason{},
+	}
+	avgRating, _ := rating.Average(candidate.AvgRating)
+	reviewCount := float64(candidate.ReviewCount.Int32)
+
+	if coldStart {
+		// Score = avg_rating * 10 + log(review_count + 1) * 5
+		b.RatingPoints = avgRating * 10
+		b.PopularityPoints = math.Log(reviewCount+1) * 5
+		b.Total = b.RatingPoints + b.PopularityPoints
+		b.Reasons = append(b.Reasons, RecommendationReason{
+			Reason: "Highly rated",
+			Score:  avgRating,
+		})
+		b.setMatchScore()
+		return b
+	}
+
+	baseScore := 0.0
+	for _, tag := range beverageTags {
+		likeWeight := likedTags[tag.Tag]
+		dislikeWeight := dislikedTags[tag.Tag]
+		contribution := likeWeight - dislikeWeight
+		baseScore += contribution
+
+		b.Tags = append(b.Tags, TagContribution{
+			Tag:            tag.Tag,
+			TagType:        tag.TagType,
+			Count:          tag.Count,
+			LikedWeight:    likeWeight,
+			DislikedWeight: dislikeWeight,
+			Contribution:   contribution,
+			Points:         contribution * 50,
+		})
+
+		if contribution > tagReasonThreshold {
+			b.Reasons = append(b.Reasons, RecommendationReason{
+				Reason: "You rate '" + tag.Tag + "' higher",
+				Score:  contribution,
+			})
+		} else if contribution < -tagReasonThreshold {
+			b.Reasons = append(b.Reasons, RecommendationReason{
+				Reason: "You rate '" + tag.Tag + "' lower",
+				Score:  contribution,
+			})
+		}
+	}
+
+	// Global popularity bonus (smaller weight)
+	popularityBonus := avgRating/float64(rating.Max) + (math.Log(reviewCount+1) / 10.0)
+
+	b.Baseline = 50
+	b.TagPoints = baseScore * 50
+	b.RatingPoints = avgRating / float64(rating.Max) * 10
+	b.PopularityPoints = math.Log(reviewCount + 1)
+	b.Total = (baseScore * 50) + (popularityBonus * 10) + 50 // Scale to ~0-100
+	b.setMatchScore()
+	return b
+}
+
+// addEmbedding blends in the cosine similarity of the beverage's embedding to the user's
+func (b *ScoreBreakdown) addEmbedding(sim float64) {
+	b.EmbeddingSimilarity = &sim
+	b.EmbeddingPoints = embeddingBlendWeight * sim
+	b.Total += b.EmbeddingPoints
+	if sim >= embeddingReasonThreshold {
+		b.Reasons = append(b.Reasons, RecommendationReason{
+			Reason: "Close to your overall taste",
+			Score:  sim,
+		})
+	}
+	b.setMatchScore()
+}
+
+// addCoRating blends in the co-rating neighbor signal
+func (b *ScoreBreakdown) addCoRating(signal neighborSignal) {
+	weight := signal.weight
+	b.CoRatingWeight = &weight
+	b.CoRatingBecause = signal.because
+	b.CoRatingPoints = neighborBlendWeight * weight
+	b.Total += b.CoRatingPoints
+	if weight >= neighborReasonThreshold {
+		b.Reasons = append(b.Reasons, RecommendationReason{
+			Reason: "People who liked " + signal.because + " also liked this",
+			Score:  weight,
+		})
+	}
+	b.setMatchScore()
+}
+
+// addVenueSighting boosts a beverage by how recently it was seen at the venue
+func (b *ScoreBreakdown) addVenueSighting(recency float64) {
+	b.VenueRecency = &recency
+	b.VenuePoints = venueBlendWeight * recency
+	b.Total += b.VenuePoints
+	if recency >= venueReasonThreshold {
+		b.Reasons = append(b.Reasons, RecommendationReason{
+			Reason: "Spotted here recently",
+			Score:  recency,
+		})
+	}
+	b.setMatchScore()
+}
+
+func (b *ScoreBreakdown) setMatchScore() {
+	b.MatchScore = int(math.Min(100, math.Max(0, b.Total)))
+}
+
+// ProfileSnapshot is the taste profile a score was computed against
+type ProfileSnapshot struct {
+	Exists             bool         `json:"exists"`
+	PostCount          int32        `json:"post_count"`
+	ColdStartThreshold int32        `json:"cold_start_threshold"`
+	Recent             TasteSummary `json:"recent"`
+	ComputedAt         *time.Time   `json:"computed_at,omitempty"`
+	InferredFrom       []string     `json:"inferred_from,omitempty"`
+	QuizWeight         float64      `json:"quiz_weight"`
+}
+
+// FeedbackEffect is one piece of feedback the user gave on the beverage and
+// what it does to their recommendations
+type FeedbackEffect struct {
+	FeedbackType string     `json:"feedback_type"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	Effect       string     `json:"effect"`
+}
+
+// Explanation is the full scoring breakdown behind one recommendation
+type Explanation struct {
+	BeverageID string           `json:"beverage_id"`
+	Name       string           `json:"name"`
+	Category   string           `json:"category"`
+	Hidden     bool             `json:"hidden"`
+	Score      ScoreBreakdown   `json:"score"`
+	Profile    ProfileSnapshot  `json:"profile"`
+	Feedback   []FeedbackEffect `json:"feedback"`
+}
+
+// Explain backs GET /v1/recommendations/{beverage_id}/explain. It scores the
+// beverage for the user exactly as RankRecommendations does and returns every
+// component rather than the top three reason strings.
+func (r *Ranker) Explain(ctx context.Context, userID, beverageID pgtype.UUID) (Explanation, error) {
+	beverage, err := r.Q.GetBeverageByID(ctx, beverageID)
+	if err != nil {
+		return Explanation{}, err
+	}
+	category := beverage.Category
+
+	snapshot := ProfileSnapshot{
+		ColdStartThreshold: coldStartPostCount,
+		Recent:             TasteSummary{LikedTags: TagWeights{}, DislikedTags: TagWeights{}},
+	}
+	likedTags, dislikedTags := TagWeights{}, TagWeights{}
+	profile, err := r.Q.GetUserTasteProfile(ctx, sqlc.GetUserTasteProfileParams{
+		UserID:   userID,
+		Category: category,
+	})
+	switch {
+	case err == nil:
+		snapshot.Exists = true
+		snapshot.PostCount = profile.PostCount.Int32
+		snapshot.InferredFrom = profile.InferredFrom
+		snapshot.QuizWeight = profile.QuizWeight
+		snapshot.Recent = tasteSummaryView(profile.LikedTagsJson, profile.DislikedTagsJson, profile.MeanRating, profile.StdRating)
+		if profile.LastComputedAt.Valid {
+			computedAt := profile.LastComputedAt.Time
+			snapshot.ComputedAt = &computedAt
+		}
+		json.Unmarshal(profile.LikedTagsJson, &likedTags)
+		json.Unmarshal(profile.DislikedTagsJson, &dislikedTags)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return Explanation{}, err
+	}
+	coldStart := !snapshot.Exists || !personalized(profile)
+
+	tagsByBeverage, err := loadBeverageTags(ctx, r.Q, []pgtype.UUID{beverageID})
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	candidate := sqlc.GetRecommendationCandidatesRow{
+		ID:          beverage.ID,
+		Name:        beverage.Name,
+		Brand:       beverage.Brand,
+		Category:    beverage.Category,
+		ImageUrl:    beverage.ImageUrl,
+		CreatedAt:   beverage.CreatedAt,
+		UpdatedAt:   beverage.UpdatedAt,
+		ReviewCount: beverage.TotalReviews,
+		AvgRating:   beverage.AvgRating,
+		ProducerID:  beverage.ProducerID,
+		Style:       beverage.Style,
+	}
+	breakdown := breakdownScore(candidate, tagsByBeverage[beverageID], likedTags, dislikedTags, coldStart)
+	if !coldStart && r.Embeddings != nil {
+		if sim, ok := r.embeddingSimilarity(ctx, userID, category, beverageID); ok {
+			breakdown.addEmbedding(sim)
+		}
+	}
+	if !coldStart && r.CoRatings {
+		if signal, ok := r.coRatingSignal(ctx, userID, category, beverageID); ok {
+			breakdown.addCoRating(signal)
+		}
+	}
+	sort.SliceStable(breakdown.Reasons, func(i, j int) bool {
+		return math.Abs(breakdown.Reasons[i].Score) > math.Abs(breakdown.Reasons[j].Score)
+	})
+
+	feedback, err := r.Q.GetUserFeedbackForBeverage(ctx, sqlc.GetUserFeedbackForBeverageParams{
+		UserID:     userID,
+		BeverageID: beverageID,
+	})
+	if err != nil {
+		return Explanation{}, err
+	}
+	explanation := Explanation{
+		BeverageID: uuid.UUID(beverage.ID.Bytes).String(),
+		Name:       beverage.Name,
+		Category:   category,
+		Score:      breakdown,
+		Profile:    snapshot,
+		Feedback:   []FeedbackEffect{},
+	}
+	for _, f := range feedback {
+		effect := FeedbackEffect{FeedbackType: f.FeedbackType, Effect: feedbackEffect(f.FeedbackType)}
+		if f.CreatedAt.Valid {
+			createdAt := f.CreatedAt.Time
+			effect.CreatedAt = &createdAt
+		}
+		if f.FeedbackType == HideFeedback {
+			explanation.Hidden = true
+		}
+		explanation.Feedback = append(explanation.Feedback, effect)
+	}
+	return explanation, nil
+}
+
+// embeddingSimilarity is the similarity between the user's and the beverage's embeddings
+func (r *Ranker) embeddingSimilarity(ctx context.Context, userID pgtype.UUID, category string, beverageID pgtype.UUID) (float64, bool) {
+	embedding, err := r.Q.GetUserEmbedding(ctx, sqlc.GetUserEmbeddingParams{
+		UserID:   userID,
+		Category: category,
+	})
+	if err != nil || len(embedding.EmbeddingVector) == 0 || embedding.Model.String != r.Embeddings.Model {
+		return 0, false
+	}
+	sims, err := r.Embeddings.Similarities(ctx, category, embedding.EmbeddingVector, []pgtype.UUID{beverageID})
+	if err != nil {
+		return 0, false
+	}
+	sim, ok := sims[beverageID]
+	return sim, ok
+}
+
+// coRatingSignal is the beverage's co-rating neighbor signal for the user, if any
+func (r *Ranker) coRatingSignal(ctx context.Context, userID pgtype.UUID, category string, beverageID pgtype.UUID) (neighborSignal, bool) {
+	rows, err := r.Q.GetUserNeighborScores(ctx, sqlc.GetUserNeighborScoresParams{
+		UserID:        userID,
+		DrinkCategory: category,
+		Limit:         explainNeighborLimit,
+	})
+	if err != nil || len(rows) == 0 || rows[0].Score <= 0 {
+		return neighborSignal{}, false
+	}
+	for _, row := range rows {
+		if row.NeighborID == beverageID {
+			return neighborSignal{weight: row.Score / rows[0].Score, because: row.BecauseName}, true
+		}
+	}
+	return neighborSignal{}, false
+}
+
+// feedbackEffect describes what a feedback type does, mirroring
+// replayFeedback and the candidate queries
+func feedbackEffect(feedbackType string) string {
+	switch feedbackType {
+	case HideFeedback:
+		return "Excluded from your recommendations"
+	case MoreLikeThisFeedback:
+		return "Raised this beverage's tags in your liked tags"
+	case LessLikeThisFeedback:
+		return "Raised this beverage's tags in your disliked tags"
+	case AlreadyTriedFeedback:
+		return "Excluded from your recommendations since you've tried it"
+	case NotAvailableFeedback:
+		return "Excluded from your recommendations for 30 days, except at venues where it's been seen"
+	}
+	return "No effect on scoring"
+}
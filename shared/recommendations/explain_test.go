@@ -0,0 +1,114 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestExplainPersonalizedMatchesRanking(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0, "citrus": 0.6}, TagWeights{"sour": 0.8}, 12)
+	store.addBeverage(testUUID(1), "Sour IPA", "beer", 7.0, 5, "hoppy", "citrus", "sour")
+
+	ranked, err := NewRanker(store).RankRecommendations(context.Background(), user, "beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explanation, err := NewRanker(store).Explain(context.Background(), user, testUUID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := explanation.Score
+	if score.ColdStart {
+		t.Error("profile with 12 posts explained as cold start")
+	}
+	if score.MatchScore != ranked[0].MatchScore {
+		t.Errorf("explained match score %d, ranked %d", score.MatchScore, ranked[0].MatchScore)
+	}
+	sum := score.Baseline + score.TagPoints + score.RatingPoints + score.PopularityPoints
+	if math.Abs(sum-score.Total) > 1e-9 {
+		t.Errorf("components sum to %f, total %f", sum, score.Total)
+	}
+	if len(score.Tags) != 3 {
+		t.Fatalf("got %d tag contributions", len(score.Tags))
+	}
+	for _, tag := range score.Tags {
+		if tag.Tag == "sour" && (tag.DislikedWeight != 0.8 || tag.Points != -40) {
+			t.Errorf("sour contribution = %+v", tag)
+		}
+	}
+	// All reasons survive with their scores, strongest first
+	if len(score.Reasons) != 3 || score.Reasons[0].Score != 1.0 || score.Reasons[2].Score != 0.6 {
+		t.Errorf("reasons = %+v", score.Reasons)
+	}
+	if !explanation.Profile.Exists || explanation.Profile.PostCount != 12 || explanation.Profile.Recent.LikedTags["hoppy"] != 1.0 {
+		t.Errorf("profile snapshot = %+v", explanation.Profile)
+	}
+}
+
+func TestExplainColdStartAndFeedback(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Lager", "beer", 8.0, 10, "crisp")
+	store.feedback[user] = []sqlc.RecommendationFeedback{
+		{UserID: user, BeverageID: testUUID(1), FeedbackType: "hide"},
+		{UserID: user, BeverageID: testUUID(1), FeedbackType: "less_like_this"},
+		{UserID: user, BeverageID: testUUID(2), FeedbackType: "more_like_this"},
+	}
+
+	explanation, err := NewRanker(store).Explain(context.Background(), user, testUUID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !explanation.Score.ColdStart || explanation.Profile.Exists {
+		t.Errorf("want cold start without profile, got %+v / %+v", explanation.Score, explanation.Profile)
+	}
+	if explanation.Score.RatingPoints != 80 || explanation.Score.Baseline != 0 {
+		t.Errorf("cold start breakdown = %+v", explanation.Score)
+	}
+	if !explanation.Hidden || len(explanation.Feedback) != 2 {
+		t.Errorf("feedback = %+v, hidden %v", explanation.Feedback, explanation.Hidden)
+	}
+	if explanation.Feedback[0].Effect != "Excluded from your recommendations" {
+		t.Errorf("hide effect = %q", explanation.Feedback[0].Effect)
+	}
+}
+
+func TestExplainIncludesCoRating(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "Porter", "beer", 7.0, 5, "roasty")
+	store.coRated[profileKey{user, "beer"}] = []sqlc.GetUserNeighborScoresRow{
+		{NeighborID: testUUID(2), Score: 2.0, BecauseName: "Old Stout"},
+		{NeighborID: testUUID(1), Score: 1.5, BecauseName: "Old Stout"},
+	}
+
+	ranker := NewRanker(store)
+	ranker.CoRatings = true
+	explanation, err := ranker.Explain(context.Background(), user, testUUID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := explanation.Score
+	if score.CoRatingWeight == nil || *score.CoRatingWeight != 0.75 || score.CoRatingPoints != 0.75*neighborBlendWeight {
+		t.Errorf("co-rating breakdown = %+v", score)
+	}
+	if score.CoRatingBecause != "Old Stout" {
+		t.Errorf("because = %q", score.CoRatingBecause)
+	}
+}
+
+func TestExplainUnknownBeverage(t *testing.T) {
+	_, err := NewRanker(newFakeStore()).Explain(context.Background(), testUUID(100), testUUID(1))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("err = %v, want ErrNoRows", err)
+	}
+}
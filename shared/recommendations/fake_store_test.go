@@ -0,0 +1,916 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type profileKey struct {
+	userID   pgtype.UUID
+	category string
+}
+
+type contributionKey struct {
+	profileKey
+	postID pgtype.UUID
+}
+
+// fakeStore is an in-memory Store. Candidates are derived from the stored
+// beverages the same way GetRecommendationCandidates orders them.
+type fakeStore struct {
+	beverages   map[pgtype.UUID]sqlc.Beverage
+	beverageErr error
+	order       []pgtype.UUID
+	tags        map[pgtype.UUID][]sqlc.GetTagsForBeveragesRow
+	posts       map[profileKey][]sqlc.GetUserPostsForCategoryRow
+	profiles    map[profileKey]sqlc.UserTasteProfile
+	decay       map[string]sqlc.TasteDecaySetting
+	stats       map[profileKey]sqlc.UserTasteStat
+	ledger      map[contributionKey]sqlc.UserTasteContribution
+	bevEmbeds   map[pgtype.UUID]sqlc.BeverageEmbedding
+	userEmbeds  map[profileKey]sqlc.UserEmbedding
+	neighbors   map[pgtype.UUID][]sqlc.BeverageNeighbor
+	coRated     map[profileKey][]sqlc.GetUserNeighborScoresRow
+	prices      map[pgtype.UUID]int32
+	hidden      map[pgtype.UUID][]pgtype.UUID
+	feedback    map[pgtype.UUID][]sqlc.RecommendationFeedback
+	lists       []sqlc.RecommendationList
+	listErr     error
+	engagement  []sqlc.GetExperimentEngagementRow
+	impressions []sqlc.RecommendationImpression
+	converted   int64
+	sightings   map[pgtype.UUID][]sqlc.GetVenueCandidatesRow
+	coRatings   map[[2]pgtype.UUID][]sqlc.GetCoRatedBeveragesRow
+	compat      map[string]sqlc.TasteCompatibility
+	quiz        map[profileKey][]sqlc.TasteQuizAnswer
+	calls       map[string]int
+}
+
+var _ Store = (*fakeStore)(nil)
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		beverages:  make(map[pgtype.UUID]sqlc.Beverage),
+		tags:       make(map[pgtype.UUID][]sqlc.GetTagsForBeveragesRow),
+		posts:      make(map[profileKey][]sqlc.GetUserPostsForCategoryRow),
+		profiles:   make(map[profileKey]sqlc.UserTasteProfile),
+		decay:      make(map[string]sqlc.TasteDecaySetting),
+		stats:      make(map[profileKey]sqlc.UserTasteStat),
+		ledger:     make(map[contributionKey]sqlc.UserTasteContribution),
+		bevEmbeds:  make(map[pgtype.UUID]sqlc.BeverageEmbedding),
+		userEmbeds: make(map[profileKey]sqlc.UserEmbedding),
+		neighbors:  make(map[pgtype.UUID][]sqlc.BeverageNeighbor),
+		coRated:    make(map[profileKey][]sqlc.GetUserNeighborScoresRow),
+		prices:     make(map[pgtype.UUID]int32),
+		hidden:     make(map[pgtype.UUID][]pgtype.UUID),
+		feedback:   make(map[pgtype.UUID][]sqlc.RecommendationFeedback),
+		sightings:  make(map[pgtype.UUID][]sqlc.GetVenueCandidatesRow),
+		coRatings:  make(map[[2]pgtype.UUID][]sqlc.GetCoRatedBeveragesRow),
+		compat:     make(map[string]sqlc.TasteCompatibility),
+		quiz:       make(map[profileKey][]sqlc.TasteQuizAnswer),
+		calls:      make(map[string]int),
+	}
+}
+
+// AttributeConversions reports the converted count a test set
+func (f *fakeStore) AttributeConversions(ctx context.Context, arg sqlc.AttributeConversionsParams) (int64, error) {
+	f.calls["AttributeConversions"]++
+	return f.converted, nil
+}
+
+// CreateRecommendationFeedback returns pgx.ErrNoRows for a duplicate, as
+// ON CONFLICT DO NOTHING does
+func (f *fakeStore) CreateRecommendationFeedback(ctx context.Context, arg sqlc.CreateRecommendationFeedbackParams) (sqlc.RecommendationFeedback, error) {
+	f.calls["CreateRecommendationFeedback"]++
+	for _, fb := range f.feedback[arg.UserID] {
+		if fb.BeverageID == arg.BeverageID && fb.FeedbackType == arg.FeedbackType {
+			return sqlc.RecommendationFeedback{}, pgx.ErrNoRows
+		}
+	}
+	fb := sqlc.RecommendationFeedback{
+		ID:           testUUID(5000 + len(f.feedback[arg.UserID])),
+		UserID:       arg.UserID,
+		BeverageID:   arg.BeverageID,
+		FeedbackType: arg.FeedbackType,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	f.feedback[arg.UserID] = append(f.feedback[arg.UserID], fb)
+	return fb, nil
+}
+
+func (f *fakeStore) DeleteFeedback(ctx context.Context, arg sqlc.DeleteFeedbackParams) error {
+	f.calls["DeleteFeedback"]++
+	f.feedback[arg.UserID] = slices.DeleteFunc(f.feedback[arg.UserID], func(fb sqlc.RecommendationFeedback) bool {
+		return fb.BeverageID == arg.BeverageID && fb.FeedbackType == arg.FeedbackType
+	})
+	return nil
+}
+
+func (f *fakeStore) DeleteTasteContribution(ctx context.Context, arg sqlc.DeleteTasteContributionParams) error {
+	f.calls["DeleteTasteContribution"]++
+	delete(f.ledger, contributionKey{profileKey{arg.UserID, arg.Category}, arg.PostID})
+	return nil
+}
+
+func (f *fakeStore) DeleteTasteContributions(ctx context.Context, arg sqlc.DeleteTasteContributionsParams) error {
+	f.calls["DeleteTasteContributions"]++
+	for k := range f.ledger {
+		if k.profileKey == (profileKey{arg.UserID, arg.Category}) {
+			delete(f.ledger, k)
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) GetBeverageEmbeddingsByCategory(ctx context.Context, arg sqlc.GetBeverageEmbeddingsByCategoryParams) ([]sqlc.GetBeverageEmbeddingsByCategoryRow, error) {
+	f.calls["GetBeverageEmbeddingsByCategory"]++
+	var rows []sqlc.GetBeverageEmbeddingsByCategoryRow
+	for _, id := range f.order {
+		e, ok := f.bevEmbeds[id]
+		if !ok || e.Model != arg.Model || f.beverages[id].Category != arg.Category {
+			continue
+		}
+		rows = append(rows, sqlc.GetBeverageEmbeddingsByCategoryRow{BeverageID: id, EmbeddingVector: e.EmbeddingVector})
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetBeverageByID(ctx context.Context, id pgtype.UUID) (sqlc.Beverage, error) {
+	f.calls["GetBeverageByID"]++
+	if f.beverageErr != nil {
+		return sqlc.Beverage{}, f.beverageErr
+	}
+	b, ok := f.beverages[id]
+	if !ok {
+		return sqlc.Beverage{}, pgx.ErrNoRows
+	}
+	return b, nil
+}
+
+func (f *fakeStore) GetBeverageNeighbors(ctx context.Context, arg sqlc.GetBeverageNeighborsParams) ([]sqlc.GetBeverageNeighborsRow, error) {
+	f.calls["GetBeverageNeighbors"]++
+	var rows []sqlc.GetBeverageNeighborsRow
+	for _, n := range f.neighbors[arg.BeverageID] {
+		b := f.beverages[n.NeighborID]
+		rows = append(rows, sqlc.GetBeverageNeighborsRow{
+			ID:          b.ID,
+			Name:        b.Name,
+			Brand:       b.Brand,
+			Category:    b.Category,
+			ReviewCount: b.TotalReviews,
+			AvgRating:   b.AvgRating,
+			Similarity:  n.Similarity,
+			CoRaters:    n.CoRaters,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Similarity > rows[j].Similarity })
+	if len(rows) > int(arg.Limit) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetCategoryTagProfiles(ctx context.Context, category string) ([]sqlc.GetCategoryTagProfilesRow, error) {
+	f.calls["GetCategoryTagProfiles"]++
+	var rows []sqlc.GetCategoryTagProfilesRow
+	for _, id := range f.order {
+		b := f.beverages[id]
+		if b.Category != category || len(f.tags[id]) == 0 {
+			continue
+		}
+		var tags []map[string]any
+		for _, t := range f.tags[id] {
+			tags = append(tags, map[string]any{"tag": t.Tag, "count": t.Count})
+		}
+		tagsJSON, _ := json.Marshal(tags)
+		rows = append(rows, sqlc.GetCategoryTagProfilesRow{
+			ID:               b.ID,
+			Name:             b.Name,
+			Brand:            b.Brand,
+			Style:            b.Style,
+			ReviewCount:      b.TotalReviews,
+			AvgRating:        b.AvgRating,
+			MedianPriceCents: f.prices[id],
+			TagsJson:         tagsJSON,
+		})
+	}
+	return rows, nil
+}
+
+// GetCoRatedBeverages returns the rows a test set for the pair
+func (f *fakeStore) GetCoRatedBeverages(ctx context.Context, arg sqlc.GetCoRatedBeveragesParams) ([]sqlc.GetCoRatedBeveragesRow, error) {
+	f.calls["GetCoRatedBeverages"]++
+	return f.coRatings[[2]pgtype.UUID{arg.UserID, arg.OtherUserID}], nil
+}
+
+func (f *fakeStore) GetExperimentEngagement(ctx context.Context, arg sqlc.GetExperimentEngagementParams) ([]sqlc.GetExperimentEngagementRow, error) {
+	f.calls["GetExperimentEngagement"]++
+	return f.engagement, nil
+}
+
+func (f *fakeStore) GetHiddenBeveragesForUser(ctx context.Context, userID pgtype.UUID) ([]pgtype.UUID, error) {
+	f.calls["GetHiddenBeveragesForUser"]++
+	return f.hidden[userID], nil
+}
+
+func (f *fakeStore) GetImpressionStatsByRank(ctx context.Context, arg sqlc.GetImpressionStatsByRankParams) ([]sqlc.GetImpressionStatsByRankRow, error) {
+	f.calls["GetImpressionStatsByRank"]++
+	var rows []sqlc.GetImpressionStatsByRankRow
+	index := make(map[int32]int)
+	for _, imp := range f.impressions {
+		if imp.Surface != arg.Surface || !imp.Rank.Valid {
+			continue
+		}
+		i, ok := index[imp.Rank.Int32]
+		if !ok {
+			i = len(rows)
+			index[imp.Rank.Int32] = i
+			rows = append(rows, sqlc.GetImpressionStatsByRankRow{Rank: imp.Rank})
+		}
+		rows[i].Impressions++
+		if imp.ClickedAt.Valid {
+			rows[i].Clicks++
+		}
+		if imp.ConvertedAt.Valid {
+			rows[i].Conversions++
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Rank.Int32 < rows[j].Rank.Int32 })
+	return rows, nil
+}
+
+func (f *fakeStore) GetImpressionStatsBySurface(ctx context.Context, createdAt pgtype.Timestamptz) ([]sqlc.GetImpressionStatsBySurfaceRow, error) {
+	f.calls["GetImpressionStatsBySurface"]++
+	var rows []sqlc.GetImpressionStatsBySurfaceRow
+	index := make(map[string]int)
+	for _, imp := range f.impressions {
+		i, ok := index[imp.Surface]
+		if !ok {
+			i = len(rows)
+			index[imp.Surface] = i
+			rows = append(rows, sqlc.GetImpressionStatsBySurfaceRow{Surface: imp.Surface})
+		}
+		rows[i].Impressions++
+		if imp.ClickedAt.Valid {
+			rows[i].Clicks++
+		}
+		if imp.ConvertedAt.Valid {
+			rows[i].Conversions++
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Surface < rows[j].Surface })
+	return rows, nil
+}
+
+func (f *fakeStore) GetPostForTasteProfile(ctx context.Context, id pgtype.UUID) ([]sqlc.GetPostForTasteProfileRow, error) {
+	f.calls["GetPostForTasteProfile"]++
+	var rows []sqlc.GetPostForTasteProfileRow
+	for _, posts := range f.posts {
+		for _, p := range posts {
+			if p.ID == id {
+				rows = append(rows, sqlc.GetPostForTasteProfileRow(p))
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetRecommendationCandidates(ctx context.Context, arg sqlc.GetRecommendationCandidatesParams) ([]sqlc.GetRecommendationCandidatesRow, error) {
+	f.calls["GetRecommendationCandidates"]++
+
+	var rows []sqlc.GetRecommendationCandidatesRow
+	for _, id := range f.order {
+		b := f.beverages[id]
+		if b.Category != arg.Category || !f.matchesFilter(b, arg.UserID, arg.MinReviews, arg.Styles, arg.ExcludeTried, false) {
+			continue
+		}
+		rows = append(rows, sqlc.GetRecommendationCandidatesRow{
+			ID:          b.ID,
+			Name:        b.Name,
+			Brand:       b.Brand,
+			Category:    b.Category,
+			ReviewCount: b.TotalReviews,
+			AvgRating:   b.AvgRating,
+			ProducerID:  b.ProducerID,
+			Style:       b.Style,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].ReviewCount.Int32 != rows[j].ReviewCount.Int32 {
+			return rows[i].ReviewCount.Int32 > rows[j].ReviewCount.Int32
+		}
+		a, _ := rows[i].AvgRating.Float64Value()
+		b, _ := rows[j].AvgRating.Float64Value()
+		return a.Float64 > b.Float64
+	})
+
+	if len(rows) > int(arg.Limit) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+// matchesFilter applies the user's hidden and feedback-excluded beverages
+// and the review, style and tried parts of a RecommendationFilter the way
+// the candidate queries do
+func (f *fakeStore) matchesFilter(b sqlc.Beverage, userID pgtype.UUID, minReviews int32, styles []string, excludeTried, atVenue bool) bool {
+	if slices.Contains(f.hidden[userID], b.ID) {
+		return false
+	}
+	for _, fb := range f.feedback[userID] {
+		if fb.BeverageID != b.ID {
+			continue
+		}
+		switch fb.FeedbackType {
+		case "hide", "already_tried":
+			return false
+		case "not_available":
+			if !atVenue && time.Since(fb.CreatedAt.Time) < 30*24*time.Hour {
+				return false
+			}
+		}
+	}
+	if b.TotalReviews.Int32 < minReviews {
+		return false
+	}
+	if len(styles) > 0 && !slices.Contains(styles, strings.ToLower(b.Style.String)) {
+		return false
+	}
+	if excludeTried {
+		for _, p := range f.posts[profileKey{userID, b.Category}] {
+			if p.BeverageID == b.ID {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *fakeStore) GetRecommendationCandidatesByIDs(ctx context.Context, arg sqlc.GetRecommendationCandidatesByIDsParams) ([]sqlc.GetRecommendationCandidatesByIDsRow, error) {
+	f.calls["GetRecommendationCandidatesByIDs"]++
+	var rows []sqlc.GetRecommendationCandidatesByIDsRow
+	for _, id := range arg.Ids {
+		b, ok := f.beverages[id]
+		if !ok || b.Category != arg.Category || !f.matchesFilter(b, arg.UserID, arg.MinReviews, arg.Styles, arg.ExcludeTried, false) {
+			continue
+		}
+		rows = append(rows, sqlc.GetRecommendationCandidatesByIDsRow{
+			ID:          b.ID,
+			Name:        b.Name,
+			Brand:       b.Brand,
+			Category:    b.Category,
+			ReviewCount: b.TotalReviews,
+			AvgRating:   b.AvgRating,
+			ProducerID:  b.ProducerID,
+			Style:       b.Style,
+		})
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetTagsForBeverages(ctx context.Context, ids []pgtype.UUID) ([]sqlc.GetTagsForBeveragesRow, error) {
+	f.calls["GetTagsForBeverages"]++
+	var rows []sqlc.GetTagsForBeveragesRow
+	for _, id := range ids {
+		rows = append(rows, f.tags[id]...)
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetTasteCompatibility(ctx context.Context, arg sqlc.GetTasteCompatibilityParams) ([]sqlc.TasteCompatibility, error) {
+	f.calls["GetTasteCompatibility"]++
+	var rows []sqlc.TasteCompatibility
+	for _, row := range f.compat {
+		if row.UserID == arg.UserID && row.OtherUserID == arg.OtherUserID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetTasteContribution(ctx context.Context, arg sqlc.GetTasteContributionParams) (sqlc.UserTasteContribution, error) {
+	f.calls["GetTasteContribution"]++
+	c, ok := f.ledger[contributionKey{profileKey{arg.UserID, arg.Category}, arg.PostID}]
+	if !ok {
+		return sqlc.UserTasteContribution{}, pgx.ErrNoRows
+	}
+	return c, nil
+}
+
+func (f *fakeStore) GetTasteDecaySetting(ctx context.Context, category string) (sqlc.TasteDecaySetting, error) {
+	f.calls["GetTasteDecaySetting"]++
+	d, ok := f.decay[category]
+	if !ok {
+		return sqlc.TasteDecaySetting{}, pgx.ErrNoRows
+	}
+	return d, nil
+}
+
+func (f *fakeStore) GetUserEmbedding(ctx context.Context, arg sqlc.GetUserEmbeddingParams) (sqlc.UserEmbedding, error) {
+	f.calls["GetUserEmbedding"]++
+	e, ok := f.userEmbeds[profileKey{arg.UserID, arg.Category}]
+	if !ok {
+		return sqlc.UserEmbedding{}, pgx.ErrNoRows
+	}
+	return e, nil
+}
+
+func (f *fakeStore) GetUserFeedbackForBeverage(ctx context.Context, arg sqlc.GetUserFeedbackForBeverageParams) ([]sqlc.RecommendationFeedback, error) {
+	f.calls["GetUserFeedbackForBeverage"]++
+	var rows []sqlc.RecommendationFeedback
+	for _, fb := range f.feedback[arg.UserID] {
+		if fb.BeverageID == arg.BeverageID {
+			rows = append(rows, fb)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetUserFeedbackForCategory(ctx context.Context, arg sqlc.GetUserFeedbackForCategoryParams) ([]sqlc.RecommendationFeedback, error) {
+	f.calls["GetUserFeedbackForCategory"]++
+	var rows []sqlc.RecommendationFeedback
+	for _, fb := range f.feedback[arg.UserID] {
+		if f.beverages[fb.BeverageID].Category == arg.Category {
+			rows = append(rows, fb)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetUserNeighborScores(ctx context.Context, arg sqlc.GetUserNeighborScoresParams) ([]sqlc.GetUserNeighborScoresRow, error) {
+	f.calls["GetUserNeighborScores"]++
+	rows := f.coRated[profileKey{arg.UserID, arg.DrinkCategory}]
+	if len(rows) > int(arg.Limit) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetUserPostsForCategory(ctx context.Context, arg sqlc.GetUserPostsForCategoryParams) ([]sqlc.GetUserPostsForCategoryRow, error) {
+	f.calls["GetUserPostsForCategory"]++
+	return f.posts[profileKey{arg.UserID, arg.DrinkCategory}], nil
+}
+
+func (f *fakeStore) GetUserTasteProfile(ctx context.Context, arg sqlc.GetUserTasteProfileParams) (sqlc.UserTasteProfile, error) {
+	f.calls["GetUserTasteProfile"]++
+	p, ok := f.profiles[profileKey{arg.UserID, arg.Category}]
+	if !ok {
+		return sqlc.UserTasteProfile{}, pgx.ErrNoRows
+	}
+	return p, nil
+}
+
+func (f *fakeStore) GetUserTasteStatsForUpdate(ctx context.Context, arg sqlc.GetUserTasteStatsForUpdateParams) (sqlc.UserTasteStat, error) {
+	f.calls["GetUserTasteStatsForUpdate"]++
+	s, ok := f.stats[profileKey{arg.UserID, arg.Category}]
+	if !ok {
+		return sqlc.UserTasteStat{}, pgx.ErrNoRows
+	}
+	return s, nil
+}
+
+// GetVenueCandidates matches venues by ID only
+func (f *fakeStore) GetVenueCandidates(ctx context.Context, arg sqlc.GetVenueCandidatesParams) ([]sqlc.GetVenueCandidatesRow, error) {
+	f.calls["GetVenueCandidates"]++
+	var rows []sqlc.GetVenueCandidatesRow
+	for _, row := range f.sightings[arg.VenueID] {
+		if row.Category == arg.Category && f.matchesFilter(f.beverages[row.ID], arg.UserID, arg.MinReviews, arg.Styles, arg.ExcludeTried, true) {
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].LastSeenAt.Time.After(rows[j].LastSeenAt.Time)
+	})
+	if len(rows) > int(arg.Limit) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) InsertRecommendationImpressions(ctx context.Context, arg sqlc.InsertRecommendationImpressionsParams) ([]sqlc.InsertRecommendationImpressionsRow, error) {
+	f.calls["InsertRecommendationImpressions"]++
+	var rows []sqlc.InsertRecommendationImpressionsRow
+	for i, beverageID := range arg.BeverageIds {
+		imp := sqlc.RecommendationImpression{
+			ID:         testUUID(8000 + len(f.impressions)),
+			UserID:     arg.UserID,
+			BeverageID: beverageID,
+			Surface:    arg.Surface,
+			Rank:       pgtype.Int4{Int32: arg.Ranks[i], Valid: arg.Ranks[i] != 0},
+			MatchScore: arg.MatchScores[i],
+			ListID:     arg.ListID,
+			CreatedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		}
+		f.impressions = append(f.impressions, imp)
+		rows = append(rows, sqlc.InsertRecommendationImpressionsRow{ID: imp.ID, BeverageID: beverageID})
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) InsertRecommendationList(ctx context.Context, arg sqlc.InsertRecommendationListParams) (sqlc.RecommendationList, error) {
+	f.calls["InsertRecommendationList"]++
+	if f.listErr != nil {
+		return sqlc.RecommendationList{}, f.listErr
+	}
+	list := sqlc.RecommendationList{
+		ID:          testUUID(7000 + len(f.lists)),
+		UserID:      arg.UserID,
+		Category:    arg.Category,
+		Experiment:  arg.Experiment,
+		Variant:     arg.Variant,
+		Strategy:    arg.Strategy,
+		BeverageIds: arg.BeverageIds,
+		CreatedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	f.lists = append(f.lists, list)
+	return list, nil
+}
+
+// ListBeveragesNeedingEmbeddings treats an embedding without updated_at as stale
+func (f *fakeStore) ListBeveragesNeedingEmbeddings(ctx context.Context, arg sqlc.ListBeveragesNeedingEmbeddingsParams) ([]sqlc.ListBeveragesNeedingEmbeddingsRow, error) {
+	f.calls["ListBeveragesNeedingEmbeddings"]++
+	var rows []sqlc.ListBeveragesNeedingEmbeddingsRow
+	for _, id := range f.order {
+		e, ok := f.bevEmbeds[id]
+		if ok && e.Model == arg.Model && e.UpdatedAt.Valid {
+			continue
+		}
+		b := f.beverages[id]
+		row := sqlc.ListBeveragesNeedingEmbeddingsRow{
+			ID:       b.ID,
+			Name:     b.Name,
+			Brand:    b.Brand,
+			Category: b.Category,
+			Style:    b.Style,
+			Varietal: b.Varietal,
+			Region:   b.Region,
+		}
+		if ok {
+			row.CurrentText = pgtype.Text{String: e.EmbeddingText, Valid: true}
+			row.CurrentModel = pgtype.Text{String: e.Model, Valid: true}
+		}
+		rows = append(rows, row)
+		if len(rows) == int(arg.Limit) {
+			break
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) ListQuizBeverages(ctx context.Context, arg sqlc.ListQuizBeveragesParams) ([]sqlc.ListQuizBeveragesRow, error) {
+	f.calls["ListQuizBeverages"]++
+	var beverages []sqlc.Beverage
+	for _, id := range f.order {
+		if b := f.beverages[id]; b.Category == arg.Category && len(f.tags[id]) > 0 {
+			beverages = append(beverages, b)
+		}
+	}
+	sort.SliceStable(beverages, func(i, j int) bool {
+		return beverages[i].TotalReviews.Int32 > beverages[j].TotalReviews.Int32
+	})
+
+	var rows []sqlc.ListQuizBeveragesRow
+	for _, b := range beverages[:min(len(beverages), int(arg.Limit))] {
+		rows = append(rows, sqlc.ListQuizBeveragesRow{
+			ID:          b.ID,
+			Name:        b.Name,
+			Brand:       b.Brand,
+			ImageUrl:    b.ImageUrl,
+			ReviewCount: b.TotalReviews,
+		})
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) ListTasteProfilesForUsers(ctx context.Context, userIds []pgtype.UUID) ([]sqlc.UserTasteProfile, error) {
+	f.calls["ListTasteProfilesForUsers"]++
+	var rows []sqlc.UserTasteProfile
+	for k, p := range f.profiles {
+		if slices.Contains(userIds, k.userID) {
+			rows = append(rows, p)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) ListTasteQuizAnswers(ctx context.Context, arg sqlc.ListTasteQuizAnswersParams) ([]sqlc.TasteQuizAnswer, error) {
+	f.calls["ListTasteQuizAnswers"]++
+	return f.quiz[profileKey{arg.UserID, arg.Category}], nil
+}
+
+// ListUserEmbeddingsToRefresh treats an embedding without updated_at as stale
+func (f *fakeStore) ListUserEmbeddingsToRefresh(ctx context.Context, arg sqlc.ListUserEmbeddingsToRefreshParams) ([]sqlc.ListUserEmbeddingsToRefreshRow, error) {
+	f.calls["ListUserEmbeddingsToRefresh"]++
+	var rows []sqlc.ListUserEmbeddingsToRefreshRow
+	for key, p := range f.profiles {
+		e, ok := f.userEmbeds[key]
+		if ok && e.Model == arg.Model && e.UpdatedAt.Valid {
+			continue
+		}
+		row := sqlc.ListUserEmbeddingsToRefreshRow{
+			UserID:        p.UserID,
+			Category:      p.Category,
+			LikedTagsJson: p.LikedTagsJson,
+		}
+		if ok {
+			row.CurrentText = pgtype.Text{String: e.EmbeddingText, Valid: true}
+			row.CurrentModel = e.Model
+		}
+		rows = append(rows, row)
+		if len(rows) == int(arg.Limit) {
+			break
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) RecordImpressionClick(ctx context.Context, arg sqlc.RecordImpressionClickParams) error {
+	f.calls["RecordImpressionClick"]++
+	for i, imp := range f.impressions {
+		if imp.ID == arg.ID && imp.UserID == arg.UserID && !imp.ClickedAt.Valid {
+			f.impressions[i].ClickedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) TouchBeverageEmbedding(ctx context.Context, beverageID pgtype.UUID) error {
+	f.calls["TouchBeverageEmbedding"]++
+	e := f.bevEmbeds[beverageID]
+	e.UpdatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	f.bevEmbeds[beverageID] = e
+	return nil
+}
+
+func (f *fakeStore) TouchUserEmbedding(ctx context.Context, arg sqlc.TouchUserEmbeddingParams) error {
+	f.calls["TouchUserEmbedding"]++
+	key := profileKey{arg.UserID, arg.Category}
+	e := f.userEmbeds[key]
+	e.UpdatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	f.userEmbeds[key] = e
+	return nil
+}
+
+func (f *fakeStore) UpsertBeverageEmbedding(ctx context.Context, arg sqlc.UpsertBeverageEmbeddingParams) error {
+	f.calls["UpsertBeverageEmbedding"]++
+	f.bevEmbeds[arg.BeverageID] = sqlc.BeverageEmbedding{
+		BeverageID:      arg.BeverageID,
+		EmbeddingText:   arg.EmbeddingText,
+		EmbeddingVector: arg.EmbeddingVector,
+		Model:           arg.Model,
+		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	return nil
+}
+
+// UpsertTasteCompatibility keys rows by category; tests cache one pair at a time
+func (f *fakeStore) UpsertTasteCompatibility(ctx context.Context, arg sqlc.UpsertTasteCompatibilityParams) error {
+	f.calls["UpsertTasteCompatibility"]++
+	f.compat[arg.Category] = sqlc.TasteCompatibility{
+		UserID:                 arg.UserID,
+		OtherUserID:            arg.OtherUserID,
+		Category:               arg.Category,
+		Score:                  arg.Score,
+		TagSimilarity:          arg.TagSimilarity,
+		RatingAgreement:        arg.RatingAgreement,
+		CoRated:                arg.CoRated,
+		SharedTagsJson:         arg.SharedTagsJson,
+		ClashingTagsJson:       arg.ClashingTagsJson,
+		ProfileComputedAt:      arg.ProfileComputedAt,
+		OtherProfileComputedAt: arg.OtherProfileComputedAt,
+		ComputedAt:             pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	return nil
+}
+
+func (f *fakeStore) UpsertTasteContribution(ctx context.Context, arg sqlc.UpsertTasteContributionParams) error {
+	f.calls["UpsertTasteContribution"]++
+	f.ledger[contributionKey{profileKey{arg.UserID, arg.Category}, arg.PostID}] = sqlc.UserTasteContribution(arg)
+	return nil
+}
+
+// UpsertTasteQuizAnswer replaces an earlier answer to the same item in place
+func (f *fakeStore) UpsertTasteQuizAnswer(ctx context.Context, arg sqlc.UpsertTasteQuizAnswerParams) error {
+	f.calls["UpsertTasteQuizAnswer"]++
+	key := profileKey{arg.UserID, arg.Category}
+	answer := sqlc.TasteQuizAnswer{
+		UserID:     arg.UserID,
+		Category:   arg.Category,
+		ItemID:     arg.ItemID,
+		Liked:      arg.Liked,
+		Tags:       arg.Tags,
+		AnsweredAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	for i, a := range f.quiz[key] {
+		if a.ItemID == arg.ItemID {
+			f.quiz[key][i] = answer
+			return nil
+		}
+	}
+	f.quiz[key] = append(f.quiz[key], answer)
+	return nil
+}
+
+func (f *fakeStore) UpsertUserTasteStats(ctx context.Context, arg sqlc.UpsertUserTasteStatsParams) error {
+	f.calls["UpsertUserTasteStats"]++
+	f.stats[profileKey{arg.UserID, arg.Category}] = sqlc.UserTasteStat{
+		UserID:          arg.UserID,
+		Category:        arg.Category,
+		HalfLifeDays:    arg.HalfLifeDays,
+		RecentJson:      arg.RecentJson,
+		AllTimeJson:     arg.AllTimeJson,
+		AsOf:            arg.AsOf,
+		FullRecomputeAt: arg.FullRecomputeAt,
+	}
+	return nil
+}
+
+func (f *fakeStore) UpsertUserEmbedding(ctx context.Context, arg sqlc.UpsertUserEmbeddingParams) (sqlc.UserEmbedding, error) {
+	f.calls["UpsertUserEmbedding"]++
+	e := sqlc.UserEmbedding{
+		UserID:          arg.UserID,
+		Category:        arg.Category,
+		EmbeddingText:   arg.EmbeddingText,
+		EmbeddingVector: arg.EmbeddingVector,
+		Model:           arg.Model,
+		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	f.userEmbeds[profileKey{arg.UserID, arg.Category}] = e
+	return e, nil
+}
+
+func (f *fakeStore) UpsertUserTasteProfile(ctx context.Context, arg sqlc.UpsertUserTasteProfileParams) (sqlc.UserTasteProfile, error) {
+	f.calls["UpsertUserTasteProfile"]++
+	p := sqlc.UserTasteProfile{
+		UserID:                  arg.UserID,
+		Category:                arg.Category,
+		LikedTagsJson:           arg.LikedTagsJson,
+		DislikedTagsJson:        arg.DislikedTagsJson,
+		MeanRating:              arg.MeanRating,
+		StdRating:               arg.StdRating,
+		PostCount:               arg.PostCount,
+		LastComputedAt:          pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		AllTimeLikedTagsJson:    arg.AllTimeLikedTagsJson,
+		AllTimeDislikedTagsJson: arg.AllTimeDislikedTagsJson,
+		AllTimeMeanRating:       arg.AllTimeMeanRating,
+		AllTimeStdRating:        arg.AllTimeStdRating,
+		HalfLifeDays:            arg.HalfLifeDays,
+		InferredFrom:            arg.InferredFrom,
+		QuizWeight:              arg.QuizWeight,
+	}
+	f.profiles[profileKey{arg.UserID, arg.Category}] = p
+	return p, nil
+}
+
+// Fixture helpers
+
+func testUUID(n int) pgtype.UUID {
+	id := pgtype.UUID{Valid: true}
+	id.Bytes[14], id.Bytes[15] = byte(n>>8), byte(n)
+	return id
+}
+
+func testNumeric(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	_ = n.Scan(strconv.FormatFloat(f, 'f', 2, 64))
+	return n
+}
+
+func (f *fakeStore) addBeverage(id pgtype.UUID, name, category string, avgRating float64, reviews int32, tags ...string) {
+	f.beverages[id] = sqlc.Beverage{
+		ID:           id,
+		Name:         name,
+		Category:     category,
+		AvgRating:    testNumeric(avgRating),
+		TotalReviews: pgtype.Int4{Int32: reviews, Valid: true},
+	}
+	f.order = append(f.order, id)
+	for i, tag := range tags {
+		f.tags[id] = append(f.tags[id], sqlc.GetTagsForBeveragesRow{
+			BeverageID: id,
+			Tag:        tag,
+			TagType:    "descriptor",
+			Count:      int32(len(tags) - i),
+		})
+	}
+}
+
+func (f *fakeStore) setProfile(userID pgtype.UUID, category string, liked, disliked TagWeights, postCount int32) {
+	likedJSON, _ := json.Marshal(liked)
+	dislikedJSON, _ := json.Marshal(disliked)
+	f.profiles[profileKey{userID, category}] = sqlc.UserTasteProfile{
+		UserID:           userID,
+		Category:         category,
+		LikedTagsJson:    likedJSON,
+		DislikedTagsJson: dislikedJSON,
+		PostCount:        pgtype.Int4{Int32: postCount, Valid: true},
+		LastComputedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+func (f *fakeStore) setHalfLife(category string, days pgtype.Int4) {
+	f.decay[category] = sqlc.TasteDecaySetting{Category: category, HalfLifeDays: days}
+}
+
+func (f *fakeStore) setBeverageEmbedding(id pgtype.UUID, model string, vector ...float32) {
+	f.bevEmbeds[id] = sqlc.BeverageEmbedding{
+		BeverageID:      id,
+		EmbeddingVector: vector,
+		Model:           model,
+		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+// addSighting records a beverage added with addBeverage as posted at a venue
+func (f *fakeStore) addSighting(venueID, beverageID pgtype.UUID, sightings int32, lastSeen time.Time, priceCents int32) {
+	b := f.beverages[beverageID]
+	f.sightings[venueID] = append(f.sightings[venueID], sqlc.GetVenueCandidatesRow{
+		ID:             b.ID,
+		Name:           b.Name,
+		Category:       b.Category,
+		ReviewCount:    b.TotalReviews,
+		AvgRating:      b.AvgRating,
+		ProducerID:     b.ProducerID,
+		Style:          b.Style,
+		Sightings:      sightings,
+		LastSeenAt:     pgtype.Timestamptz{Time: lastSeen, Valid: true},
+		LastPriceCents: priceCents,
+	})
+}
+
+// addPost adds a rated post as GetUserPostsForCategory returns it: one row per tag
+func (f *fakeStore) addPost(userID pgtype.UUID, category string, postID pgtype.UUID, rating float64, tags ...string) {
+	f.addPostAt(userID, category, postID, rating, time.Time{}, tags...)
+}
+
+// addPostAt is addPost with a creation time; the zero time leaves created_at NULL
+func (f *fakeStore) addPostAt(userID pgtype.UUID, category string, postID pgtype.UUID, rating float64, createdAt time.Time, tags ...string) {
+	key := profileKey{userID, category}
+	row := sqlc.GetUserPostsForCategoryRow{
+		ID:            postID,
+		UserID:        userID,
+		DrinkCategory: category,
+		Rating:        testNumeric(rating),
+		CreatedAt:     pgtype.Timestamptz{Time: createdAt, Valid: !createdAt.IsZero()},
+	}
+	if len(tags) == 0 {
+		f.posts[key] = append(f.posts[key], row)
+		return
+	}
+	for _, tag := range tags {
+		row.Tag = pgtype.Text{String: tag, Valid: true}
+		row.TagType = pgtype.Text{String: "descriptor", Valid: true}
+		f.posts[key] = append(f.posts[key], row)
+	}
+}
+
+// removePost deletes a post's rows, as a post delete would
+func (f *fakeStore) removePost(postID pgtype.UUID) {
+	for key, posts := range f.posts {
+		kept := posts[:0]
+		for _, p := range posts {
+			if p.ID != postID {
+				kept = append(kept, p)
+			}
+		}
+		f.posts[key] = kept
+	}
+}
+
+// setPostRating edits a post's rating in place
+func (f *fakeStore) setPostRating(postID pgtype.UUID, rating float64) {
+	for _, posts := range f.posts {
+		for i := range posts {
+			if posts[i].ID == postID {
+				posts[i].Rating = testNumeric(rating)
+			}
+		}
+	}
+}
+
+func (f *fakeStore) profile(t *testing.T, userID pgtype.UUID, category string) (sqlc.UserTasteProfile, TagWeights, TagWeights) {
+	t.Helper()
+	p, ok := f.profiles[profileKey{userID, category}]
+	if !ok {
+		t.Fatalf("no profile stored for %s", category)
+	}
+	var liked, disliked TagWeights
+	if err := json.Unmarshal(p.LikedTagsJson, &liked); err != nil {
+		t.Fatalf("liked tags: %v", err)
+	}
+	if err := json.Unmarshal(p.DislikedTagsJson, &disliked); err != nil {
+		t.Fatalf("disliked tags: %v", err)
+	}
+	return p, liked, disliked
+}
@@ -0,0 +1,155 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Feedback types. Only more_like_this and less_like_this change taste; the
+// others only change which beverages the candidate queries return.
+const (
+	// MoreLikeThisFeedback raises the beverage's tags in the liked tags
+	MoreLikeThisFeedback = "more_like_this"
+	// LessLikeThisFeedback raises the beverage's tags in the disliked tags
+	LessLikeThisFeedback = "less_like_this"
+	// HideFeedback excludes the beverage from recommendations and similar lists
+	HideFeedback = "hide"
+	// AlreadyTriedFeedback excludes the beverage from recommendations like
+	// a posted one. Without a rating there is no taste to learn from it.
+	AlreadyTriedFeedback = "already_tried"
+	// NotAvailableFeedback excludes the beverage from recommendations for 30
+	// days, except at venues where it has been posted
+	NotAvailableFeedback = "not_available"
+)
+
+// feedbackTagWeight is how far one piece of feedback moves each of the
+// beverage's tags before the weights are renormalized
+const feedbackTagWeight = 0.5
+
+// FeedbackTypes are the feedback types a user can give
+var FeedbackTypes = []string{MoreLikeThisFeedback, LessLikeThisFeedback, HideFeedback, AlreadyTriedFeedback, NotAvailableFeedback}
+
+// changesTaste reports whether a feedback type is replayed into profiles
+func changesTaste(feedbackType string) bool {
+	return feedbackType == MoreLikeThisFeedback || feedbackType == LessLikeThisFeedback
+}
+
+// checkFeedbackType rejects feedback types outside FeedbackTypes
+func checkFeedbackType(feedbackType string) error {
+	for _, t := range FeedbackTypes {
+		if t == feedbackType {
+			return nil
+		}
+	}
+	return errors.New("unknown feedback type " + feedbackType)
+}
+
+// GiveFeedback records feedback and rebuilds the profile in one transaction,
+// so the rebuild holds the stats row lock against concurrent post updates
+func (u *ProfileUpdater) GiveFeedback(ctx context.Context, userID, beverageID pgtype.UUID, feedbackType string) error {
+	return u.inTx(ctx, func(c *TasteProfileComputer) error {
+		return c.GiveFeedback(ctx, userID, beverageID, feedbackType)
+	})
+}
+
+// RemoveFeedback deletes feedback and rebuilds the profile in one transaction
+func (u *ProfileUpdater) RemoveFeedback(ctx context.Context, userID, beverageID pgtype.UUID, feedbackType string) error {
+	return u.inTx(ctx, func(c *TasteProfileComputer) error {
+		return c.RemoveFeedback(ctx, userID, beverageID, feedbackType)
+	})
+}
+
+// GiveFeedback records feedback on a beverage and rebuilds the user's
+// profile with it. Giving the same feedback again changes nothing. Like
+// ApplyPost it should run inside a transaction; ProfileUpdater.GiveFeedback
+// starts one.
+func (c *TasteProfileComputer) GiveFeedback(ctx context.Context, userID, beverageID pgtype.UUID, feedbackType string) error {
+	if err := checkFeedbackType(feedbackType); err != nil {
+		return err
+	}
+
+	_, err := c.Q.CreateRecommendationFeedback(ctx, sqlc.CreateRecommendationFeedbackParams{
+		UserID:       userID,
+		BeverageID:   beverageID,
+		FeedbackType: feedbackType,
+	})
+	// No row back means it was already given
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	return c.UpdateProfileWithFeedback(ctx, userID, beverageID, feedbackType)
+}
+
+// RemoveFeedback deletes feedback on a beverage and rebuilds the user's
+// profile without it, exactly undoing GiveFeedback. It should run inside a
+// transaction too.
+func (c *TasteProfileComputer) RemoveFeedback(ctx context.Context, userID, beverageID pgtype.UUID, feedbackType string) error {
+	if err := checkFeedbackType(feedbackType); err != nil {
+		return err
+	}
+	if err := c.Q.DeleteFeedback(ctx, sqlc.DeleteFeedbackParams{
+		UserID:       userID,
+		BeverageID:   beverageID,
+		FeedbackType: feedbackType,
+	}); err != nil {
+		return err
+	}
+	return c.UpdateProfileWithFeedback(ctx, userID, beverageID, feedbackType)
+}
+
+// replayFeedback applies the user's feedback log for a category to tag
+// weights built from their posts, oldest first. Each piece raises the
+// beverage's tags on one side and lowers them on the other; the result is
+// renormalized. Weights come back unchanged when there is no taste feedback.
+func (c *TasteProfileComputer) replayFeedback(ctx context.Context, userID pgtype.UUID, category string, liked, disliked TagWeights) (TagWeights, TagWeights, error) {
+	rows, err := c.Q.GetUserFeedbackForCategory(ctx, sqlc.GetUserFeedbackForCategoryParams{UserID: userID, Category: category})
+	if err != nil {
+		return nil, nil, err
+	}
+	var feedback []sqlc.RecommendationFeedback
+	var ids []pgtype.UUID
+	for _, f := range rows {
+		if changesTaste(f.FeedbackType) {
+			feedback = append(feedback, f)
+			ids = append(ids, f.BeverageID)
+		}
+	}
+	if len(feedback) == 0 {
+		return liked, disliked, nil
+	}
+	tagsByBeverage, err := loadBeverageTags(ctx, c.Q, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outLiked, outDisliked := make(TagWeights, len(liked)), make(TagWeights, len(disliked))
+	for tag, w := range liked {
+		outLiked[tag] = w
+	}
+	for tag, w := range disliked {
+		outDisliked[tag] = w
+	}
+	for _, f := range feedback {
+		raise, lower := outLiked, outDisliked
+		if f.FeedbackType == LessLikeThisFeedback {
+			raise, lower = outDisliked, outLiked
+		}
+		for _, tag := range tagsByBeverage[f.BeverageID] {
+			raise[tag.Tag] += feedbackTagWeight
+			if w, ok := lower[tag.Tag]; ok {
+				if w -= feedbackTagWeight; w > 0 {
+					lower[tag.Tag] = w
+				} else {
+					delete(lower, tag.Tag)
+				}
+			}
+		}
+	}
+	normalizeWeights(outLiked)
+	normalizeWeights(outDisliked)
+	return outLiked, outDisliked, nil
+}
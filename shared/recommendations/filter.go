@@ -0,0 +1,152 @@
+package recommendations
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RecommendationFilter narrows candidates before they are ranked. The
+// candidate queries apply it in SQL, so a page is never short because
+// ranked picks were filtered out afterwards. Zero values don't filter.
+type RecommendationFilter struct {
+	// Price bounds compare against the median price_cents posted for the
+	// beverage; beverages without a posted price fail any price bound
+	MinPriceCents *int32 `json:"min_price_cents,omitempty"`
+	MaxPriceCents *int32 `json:"max_price_cents,omitempty"`
+	// ABV bounds are percentages; beverages without an ABV fail any bound
+	MinABV *float64 `json:"min_abv,omitempty"`
+	MaxABV *float64 `json:"max_abv,omitempty"`
+	// Styles, Regions and Varietals match case-insensitively; any listed
+	// value matches
+	Styles    []string `json:"styles,omitempty"`
+	Regions   []string `json:"regions,omitempty"`
+	Varietals []string `json:"varietals,omitempty"`
+	// MinReviews is the fewest posts a beverage needs
+	MinReviews int32 `json:"min_reviews,omitempty"`
+	// ExcludeTried drops every beverage the user has ever posted, not just
+	// the recent ones recommendations always skip
+	ExcludeTried bool `json:"exclude_tried,omitempty"`
+}
+
+// Validate rejects negative and inverted bounds
+func (f RecommendationFilter) Validate() error {
+	if (f.MinPriceCents != nil && *f.MinPriceCents < 0) || (f.MaxPriceCents != nil && *f.MaxPriceCents < 0) {
+		return errors.New("price bounds must not be negative")
+	}
+	if f.MinPriceCents != nil && f.MaxPriceCents != nil && *f.MinPriceCents > *f.MaxPriceCents {
+		return errors.New("min_price_cents is above max_price_cents")
+	}
+	if (f.MinABV != nil && *f.MinABV < 0) || (f.MaxABV != nil && *f.MaxABV < 0) {
+		return errors.New("ABV bounds must not be negative")
+	}
+	if f.MinABV != nil && f.MaxABV != nil && *f.MinABV > *f.MaxABV {
+		return errors.New("min_abv is above max_abv")
+	}
+	if f.MinReviews < 0 {
+		return errors.New("min_reviews must not be negative")
+	}
+	return nil
+}
+
+// filterArgs is the filter as the candidate queries take it
+type filterArgs struct {
+	minReviews                   int32
+	minAbv, maxAbv               pgtype.Numeric
+	styles, regions, varietals   []string
+	excludeTried                 bool
+	minPriceCents, maxPriceCents pgtype.Int4
+}
+
+func (f RecommendationFilter) args() filterArgs {
+	a := filterArgs{
+		minReviews:   f.MinReviews,
+		styles:       lowerAll(f.Styles),
+		regions:      lowerAll(f.Regions),
+		varietals:    lowerAll(f.Varietals),
+		excludeTried: f.ExcludeTried,
+	}
+	if f.MinABV != nil {
+		a.minAbv = floatToNumeric(*f.MinABV)
+	}
+	if f.MaxABV != nil {
+		a.maxAbv = floatToNumeric(*f.MaxABV)
+	}
+	if f.MinPriceCents != nil {
+		a.minPriceCents = pgtype.Int4{Int32: *f.MinPriceCents, Valid: true}
+	}
+	if f.MaxPriceCents != nil {
+		a.maxPriceCents = pgtype.Int4{Int32: *f.MaxPriceCents, Valid: true}
+	}
+	return a
+}
+
+// lowerAll never returns nil: the queries test cardinality, which is NULL
+// rather than 0 for a NULL array
+func lowerAll(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (f RecommendationFilter) candidatesParams(category string, userID pgtype.UUID, limit int32) sqlc.GetRecommendationCandidatesParams {
+	a := f.args()
+	return sqlc.GetRecommendationCandidatesParams{
+		Category:      category,
+		UserID:        userID,
+		MinReviews:    a.minReviews,
+		MinAbv:        a.minAbv,
+		MaxAbv:        a.maxAbv,
+		Styles:        a.styles,
+		Regions:       a.regions,
+		Varietals:     a.varietals,
+		ExcludeTried:  a.excludeTried,
+		MinPriceCents: a.minPriceCents,
+		MaxPriceCents: a.maxPriceCents,
+		Limit:         limit,
+	}
+}
+
+func (f RecommendationFilter) byIDsParams(category string, userID pgtype.UUID, ids []pgtype.UUID) sqlc.GetRecommendationCandidatesByIDsParams {
+	a := f.args()
+	return sqlc.GetRecommendationCandidatesByIDsParams{
+		Category:      category,
+		Ids:           ids,
+		UserID:        userID,
+		MinReviews:    a.minReviews,
+		MinAbv:        a.minAbv,
+		MaxAbv:        a.maxAbv,
+		Styles:        a.styles,
+		Regions:       a.regions,
+		Varietals:     a.varietals,
+		ExcludeTried:  a.excludeTried,
+		MinPriceCents: a.minPriceCents,
+		MaxPriceCents: a.maxPriceCents,
+	}
+}
+
+func (f RecommendationFilter) venueParams(venue VenueRef, category string, userID pgtype.UUID, limit int32) sqlc.GetVenueCandidatesParams {
+	a := f.args()
+	return sqlc.GetVenueCandidatesParams{
+		VenueID:         venue.ID,
+		ExternalPlaceID: pgtype.Text{String: venue.ExternalPlaceID, Valid: venue.ExternalPlaceID != ""},
+		Category:        category,
+		UserID:          userID,
+		MinReviews:      a.minReviews,
+		MinAbv:          a.minAbv,
+		MaxAbv:          a.maxAbv,
+		Styles:          a.styles,
+		Regions:         a.regions,
+		Varietals:       a.varietals,
+		ExcludeTried:    a.excludeTried,
+		MinPriceCents:   a.minPriceCents,
+		MaxPriceCents:   a.maxPriceCents,
+		Limit:           limit,
+	}
+}
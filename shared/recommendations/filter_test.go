@@ -0,0 +1,105 @@
+package recommendations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRecommendationFilterValidate(t *testing.T) {
+	price := func(n int32) *int32 { return &n }
+	abv := func(f float64) *float64 { return &f }
+
+	valid := []RecommendationFilter{
+		{},
+		{MinPriceCents: price(0), MaxPriceCents: price(0)},
+		{MinABV: abv(4.5), MaxABV: abv(7)},
+		{Styles: []string{"IPA"}, MinReviews: 5, ExcludeTried: true},
+	}
+	for _, f := range valid {
+		if err := f.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", f, err)
+		}
+	}
+
+	invalid := []RecommendationFilter{
+		{MinPriceCents: price(-1)},
+		{MinPriceCents: price(2000), MaxPriceCents: price(1000)},
+		{MaxABV: abv(-0.5)},
+		{MinABV: abv(8), MaxABV: abv(5)},
+		{MinReviews: -1},
+	}
+	for _, f := range invalid {
+		if err := f.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid filter", f)
+		}
+	}
+}
+
+func TestRecommendationFilterArgs(t *testing.T) {
+	maxPrice := int32(1500)
+	minABV := 5.5
+	a := RecommendationFilter{
+		MaxPriceCents: &maxPrice,
+		MinABV:        &minABV,
+		Styles:        []string{" IPA ", "", "Pale Ale"},
+	}.args()
+
+	if !reflect.DeepEqual(a.styles, []string{"ipa", "pale ale"}) {
+		t.Errorf("styles = %q", a.styles)
+	}
+	// Empty lists must reach SQL as empty arrays, not NULL
+	if a.regions == nil || a.varietals == nil {
+		t.Error("unset lists passed as nil")
+	}
+	if a.minPriceCents.Valid || a.maxPriceCents != (pgtype.Int4{Int32: 1500, Valid: true}) {
+		t.Errorf("price bounds = %+v, %+v", a.minPriceCents, a.maxPriceCents)
+	}
+	if got, _ := a.minAbv.Float64Value(); !got.Valid || got.Float64 != 5.5 {
+		t.Errorf("min abv = %+v", got)
+	}
+	if a.maxAbv.Valid {
+		t.Error("unset max abv is valid")
+	}
+}
+
+func TestRankWithOptionsAppliesFilter(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "Big Lager", "beer", 9.0, 500, "crisp")
+	store.addBeverage(testUUID(2), "Hazy IPA", "beer", 8.0, 40, "hoppy")
+	store.addBeverage(testUUID(3), "Tried IPA", "beer", 8.5, 60, "hoppy")
+	store.addBeverage(testUUID(4), "Rare IPA", "beer", 8.0, 2, "hoppy")
+	for _, id := range []pgtype.UUID{testUUID(2), testUUID(3), testUUID(4)} {
+		b := store.beverages[id]
+		b.Style = pgtype.Text{String: "IPA", Valid: true}
+		store.beverages[id] = b
+	}
+	key := profileKey{user, "beer"}
+	store.posts[key] = append(store.posts[key], sqlc.GetUserPostsForCategoryRow{
+		ID:            testUUID(900),
+		UserID:        user,
+		DrinkCategory: "beer",
+		BeverageID:    testUUID(3),
+	})
+
+	opts := DefaultRankOptions(5)
+	opts.Filter = RecommendationFilter{Styles: []string{"ipa"}, MinReviews: 10, ExcludeTried: true}
+	results, err := NewRanker(store).RankWithOptions(context.Background(), user, "beer", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(results), []string{"Hazy IPA"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+
+	min, max := int32(2000), int32(1000)
+	opts.Filter = RecommendationFilter{MinPriceCents: &min, MaxPriceCents: &max}
+	if _, err := NewRanker(store).RankWithOptions(context.Background(), user, "beer", opts); err == nil {
+		t.Error("inverted price bounds accepted")
+	}
+}
@@ -3,8 +3,27 @@ module github.com/burkebarcode/backend/shared/recommendations
 go 1.25.3
 
 require (
+	github.com/burkebarcode/backend/shared/catalog v0.0.0-00010101000000-000000000000
 	github.com/burkebarcode/backend/shared/db v0.0.0-00010101000000-000000000000
+	github.com/burkebarcode/backend/shared/openai v0.0.0-00010101000000-000000000000
+	github.com/burkebarcode/backend/shared/rating v0.0.0-00010101000000-000000000000
+	github.com/google/uuid v1.6.0
 	github.com/jackc/pgx/v5 v5.7.6
 )
 
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/crypto v0.45.0 // indirect
+	golang.org/x/sync v0.18.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+)
+
+replace github.com/burkebarcode/backend/shared/catalog => ../catalog
+
 replace github.com/burkebarcode/backend/shared/db => ../db
+
+replace github.com/burkebarcode/backend/shared/openai => ../openai
+
+replace github.com/burkebarcode/backend/shared/rating => ../rating
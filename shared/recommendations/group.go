@@ -0,0 +1,207 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Strategies for combining group members' match scores
+const (
+	// LeastMiseryStrategy scores a beverage by its least happy member
+	LeastMiseryStrategy = "least_misery"
+	// AverageStrategy scores a beverage by the members' mean match
+	AverageStrategy = "average"
+)
+
+// maxGroupSize bounds the per-member queries a group request makes
+const maxGroupSize = 12
+
+// MemberScore is one group member's match with a beverage
+type MemberScore struct {
+	UserID     string   `json:"user_id"`
+	MatchScore int      `json:"match_score"` // 0-100
+	Reasons    []string `json:"reasons"`
+}
+
+// GroupBeverage is a group recommendation. MatchScore is the combined
+// score; Members has each member's own score, in request order.
+type GroupBeverage struct {
+	RankedBeverage
+	Strategy string        `json:"strategy"`
+	Members  []MemberScore `json:"members"`
+}
+
+// groupMember is a member's taste as ScoreBeverageForMatch sees it
+type groupMember struct {
+	id              pgtype.UUID
+	liked, disliked TagWeights
+	coldStart       bool
+}
+
+// RankForGroup recommends beverages for several users drinking together,
+// scoring each candidate per member with ScoreBeverageForMatch's scoring and
+// combining the scores with strategy. Candidates are those every member
+// could be recommended on their own, so nobody's hidden or recently posted
+// beverages come up. Nothing is logged as an impression.
+func (r *Ranker) RankForGroup(ctx context.Context, userIDs []pgtype.UUID, category, strategy string, opts RankOptions) ([]GroupBeverage, error) {
+	if strategy != LeastMiseryStrategy && strategy != AverageStrategy {
+		return nil, errors.New("unknown group strategy " + strategy)
+	}
+	if err := opts.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	userIDs = uniqueUsers(userIDs)
+	if len(userIDs) == 0 {
+		return nil, errors.New("group has no members")
+	}
+	if len(userIDs) > maxGroupSize {
+		return nil, errors.New("group has too many members")
+	}
+
+	members := make([]groupMember, len(userIDs))
+	for i, id := range userIDs {
+		liked, disliked, coldStart := r.loadMatchProfile(ctx, id, category)
+		members[i] = groupMember{id: id, liked: liked, disliked: disliked, coldStart: coldStart}
+	}
+
+	candidates, err := r.loadGroupCandidates(ctx, userIDs, category, opts)
+	if err != nil {
+		return nil, err
+	}
+	tagsByBeverage, err := r.loadCandidateTags(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	scored := make([]scoredCandidate, len(candidates))
+	memberScores := make(map[pgtype.UUID][]MemberScore, len(candidates))
+	for i, candidate := range candidates {
+		tags := tagsByBeverage[candidate.ID]
+		scores := make([]MemberScore, len(members))
+		for j, m := range members {
+			score, reasons := scoreMatch(tags, m.liked, m.disliked, m.coldStart)
+			scores[j] = MemberScore{
+				UserID:     uuid.UUID(m.id.Bytes).String(),
+				MatchScore: score,
+				Reasons:    reasons,
+			}
+		}
+		memberScores[candidate.ID] = scores
+		scored[i] = scoredCandidate{
+			bev:     candidate,
+			score:   combineGroupScores(scores, strategy),
+			reasons: sharedReasons(tags, members),
+			tags:    tags,
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	results := []GroupBeverage{}
+	for _, item := range rerankForDiversity(scored, int(opts.Limit), opts.Diversity, opts.Serendipity) {
+		ranked := item.ranked()
+		if len(ranked.Reasons) == 0 {
+			ranked.Reasons = []string{"Popular choice in this category"}
+		}
+		results = append(results, GroupBeverage{
+			RankedBeverage: ranked,
+			Strategy:       strategy,
+			Members:        memberScores[item.bev.ID],
+		})
+	}
+	return results, nil
+}
+
+// loadGroupCandidates returns the first member's candidates that are also
+// candidates for every other member, in the first member's order
+func (r *Ranker) loadGroupCandidates(ctx context.Context, userIDs []pgtype.UUID, category string, opts RankOptions) ([]sqlc.GetRecommendationCandidatesRow, error) {
+	// Members' exclusions shrink the shared pool, so fetch more than a single user would
+	limit := opts.Limit * 3 * int32(len(userIDs))
+
+	var candidates []sqlc.GetRecommendationCandidatesRow
+	for i, id := range userIDs {
+		rows, err := r.Q.GetRecommendationCandidates(ctx, opts.Filter.candidatesParams(category, id, limit))
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			candidates = rows
+			continue
+		}
+		allowed := make(map[pgtype.UUID]bool, len(rows))
+		for _, row := range rows {
+			allowed[row.ID] = true
+		}
+		kept := candidates[:0]
+		for _, c := range candidates {
+			if allowed[c.ID] {
+				kept = append(kept, c)
+			}
+		}
+		candidates = kept
+	}
+	return candidates, nil
+}
+
+// combineGroupScores folds member match scores into the group's score
+func combineGroupScores(scores []MemberScore, strategy string) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	if strategy == LeastMiseryStrategy {
+		lowest := scores[0].MatchScore
+		for _, s := range scores[1:] {
+			lowest = min(lowest, s.MatchScore)
+		}
+		return float64(lowest)
+	}
+	total := 0
+	for _, s := range scores {
+		total += s.MatchScore
+	}
+	return float64(total) / float64(len(scores))
+}
+
+// sharedReasons names the tags every member likes. A cold-start member
+// has no likes to share, so then there are none.
+func sharedReasons(tags []beverageTag, members []groupMember) []RecommendationReason {
+	for _, m := range members {
+		if m.coldStart {
+			return nil
+		}
+	}
+	var reasons []RecommendationReason
+	for _, tag := range tags {
+		weakest := math.Inf(1)
+		for _, m := range members {
+			weakest = min(weakest, m.liked[tag.Tag]-m.disliked[tag.Tag])
+		}
+		if weakest > tagReasonThreshold {
+			reasons = append(reasons, RecommendationReason{
+				Reason: "Everyone likes '" + tag.Tag + "'",
+				Score:  weakest,
+			})
+		}
+	}
+	return reasons
+}
+
+func uniqueUsers(userIDs []pgtype.UUID) []pgtype.UUID {
+	seen := make(map[pgtype.UUID]bool, len(userIDs))
+	out := make([]pgtype.UUID, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id.Valid && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
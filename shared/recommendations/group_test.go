@@ -0,0 +1,112 @@
+package recommendations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// splitGroup is two users who agree on IPAs and disagree on everything else
+func splitGroup(store *fakeStore) (hophead, stoutFan pgtype.UUID) {
+	hophead, stoutFan = testUUID(100), testUUID(101)
+	store.setProfile(hophead, "beer", TagWeights{"hoppy": 1.0, "citrus": 0.6}, TagWeights{"roasty": 0.9}, 12)
+	store.setProfile(stoutFan, "beer", TagWeights{"roasty": 1.0, "citrus": 0.5}, TagWeights{"hoppy": 0.2}, 12)
+	store.addBeverage(testUUID(1), "Double IPA", "beer", 8.0, 50, "hoppy")
+	store.addBeverage(testUUID(2), "Imperial Stout", "beer", 8.0, 50, "roasty")
+	store.addBeverage(testUUID(3), "Citrus Pale", "beer", 7.5, 40, "citrus")
+	return hophead, stoutFan
+}
+
+func TestRankForGroupStrategies(t *testing.T) {
+	store := newFakeStore()
+	hophead, stoutFan := splitGroup(store)
+	ranker := NewRanker(store)
+	ranker.Impressions = NewImpressionLogger(store)
+	group := []pgtype.UUID{hophead, stoutFan}
+
+	results, err := ranker.RankForGroup(context.Background(), group, "beer", LeastMiseryStrategy, DefaultRankOptions(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Everyone likes citrus; each of the others leaves one member unhappy
+	if len(results) != 3 || results[0].Name != "Citrus Pale" {
+		t.Fatalf("least misery results = %+v", results)
+	}
+	pale := results[0]
+	if len(pale.Members) != 2 || pale.Members[0].UserID != "00000000-0000-0000-0000-000000000064" {
+		t.Fatalf("members = %+v", pale.Members)
+	}
+	if pale.MatchScore != min(pale.Members[0].MatchScore, pale.Members[1].MatchScore) {
+		t.Errorf("group score %d, member scores %+v", pale.MatchScore, pale.Members)
+	}
+	if !reflect.DeepEqual(pale.Reasons, []string{"Everyone likes 'citrus'"}) {
+		t.Errorf("reasons = %v", pale.Reasons)
+	}
+
+	// Per-member scores are what ScoreBeverageForMatch gives each member
+	for _, b := range results {
+		for i, id := range group {
+			want, _, err := NewRanker(store).ScoreBeverageForMatch(context.Background(), id, beverageID(t, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Members[i].MatchScore != want {
+				t.Errorf("%s: member %d scored %d, ScoreBeverageForMatch %d", b.Name, i, b.Members[i].MatchScore, want)
+			}
+		}
+	}
+
+	avg, err := ranker.RankForGroup(context.Background(), group, "beer", AverageStrategy, DefaultRankOptions(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range avg {
+		want := (b.Members[0].MatchScore + b.Members[1].MatchScore) / 2
+		if b.MatchScore != want {
+			t.Errorf("%s average = %d, want %d", b.Name, b.MatchScore, want)
+		}
+	}
+	if store.calls["InsertRecommendationImpressions"] != 0 {
+		t.Error("group ranking logged impressions")
+	}
+}
+
+func TestRankForGroupSkipsBeveragesAnyMemberHid(t *testing.T) {
+	store := newFakeStore()
+	hophead, stoutFan := splitGroup(store)
+	store.hidden[stoutFan] = []pgtype.UUID{testUUID(3)}
+
+	results, err := NewRanker(store).RankForGroup(context.Background(), []pgtype.UUID{hophead, stoutFan, hophead}, "beer", AverageStrategy, DefaultRankOptions(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range results {
+		if b.Name == "Citrus Pale" {
+			t.Error("recommended a beverage a member hid")
+		}
+		if len(b.Members) != 2 {
+			t.Errorf("duplicate member scored: %+v", b.Members)
+		}
+	}
+}
+
+func TestRankForGroupRejectsBadRequests(t *testing.T) {
+	ranker := NewRanker(newFakeStore())
+	if _, err := ranker.RankForGroup(context.Background(), []pgtype.UUID{testUUID(1)}, "beer", "median", DefaultRankOptions(3)); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if _, err := ranker.RankForGroup(context.Background(), nil, "beer", AverageStrategy, DefaultRankOptions(3)); err == nil {
+		t.Error("empty group accepted")
+	}
+}
+
+func beverageID(t *testing.T, b GroupBeverage) pgtype.UUID {
+	t.Helper()
+	var id pgtype.UUID
+	if err := id.Scan(b.BeverageID); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
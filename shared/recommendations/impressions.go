@@ -0,0 +1,163 @@
+package recommendations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Surfaces that log impressions
+const (
+	SurfaceRecommendations = "recommendations"
+	SurfaceScan            = "scan"
+)
+
+// ImpressionLogger records which beverages recommendation surfaces showed,
+// and attributes a later post on a shown beverage to the impression
+type ImpressionLogger struct {
+	Q Store
+	// AttributionDays is how long after an impression a post still counts as a conversion
+	AttributionDays int32
+}
+
+func NewImpressionLogger(q Store) *ImpressionLogger {
+	return &ImpressionLogger{Q: q, AttributionDays: DefaultAttributionDays}
+}
+
+// LogList records a ranked list, 1-based rank by position, and sets each
+// beverage's ImpressionID. listID links the impressions to an experiment
+// list and may be invalid. Failures are logged, not returned.
+func (l *ImpressionLogger) LogList(ctx context.Context, userID, listID pgtype.UUID, beverages []RankedBeverage) {
+	if len(beverages) == 0 {
+		return
+	}
+	arg := sqlc.InsertRecommendationImpressionsParams{
+		UserID:  userID,
+		Surface: SurfaceRecommendations,
+		ListID:  listID,
+	}
+	for i, b := range beverages {
+		id, err := uuid.Parse(b.BeverageID)
+		if err != nil {
+			continue
+		}
+		arg.BeverageIds = append(arg.BeverageIds, pgtype.UUID{Bytes: id, Valid: true})
+		arg.Ranks = append(arg.Ranks, int32(i+1))
+		arg.MatchScores = append(arg.MatchScores, int32(b.MatchScore))
+	}
+
+	rows, err := l.Q.InsertRecommendationImpressions(ctx, arg)
+	if err != nil {
+		log.Printf("Failed to log recommendation impressions: %v", err)
+		return
+	}
+	impressions := make(map[string]string, len(rows))
+	for _, row := range rows {
+		impressions[uuid.UUID(row.BeverageID.Bytes).String()] = uuid.UUID(row.ID.Bytes).String()
+	}
+	for i := range beverages {
+		beverages[i].ImpressionID = impressions[beverages[i].BeverageID]
+	}
+}
+
+// LogScan records a single beverage scored for a scan result
+func (l *ImpressionLogger) LogScan(ctx context.Context, userID, beverageID pgtype.UUID, matchScore int) {
+	_, err := l.Q.InsertRecommendationImpressions(ctx, sqlc.InsertRecommendationImpressionsParams{
+		UserID:      userID,
+		Surface:     SurfaceScan,
+		BeverageIds: []pgtype.UUID{beverageID},
+		Ranks:       []int32{0},
+		MatchScores: []int32{int32(matchScore)},
+	})
+	if err != nil {
+		log.Printf("Failed to log scan impression: %v", err)
+	}
+}
+
+// RecordClick marks an impression clicked; backs POST /v1/recommendations/impressions/{id}/click
+func (l *ImpressionLogger) RecordClick(ctx context.Context, userID, impressionID pgtype.UUID) error {
+	return l.Q.RecordImpressionClick(ctx, sqlc.RecordImpressionClickParams{
+		ID:     impressionID,
+		UserID: userID,
+	})
+}
+
+// AttributeConversions marks impressions converted by posts created since
+// the given time and returns how many were marked
+func (l *ImpressionLogger) AttributeConversions(ctx context.Context, since time.Time) (int64, error) {
+	return l.Q.AttributeConversions(ctx, sqlc.AttributeConversionsParams{
+		CreatedAt:       pgtype.Timestamptz{Time: since, Valid: true},
+		AttributionDays: l.AttributionDays,
+	})
+}
+
+// Run attributes conversions once. Every run rescans the whole attribution
+// window, since a post can be linked to its beverage after it is created.
+func (l *ImpressionLogger) Run(ctx context.Context) error {
+	since := time.Now().AddDate(0, 0, -int(l.AttributionDays))
+	n, err := l.AttributeConversions(ctx, since)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("Recommendation conversion attribution marked %d impressions", n)
+	}
+	return nil
+}
+
+// ImpressionStats aggregates impressions for one surface, or one rank
+// within a surface
+type ImpressionStats struct {
+	Surface        string  `json:"surface"`
+	Rank           int     `json:"rank,omitempty"`
+	Impressions    int     `json:"impressions"`
+	Clicks         int     `json:"clicks"`
+	Conversions    int     `json:"conversions"`
+	CTR            float64 `json:"ctr"`
+	ConversionRate float64 `json:"conversion_rate"`
+}
+
+func newImpressionStats(surface string, rank pgtype.Int4, impressions, clicks, conversions int32) ImpressionStats {
+	return ImpressionStats{
+		Surface:        surface,
+		Rank:           int(rank.Int32),
+		Impressions:    int(impressions),
+		Clicks:         int(clicks),
+		Conversions:    int(conversions),
+		CTR:            ratio(int(clicks), int(impressions)),
+		ConversionRate: ratio(int(conversions), int(impressions)),
+	}
+}
+
+// StatsBySurface reports CTR and conversion per surface since the given time
+func (l *ImpressionLogger) StatsBySurface(ctx context.Context, since time.Time) ([]ImpressionStats, error) {
+	rows, err := l.Q.GetImpressionStatsBySurface(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ImpressionStats, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, newImpressionStats(row.Surface, pgtype.Int4{}, row.Impressions, row.Clicks, row.Conversions))
+	}
+	return out, nil
+}
+
+// StatsByRank reports CTR and conversion per list position for a surface
+func (l *ImpressionLogger) StatsByRank(ctx context.Context, surface string, since time.Time) ([]ImpressionStats, error) {
+	rows, err := l.Q.GetImpressionStatsByRank(ctx, sqlc.GetImpressionStatsByRankParams{
+		Surface:   surface,
+		CreatedAt: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ImpressionStats, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, newImpressionStats(surface, row.Rank, row.Impressions, row.Clicks, row.Conversions))
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package recommendations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRankRecommendationsLogsImpressions(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Lager", "beer", 8.0, 100, "crisp")
+	store.addBeverage(testUUID(2), "IPA", "beer", 7.0, 50, "hoppy")
+
+	ranker := NewRanker(store)
+	ranker.Impressions = NewImpressionLogger(store)
+	results, err := ranker.RankRecommendations(context.Background(), user, "beer", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.impressions) != 2 {
+		t.Fatalf("logged %d impressions", len(store.impressions))
+	}
+	for i, imp := range store.impressions {
+		if imp.Surface != SurfaceRecommendations || imp.Rank.Int32 != int32(i+1) || imp.ListID.Valid {
+			t.Errorf("impression %d = %+v", i, imp)
+		}
+		if uuid.UUID(imp.BeverageID.Bytes).String() != results[i].BeverageID || int(imp.MatchScore) != results[i].MatchScore {
+			t.Errorf("impression %d logged %v, served %+v", i, imp.BeverageID, results[i])
+		}
+		if results[i].ImpressionID != uuid.UUID(imp.ID.Bytes).String() {
+			t.Errorf("result %d impression id = %q", i, results[i].ImpressionID)
+		}
+	}
+
+	// RankWithOptions is also used by evaluation and experiments, so it doesn't log
+	if _, err := ranker.RankWithOptions(context.Background(), user, "beer", DefaultRankOptions(2)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.impressions) != 2 {
+		t.Errorf("RankWithOptions logged impressions")
+	}
+}
+
+func TestScoreBeverageForMatchLogsScanImpression(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "Lager", "beer", 8.0, 100, "crisp")
+
+	ranker := NewRanker(store)
+	ranker.Impressions = NewImpressionLogger(store)
+	score, _, err := ranker.ScoreBeverageForMatch(context.Background(), testUUID(100), testUUID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.impressions) != 1 {
+		t.Fatalf("logged %d impressions", len(store.impressions))
+	}
+	imp := store.impressions[0]
+	if imp.Surface != SurfaceScan || imp.Rank.Valid || int(imp.MatchScore) != score {
+		t.Errorf("scan impression = %+v", imp)
+	}
+}
+
+func TestExperimentRankerLinksImpressionsToList(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "Lager", "beer", 8.0, 100, "crisp")
+
+	ranker := NewExperimentRanker(store, splitExperiment(store))
+	ranker.Impressions = NewImpressionLogger(store)
+	if _, err := ranker.Recommend(context.Background(), testUUID(100), "beer", RankOptions{Limit: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.impressions) != 1 || store.impressions[0].ListID != store.lists[0].ID {
+		t.Errorf("impressions = %+v, list %v", store.impressions, store.lists[0].ID)
+	}
+}
+
+func TestImpressionStats(t *testing.T) {
+	store := newFakeStore()
+	logger := NewImpressionLogger(store)
+	user := testUUID(100)
+	for i := range 4 {
+		logger.LogList(context.Background(), user, pgtype.UUID{}, []RankedBeverage{
+			{BeverageID: uuid.UUID(testUUID(10 + i).Bytes).String()},
+			{BeverageID: uuid.UUID(testUUID(20 + i).Bytes).String()},
+		})
+	}
+	logger.LogScan(context.Background(), user, testUUID(1), 80)
+
+	// Click the top pick of two lists; one of them also converted
+	if err := logger.RecordClick(context.Background(), user, store.impressions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	logger.RecordClick(context.Background(), user, store.impressions[2].ID)
+	// Another user can't click someone else's impression
+	logger.RecordClick(context.Background(), testUUID(101), store.impressions[4].ID)
+	store.impressions[0].ConvertedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	bySurface, err := logger.StatsBySurface(context.Background(), time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bySurface) != 2 || bySurface[0].Surface != SurfaceRecommendations || bySurface[0].Impressions != 8 || bySurface[0].CTR != 0.25 {
+		t.Errorf("by surface = %+v", bySurface)
+	}
+
+	byRank, err := logger.StatsByRank(context.Background(), SurfaceRecommendations, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byRank) != 2 || byRank[0].Rank != 1 || byRank[0].CTR != 0.5 || byRank[0].ConversionRate != 0.25 || byRank[1].Clicks != 0 {
+		t.Errorf("by rank = %+v", byRank)
+	}
+}
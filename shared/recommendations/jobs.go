@@ -0,0 +1,195 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// jobTick is how often the runner checks leadership and due jobs
+	jobTick = 30 * time.Second
+	// JobLeaderLockKey is the advisory lock instances campaign on to run jobs
+	JobLeaderLockKey int64 = 0x6a6f6273 // "jobs"
+)
+
+// Leader elects the one instance that runs scheduled jobs
+type Leader interface {
+	// Acquire tries to become leader without waiting and reports whether
+	// this instance now leads
+	Acquire(ctx context.Context) (bool, error)
+	// Check returns an error once leadership has been lost
+	Check(ctx context.Context) error
+	// Release gives up leadership
+	Release(ctx context.Context)
+}
+
+// AdvisoryLeader elects a leader with a Postgres session advisory lock held
+// on a connection taken out of the pool. If the instance dies its session
+// ends and the lock passes to the next instance that campaigns.
+type AdvisoryLeader struct {
+	Pool *pgxpool.Pool
+	Key  int64
+
+	conn *pgxpool.Conn
+}
+
+func NewAdvisoryLeader(pool *pgxpool.Pool) *AdvisoryLeader {
+	return &AdvisoryLeader{Pool: pool, Key: JobLeaderLockKey}
+}
+
+func (l *AdvisoryLeader) Acquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		return true, nil
+	}
+	conn, err := l.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	locked, err := sqlc.New(conn).TryAdvisoryLock(ctx, l.Key)
+	if err != nil || !locked {
+		conn.Release()
+		return false, err
+	}
+	l.conn = conn
+	return true, nil
+}
+
+func (l *AdvisoryLeader) Check(ctx context.Context) error {
+	if l.conn == nil {
+		return errors.New("not leader")
+	}
+	held, err := sqlc.New(l.conn).HoldsAdvisoryLock(ctx, l.Key)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return errors.New("advisory lock no longer held")
+	}
+	return nil
+}
+
+func (l *AdvisoryLeader) Release(ctx context.Context) {
+	if l.conn == nil {
+		return
+	}
+	if _, err := sqlc.New(l.conn).AdvisoryUnlock(ctx, l.Key); err != nil {
+		// The lock goes with the session, so don't hand the connection back
+		log.Printf("Failed to release job leader lock: %v", err)
+		l.conn.Hijack().Close(context.Background())
+	} else {
+		l.conn.Release()
+	}
+	l.conn = nil
+}
+
+// Job is work the runner does on a schedule
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+}
+
+// JobRunner runs jobs on their schedules on whichever instance is leader.
+// Jobs run one at a time in the order they were added, so jobs sharing a
+// schedule can depend on the ones before them.
+type JobRunner struct {
+	Leader Leader
+	Jobs   []Job
+
+	leading bool
+	next    []time.Time
+	now     func() time.Time
+}
+
+func NewJobRunner(leader Leader) *JobRunner {
+	return &JobRunner{Leader: leader, now: time.Now}
+}
+
+// Add schedules run under a cron expression; see ParseSchedule. Jobs must
+// be added before Run.
+func (r *JobRunner) Add(name, spec string, run func(ctx context.Context) error) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	r.Jobs = append(r.Jobs, Job{Name: name, Schedule: schedule, Run: run})
+	return nil
+}
+
+// Run campaigns for leadership and runs due jobs while leading until ctx is
+// cancelled
+func (r *JobRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(jobTick)
+	defer ticker.Stop()
+	defer r.stepDown()
+
+	r.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+// tick confirms or takes leadership, then runs each job whose time has come.
+// A new leader schedules from the moment it took over, so runs missed
+// during a handover are skipped rather than caught up.
+func (r *JobRunner) tick(ctx context.Context) {
+	if r.leading {
+		if err := r.Leader.Check(ctx); err != nil {
+			log.Printf("Job runner lost leadership: %v", err)
+			r.stepDown()
+			return
+		}
+	} else {
+		ok, err := r.Leader.Acquire(ctx)
+		if err != nil {
+			log.Printf("Job runner leader election failed: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		log.Printf("Job runner is leader")
+		r.leading = true
+		now := r.now()
+		r.next = make([]time.Time, len(r.Jobs))
+		for i, job := range r.Jobs {
+			r.next[i] = job.Schedule.Next(now)
+		}
+	}
+
+	for i, job := range r.Jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if r.next[i].IsZero() || r.now().Before(r.next[i]) {
+			continue
+		}
+		started := r.now()
+		if err := job.Run(ctx); err != nil {
+			log.Printf("Job %s failed after %s: %v", job.Name, r.now().Sub(started).Round(time.Second), err)
+		} else {
+			log.Printf("Job %s finished in %s", job.Name, r.now().Sub(started).Round(time.Second))
+		}
+		// Runs that came due while the job was running are skipped
+		r.next[i] = job.Schedule.Next(r.now())
+	}
+}
+
+func (r *JobRunner) stepDown() {
+	if !r.leading {
+		return
+	}
+	r.Leader.Release(context.Background())
+	r.leading = false
+	r.next = nil
+}
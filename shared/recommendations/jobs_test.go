@@ -0,0 +1,132 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeLeader struct {
+	available bool
+	leading   bool
+	released  int
+}
+
+func (l *fakeLeader) Acquire(ctx context.Context) (bool, error) {
+	l.leading = l.leading || l.available
+	return l.leading, nil
+}
+
+func (l *fakeLeader) Check(ctx context.Context) error {
+	if !l.available {
+		return errors.New("lock lost")
+	}
+	return nil
+}
+
+func (l *fakeLeader) Release(ctx context.Context) {
+	l.leading = false
+	l.released++
+}
+
+func TestJobRunnerRunsOnlyWhileLeader(t *testing.T) {
+	leader := &fakeLeader{}
+	r := NewJobRunner(leader)
+	now := time.Date(2026, 3, 4, 2, 59, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	var ran []string
+	for _, name := range []string{"first", "second"} {
+		if err := r.Add(name, "0 3 * * *", func(ctx context.Context) error {
+			ran = append(ran, name)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	// Another instance leads through 3am
+	now = now.Add(90 * time.Second)
+	r.tick(ctx)
+	if len(ran) != 0 {
+		t.Fatalf("ran %v without leading", ran)
+	}
+
+	// Taking over schedules from now, so 3am isn't caught up
+	leader.available = true
+	r.tick(ctx)
+	if len(ran) != 0 {
+		t.Fatalf("ran %v on taking over", ran)
+	}
+
+	now = time.Date(2026, 3, 5, 3, 0, 10, 0, time.UTC)
+	r.tick(ctx)
+	r.tick(ctx)
+	if len(ran) != 2 || ran[0] != "first" || ran[1] != "second" {
+		t.Fatalf("ran %v, want each job once in order", ran)
+	}
+
+	// Losing the lock stops the runner until it wins it back
+	leader.available = false
+	now = time.Date(2026, 3, 6, 3, 0, 10, 0, time.UTC)
+	r.tick(ctx)
+	r.tick(ctx)
+	if len(ran) != 2 {
+		t.Errorf("ran %v after losing leadership", ran)
+	}
+	if leader.released != 1 {
+		t.Errorf("released %d times, want 1", leader.released)
+	}
+}
+
+func TestJobRunnerKeepsSchedulingAfterFailure(t *testing.T) {
+	r := NewJobRunner(&fakeLeader{available: true})
+	now := time.Date(2026, 3, 4, 10, 0, 30, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	runs := 0
+	if err := r.Add("flaky", "*/5 * * * *", func(ctx context.Context) error {
+		runs++
+		return errors.New("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	r.tick(ctx)
+	for range 2 {
+		now = now.Add(5 * time.Minute)
+		r.tick(ctx)
+	}
+	if runs != 2 {
+		t.Errorf("runs = %d, want 2", runs)
+	}
+}
+
+func TestScheduledJobsRegisterInOrder(t *testing.T) {
+	r := NewJobRunner(&fakeLeader{})
+	if err := AddNightlyJobs(r, nil, DefaultNightlySchedule, DefaultProfileStaleAfter, NewHashEmbedder(8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddFrequentJobs(r, nil, DefaultFrequentSchedule); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"refresh-beverage-stats", "rebuild-tag-aggregates", "rebuild-beverage-neighbors", "recompute-stale-profiles", "refresh-embeddings",
+		"refresh-beverage-attributes", "apply-profile-events", "attribute-conversions",
+	}
+	if len(r.Jobs) != len(want) {
+		t.Fatalf("%d jobs registered, want %d", len(r.Jobs), len(want))
+	}
+	for i, job := range r.Jobs {
+		if job.Name != want[i] {
+			t.Errorf("job %d = %s, want %s", i, job.Name, want[i])
+		}
+	}
+
+	if err := AddFrequentJobs(r, nil, "every minute"); err == nil {
+		t.Error("invalid schedule accepted")
+	}
+}
@@ -0,0 +1,239 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultNeighborsPerBeverage is how many neighbors are kept per beverage
+	DefaultNeighborsPerBeverage = 20
+	// DefaultMinCoRaters is how many users must have rated both beverages
+	DefaultMinCoRaters = 3
+
+	// neighborShrinkage damps similarities backed by few co-raters:
+	// sim * n / (n + shrinkage)
+	neighborShrinkage = 10.0
+)
+
+// NeighborCategories are the categories NeighborBuilder rebuilds
+var NeighborCategories = []string{"beer", "wine", "cocktail"}
+
+// NeighborBuilder computes beverage-to-beverage similarity from co-rating
+// patterns in posts: X and Y are neighbors when the users who rated X above
+// their own average also rated Y above it.
+type NeighborBuilder struct {
+	DB          ProfileDB
+	Q           *sqlc.Queries
+	TopN        int
+	MinCoRaters int
+}
+
+func NewNeighborBuilder(db ProfileDB) *NeighborBuilder {
+	return &NeighborBuilder{
+		DB:          db,
+		Q:           sqlc.New(db),
+		TopN:        DefaultNeighborsPerBeverage,
+		MinCoRaters: DefaultMinCoRaters,
+	}
+}
+
+// Build replaces the stored neighbors of every beverage in the category and
+// returns how many beverages got at least one neighbor
+func (b *NeighborBuilder) Build(ctx context.Context, category string) (int, error) {
+	ratings, err := b.Q.ListCoRatingInputs(ctx, category)
+	if err != nil {
+		return 0, err
+	}
+	neighbors := computeNeighbors(ratings, b.TopN, b.MinCoRaters)
+
+	tx, err := b.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := b.Q.WithTx(tx)
+	if err := q.DeleteBeverageNeighborsForCategory(ctx, category); err != nil {
+		return 0, err
+	}
+	for beverageID, list := range neighbors {
+		for _, n := range list {
+			if err := q.InsertBeverageNeighbor(ctx, sqlc.InsertBeverageNeighborParams{
+				BeverageID: beverageID,
+				NeighborID: n.BeverageID,
+				Similarity: n.Similarity,
+				CoRaters:   int32(n.CoRaters),
+			}); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(neighbors), nil
+}
+
+// Run rebuilds neighbors for every category; the job runner schedules it.
+// A failed category doesn't stop the others.
+func (b *NeighborBuilder) Run(ctx context.Context) error {
+	var errs []error
+	for _, category := range NeighborCategories {
+		n, err := b.Build(ctx, category)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", category, err))
+			continue
+		}
+		log.Printf("Beverage neighbor build stored neighbors for %d %s beverages", n, category)
+	}
+	return errors.Join(errs...)
+}
+
+// beverageNeighbor is one computed neighbor of a beverage
+type beverageNeighbor struct {
+	BeverageID pgtype.UUID
+	Similarity float64
+	CoRaters   int
+}
+
+// computeNeighbors returns the topN most similar beverages for each beverage
+// by adjusted cosine: ratings are centered on each user's mean, so a
+// neighbor is something the same people liked more than they usually do.
+// Pairs with fewer than minCoRaters users or no positive similarity are dropped.
+func computeNeighbors(ratings []sqlc.ListCoRatingInputsRow, topN, minCoRaters int) map[pgtype.UUID][]beverageNeighbor {
+	type centered struct {
+		beverageID pgtype.UUID
+		r          float64
+	}
+	byUser := make(map[pgtype.UUID][]centered)
+	for _, row := range ratings {
+		byUser[row.UserID] = append(byUser[row.UserID], centered{row.BeverageID, row.Rating})
+	}
+
+	type pairKey struct{ a, b pgtype.UUID }
+	type pairSums struct {
+		n          int
+		ab, aa, bb float64
+	}
+	pairs := make(map[pairKey]*pairSums)
+
+	for _, items := range byUser {
+		if len(items) < 2 {
+			continue
+		}
+		var sum float64
+		for _, it := range items {
+			sum += it.r
+		}
+		userMean := sum / float64(len(items))
+		for i := range items {
+			items[i].r -= userMean
+		}
+
+		for i := 0; i < len(items); i++ {
+			for j := i + 1; j < len(items); j++ {
+				a, b := items[i], items[j]
+				if uuidLess(b.beverageID, a.beverageID) {
+					a, b = b, a
+				}
+				k := pairKey{a.beverageID, b.beverageID}
+				p := pairs[k]
+				if p == nil {
+					p = &pairSums{}
+					pairs[k] = p
+				}
+				p.n++
+				p.ab += a.r * b.r
+				p.aa += a.r * a.r
+				p.bb += b.r * b.r
+			}
+		}
+	}
+
+	out := make(map[pgtype.UUID][]beverageNeighbor)
+	for k, p := range pairs {
+		if p.n < minCoRaters || p.aa == 0 || p.bb == 0 {
+			continue
+		}
+		sim := p.ab / math.Sqrt(p.aa*p.bb)
+		sim *= float64(p.n) / (float64(p.n) + neighborShrinkage)
+		if sim <= 0 {
+			continue
+		}
+		out[k.a] = append(out[k.a], beverageNeighbor{k.b, sim, p.n})
+		out[k.b] = append(out[k.b], beverageNeighbor{k.a, sim, p.n})
+	}
+
+	for id, list := range out {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Similarity != list[j].Similarity {
+				return list[i].Similarity > list[j].Similarity
+			}
+			return uuidLess(list[i].BeverageID, list[j].BeverageID)
+		})
+		if len(list) > topN {
+			list = list[:topN]
+		}
+		out[id] = list
+	}
+	return out
+}
+
+func uuidLess(a, b pgtype.UUID) bool {
+	for i := range a.Bytes {
+		if a.Bytes[i] != b.Bytes[i] {
+			return a.Bytes[i] < b.Bytes[i]
+		}
+	}
+	return false
+}
+
+// AlsoLikedBeverage is a co-rating neighbor shown on the beverage detail page
+type AlsoLikedBeverage struct {
+	BeverageID  string  `json:"beverage_id"`
+	Name        string  `json:"name"`
+	Brand       string  `json:"brand,omitempty"`
+	Category    string  `json:"category"`
+	AvgRating   float64 `json:"avg_rating"`
+	ReviewCount int     `json:"review_count"`
+	Similarity  float64 `json:"similarity"`
+	CoRaters    int     `json:"co_raters"`
+}
+
+// AlsoLiked backs GET /v1/beverages/{id}/also-liked: beverages that people
+// who liked this one also liked
+func (r *Ranker) AlsoLiked(ctx context.Context, beverageID pgtype.UUID, limit int32) ([]AlsoLikedBeverage, error) {
+	rows, err := r.Q.GetBeverageNeighbors(ctx, sqlc.GetBeverageNeighborsParams{
+		BeverageID: beverageID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]AlsoLikedBeverage, 0, len(rows))
+	for _, row := range rows {
+		avgRating, _ := rating.Average(row.AvgRating)
+		out = append(out, AlsoLikedBeverage{
+			BeverageID:  uuid.UUID(row.ID.Bytes).String(),
+			Name:        row.Name,
+			Brand:       row.Brand.String,
+			Category:    row.Category,
+			AvgRating:   avgRating,
+			ReviewCount: int(row.ReviewCount.Int32),
+			Similarity:  row.Similarity,
+			CoRaters:    int(row.CoRaters),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package recommendations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func coRating(user, beverage int, r float64) sqlc.ListCoRatingInputsRow {
+	return sqlc.ListCoRatingInputsRow{UserID: testUUID(user), BeverageID: testUUID(beverage), Rating: r}
+}
+
+func TestComputeNeighborsFromCoRatings(t *testing.T) {
+	// Beverages 1 and 2 are liked together; 3 is liked by the people who dislike 1
+	var ratings []sqlc.ListCoRatingInputsRow
+	for u := 100; u < 105; u++ {
+		ratings = append(ratings, coRating(u, 1, 9), coRating(u, 2, 8.5), coRating(u, 3, 4))
+	}
+	for u := 200; u < 203; u++ {
+		ratings = append(ratings, coRating(u, 1, 3), coRating(u, 2, 4), coRating(u, 3, 9))
+	}
+
+	got := computeNeighbors(ratings, 10, 3)
+
+	if len(got[testUUID(1)]) != 1 || got[testUUID(1)][0].BeverageID != testUUID(2) {
+		t.Fatalf("neighbors of 1 = %+v, want only 2", got[testUUID(1)])
+	}
+	n := got[testUUID(1)][0]
+	if n.CoRaters != 8 || n.Similarity <= 0 || n.Similarity >= 1 {
+		t.Errorf("neighbor 1-2 = %+v", n)
+	}
+	if got[testUUID(2)][0].Similarity != n.Similarity {
+		t.Error("similarity should be symmetric")
+	}
+	if _, ok := got[testUUID(3)]; ok {
+		t.Errorf("3 is anti-correlated and should have no neighbors, got %+v", got[testUUID(3)])
+	}
+}
+
+func TestComputeNeighborsMinCoRatersAndTopN(t *testing.T) {
+	var ratings []sqlc.ListCoRatingInputsRow
+	for u := 100; u < 102; u++ {
+		ratings = append(ratings, coRating(u, 1, 9), coRating(u, 2, 9), coRating(u, 9, 2))
+	}
+	if got := computeNeighbors(ratings, 10, 3); len(got) != 0 {
+		t.Errorf("two co-raters should not make neighbors, got %+v", got)
+	}
+
+	ratings = nil
+	for u := 100; u < 106; u++ {
+		ratings = append(ratings, coRating(u, 1, 9), coRating(u, 2, 9), coRating(u, 3, 9), coRating(u, 4, 9), coRating(u, 9, 2))
+	}
+	got := computeNeighbors(ratings, 2, 3)
+	if len(got[testUUID(1)]) != 2 {
+		t.Errorf("kept %d neighbors, want topN 2", len(got[testUUID(1)]))
+	}
+}
+
+func TestComputeNeighborsIgnoresSingleRatingUsers(t *testing.T) {
+	ratings := []sqlc.ListCoRatingInputsRow{
+		coRating(100, 1, 9), coRating(101, 1, 9), coRating(102, 2, 9),
+	}
+	if got := computeNeighbors(ratings, 10, 1); len(got) != 0 {
+		t.Errorf("got %+v, want no neighbors", got)
+	}
+}
+
+func TestRankRecommendationsBlendsCoRatings(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "Lager A", "beer", 8.0, 30, "crisp")
+	store.addBeverage(testUUID(2), "Lager B", "beer", 8.0, 20, "crisp")
+	store.addBeverage(testUUID(3), "Lager C", "beer", 8.0, 10, "crisp")
+	store.addBeverage(testUUID(4), "Hidden Porter", "beer", 7.0, 1, "roasty")
+	store.coRated[profileKey{user, "beer"}] = []sqlc.GetUserNeighborScoresRow{
+		{NeighborID: testUUID(4), Score: 1.6, BecauseName: "Old Stout"},
+		{NeighborID: testUUID(2), Score: 0.4, BecauseName: "Old Stout"},
+	}
+
+	ranker := NewRanker(store)
+	ranker.CoRatings = true
+	results, err := ranker.RankRecommendations(context.Background(), user, "beer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(results); !reflect.DeepEqual(got, []string{"Hidden Porter"}) {
+		t.Fatalf("results = %v", got)
+	}
+	if got := results[0].Reasons; !reflect.DeepEqual(got, []string{"People who liked Old Stout also liked this"}) {
+		t.Errorf("reasons = %v", got)
+	}
+}
+
+func TestRankRecommendationsSkipsCoRatingsWhenDisabled(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "IPA", "beer", 8.0, 10, "hoppy")
+
+	if _, err := NewRanker(store).RankRecommendations(context.Background(), user, "beer", 10); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.calls["GetUserNeighborScores"]; n != 0 {
+		t.Errorf("neighbor scores loaded %d times with CoRatings off", n)
+	}
+}
+
+func TestAlsoLiked(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "IPA", "beer", 8.0, 10)
+	store.addBeverage(testUUID(2), "Pale Ale", "beer", 7.5, 6)
+	store.addBeverage(testUUID(3), "DIPA", "beer", 8.5, 4)
+	store.neighbors[testUUID(1)] = []sqlc.BeverageNeighbor{
+		{BeverageID: testUUID(1), NeighborID: testUUID(2), Similarity: 0.4, CoRaters: 5},
+		{BeverageID: testUUID(1), NeighborID: testUUID(3), Similarity: 0.7, CoRaters: 3},
+	}
+
+	got, err := NewRanker(store).AlsoLiked(context.Background(), testUUID(1), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "DIPA" || got[1].Name != "Pale Ale" {
+		t.Fatalf("also liked = %+v", got)
+	}
+	if got[0].CoRaters != 3 || got[0].AvgRating != 8.5 {
+		t.Errorf("first = %+v", got[0])
+	}
+
+	none, err := NewRanker(store).AlsoLiked(context.Background(), pgtype.UUID{}, 10)
+	if err != nil || none == nil || len(none) != 0 {
+		t.Errorf("unknown beverage = %v, %v; want empty list", none, err)
+	}
+}
@@ -0,0 +1,76 @@
+package recommendations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/catalog"
+)
+
+const (
+	// DefaultNightlySchedule runs the nightly batch at 3am
+	DefaultNightlySchedule = "0 3 * * *"
+	// DefaultFrequentSchedule runs the catch-up jobs every minute
+	DefaultFrequentSchedule = "* * * * *"
+	// DefaultProfileStaleAfter is how long a profile can go without an update
+	// before the nightly batch recomputes it
+	DefaultProfileStaleAfter = 24 * time.Hour
+)
+
+type scheduledJob struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// AddNightlyJobs schedules the batch that catches up on anything requests
+// didn't trigger: beverage stats are refreshed from posts, tag aggregates
+// are rebuilt from post_tags, beverage neighbors are rebuilt from
+// co-ratings, then profiles not updated within staleAfter are recomputed on
+// top of them. With an embedder, beverage and user embeddings are refreshed
+// last, from the rebuilt tags and profiles.
+func AddNightlyJobs(r *JobRunner, db ProfileDB, spec string, staleAfter time.Duration, embedder Embedder) error {
+	u := NewProfileUpdater(db)
+	jobs := []scheduledJob{
+		{"refresh-beverage-stats", catalog.NewStatsReconciler(u.Q).Run},
+		{"rebuild-tag-aggregates", func(ctx context.Context) error {
+			n, err := u.Q.RebuildBeverageTagAggregates(ctx)
+			if err == nil {
+				log.Printf("Beverage tag aggregates rebuilt, %d rows changed", n)
+			}
+			return err
+		}},
+		{"rebuild-beverage-neighbors", NewNeighborBuilder(db).Run},
+		{"recompute-stale-profiles", func(ctx context.Context) error {
+			n, err := u.RecomputeUpdatedBefore(ctx, time.Now().Add(-staleAfter), profileRecomputeBatch)
+			log.Printf("Taste profile nightly recompute rebuilt %d profiles", n)
+			return err
+		}},
+	}
+	if embedder != nil {
+		jobs = append(jobs, scheduledJob{"refresh-embeddings", NewEmbeddingService(u.Q, embedder).Run})
+	}
+	return addJobs(r, spec, jobs)
+}
+
+// AddFrequentJobs schedules the jobs that work through queued changes:
+// beverage attributes are recomputed from new posts and tags, profile
+// events are applied and profiles due a full recompute are rebuilt, then
+// recent posts are attributed to the impressions that preceded them.
+func AddFrequentJobs(r *JobRunner, db ProfileDB, spec string) error {
+	u := NewProfileUpdater(db)
+	return addJobs(r, spec, []scheduledJob{
+		{"refresh-beverage-attributes", catalog.NewAttributeService(db).Run},
+		{"apply-profile-events", u.Run},
+		{"attribute-conversions", NewImpressionLogger(u.Q).Run},
+	})
+}
+
+func addJobs(r *JobRunner, spec string, jobs []scheduledJob) error {
+	for _, job := range jobs {
+		if err := r.Add(job.name, spec, job.run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
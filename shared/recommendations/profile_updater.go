@@ -0,0 +1,210 @@
+package recommendations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultFullRecomputeAfter is how long incremental updates run before a
+	// profile is rebuilt from scratch as a safety net
+	DefaultFullRecomputeAfter = 7 * 24 * time.Hour
+
+	profileEventBatch     = 200
+	profileRecomputeBatch = 50
+)
+
+// ProfileDB is a connection pool that can also start transactions;
+// *pgxpool.Pool satisfies it
+type ProfileDB interface {
+	sqlc.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// ProfileUpdater keeps taste profiles current from taste_profile_events,
+// which triggers queue when a post_tagging job finishes or a post is edited
+// or deleted
+type ProfileUpdater struct {
+	DB                 ProfileDB
+	Q                  *sqlc.Queries
+	FullRecomputeAfter time.Duration
+}
+
+func NewProfileUpdater(db ProfileDB) *ProfileUpdater {
+	return &ProfileUpdater{DB: db, Q: sqlc.New(db), FullRecomputeAfter: DefaultFullRecomputeAfter}
+}
+
+// ProcessEvents applies up to limit of the oldest queued events, each
+// affected post once. A post's events are deleted in the transaction that
+// applies them, so a failed update leaves them queued for the next run.
+// When applying a post fails its profile is recomputed in full instead.
+func (u *ProfileUpdater) ProcessEvents(ctx context.Context, limit int32) (int, error) {
+	events, err := u.Q.ListTasteProfileEvents(ctx, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	upTo := events[len(events)-1].ID
+
+	applied := 0
+	for _, e := range dedupeProfileEvents(events) {
+		ok, err := u.applyEvents(ctx, e, upTo, func(c *TasteProfileComputer) error {
+			return c.ApplyPost(ctx, e.UserID, e.Category, e.PostID)
+		})
+		if err != nil {
+			log.Printf("Taste profile update failed for user %v %s post %v, recomputing: %v", e.UserID, e.Category, e.PostID, err)
+			ok, err = u.applyEvents(ctx, e, upTo, func(c *TasteProfileComputer) error {
+				return c.ComputeProfile(ctx, e.UserID, e.Category)
+			})
+		}
+		if err != nil {
+			log.Printf("Taste profile recompute failed for user %v %s, events stay queued: %v", e.UserID, e.Category, err)
+			continue
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// applyEvents claims e's post's events up to upTo and runs fn in the same
+// transaction. It reports false without running fn when another worker
+// holds the events.
+func (u *ProfileUpdater) applyEvents(ctx context.Context, e sqlc.TasteProfileEvent, upTo int64, fn func(c *TasteProfileComputer) error) (bool, error) {
+	tx, err := u.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	q := u.Q.WithTx(tx)
+
+	claimed, err := q.DeleteTasteProfileEvents(ctx, sqlc.DeleteTasteProfileEventsParams{
+		UserID:   e.UserID,
+		Category: e.Category,
+		PostID:   e.PostID,
+		UpTo:     upTo,
+	})
+	if err != nil || claimed == 0 {
+		return false, err
+	}
+	if err := fn(NewTasteProfileComputer(q)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// RecomputeStale fully recomputes profiles that have no stats yet or whose
+// last full recompute is older than FullRecomputeAfter
+func (u *ProfileUpdater) RecomputeStale(ctx context.Context, limit int32) (int, error) {
+	cutoff := time.Now().Add(-u.FullRecomputeAfter)
+	due, err := u.Q.ListTasteProfilesDueForRecompute(ctx, sqlc.ListTasteProfilesDueForRecomputeParams{
+		FullRecomputeAt: pgtype.Timestamptz{Time: cutoff, Valid: true},
+		Limit:           limit,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	recomputed := 0
+	for _, p := range due {
+		err := u.inTx(ctx, func(c *TasteProfileComputer) error {
+			return c.ComputeProfile(ctx, p.UserID, p.Category)
+		})
+		if err != nil {
+			log.Printf("Taste profile recompute failed for user %v %s: %v", p.UserID, p.Category, err)
+			continue
+		}
+		recomputed++
+	}
+	return recomputed, nil
+}
+
+// RecomputeUpdatedBefore fully recomputes every profile not updated since
+// cutoff, oldest first, in batches of limit. Recomputing bumps updated_at,
+// so each batch picks up where the last left off; it stops when a whole
+// batch fails rather than retrying the same profiles.
+func (u *ProfileUpdater) RecomputeUpdatedBefore(ctx context.Context, cutoff time.Time, limit int32) (int, error) {
+	recomputed := 0
+	for ctx.Err() == nil {
+		stale, err := u.Q.ListTasteProfilesUpdatedBefore(ctx, sqlc.ListTasteProfilesUpdatedBeforeParams{
+			UpdatedAt: pgtype.Timestamptz{Time: cutoff, Valid: true},
+			Limit:     limit,
+		})
+		if err != nil {
+			return recomputed, err
+		}
+
+		batch := 0
+		for _, p := range stale {
+			err := u.inTx(ctx, func(c *TasteProfileComputer) error {
+				return c.ComputeProfile(ctx, p.UserID, p.Category)
+			})
+			if err != nil {
+				log.Printf("Taste profile recompute failed for user %v %s: %v", p.UserID, p.Category, err)
+				continue
+			}
+			batch++
+		}
+		recomputed += batch
+		if len(stale) < int(limit) || batch == 0 {
+			break
+		}
+	}
+	return recomputed, ctx.Err()
+}
+
+// Run drains profile events and recomputes stale profiles once; the job
+// runner schedules it
+func (u *ProfileUpdater) Run(ctx context.Context) error {
+	n, err := u.ProcessEvents(ctx, profileEventBatch)
+	if err != nil {
+		return err
+	}
+	log.Printf("Taste profile events applied %d post updates", n)
+
+	n, err = u.RecomputeStale(ctx, profileRecomputeBatch)
+	if err != nil {
+		return err
+	}
+	log.Printf("Taste profile recompute rebuilt %d profiles", n)
+	return nil
+}
+
+func (u *ProfileUpdater) inTx(ctx context.Context, fn func(c *TasteProfileComputer) error) error {
+	tx, err := u.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(NewTasteProfileComputer(u.Q.WithTx(tx))); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// dedupeProfileEvents keeps the first event per profile and post, since
+// ApplyPost reads the post's current state anyway
+func dedupeProfileEvents(events []sqlc.TasteProfileEvent) []sqlc.TasteProfileEvent {
+	type key struct {
+		userID   pgtype.UUID
+		category string
+		postID   pgtype.UUID
+	}
+	seen := make(map[key]bool)
+	out := make([]sqlc.TasteProfileEvent, 0, len(events))
+	for _, e := range events {
+		k := key{e.UserID, e.Category, e.PostID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, e)
+	}
+	return out
+}
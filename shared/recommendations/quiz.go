@@ -0,0 +1,251 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// quizBeverageCount is how many beverages the quiz shows per category
+	quizBeverageCount = 5
+	// quizBeveragePool is how many popular beverages they're picked from
+	quizBeveragePool = 30
+	// quizBeverageTags is how many of a beverage's top tags an answer covers
+	quizBeverageTags = 3
+	// quizFadePosts is how many posts it takes to blend the quiz out of a profile
+	quizFadePosts = 5
+)
+
+// Kinds of quiz item
+const (
+	QuizBeverageItem  = "beverage"
+	QuizStatementItem = "statement"
+)
+
+// QuizItem is one onboarding quiz question: a beverage to like or dislike,
+// or a flavor statement to agree or disagree with. Text is the beverage's
+// name or the statement.
+type QuizItem struct {
+	ID       string   `json:"id"`
+	Kind     string   `json:"kind"`
+	Text     string   `json:"text"`
+	Brand    string   `json:"brand,omitempty"`
+	ImageURL string   `json:"image_url,omitempty"`
+	Tags     []string `json:"tags"`
+}
+
+// QuizAnswer answers a QuizItem; for a statement, Liked means agreeing
+type QuizAnswer struct {
+	ItemID string `json:"item_id"`
+	Liked  bool   `json:"liked"`
+}
+
+// quizStatement is a flavor statement; agreeing likes its tags
+type quizStatement struct {
+	id   string
+	text string
+	tags []string
+}
+
+// quizStatements are the flavor statements asked per category
+var quizStatements = map[string][]quizStatement{
+	"wine": {
+		{"wine-dry", "I prefer dry wines to sweet ones", []string{"dry"}},
+		{"wine-fruity", "I like fruit-forward wines", []string{"fruity", "berry"}},
+		{"wine-oaky", "I enjoy oak and vanilla notes", []string{"oaky", "vanilla"}},
+		{"wine-bold", "I like bold, full-bodied reds", []string{"full-bodied", "tannic"}},
+		{"wine-crisp", "I like crisp, citrusy whites", []string{"crisp", "citrus"}},
+	},
+	"beer": {
+		{"beer-hoppy", "I like hoppy, bitter beers", []string{"hoppy", "bitter"}},
+		{"beer-roasty", "I enjoy dark, roasty beers", []string{"roasty", "coffee", "chocolate"}},
+		{"beer-sour", "I like sour or tart beers", []string{"sour", "tart"}},
+		{"beer-crisp", "I like light, crisp beers", []string{"crisp", "light-bodied"}},
+		{"beer-fruity", "I like fruity or citrusy beers", []string{"fruity", "citrus"}},
+	},
+	"cocktail": {
+		{"cocktail-sweet", "I like sweet cocktails", []string{"sweet"}},
+		{"cocktail-bitter", "I enjoy bitter, spirit-forward cocktails", []string{"bitter", "strong"}},
+		{"cocktail-sour", "I like sour, citrusy cocktails", []string{"sour", "citrus"}},
+		{"cocktail-smoky", "I like smoky flavors", []string{"smoky"}},
+		{"cocktail-herbal", "I enjoy herbal or floral notes", []string{"herbal", "floral"}},
+	},
+}
+
+// QuizItems returns the onboarding quiz for a category: popular beverages
+// picked to cover as many different tags as possible, then the category's
+// flavor statements
+func (c *TasteProfileComputer) QuizItems(ctx context.Context, category string) ([]QuizItem, error) {
+	statements, ok := quizStatements[category]
+	if !ok {
+		return nil, errors.New("no quiz for category " + category)
+	}
+
+	pool, err := c.Q.ListQuizBeverages(ctx, sqlc.ListQuizBeveragesParams{Category: category, Limit: quizBeveragePool})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]pgtype.UUID, len(pool))
+	for i, b := range pool {
+		ids[i] = b.ID
+	}
+	tagsByBeverage, err := loadBeverageTags(ctx, c.Q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []QuizItem{}
+	covered := make(map[string]bool)
+	picked := make(map[int]bool)
+	for len(picked) < min(quizBeverageCount, len(pool)) {
+		// The beverage adding the most uncovered tags; ties go to the more popular
+		best, bestNew := -1, -1
+		for i, b := range pool {
+			if picked[i] {
+				continue
+			}
+			n := 0
+			for _, tag := range quizTags(tagsByBeverage[b.ID]) {
+				if !covered[tag] {
+					n++
+				}
+			}
+			if n > bestNew {
+				best, bestNew = i, n
+			}
+		}
+		picked[best] = true
+		b := pool[best]
+		tags := quizTags(tagsByBeverage[b.ID])
+		for _, tag := range tags {
+			covered[tag] = true
+		}
+		items = append(items, QuizItem{
+			ID:       uuid.UUID(b.ID.Bytes).String(),
+			Kind:     QuizBeverageItem,
+			Text:     b.Name,
+			Brand:    b.Brand.String,
+			ImageURL: b.ImageUrl.String,
+			Tags:     tags,
+		})
+	}
+
+	for _, s := range statements {
+		items = append(items, QuizItem{ID: s.id, Kind: QuizStatementItem, Text: s.text, Tags: s.tags})
+	}
+	return items, nil
+}
+
+// AnswerQuiz records quiz answers and recomputes the user's profile for the
+// category. Before the user posts there, the profile is built from the
+// answers alone; ComputeProfile blends them out as posts accumulate.
+// Answering an item again replaces the earlier answer.
+func (c *TasteProfileComputer) AnswerQuiz(ctx context.Context, userID pgtype.UUID, category string, answers []QuizAnswer) error {
+	statements, ok := quizStatements[category]
+	if !ok {
+		return errors.New("no quiz for category " + category)
+	}
+	statementTags := make(map[string][]string, len(statements))
+	for _, s := range statements {
+		statementTags[s.id] = s.tags
+	}
+
+	args := make([]sqlc.UpsertTasteQuizAnswerParams, len(answers))
+	var beverageIDs []pgtype.UUID
+	for i, a := range answers {
+		args[i] = sqlc.UpsertTasteQuizAnswerParams{
+			UserID:   userID,
+			Category: category,
+			ItemID:   a.ItemID,
+			Liked:    a.Liked,
+			Tags:     statementTags[a.ItemID],
+		}
+		if args[i].Tags != nil {
+			continue
+		}
+		id, err := uuid.Parse(a.ItemID)
+		if err != nil {
+			return errors.New("unknown quiz item " + a.ItemID)
+		}
+		beverage, err := c.Q.GetBeverageByID(ctx, pgtype.UUID{Bytes: id, Valid: true})
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && beverage.Category != category) {
+			return errors.New("unknown quiz item " + a.ItemID)
+		}
+		if err != nil {
+			return err
+		}
+		// Canonical form, so answering by a differently cased ID replaces the answer
+		args[i].ItemID = id.String()
+		beverageIDs = append(beverageIDs, beverage.ID)
+	}
+
+	if len(beverageIDs) > 0 {
+		tagsByBeverage, err := loadBeverageTags(ctx, c.Q, beverageIDs)
+		if err != nil {
+			return err
+		}
+		for i := range args {
+			if args[i].Tags != nil {
+				continue
+			}
+			id, _ := uuid.Parse(args[i].ItemID)
+			args[i].Tags = quizTags(tagsByBeverage[pgtype.UUID{Bytes: id, Valid: true}])
+		}
+	}
+
+	for _, arg := range args {
+		if err := c.Q.UpsertTasteQuizAnswer(ctx, arg); err != nil {
+			return err
+		}
+	}
+	return c.ComputeProfile(ctx, userID, category)
+}
+
+// quizTags is the tags an answer on a beverage speaks to: its most used ones.
+// It is never nil, so an untagged beverage is still recorded.
+func quizTags(tags []beverageTag) []string {
+	out := []string{}
+	for _, t := range tags[:min(len(tags), quizBeverageTags)] {
+		out = append(out, t.Tag)
+	}
+	return out
+}
+
+// quizShare is how much of a profile comes from the quiz after posts posts
+func quizShare(posts int) float64 {
+	return max(0, 1-float64(posts)/quizFadePosts)
+}
+
+// quizWeights turns quiz answers into tag weights, normalized like a profile's
+func quizWeights(answers []sqlc.TasteQuizAnswer) (liked, disliked TagWeights) {
+	liked, disliked = make(TagWeights), make(TagWeights)
+	for _, a := range answers {
+		weights := disliked
+		if a.Liked {
+			weights = liked
+		}
+		for _, tag := range a.Tags {
+			weights[tag]++
+		}
+	}
+	normalizeWeights(liked)
+	normalizeWeights(disliked)
+	return liked, disliked
+}
+
+// blendWeights mixes two sets of tag weights, share of them from quiz
+func blendWeights(posts, quiz TagWeights, share float64) TagWeights {
+	out := make(TagWeights, len(posts)+len(quiz))
+	for tag, w := range posts {
+		out[tag] += (1 - share) * w
+	}
+	for tag, w := range quiz {
+		out[tag] += share * w
+	}
+	return out
+}
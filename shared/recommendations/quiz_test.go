@@ -0,0 +1,148 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestQuizItemsCoverDifferentTags(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "Hazy IPA", "beer", 8.0, 600, "hoppy", "citrus")
+	store.addBeverage(testUUID(2), "West Coast IPA", "beer", 8.0, 500, "hoppy", "citrus")
+	store.addBeverage(testUUID(3), "Stout", "beer", 8.0, 400, "roasty")
+	store.addBeverage(testUUID(4), "Gose", "beer", 8.0, 300, "sour")
+	store.addBeverage(testUUID(5), "Pilsner", "beer", 8.0, 200, "crisp")
+	store.addBeverage(testUUID(6), "Bock", "beer", 8.0, 100, "malty")
+	store.addBeverage(testUUID(7), "Untagged Lager", "beer", 8.0, 900)
+
+	items, err := NewTasteProfileComputer(store).QuizItems(context.Background(), "beer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var beverages []string
+	statements := 0
+	for _, item := range items {
+		switch item.Kind {
+		case QuizBeverageItem:
+			beverages = append(beverages, item.Text)
+		case QuizStatementItem:
+			statements++
+		}
+	}
+	// The second IPA adds no new tags, so the least popular beverage beats it
+	if want := []string{"Hazy IPA", "Stout", "Gose", "Pilsner", "Bock"}; !reflect.DeepEqual(beverages, want) {
+		t.Errorf("beverages = %v, want %v", beverages, want)
+	}
+	if statements != len(quizStatements["beer"]) {
+		t.Errorf("statements = %d, want %d", statements, len(quizStatements["beer"]))
+	}
+	if !reflect.DeepEqual(items[0].Tags, []string{"hoppy", "citrus"}) {
+		t.Errorf("tags = %v", items[0].Tags)
+	}
+
+	if _, err := NewTasteProfileComputer(store).QuizItems(context.Background(), "sake"); err == nil {
+		t.Error("quiz served for an unknown category")
+	}
+}
+
+func TestAnswerQuizSeedsProfileThatPostsBlendOut(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Hazy IPA", "beer", 8.0, 600, "hoppy", "citrus")
+	store.addBeverage(testUUID(2), "Popular Lager", "beer", 8.0, 900, "crisp")
+	c := NewTasteProfileComputer(store)
+
+	err := c.AnswerQuiz(context.Background(), user, "beer", []QuizAnswer{
+		{ItemID: uuid.UUID(testUUID(1).Bytes).String(), Liked: true},
+		{ItemID: "beer-sour", Liked: false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, liked, disliked := store.profile(t, user, "beer")
+	if p.PostCount.Int32 != 0 || p.QuizWeight != 1 {
+		t.Errorf("post count = %d, quiz weight = %v", p.PostCount.Int32, p.QuizWeight)
+	}
+	assertWeights(t, "liked", liked, TagWeights{"hoppy": 1.0, "citrus": 1.0})
+	assertWeights(t, "disliked", disliked, TagWeights{"sour": 1.0, "tart": 1.0})
+
+	// The quiz profile is personalized despite having no posts
+	results, err := NewRanker(store).RankRecommendations(context.Background(), user, "beer", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Name != "Hazy IPA" {
+		t.Errorf("results = %v, want the hoppy beer first", names(results))
+	}
+
+	store.addPost(user, "beer", testUUID(901), 9.0, "malty")
+	if err := c.ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+	p, liked, _ = store.profile(t, user, "beer")
+	if !approx(p.QuizWeight, 0.8) || !approx(liked["hoppy"], 0.8) {
+		t.Errorf("after one post: quiz weight = %v, liked = %v", p.QuizWeight, liked)
+	}
+
+	for i := range quizFadePosts - 1 {
+		store.addPost(user, "beer", testUUID(902+i), 9.0, "malty")
+	}
+	if err := c.ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+	p, liked, disliked = store.profile(t, user, "beer")
+	if p.QuizWeight != 0 || liked["hoppy"] != 0 || disliked["sour"] != 0 {
+		t.Errorf("after %d posts: quiz weight = %v, liked = %v, disliked = %v", quizFadePosts, p.QuizWeight, liked, disliked)
+	}
+}
+
+func TestAnswerQuizReplacesEarlierAnswer(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	c := NewTasteProfileComputer(store)
+
+	if err := c.AnswerQuiz(context.Background(), user, "wine", []QuizAnswer{{ItemID: "wine-dry", Liked: false}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AnswerQuiz(context.Background(), user, "wine", []QuizAnswer{{ItemID: "wine-dry", Liked: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, liked, disliked := store.profile(t, user, "wine")
+	assertWeights(t, "liked", liked, TagWeights{"dry": 1.0})
+	assertWeights(t, "disliked", disliked, TagWeights{})
+}
+
+func TestAnswerQuizRejectsUnknownItems(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Hazy IPA", "beer", 8.0, 600, "hoppy")
+	c := NewTasteProfileComputer(store)
+
+	for _, itemID := range []string{"beer-hoppy", "not-an-item", uuid.UUID(testUUID(2).Bytes).String(), uuid.UUID(testUUID(1).Bytes).String()} {
+		if err := c.AnswerQuiz(context.Background(), user, "wine", []QuizAnswer{{ItemID: itemID, Liked: true}}); err == nil {
+			t.Errorf("answer to %q accepted for wine", itemID)
+		}
+	}
+	if store.calls["UpsertTasteQuizAnswer"] != 0 {
+		t.Error("rejected answers were recorded")
+	}
+}
+
+func TestAnswerQuizPassesLookupErrorsThrough(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "Hazy IPA", "beer", 8.0, 600, "hoppy")
+	store.beverageErr = errors.New("connection reset")
+
+	itemID := uuid.UUID(testUUID(1).Bytes).String()
+	err := NewTasteProfileComputer(store).AnswerQuiz(context.Background(), testUUID(100), "beer", []QuizAnswer{{ItemID: itemID, Liked: true}})
+	if !errors.Is(err, store.beverageErr) {
+		t.Fatalf("error = %v, want the lookup error", err)
+	}
+}
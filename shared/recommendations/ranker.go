@@ -3,12 +3,16 @@ package recommendations
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"math"
 	"sort"
+	"time"
 
 	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -28,24 +32,81 @@ type RankedBeverage struct {
 	Reasons     []string                `json:"reasons"`
 	AvgRating   float64                 `json:"avg_rating"`
 	ReviewCount int                     `json:"review_count"`
+	Exploratory bool                    `json:"exploratory,omitempty"`
+	// ImpressionID identifies this showing for click tracking when impressions are logged
+	ImpressionID string `json:"impression_id,omitempty"`
+	// Set when ranking at a venue: how often and when the beverage was
+	// posted there, and the last price posted
+	VenueSightings     int        `json:"venue_sightings,omitempty"`
+	LastSeenAt         *time.Time `json:"last_seen_at,omitempty"`
+	LastSeenPriceCents *int       `json:"last_seen_price_cents,omitempty"`
 }
 
+const (
+	// coldStartPostCount is how many posts a profile needs before scoring is personalized
+	coldStartPostCount = 3
+	// tagReasonThreshold is the tag contribution that becomes a reason
+	tagReasonThreshold = 0.3
+
+	// embeddingBlendWeight is the score a perfect embedding match adds
+	embeddingBlendWeight = 30.0
+	// embeddingReasonThreshold is the similarity above which the match is given as a reason
+	embeddingReasonThreshold = 0.6
+
+	// neighborBlendWeight is the score the strongest co-rating neighbor adds
+	neighborBlendWeight = 25.0
+	// neighborReasonThreshold is the relative neighbor score above which the liked beverage is given as a reason
+	neighborReasonThreshold = 0.5
+)
+
 // Ranker ranks beverages for personalized recommendations
 type Ranker struct {
-	Q *sqlc.Queries
+	Q Store
+	// Embeddings adds embedding-similar candidates and blends similarity into
+	// personalized scores; nil when RECO_EMBEDDINGS_ENABLED is off
+	Embeddings *EmbeddingIndex
+	// CoRatings adds neighbors of the beverages the user liked from
+	// beverage_neighbors; enable once NeighborBuilder is running
+	CoRatings bool
+	// Impressions logs what RankRecommendations and ScoreBeverageForMatch
+	// show; nil disables logging
+	Impressions *ImpressionLogger
 }
 
-func NewRanker(q *sqlc.Queries) *Ranker {
+func NewRanker(q Store) *Ranker {
 	return &Ranker{Q: q}
 }
 
 // RankRecommendations generates personalized recommendations for a user
 func (r *Ranker) RankRecommendations(ctx context.Context, userID pgtype.UUID, category string, limit int32) ([]RankedBeverage, error) {
+	results, err := r.RankWithOptions(ctx, userID, category, DefaultRankOptions(limit))
+	if err != nil {
+		return nil, err
+	}
+	if r.Impressions != nil {
+		r.Impressions.LogList(ctx, userID, pgtype.UUID{}, results)
+	}
+	return results, nil
+}
+
+// RankWithOptions is RankRecommendations with per-request diversity and serendipity
+func (r *Ranker) RankWithOptions(ctx context.Context, userID pgtype.UUID, category string, opts RankOptions) ([]RankedBeverage, error) {
+	if err := opts.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
 	// Get user's taste profile
-	profile, err := r.Q.GetUserTasteProfile(ctx, sqlc.GetUserTasteProfileParams{
-		UserID:   userID,
-		Category: category,
-	})
+	params := sqlc.GetUserTasteProfileParams{UserID: userID, Category: category}
+	profile, err := r.Q.GetUserTasteProfile(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Never computed for this category; computing it can infer a profile
+		// from the user's other categories
+		if cerr := NewTasteProfileComputer(r.Q).ComputeProfile(ctx, userID, category); cerr != nil {
+			log.Printf("Failed to compute missing taste profile: %v", cerr)
+		} else {
+			profile, err = r.Q.GetUserTasteProfile(ctx, params)
+		}
+	}
 
 	var likedTags TagWeights
 	var dislikedTags TagWeights
@@ -61,18 +122,21 @@ func (r *Ranker) RankRecommendations(ctx context.Context, userID pgtype.UUID, ca
 		json.Unmarshal(profile.LikedTagsJson, &likedTags)
 		json.Unmarshal(profile.DislikedTagsJson, &dislikedTags)
 
-		// Also cold start if user has very few posts
-		if profile.PostCount.Int32 < 3 {
+		// Also cold start if user has very few posts and nothing inferred
+		if !personalized(profile) {
 			coldStart = true
 		}
 	}
 
 	// Get candidate beverages
-	candidates, err := r.Q.GetRecommendationCandidates(ctx, sqlc.GetRecommendationCandidatesParams{
-		Category: category,
-		UserID:   userID,
-		Limit:    limit * 3, // Get more candidates than needed for better ranking
-	})
+	var candidates []sqlc.GetRecommendationCandidatesRow
+	var sightings map[pgtype.UUID]*venueSighting
+	if opts.Venue != nil {
+		candidates, sightings, err = r.loadVenueCandidates(ctx, userID, category, *opts.Venue, opts.Filter, limit*3)
+	} else {
+		// Get more candidates than needed for better ranking
+		candidates, err = r.Q.GetRecommendationCandidates(ctx, opts.Filter.candidatesParams(category, userID, limit*3))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -81,106 +145,160 @@ func (r *Ranker) RankRecommendations(ctx context.Context, userID pgtype.UUID, ca
 		return []RankedBeverage{}, nil
 	}
 
+	// At a venue the candidates are what the venue has, so other signals
+	// don't add any
+	var similarity map[pgtype.UUID]float64
+	if !coldStart && r.Embeddings != nil && opts.Venue == nil {
+		candidates, similarity = r.addEmbeddingCandidates(ctx, userID, category, limit, opts.Filter, candidates)
+	}
+	var coRated map[pgtype.UUID]neighborSignal
+	if !coldStart && r.CoRatings && opts.Venue == nil {
+		candidates, coRated = r.addNeighborCandidates(ctx, userID, category, limit, opts.Filter, candidates)
+	}
+
+	// Cold start ranks on popularity alone, so tags are only needed for personalized scoring
+	var tagsByBeverage map[pgtype.UUID][]beverageTag
+	if !coldStart {
+		tagsByBeverage, err = r.loadCandidateTags(ctx, candidates)
+		if err != nil {
+			log.Printf("Failed to load candidate tags, falling back to popularity: %v", err)
+			coldStart = true
+		}
+	}
+
 	// Rank each candidate
-	scored := make([]struct {
-		bev     sqlc.GetRecommendationCandidatesRow
-		score   float64
-		reasons []RecommendationReason
-	}, len(candidates))
+	scored := make([]scoredCandidate, len(candidates))
+	now := time.Now()
 
 	for i, candidate := range candidates {
-		score, reasons := r.scoreBeverage(ctx, candidate, likedTags, dislikedTags, coldStart)
-		scored[i] = struct {
-			bev     sqlc.GetRecommendationCandidatesRow
-			score   float64
-			reasons []RecommendationReason
-		}{candidate, score, reasons}
+		b := breakdownScore(candidate, tagsByBeverage[candidate.ID], likedTags, dislikedTags, coldStart)
+		if sim, ok := similarity[candidate.ID]; ok && !coldStart {
+			b.addEmbedding(sim)
+		}
+		if signal, ok := coRated[candidate.ID]; ok && !coldStart {
+			b.addCoRating(signal)
+		}
+		sighting := sightings[candidate.ID]
+		if sighting != nil {
+			b.addVenueSighting(sightingRecency(sighting.lastSeen, now))
+		}
+		scored[i] = scoredCandidate{
+			bev:      candidate,
+			score:    b.Total,
+			reasons:  b.Reasons,
+			tags:     tagsByBeverage[candidate.ID],
+			sighting: sighting,
+		}
 	}
 
 	// Sort by score descending
-	sort.Slice(scored, func(i, j int) bool {
+	sort.SliceStable(scored, func(i, j int) bool {
 		return scored[i].score > scored[j].score
 	})
 
-	// Convert to output format (limit results)
-	results := []RankedBeverage{}
-	for i := 0; i < len(scored) && i < int(limit); i++ {
-		item := scored[i]
+	// Re-rank so the page isn't several near-copies of the top pick
+	picked := rerankForDiversity(scored, int(limit), opts.Diversity, opts.Serendipity)
 
-		// Extract top 2-3 reasons
-		topReasons := extractTopReasons(item.reasons, 3)
+	// Convert to output format
+	results := []RankedBeverage{}
+	for _, item := range picked {
+		ranked := item.ranked()
+		// Say when the profile was inferred from other categories
+		if !coldStart && len(profile.InferredFrom) > 0 {
+			ranked.Reasons = append([]string{inferredReason(profile.InferredFrom)}, ranked.Reasons[:min(len(ranked.Reasons), 2)]...)
+		}
+		results = append(results, ranked)
+	}
 
-		matchScore := int(math.Min(100, math.Max(0, item.score)))
+	return results, nil
+}
 
-		bevID := ""
-		if item.bev.ID.Valid {
-			bevID = uuid.UUID(item.bev.ID.Bytes).String()
-		}
+// ranked converts a scored pick to its API form with its top reasons
+func (item scoredCandidate) ranked() RankedBeverage {
+	// Extract top 2-3 reasons
+	topReasons := extractTopReasons(item.reasons, 3)
+	if item.exploratory {
+		topReasons = append([]string{"Something different to try"}, topReasons[:min(len(topReasons), 2)]...)
+	}
 
-		brand := ""
-		if item.bev.Brand.Valid {
-			brand = item.bev.Brand.String
-		}
+	matchScore := int(math.Min(100, math.Max(0, item.score)))
 
-		avgRating := 0.0
-		if rating, ok := item.bev.AvgRating.(float64); ok {
-			avgRating = rating
-		}
+	bevID := ""
+	if item.bev.ID.Valid {
+		bevID = uuid.UUID(item.bev.ID.Bytes).String()
+	}
 
-		results = append(results, RankedBeverage{
-			BeverageID:  bevID,
-			Name:        item.bev.Name,
-			Brand:       brand,
-			Category:    item.bev.Category,
-			MatchScore:  matchScore,
-			Reasons:     topReasons,
-			AvgRating:   avgRating,
-			ReviewCount: int(item.bev.ReviewCount),
-		})
+	brand := ""
+	if item.bev.Brand.Valid {
+		brand = item.bev.Brand.String
 	}
 
-	return results, nil
+	avgRating, _ := rating.Average(item.bev.AvgRating)
+
+	ranked := RankedBeverage{
+		BeverageID:  bevID,
+		Name:        item.bev.Name,
+		Brand:       brand,
+		Category:    item.bev.Category,
+		MatchScore:  matchScore,
+		Reasons:     topReasons,
+		AvgRating:   avgRating,
+		ReviewCount: int(item.bev.ReviewCount.Int32),
+		Exploratory: item.exploratory,
+	}
+	if item.sighting != nil {
+		ranked.VenueSightings = item.sighting.sightings
+		ranked.LastSeenAt = &item.sighting.lastSeen
+		if item.sighting.priceCents > 0 {
+			price := int(item.sighting.priceCents)
+			ranked.LastSeenPriceCents = &price
+		}
+	}
+	return ranked
 }
 
 // ScoreBeverageForMatch scores a single beverage for a user (used in scan results)
 func (r *Ranker) ScoreBeverageForMatch(ctx context.Context, userID pgtype.UUID, beverageID pgtype.UUID) (int, []string, error) {
-	// Get beverage with tags
-	beverage, err := r.Q.GetBeverageWithTags(ctx, beverageID)
+	beverage, err := r.Q.GetBeverageByID(ctx, beverageID)
 	if err != nil {
 		return 0, nil, err
 	}
 
-	// Get user's taste profile
-	profile, err := r.Q.GetUserTasteProfile(ctx, sqlc.GetUserTasteProfileParams{
-		UserID:   userID,
-		Category: beverage.Category,
-	})
-
-	var likedTags TagWeights
-	var dislikedTags TagWeights
-	coldStart := false
+	likedTags, dislikedTags, coldStart := r.loadMatchProfile(ctx, userID, beverage.Category)
 
-	if err != nil || profile.PostCount.Int32 < 3 {
-		coldStart = true
-		likedTags = make(TagWeights)
-		dislikedTags = make(TagWeights)
-	} else {
-		json.Unmarshal(profile.LikedTagsJson, &likedTags)
-		json.Unmarshal(profile.DislikedTagsJson, &dislikedTags)
+	tagsByBeverage, err := loadBeverageTags(ctx, r.Q, []pgtype.UUID{beverageID})
+	if err != nil {
+		return 0, nil, err
 	}
+	matchScore, topReasons := scoreMatch(tagsByBeverage[beverageID], likedTags, dislikedTags, coldStart)
 
-	// Parse beverage tags
-	var beverageTags []struct {
-		Tag     string `json:"tag"`
-		TagType string `json:"tag_type"`
-		Count   int    `json:"count"`
+	if r.Impressions != nil {
+		r.Impressions.LogScan(ctx, userID, beverageID, matchScore)
 	}
-	if beverage.TagsJson != nil {
-		if tagsBytes, ok := beverage.TagsJson.([]byte); ok {
-			json.Unmarshal(tagsBytes, &beverageTags)
-		}
+
+	return matchScore, topReasons, nil
+}
+
+// loadMatchProfile loads the tag weights ScoreBeverageForMatch scores with;
+// coldStart is set when the user has no profile or too few posts and
+// nothing inferred
+func (r *Ranker) loadMatchProfile(ctx context.Context, userID pgtype.UUID, category string) (likedTags, dislikedTags TagWeights, coldStart bool) {
+	profile, err := r.Q.GetUserTasteProfile(ctx, sqlc.GetUserTasteProfileParams{
+		UserID:   userID,
+		Category: category,
+	})
+
+	if err != nil || !personalized(profile) {
+		return make(TagWeights), make(TagWeights), true
 	}
+	json.Unmarshal(profile.LikedTagsJson, &likedTags)
+	json.Unmarshal(profile.DislikedTagsJson, &dislikedTags)
+	return likedTags, dislikedTags, false
+}
 
+// scoreMatch is ScoreBeverageForMatch's scoring of one beverage's tags
+// against one user's tag weights
+func scoreMatch(beverageTags []beverageTag, likedTags, dislikedTags TagWeights, coldStart bool) (int, []string) {
 	// Score based on tags
 	score := 50.0 // Base score for cold start
 	reasons := []RecommendationReason{}
@@ -217,95 +335,149 @@ func (r *Ranker) ScoreBeverageForMatch(ctx context.Context, userID pgtype.UUID,
 	if coldStart || len(topReasons) == 0 {
 		topReasons = []string{"Popular choice in this category"}
 	}
-
-	return matchScore, topReasons, nil
+	return matchScore, topReasons
 }
 
-// scoreBeverage calculates a score for a candidate beverage
-func (r *Ranker) scoreBeverage(ctx context.Context, candidate sqlc.GetRecommendationCandidatesRow, likedTags, dislikedTags TagWeights, coldStart bool) (float64, []RecommendationReason) {
-	// Get beverage tags
-	var beverageID pgtype.UUID
-	if candidate.ID.Valid {
-		beverageID = candidate.ID
+// addEmbeddingCandidates appends the beverages nearest the user's embedding
+// to the popularity candidates and returns similarity for all of them.
+// Failures are logged and leave the candidates unchanged.
+func (r *Ranker) addEmbeddingCandidates(ctx context.Context, userID pgtype.UUID, category string, limit int32, filter RecommendationFilter, candidates []sqlc.GetRecommendationCandidatesRow) ([]sqlc.GetRecommendationCandidatesRow, map[pgtype.UUID]float64) {
+	embedding, err := r.Q.GetUserEmbedding(ctx, sqlc.GetUserEmbeddingParams{
+		UserID:   userID,
+		Category: category,
+	})
+	if err != nil || len(embedding.EmbeddingVector) == 0 || embedding.Model.String != r.Embeddings.Model {
+		return candidates, nil
 	}
 
-	beverage, err := r.Q.GetBeverageWithTags(ctx, beverageID)
+	nearest, err := r.Embeddings.Nearest(ctx, category, embedding.EmbeddingVector, int(limit))
 	if err != nil {
-		log.Printf("Failed to get tags for beverage: %v", err)
-		// Fall back to global score
-		avgRating := 0.0
-		if rating, ok := candidate.AvgRating.(float64); ok {
-			avgRating = rating
-		}
-		return avgRating * 10, []RecommendationReason{{Reason: "Popular choice", Score: avgRating}}
+		log.Printf("Embedding candidate lookup failed: %v", err)
+		return candidates, nil
 	}
 
-	var beverageTags []struct {
-		Tag     string `json:"tag"`
-		TagType string `json:"tag_type"`
-		Count   int    `json:"count"`
-	}
-	if beverage.TagsJson != nil {
-		if tagsBytes, ok := beverage.TagsJson.([]byte); ok {
-			json.Unmarshal(tagsBytes, &beverageTags)
-		}
+	ids := make([]pgtype.UUID, len(nearest))
+	for i, m := range nearest {
+		ids[i] = m.BeverageID
 	}
+	candidates = r.addCandidatesByID(ctx, userID, category, ids, filter, candidates)
 
-	reasons := []RecommendationReason{}
+	ids = make([]pgtype.UUID, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+	similarity, err := r.Embeddings.Similarities(ctx, category, embedding.EmbeddingVector, ids)
+	if err != nil {
+		log.Printf("Embedding similarity failed: %v", err)
+		return candidates, nil
+	}
+	return candidates, similarity
+}
 
-	if coldStart {
-		// Cold start: rank by global popularity
-		avgRating := 0.0
-		if rating, ok := candidate.AvgRating.(float64); ok {
-			avgRating = rating
-		}
-		reviewCount := float64(candidate.ReviewCount)
+// neighborSignal is a candidate's co-rating score relative to the user's
+// strongest neighbor, and the liked beverage that contributes most to it
+type neighborSignal struct {
+	weight  float64
+	because string
+}
 
-		// Score = avg_rating * 10 + log(review_count + 1) * 5
-		popularityBonus := math.Log(reviewCount+1) * 5
-		score := avgRating*10 + popularityBonus
+// addNeighborCandidates appends co-rating neighbors of the user's liked
+// beverages to the candidates and returns their neighbor signal.
+// Failures are logged and leave the candidates unchanged.
+func (r *Ranker) addNeighborCandidates(ctx context.Context, userID pgtype.UUID, category string, limit int32, filter RecommendationFilter, candidates []sqlc.GetRecommendationCandidatesRow) ([]sqlc.GetRecommendationCandidatesRow, map[pgtype.UUID]neighborSignal) {
+	rows, err := r.Q.GetUserNeighborScores(ctx, sqlc.GetUserNeighborScoresParams{
+		UserID:        userID,
+		DrinkCategory: category,
+		Limit:         limit * 3,
+	})
+	if err != nil {
+		log.Printf("Co-rating neighbor lookup failed: %v", err)
+		return candidates, nil
+	}
+	if len(rows) == 0 || rows[0].Score <= 0 {
+		return candidates, nil
+	}
 
-		reasons = append(reasons, RecommendationReason{
-			Reason: "Highly rated",
-			Score:  avgRating,
-		})
+	// Rows are ordered by score, so the first is the strongest
+	top := rows[0].Score
+	signals := make(map[pgtype.UUID]neighborSignal, len(rows))
+	ids := make([]pgtype.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.NeighborID
+		signals[row.NeighborID] = neighborSignal{weight: row.Score / top, because: row.BecauseName}
+	}
+	return r.addCandidatesByID(ctx, userID, category, ids, filter, candidates), signals
+}
 
-		return score, reasons
+// addCandidatesByID appends the beverages in ids that aren't candidates yet,
+// applying the same exclusions and filter as GetRecommendationCandidates
+func (r *Ranker) addCandidatesByID(ctx context.Context, userID pgtype.UUID, category string, ids []pgtype.UUID, filter RecommendationFilter, candidates []sqlc.GetRecommendationCandidatesRow) []sqlc.GetRecommendationCandidatesRow {
+	have := make(map[pgtype.UUID]bool, len(candidates))
+	for _, c := range candidates {
+		have[c.ID] = true
+	}
+	var missing []pgtype.UUID
+	for _, id := range ids {
+		if !have[id] {
+			have[id] = true
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return candidates
 	}
 
-	// Personalized scoring based on tags
-	baseScore := 0.0
-	for _, tag := range beverageTags {
-		likeWeight := likedTags[tag.Tag]
-		dislikeWeight := dislikedTags[tag.Tag]
+	extra, err := r.Q.GetRecommendationCandidatesByIDs(ctx, filter.byIDsParams(category, userID, missing))
+	if err != nil {
+		log.Printf("Failed to load extra candidates: %v", err)
+		return candidates
+	}
+	for _, row := range extra {
+		candidates = append(candidates, sqlc.GetRecommendationCandidatesRow(row))
+	}
+	return candidates
+}
 
-		contribution := likeWeight - dislikeWeight
-		baseScore += contribution
+// beverageTag is one aggregated tag on a candidate beverage
+type beverageTag struct {
+	Tag     string
+	TagType string
+	Count   int
+}
 
-		if contribution > 0.3 {
-			reasons = append(reasons, RecommendationReason{
-				Reason: "You rate '" + tag.Tag + "' higher",
-				Score:  contribution,
-			})
-		} else if contribution < -0.3 {
-			reasons = append(reasons, RecommendationReason{
-				Reason: "You rate '" + tag.Tag + "' lower",
-				Score:  contribution,
-			})
+// loadCandidateTags fetches tags for every candidate in a single query
+func (r *Ranker) loadCandidateTags(ctx context.Context, candidates []sqlc.GetRecommendationCandidatesRow) (map[pgtype.UUID][]beverageTag, error) {
+	ids := make([]pgtype.UUID, 0, len(candidates))
+	for _, c := range candidates {
+		if c.ID.Valid {
+			ids = append(ids, c.ID)
 		}
 	}
+	return loadBeverageTags(ctx, r.Q, ids)
+}
 
-	// Add global popularity bonus (smaller weight)
-	avgRating := 0.0
-	if rating, ok := candidate.AvgRating.(float64); ok {
-		avgRating = rating
+// loadBeverageTags groups tag aggregates by beverage
+func loadBeverageTags(ctx context.Context, q Store, ids []pgtype.UUID) (map[pgtype.UUID][]beverageTag, error) {
+	rows, err := q.GetTagsForBeverages(ctx, ids)
+	if err != nil {
+		return nil, err
 	}
-	reviewCount := float64(candidate.ReviewCount)
-	popularityBonus := (avgRating / 10.0) + (math.Log(reviewCount+1) / 10.0)
 
-	finalScore := (baseScore * 50) + (popularityBonus * 10) + 50 // Scale to ~0-100
+	tags := make(map[pgtype.UUID][]beverageTag, len(ids))
+	for _, row := range rows {
+		tags[row.BeverageID] = append(tags[row.BeverageID], beverageTag{
+			Tag:     row.Tag,
+			TagType: row.TagType,
+			Count:   int(row.Count),
+		})
+	}
+	return tags, nil
+}
 
-	return finalScore, reasons
+// scoreBeverage calculates a score for a candidate beverage from its preloaded tags
+func scoreBeverage(candidate sqlc.GetRecommendationCandidatesRow, beverageTags []beverageTag, likedTags, dislikedTags TagWeights, coldStart bool) (float64, []RecommendationReason) {
+	b := breakdownScore(candidate, beverageTags, likedTags, dislikedTags, coldStart)
+	return b.Total, b.Reasons
 }
 
 func extractTopReasons(reasons []RecommendationReason, limit int) []string {
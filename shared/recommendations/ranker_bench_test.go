@@ -0,0 +1,222 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// latencyDB is a sqlc.DBTX that answers the ranker's queries from memory
+// after a fixed delay per round trip, standing in for a remote Postgres
+type latencyDB struct {
+	latency    time.Duration
+	profile    sqlc.UserTasteProfile
+	candidates []sqlc.GetRecommendationCandidatesRow
+	tags       map[pgtype.UUID][]sqlc.GetTagsForBeveragesRow
+	roundTrips atomic.Int64
+}
+
+func newLatencyDB(latency time.Duration, beverages int) *latencyDB {
+	liked, _ := json.Marshal(TagWeights{"hoppy": 0.9, "citrus": 0.7, "pine": 0.5})
+	disliked, _ := json.Marshal(TagWeights{"sour": 0.8})
+
+	db := &latencyDB{
+		latency: latency,
+		profile: sqlc.UserTasteProfile{
+			Category:         "beer",
+			LikedTagsJson:    liked,
+			DislikedTagsJson: disliked,
+			PostCount:        pgtype.Int4{Int32: 25, Valid: true},
+		},
+		tags: make(map[pgtype.UUID][]sqlc.GetTagsForBeveragesRow),
+	}
+
+	tagPool := []string{"hoppy", "citrus", "pine", "sour", "malty", "roasty", "crisp", "funky"}
+	for i := 0; i < beverages; i++ {
+		id := pgtype.UUID{Valid: true}
+		id.Bytes[0], id.Bytes[1] = byte(i>>8), byte(i)
+
+		var avg pgtype.Numeric
+		_ = avg.Scan(strconv.FormatFloat(5+float64(i%50)/10, 'f', 1, 64))
+
+		db.candidates = append(db.candidates, sqlc.GetRecommendationCandidatesRow{
+			ID:          id,
+			Name:        fmt.Sprintf("Beer %d", i),
+			Category:    "beer",
+			ReviewCount: pgtype.Int4{Int32: int32(i % 40), Valid: true},
+			AvgRating:   avg,
+		})
+		for t := 0; t < 4; t++ {
+			db.tags[id] = append(db.tags[id], sqlc.GetTagsForBeveragesRow{
+				BeverageID: id,
+				Tag:        tagPool[(i+t)%len(tagPool)],
+				TagType:    "descriptor",
+				Count:      int32(4 - t),
+			})
+		}
+	}
+
+	return db
+}
+
+func (db *latencyDB) roundTrip() {
+	db.roundTrips.Add(1)
+	time.Sleep(db.latency)
+}
+
+func (db *latencyDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.roundTrip()
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *latencyDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.roundTrip()
+
+	rows := &memRows{}
+	switch queryName(sql) {
+	case "GetRecommendationCandidates":
+		limit := int(args[len(args)-1].(int32))
+		for i := 0; i < len(db.candidates) && i < limit; i++ {
+			rows.values = append(rows.values, structValues(db.candidates[i]))
+		}
+	case "GetTagsForBeverages":
+		for _, id := range args[0].([]pgtype.UUID) {
+			for _, t := range db.tags[id] {
+				rows.values = append(rows.values, structValues(t))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("latencyDB: unexpected query %s", queryName(sql))
+	}
+	return rows, nil
+}
+
+func (db *latencyDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.roundTrip()
+
+	switch queryName(sql) {
+	case "GetUserTasteProfile":
+		return &memRow{values: structValues(db.profile)}
+	}
+	return &memRow{err: pgx.ErrNoRows}
+}
+
+// queryName pulls the sqlc query name out of "-- name: X :one"
+func queryName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[2]
+}
+
+// structValues flattens a sqlc row struct in field order, which is the
+// order its generated Scan call expects
+func structValues(v any) []any {
+	rv := reflect.ValueOf(v)
+	values := make([]any, rv.NumField())
+	for i := range values {
+		values[i] = rv.Field(i).Interface()
+	}
+	return values
+}
+
+func scanInto(values []any, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("scan: %d values into %d destinations", len(values), len(dest))
+	}
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(values[i]))
+	}
+	return nil
+}
+
+type memRow struct {
+	values []any
+	err    error
+}
+
+func (r *memRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scanInto(r.values, dest)
+}
+
+type memRows struct {
+	values [][]any
+	pos    int
+}
+
+func (r *memRows) Close()                                       {}
+func (r *memRows) Err() error                                   { return nil }
+func (r *memRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *memRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *memRows) RawValues() [][]byte                          { return nil }
+func (r *memRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *memRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.values)
+}
+
+func (r *memRows) Scan(dest ...any) error {
+	return scanInto(r.values[r.pos-1], dest)
+}
+
+func (r *memRows) Values() ([]any, error) {
+	return r.values[r.pos-1], nil
+}
+
+// TestRankRecommendationsRoundTripsIndependentOfLimit guards against
+// per-candidate queries creeping back into the ranker
+func TestRankRecommendationsRoundTripsIndependentOfLimit(t *testing.T) {
+	for _, limit := range []int32{5, 20, 100} {
+		db := newLatencyDB(0, 500)
+		ranker := NewRanker(sqlc.New(db))
+
+		results, err := ranker.RankRecommendations(context.Background(), pgtype.UUID{Valid: true}, "beer", limit)
+		if err != nil {
+			t.Fatalf("limit %d: %v", limit, err)
+		}
+		if len(results) != int(limit) {
+			t.Errorf("limit %d: got %d results", limit, len(results))
+		}
+		// taste profile, candidates, tags
+		if got := db.roundTrips.Load(); got != 3 {
+			t.Errorf("limit %d: got %d round trips, want 3", limit, got)
+		}
+	}
+}
+
+// BenchmarkRankRecommendations simulates 500µs per database round trip.
+// ns/op and queries/op should stay flat as the limit grows.
+func BenchmarkRankRecommendations(b *testing.B) {
+	for _, limit := range []int32{10, 20, 50, 100} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			db := newLatencyDB(500*time.Microsecond, 500)
+			ranker := NewRanker(sqlc.New(db))
+			ctx := context.Background()
+			userID := pgtype.UUID{Valid: true}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ranker.RankRecommendations(ctx, userID, "beer", limit); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(db.roundTrips.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestRankRecommendationsColdStartWithoutProfile(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "Good IPA", "beer", 8.0, 10, "hoppy")
+	store.addBeverage(testUUID(2), "Great Stout", "beer", 9.0, 30, "roasty")
+	store.addBeverage(testUUID(3), "Okay Lager", "beer", 6.0, 2, "crisp")
+
+	results, err := NewRanker(store).RankRecommendations(context.Background(), testUUID(100), "beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(results); !reflect.DeepEqual(got, []string{"Great Stout", "Good IPA", "Okay Lager"}) {
+		t.Errorf("order = %v", got)
+	}
+	for _, r := range results {
+		if !reflect.DeepEqual(r.Reasons, []string{"Highly rated"}) {
+			t.Errorf("%s reasons = %v", r.Name, r.Reasons)
+		}
+	}
+	if n := store.calls["GetTagsForBeverages"]; n != 0 {
+		t.Errorf("cold start loaded tags %d times", n)
+	}
+}
+
+func TestRankRecommendationsColdStartWithFewPosts(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"crisp": 1.0}, TagWeights{}, 2)
+	store.addBeverage(testUUID(1), "Popular Stout", "beer", 9.0, 40, "roasty")
+	store.addBeverage(testUUID(2), "Obscure Lager", "beer", 5.0, 1, "crisp")
+
+	results, err := NewRanker(store).RankRecommendations(context.Background(), user, "beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(results); !reflect.DeepEqual(got, []string{"Popular Stout", "Obscure Lager"}) {
+		t.Errorf("profile with 2 posts should rank by popularity, got %v", got)
+	}
+}
+
+func TestRankRecommendationsPersonalizedByTags(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer",
+		TagWeights{"hoppy": 1.0, "citrus": 0.6},
+		TagWeights{"sour": 0.8},
+		12)
+	store.addBeverage(testUUID(1), "Popular Sour", "beer", 9.0, 50, "sour", "funky")
+	store.addBeverage(testUUID(2), "Niche IPA", "beer", 7.0, 5, "hoppy", "citrus")
+	store.addBeverage(testUUID(3), "Plain Lager", "beer", 7.5, 20, "crisp")
+
+	results, err := NewRanker(store).RankRecommendations(context.Background(), user, "beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(results); !reflect.DeepEqual(got, []string{"Niche IPA", "Plain Lager", "Popular Sour"}) {
+		t.Errorf("order = %v", got)
+	}
+	if got := results[0].Reasons; !reflect.DeepEqual(got, []string{"You rate 'hoppy' higher", "You rate 'citrus' higher"}) {
+		t.Errorf("IPA reasons = %v", got)
+	}
+	if got := results[2].Reasons; !reflect.DeepEqual(got, []string{"You rate 'sour' lower"}) {
+		t.Errorf("sour reasons = %v", got)
+	}
+	for _, r := range results {
+		if r.MatchScore < 0 || r.MatchScore > 100 {
+			t.Errorf("%s match score %d out of range", r.Name, r.MatchScore)
+		}
+	}
+	if n := store.calls["GetTagsForBeverages"]; n != 1 {
+		t.Errorf("tags loaded %d times, want one batched call", n)
+	}
+}
+
+func TestRankRecommendationsRespectsLimitAndCategory(t *testing.T) {
+	store := newFakeStore()
+	for i := 0; i < 10; i++ {
+		store.addBeverage(testUUID(i), "Beer", "beer", 7.0, int32(i), "hoppy")
+	}
+	store.addBeverage(testUUID(50), "Wine", "wine", 9.5, 100, "oaky")
+
+	results, err := NewRanker(store).RankRecommendations(context.Background(), testUUID(100), "beer", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for _, r := range results {
+		if r.Category != "beer" {
+			t.Errorf("got %s in beer recommendations", r.Category)
+		}
+	}
+}
+
+func TestScoreBeverageReasonThresholds(t *testing.T) {
+	candidate := sqlc.GetRecommendationCandidatesRow{AvgRating: testNumeric(7.0)}
+	tags := []beverageTag{{Tag: "strong"}, {Tag: "borderline"}, {Tag: "weak"}, {Tag: "disliked"}}
+	liked := TagWeights{"strong": 0.9, "borderline": 0.3, "weak": 0.1}
+	disliked := TagWeights{"disliked": 0.5}
+
+	_, reasons := scoreBeverage(candidate, tags, liked, disliked, false)
+
+	got := make([]string, 0, len(reasons))
+	for _, r := range reasons {
+		got = append(got, r.Reason)
+	}
+	want := []string{"You rate 'strong' higher", "You rate 'disliked' lower"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reasons = %v, want %v", got, want)
+	}
+}
+
+func TestScoreBeverageLikedTagsRaiseScore(t *testing.T) {
+	candidate := sqlc.GetRecommendationCandidatesRow{AvgRating: testNumeric(7.0)}
+	liked := TagWeights{"hoppy": 1.0}
+	disliked := TagWeights{"sour": 1.0}
+
+	likedScore, _ := scoreBeverage(candidate, []beverageTag{{Tag: "hoppy"}}, liked, disliked, false)
+	neutralScore, _ := scoreBeverage(candidate, []beverageTag{{Tag: "malty"}}, liked, disliked, false)
+	dislikedScore, _ := scoreBeverage(candidate, []beverageTag{{Tag: "sour"}}, liked, disliked, false)
+
+	if !(likedScore > neutralScore && neutralScore > dislikedScore) {
+		t.Errorf("scores liked=%.1f neutral=%.1f disliked=%.1f", likedScore, neutralScore, dislikedScore)
+	}
+}
+
+func TestExtractTopReasons(t *testing.T) {
+	reasons := []RecommendationReason{
+		{Reason: "small", Score: 0.4},
+		{Reason: "big negative", Score: -0.9},
+		{Reason: "big", Score: 0.8},
+		{Reason: "medium", Score: 0.5},
+	}
+
+	got := extractTopReasons(reasons, 3)
+	want := []string{"big negative", "big", "medium"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := extractTopReasons(nil, 3); len(got) != 0 {
+		t.Errorf("no reasons should give empty list, got %v", got)
+	}
+}
+
+func TestScoreBeverageForMatchColdStart(t *testing.T) {
+	store := newFakeStore()
+	store.addBeverage(testUUID(1), "IPA", "beer", 8.0, 10, "hoppy")
+
+	score, reasons, err := NewRanker(store).ScoreBeverageForMatch(context.Background(), testUUID(100), testUUID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != 50 {
+		t.Errorf("score = %d, want 50", score)
+	}
+	if !reflect.DeepEqual(reasons, []string{"Popular choice in this category"}) {
+		t.Errorf("reasons = %v", reasons)
+	}
+}
+
+func TestScoreBeverageForMatchPersonalized(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0, "citrus": 0.6}, TagWeights{}, 8)
+	store.addBeverage(testUUID(1), "IPA", "beer", 8.0, 10, "hoppy", "citrus", "malty")
+
+	score, reasons, err := NewRanker(store).ScoreBeverageForMatch(context.Background(), user, testUUID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50 + (1.0 + 0.6) / 2 matches * 50
+	if score != 90 {
+		t.Errorf("score = %d, want 90", score)
+	}
+	if !reflect.DeepEqual(reasons, []string{"You like 'hoppy'", "You like 'citrus'"}) {
+		t.Errorf("reasons = %v", reasons)
+	}
+}
+
+func TestScoreBeverageForMatchUnknownBeverage(t *testing.T) {
+	_, _, err := NewRanker(newFakeStore()).ScoreBeverageForMatch(context.Background(), testUUID(100), testUUID(1))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("err = %v, want ErrNoRows", err)
+	}
+}
+
+func names(results []RankedBeverage) []string {
+	out := make([]string, 0, len(results))
+	for _, r := range results {
+		out = append(out, r.Name)
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package recommendations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleHorizon is how far ahead Next looks before giving up on a schedule
+// that never matches, like the 30th of February
+const scheduleHorizon = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a "*" day field; when both day fields are
+	// restricted a day matching either one runs, as in cron
+	domAny, dowAny bool
+}
+
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+var scheduleFields = []scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	// 7 is also Sunday
+	{"day of week", 0, 7},
+}
+
+var scheduleShorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// ParseSchedule parses a five-field cron expression (minute, hour, day of
+// month, month, day of week) or one of @hourly, @daily, @midnight, @weekly
+// and @monthly. Fields take *, numbers, ranges (1-5), lists (1,15) and
+// steps (*/15, 9-17/2).
+func ParseSchedule(spec string) (Schedule, error) {
+	if expanded, ok := scheduleShorthands[strings.TrimSpace(spec)]; ok {
+		spec = expanded
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(scheduleFields) {
+		return Schedule{}, fmt.Errorf("schedule %q has %d fields, want %d", spec, len(parts), len(scheduleFields))
+	}
+
+	var bits [5]uint64
+	for i, f := range scheduleFields {
+		b, err := parseScheduleField(parts[i], f)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+	// Fold Sunday as 7 onto 0
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseScheduleField(field string, f scheduleField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step in %s %q", f.name, part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		if rangePart != "*" {
+			var err error
+			from, to, isRange := strings.Cut(rangePart, "-")
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("bad %s %q", f.name, part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("bad %s %q", f.name, part)
+				}
+			} else if step > 1 {
+				// 5/15 means from 5 through the end
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s %q is outside %d-%d", f.name, part, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next is the first time after t the schedule fires, in t's location. It is
+// the zero time when the schedule can never fire.
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(scheduleHorizon)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
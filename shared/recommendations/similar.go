@@ -0,0 +1,244 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultSimilarLimit is how many similar beverages are returned by default
+	DefaultSimilarLimit = 10
+
+	// similarReasonTags is how many shared tags a similarity reason names
+	similarReasonTags = 2
+)
+
+// SimilarFilter narrows similar beverages beyond the source's category.
+// Zero values leave a constraint off; beverages without a known price are
+// dropped once a price bound is set.
+type SimilarFilter struct {
+	Style         string
+	MinPriceCents int32
+	MaxPriceCents int32
+	Limit         int32
+}
+
+// SimilarBeverage is a beverage whose tag profile resembles the one viewed
+type SimilarBeverage struct {
+	BeverageID  string   `json:"beverage_id"`
+	Name        string   `json:"name"`
+	Brand       string   `json:"brand,omitempty"`
+	Category    string   `json:"category"`
+	Style       string   `json:"style,omitempty"`
+	Similarity  float64  `json:"similarity"`
+	Reasons     []string `json:"reasons"`
+	AvgRating   float64  `json:"avg_rating"`
+	ReviewCount int      `json:"review_count"`
+}
+
+// SimilarBeverages backs GET /v1/beverages/{id}/similar. Beverages in the
+// same category are compared by TF-IDF weighted tag vectors, so tags every
+// beverage carries count for little and distinctive ones dominate. The
+// user's hidden beverages are excluded.
+func (r *Ranker) SimilarBeverages(ctx context.Context, userID, beverageID pgtype.UUID, filter SimilarFilter) ([]SimilarBeverage, error) {
+	source, err := r.Q.GetBeverageByID(ctx, beverageID)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSimilarLimit
+	}
+
+	rows, err := r.Q.GetCategoryTagProfiles(ctx, source.Category)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := r.Q.GetHiddenBeveragesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[pgtype.UUID]bool, len(hidden)+1)
+	excluded[beverageID] = true
+	for _, id := range hidden {
+		excluded[id] = true
+	}
+
+	vectors := tfidfVectors(rows)
+	query, ok := vectors[beverageID]
+	if !ok {
+		// Untagged beverages have nothing to compare
+		return []SimilarBeverage{}, nil
+	}
+
+	type match struct {
+		row     sqlc.GetCategoryTagProfilesRow
+		sim     float64
+		reasons []RecommendationReason
+	}
+	var matches []match
+	for _, row := range rows {
+		if excluded[row.ID] || !filter.matches(row) {
+			continue
+		}
+		sim, shared := cosineTags(query, vectors[row.ID])
+		if sim <= 0 {
+			continue
+		}
+		matches = append(matches, match{row, sim, similarityReasons(shared, sim, source, row)})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].sim != matches[j].sim {
+			return matches[i].sim > matches[j].sim
+		}
+		return matches[i].row.Name < matches[j].row.Name
+	})
+	if len(matches) > int(filter.Limit) {
+		matches = matches[:filter.Limit]
+	}
+
+	out := make([]SimilarBeverage, 0, len(matches))
+	for _, m := range matches {
+		avgRating, _ := rating.Average(m.row.AvgRating)
+		out = append(out, SimilarBeverage{
+			BeverageID:  uuid.UUID(m.row.ID.Bytes).String(),
+			Name:        m.row.Name,
+			Brand:       m.row.Brand.String,
+			Category:    source.Category,
+			Style:       m.row.Style.String,
+			Similarity:  m.sim,
+			Reasons:     extractTopReasons(m.reasons, 3),
+			AvgRating:   avgRating,
+			ReviewCount: int(m.row.ReviewCount.Int32),
+		})
+	}
+	return out, nil
+}
+
+func (f SimilarFilter) matches(row sqlc.GetCategoryTagProfilesRow) bool {
+	if f.Style != "" && !strings.EqualFold(row.Style.String, f.Style) {
+		return false
+	}
+	if f.MinPriceCents > 0 || f.MaxPriceCents > 0 {
+		price := row.MedianPriceCents
+		if price <= 0 {
+			return false
+		}
+		if f.MinPriceCents > 0 && price < f.MinPriceCents {
+			return false
+		}
+		if f.MaxPriceCents > 0 && price > f.MaxPriceCents {
+			return false
+		}
+	}
+	return true
+}
+
+// tfidfVectors weights each beverage's tags by term frequency (share of the
+// beverage's tag mentions) times smoothed inverse document frequency across
+// the category, normalized to unit length
+func tfidfVectors(rows []sqlc.GetCategoryTagProfilesRow) map[pgtype.UUID]map[string]float64 {
+	counts := make(map[pgtype.UUID]map[string]float64, len(rows))
+	df := make(map[string]int)
+	for _, row := range rows {
+		var tags []struct {
+			Tag   string `json:"tag"`
+			Count int    `json:"count"`
+		}
+		json.Unmarshal(row.TagsJson, &tags)
+
+		c := make(map[string]float64)
+		for _, t := range tags {
+			if t.Count > 0 {
+				c[t.Tag] += float64(t.Count)
+			}
+		}
+		if len(c) == 0 {
+			continue
+		}
+		for tag := range c {
+			df[tag]++
+		}
+		counts[row.ID] = c
+	}
+
+	n := float64(len(counts))
+	vectors := make(map[pgtype.UUID]map[string]float64, len(counts))
+	for id, c := range counts {
+		var total float64
+		for _, count := range c {
+			total += count
+		}
+		v := make(map[string]float64, len(c))
+		var norm float64
+		for tag, count := range c {
+			idf := math.Log((1+n)/(1+float64(df[tag]))) + 1
+			w := count / total * idf
+			v[tag] = w
+			norm += w * w
+		}
+		norm = math.Sqrt(norm)
+		for tag := range v {
+			v[tag] /= norm
+		}
+		vectors[id] = v
+	}
+	return vectors
+}
+
+// sharedTag is a tag two beverages have in common and its share of their similarity
+type sharedTag struct {
+	tag    string
+	weight float64
+}
+
+// cosineTags returns the cosine of two unit tag vectors and their shared
+// tags, strongest contribution first
+func cosineTags(a, b map[string]float64) (float64, []sharedTag) {
+	var sim float64
+	var shared []sharedTag
+	for tag, wa := range a {
+		if wb, ok := b[tag]; ok {
+			sim += wa * wb
+			shared = append(shared, sharedTag{tag, wa * wb})
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		if shared[i].weight != shared[j].weight {
+			return shared[i].weight > shared[j].weight
+		}
+		return shared[i].tag < shared[j].tag
+	})
+	return sim, shared
+}
+
+// similarityReasons explains a match as "Also oaky and full-bodied" from the
+// tags that contribute most, plus a matching style
+func similarityReasons(shared []sharedTag, sim float64, source sqlc.Beverage, row sqlc.GetCategoryTagProfilesRow) []RecommendationReason {
+	var reasons []RecommendationReason
+	if len(shared) > 0 {
+		var names []string
+		for i := 0; i < len(shared) && i < similarReasonTags; i++ {
+			names = append(names, shared[i].tag)
+		}
+		reasons = append(reasons, RecommendationReason{
+			Reason: "Also " + strings.Join(names, " and "),
+			Score:  sim,
+		})
+	}
+	if source.Style.Valid && source.Style.String != "" && strings.EqualFold(source.Style.String, row.Style.String) {
+		reasons = append(reasons, RecommendationReason{
+			Reason: "Same style: " + row.Style.String,
+			Score:  sim / 2,
+		})
+	}
+	return reasons
+}
@@ -0,0 +1,127 @@
+package recommendations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func similarFixture() *fakeStore {
+	store := newFakeStore()
+	// "red" is on every wine, so it should count for little
+	store.addBeverage(testUUID(1), "Napa Cab", "wine", 8.5, 20, "oaky", "full-bodied", "red")
+	store.addBeverage(testUUID(2), "Paso Cab", "wine", 8.0, 12, "oaky", "full-bodied", "red", "jammy")
+	store.addBeverage(testUUID(3), "Light Pinot", "wine", 8.0, 30, "red", "light")
+	store.addBeverage(testUUID(4), "Oaked Chardonnay", "wine", 7.5, 8, "oaky", "buttery")
+	store.addBeverage(testUUID(5), "Oaky IPA", "beer", 7.0, 40, "oaky", "full-bodied")
+	store.setStyle(testUUID(1), "Cabernet Sauvignon")
+	store.setStyle(testUUID(2), "Cabernet Sauvignon")
+	store.setStyle(testUUID(4), "Chardonnay")
+	store.prices[testUUID(2)] = 4500
+	store.prices[testUUID(4)] = 1800
+	return store
+}
+
+func (f *fakeStore) setStyle(id pgtype.UUID, style string) {
+	b := f.beverages[id]
+	b.Style = pgtype.Text{String: style, Valid: true}
+	f.beverages[id] = b
+}
+
+func TestSimilarBeveragesTFIDF(t *testing.T) {
+	store := similarFixture()
+
+	got, err := NewRanker(store).SimilarBeverages(context.Background(), testUUID(100), testUUID(1), SimilarFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, s := range got {
+		order = append(order, s.Name)
+	}
+	if !reflect.DeepEqual(order, []string{"Paso Cab", "Oaked Chardonnay", "Light Pinot"}) {
+		t.Fatalf("order = %v", order)
+	}
+	if want := []string{"Also oaky and full-bodied", "Same style: Cabernet Sauvignon"}; !reflect.DeepEqual(got[0].Reasons, want) {
+		t.Errorf("reasons = %v, want %v", got[0].Reasons, want)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Similarity > got[i-1].Similarity {
+			t.Errorf("results not sorted by similarity: %v", got)
+		}
+	}
+	if got[2].Similarity >= got[1].Similarity/2 {
+		t.Errorf("a shared ubiquitous tag scored %.2f, close to a distinctive one at %.2f", got[2].Similarity, got[1].Similarity)
+	}
+}
+
+func TestSimilarBeveragesFilters(t *testing.T) {
+	store := similarFixture()
+	ranker := NewRanker(store)
+
+	got, err := ranker.SimilarBeverages(context.Background(), testUUID(100), testUUID(1), SimilarFilter{Style: "chardonnay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "Oaked Chardonnay" {
+		t.Errorf("style filter = %+v", got)
+	}
+
+	got, err = ranker.SimilarBeverages(context.Background(), testUUID(100), testUUID(1), SimilarFilter{MaxPriceCents: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "Oaked Chardonnay" {
+		t.Errorf("price filter should drop the pricier and the unpriced wines, got %+v", got)
+	}
+
+	got, err = ranker.SimilarBeverages(context.Background(), testUUID(100), testUUID(1), SimilarFilter{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("limit 1 returned %d", len(got))
+	}
+}
+
+func TestSimilarBeveragesExcludesHidden(t *testing.T) {
+	store := similarFixture()
+	user := testUUID(100)
+	store.hidden[user] = []pgtype.UUID{testUUID(2)}
+
+	got, err := NewRanker(store).SimilarBeverages(context.Background(), user, testUUID(1), SimilarFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range got {
+		if s.Name == "Paso Cab" {
+			t.Error("hidden beverage returned")
+		}
+	}
+
+	// Other users still see it
+	got, _ = NewRanker(store).SimilarBeverages(context.Background(), testUUID(101), testUUID(1), SimilarFilter{})
+	if len(got) == 0 || got[0].Name != "Paso Cab" {
+		t.Errorf("other user results = %+v", got)
+	}
+}
+
+func TestSimilarBeveragesUntaggedOrUnknown(t *testing.T) {
+	store := similarFixture()
+	store.addBeverage(testUUID(9), "Mystery Wine", "wine", 7.0, 1)
+
+	got, err := NewRanker(store).SimilarBeverages(context.Background(), testUUID(100), testUUID(9), SimilarFilter{})
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("untagged = %v, %v; want empty list", got, err)
+	}
+
+	_, err = NewRanker(store).SimilarBeverages(context.Background(), testUUID(100), testUUID(99), SimilarFilter{})
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("err = %v, want ErrNoRows", err)
+	}
+}
@@ -0,0 +1,61 @@
+package recommendations
+
+import (
+	"context"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Store is the subset of sqlc.Querier the recommendation engine uses.
+// *sqlc.Queries satisfies it; tests use an in-memory fake.
+type Store interface {
+	AttributeConversions(ctx context.Context, arg sqlc.AttributeConversionsParams) (int64, error)
+	CreateRecommendationFeedback(ctx context.Context, arg sqlc.CreateRecommendationFeedbackParams) (sqlc.RecommendationFeedback, error)
+	DeleteFeedback(ctx context.Context, arg sqlc.DeleteFeedbackParams) error
+	DeleteTasteContribution(ctx context.Context, arg sqlc.DeleteTasteContributionParams) error
+	DeleteTasteContributions(ctx context.Context, arg sqlc.DeleteTasteContributionsParams) error
+	GetBeverageByID(ctx context.Context, id pgtype.UUID) (sqlc.Beverage, error)
+	GetBeverageEmbeddingsByCategory(ctx context.Context, arg sqlc.GetBeverageEmbeddingsByCategoryParams) ([]sqlc.GetBeverageEmbeddingsByCategoryRow, error)
+	GetBeverageNeighbors(ctx context.Context, arg sqlc.GetBeverageNeighborsParams) ([]sqlc.GetBeverageNeighborsRow, error)
+	GetCategoryTagProfiles(ctx context.Context, category string) ([]sqlc.GetCategoryTagProfilesRow, error)
+	GetCoRatedBeverages(ctx context.Context, arg sqlc.GetCoRatedBeveragesParams) ([]sqlc.GetCoRatedBeveragesRow, error)
+	GetExperimentEngagement(ctx context.Context, arg sqlc.GetExperimentEngagementParams) ([]sqlc.GetExperimentEngagementRow, error)
+	GetHiddenBeveragesForUser(ctx context.Context, userID pgtype.UUID) ([]pgtype.UUID, error)
+	GetImpressionStatsByRank(ctx context.Context, arg sqlc.GetImpressionStatsByRankParams) ([]sqlc.GetImpressionStatsByRankRow, error)
+	GetImpressionStatsBySurface(ctx context.Context, createdAt pgtype.Timestamptz) ([]sqlc.GetImpressionStatsBySurfaceRow, error)
+	GetPostForTasteProfile(ctx context.Context, id pgtype.UUID) ([]sqlc.GetPostForTasteProfileRow, error)
+	GetRecommendationCandidates(ctx context.Context, arg sqlc.GetRecommendationCandidatesParams) ([]sqlc.GetRecommendationCandidatesRow, error)
+	GetRecommendationCandidatesByIDs(ctx context.Context, arg sqlc.GetRecommendationCandidatesByIDsParams) ([]sqlc.GetRecommendationCandidatesByIDsRow, error)
+	GetTagsForBeverages(ctx context.Context, dollar_1 []pgtype.UUID) ([]sqlc.GetTagsForBeveragesRow, error)
+	GetTasteCompatibility(ctx context.Context, arg sqlc.GetTasteCompatibilityParams) ([]sqlc.TasteCompatibility, error)
+	GetTasteContribution(ctx context.Context, arg sqlc.GetTasteContributionParams) (sqlc.UserTasteContribution, error)
+	GetTasteDecaySetting(ctx context.Context, category string) (sqlc.TasteDecaySetting, error)
+	GetUserEmbedding(ctx context.Context, arg sqlc.GetUserEmbeddingParams) (sqlc.UserEmbedding, error)
+	GetUserFeedbackForBeverage(ctx context.Context, arg sqlc.GetUserFeedbackForBeverageParams) ([]sqlc.RecommendationFeedback, error)
+	GetUserFeedbackForCategory(ctx context.Context, arg sqlc.GetUserFeedbackForCategoryParams) ([]sqlc.RecommendationFeedback, error)
+	GetUserNeighborScores(ctx context.Context, arg sqlc.GetUserNeighborScoresParams) ([]sqlc.GetUserNeighborScoresRow, error)
+	GetUserPostsForCategory(ctx context.Context, arg sqlc.GetUserPostsForCategoryParams) ([]sqlc.GetUserPostsForCategoryRow, error)
+	GetUserTasteProfile(ctx context.Context, arg sqlc.GetUserTasteProfileParams) (sqlc.UserTasteProfile, error)
+	GetUserTasteStatsForUpdate(ctx context.Context, arg sqlc.GetUserTasteStatsForUpdateParams) (sqlc.UserTasteStat, error)
+	GetVenueCandidates(ctx context.Context, arg sqlc.GetVenueCandidatesParams) ([]sqlc.GetVenueCandidatesRow, error)
+	InsertRecommendationImpressions(ctx context.Context, arg sqlc.InsertRecommendationImpressionsParams) ([]sqlc.InsertRecommendationImpressionsRow, error)
+	InsertRecommendationList(ctx context.Context, arg sqlc.InsertRecommendationListParams) (sqlc.RecommendationList, error)
+	ListBeveragesNeedingEmbeddings(ctx context.Context, arg sqlc.ListBeveragesNeedingEmbeddingsParams) ([]sqlc.ListBeveragesNeedingEmbeddingsRow, error)
+	ListQuizBeverages(ctx context.Context, arg sqlc.ListQuizBeveragesParams) ([]sqlc.ListQuizBeveragesRow, error)
+	ListTasteProfilesForUsers(ctx context.Context, userIds []pgtype.UUID) ([]sqlc.UserTasteProfile, error)
+	ListTasteQuizAnswers(ctx context.Context, arg sqlc.ListTasteQuizAnswersParams) ([]sqlc.TasteQuizAnswer, error)
+	ListUserEmbeddingsToRefresh(ctx context.Context, arg sqlc.ListUserEmbeddingsToRefreshParams) ([]sqlc.ListUserEmbeddingsToRefreshRow, error)
+	RecordImpressionClick(ctx context.Context, arg sqlc.RecordImpressionClickParams) error
+	TouchBeverageEmbedding(ctx context.Context, beverageID pgtype.UUID) error
+	TouchUserEmbedding(ctx context.Context, arg sqlc.TouchUserEmbeddingParams) error
+	UpsertBeverageEmbedding(ctx context.Context, arg sqlc.UpsertBeverageEmbeddingParams) error
+	UpsertTasteCompatibility(ctx context.Context, arg sqlc.UpsertTasteCompatibilityParams) error
+	UpsertTasteContribution(ctx context.Context, arg sqlc.UpsertTasteContributionParams) error
+	UpsertTasteQuizAnswer(ctx context.Context, arg sqlc.UpsertTasteQuizAnswerParams) error
+	UpsertUserEmbedding(ctx context.Context, arg sqlc.UpsertUserEmbeddingParams) (sqlc.UserEmbedding, error)
+	UpsertUserTasteProfile(ctx context.Context, arg sqlc.UpsertUserTasteProfileParams) (sqlc.UserTasteProfile, error)
+	UpsertUserTasteStats(ctx context.Context, arg sqlc.UpsertUserTasteStatsParams) error
+}
+
+var _ Store = (*sqlc.Queries)(nil)
@@ -3,27 +3,51 @@ package recommendations
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"math"
+	"strconv"
+	"time"
 
 	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // TagWeights represents weighted preferences for tags
 type TagWeights map[string]float64
 
+// DefaultHalfLifeDays applies to categories without a taste_decay_settings row
+const DefaultHalfLifeDays = 365
+
 // TasteProfileComputer computes and updates user taste profiles
 type TasteProfileComputer struct {
-	Q *sqlc.Queries
+	Q   Store
+	Now func() time.Time
+}
+
+func NewTasteProfileComputer(q Store) *TasteProfileComputer {
+	return &TasteProfileComputer{Q: q, Now: time.Now}
 }
 
-func NewTasteProfileComputer(q *sqlc.Queries) *TasteProfileComputer {
-	return &TasteProfileComputer{Q: q}
+// tasteSummary is one view of a user's taste: rating stats and tag weights
+type tasteSummary struct {
+	liked      TagWeights
+	disliked   TagWeights
+	meanRating pgtype.Numeric
+	stdRating  pgtype.Numeric
 }
 
-// ComputeProfile computes the taste profile for a user in a category based on their posts
+// ComputeProfile computes the taste profile for a user in a category based on their posts.
+// The stored profile is decayed by the category half-life so recent posts count
+// more; the all_time_ columns keep the undecayed profile alongside it. It also
+// rebuilds the statistics ApplyPost updates incrementally.
 func (c *TasteProfileComputer) ComputeProfile(ctx context.Context, userID pgtype.UUID, category string) error {
+	halfLife, err := c.halfLifeDays(ctx, category)
+	if err != nil {
+		return err
+	}
+
 	// Get user's posts with tags for this category
 	posts, err := c.Q.GetUserPostsForCategory(ctx, sqlc.GetUserPostsForCategoryParams{
 		UserID:        userID,
@@ -33,207 +57,353 @@ func (c *TasteProfileComputer) ComputeProfile(ctx context.Context, userID pgtype
 		return err
 	}
 
-	if len(posts) == 0 {
-		// No posts yet, create empty profile
-		emptyLiked, _ := json.Marshal(TagWeights{})
-		emptyDisliked, _ := json.Marshal(TagWeights{})
-		_, err := c.Q.UpsertUserTasteProfile(ctx, sqlc.UpsertUserTasteProfileParams{
-			UserID:           userID,
-			Category:         category,
-			LikedTagsJson:    emptyLiked,
-			DislikedTagsJson: emptyDisliked,
-			MeanRating:       pgtype.Numeric{Valid: false},
-			StdRating:        pgtype.Numeric{Valid: false},
-			PostCount:        pgtype.Int4{Int32: 0, Valid: true},
-		})
+	if err := c.Q.DeleteTasteContributions(ctx, sqlc.DeleteTasteContributionsParams{
+		UserID:   userID,
+		Category: category,
+	}); err != nil {
 		return err
 	}
 
-	// Calculate rating statistics
-	ratings := []float64{}
-	for _, post := range posts {
-		var rating float64
-		if post.Score.Valid {
-			f, _ := post.Score.Float64Value()
-			rating = f.Float64
-		} else if post.Stars.Valid {
-			rating = float64(post.Stars.Int32) * 2.0
-		}
-		if rating > 0 {
-			ratings = append(ratings, rating)
+	now := c.now()
+	recent, allTime := newTasteStats(), newTasteStats()
+	for _, post := range contributionsFromPosts(posts) {
+		w := decayWeight(post.CreatedAt, now, halfLife)
+		recent.add(post, w)
+		allTime.add(post, 1)
+		if err := c.saveContribution(ctx, userID, category, post, w, now); err != nil {
+			return err
 		}
 	}
 
-	var meanRating, stdRating float64
-	if len(ratings) > 0 {
-		meanRating = mean(ratings)
-		stdRating = stdDev(ratings, meanRating)
+	return c.saveStats(ctx, userID, category, halfLife, recent, allTime, now, now)
+}
+
+// ApplyPost brings one post's contribution to a user's profile up to date
+// using the stored statistics rather than rescanning every post. Call it after
+// a post is tagged, edited or deleted, inside a transaction so the stats row
+// lock serializes concurrent updates for the same profile.
+func (c *TasteProfileComputer) ApplyPost(ctx context.Context, userID pgtype.UUID, category string, postID pgtype.UUID) error {
+	halfLife, err := c.halfLifeDays(ctx, category)
+	if err != nil {
+		return err
 	}
 
-	// Compute tag weights based on ratings
-	likedTags := make(TagWeights)
-	dislikedTags := make(TagWeights)
+	now := c.now()
+	stats, recent, allTime, err := c.lockStats(ctx, userID, category, halfLife, now)
+	if errors.Is(err, errStatsUnusable) {
+		return c.ComputeProfile(ctx, userID, category)
+	}
+	if err != nil {
+		return err
+	}
 
-	// Group posts by rating threshold
-	// High rating: > mean + 0.5*std
-	// Low rating: < mean - 0.5*std
-	highThreshold := meanRating + 0.5*stdRating
-	lowThreshold := meanRating - 0.5*stdRating
+	// Take back whatever the post contributed last time
+	key := sqlc.GetTasteContributionParams{UserID: userID, Category: category, PostID: postID}
+	previous, err := c.Q.GetTasteContribution(ctx, key)
+	switch {
+	case err == nil:
+		old := contributionFromRow(previous)
+		recent.remove(old, previous.Weight*decayWeight(previous.AppliedAt, now, halfLife))
+		allTime.remove(old, 1)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return err
+	}
 
-	for _, post := range posts {
-		var rating float64
-		if post.Score.Valid {
-			f, _ := post.Score.Float64Value()
-			rating = f.Float64
-		} else if post.Stars.Valid {
-			rating = float64(post.Stars.Int32) * 2.0
+	// Add it back as it is now, unless it was deleted or moved out of this profile
+	rows, err := c.Q.GetPostForTasteProfile(ctx, postID)
+	if err != nil {
+		return err
+	}
+	var current []sqlc.GetUserPostsForCategoryRow
+	for _, row := range rows {
+		if row.UserID == userID && row.DrinkCategory == category {
+			current = append(current, sqlc.GetUserPostsForCategoryRow(row))
 		}
+	}
 
-		if rating == 0 || !post.Tag.Valid {
-			continue
+	if posts := contributionsFromPosts(current); len(posts) > 0 {
+		w := decayWeight(posts[0].CreatedAt, now, halfLife)
+		recent.add(posts[0], w)
+		allTime.add(posts[0], 1)
+		if err := c.saveContribution(ctx, userID, category, posts[0], w, now); err != nil {
+			return err
 		}
+	} else if err := c.Q.DeleteTasteContribution(ctx, sqlc.DeleteTasteContributionParams(key)); err != nil {
+		return err
+	}
 
-		tag := post.Tag.String
-		confidence := 1.0
-		if post.Confidence.Valid {
-			f, _ := post.Confidence.Float64Value()
-			confidence = f.Float64
-		}
+	return c.saveStats(ctx, userID, category, halfLife, recent, allTime, now, stats.FullRecomputeAt.Time)
+}
 
-		// Weight contribution based on rating and confidence
-		weight := confidence
+// RefreshProfile rematerializes a user's profile from the stored statistics
+// without touching their posts, for changes such as feedback that are
+// applied on top of them. Like ApplyPost it should run inside a transaction.
+func (c *TasteProfileComputer) RefreshProfile(ctx context.Context, userID pgtype.UUID, category string) error {
+	halfLife, err := c.halfLifeDays(ctx, category)
+	if err != nil {
+		return err
+	}
 
-		if rating >= highThreshold {
-			// Liked tag
-			likedTags[tag] += weight
-		} else if rating <= lowThreshold {
-			// Disliked tag
-			dislikedTags[tag] += weight
-		}
+	now := c.now()
+	stats, recent, allTime, err := c.lockStats(ctx, userID, category, halfLife, now)
+	if errors.Is(err, errStatsUnusable) {
+		return c.ComputeProfile(ctx, userID, category)
+	}
+	if err != nil {
+		return err
+	}
+	return c.saveStats(ctx, userID, category, halfLife, recent, allTime, now, stats.FullRecomputeAt.Time)
+}
+
+// errStatsUnusable means the stored statistics can't be updated in place and
+// the profile has to be computed from posts
+var errStatsUnusable = errors.New("taste stats unusable")
+
+// lockStats locks and decodes the stored statistics, with the recent side
+// decayed to now
+func (c *TasteProfileComputer) lockStats(ctx context.Context, userID pgtype.UUID, category string, halfLife pgtype.Int4, now time.Time) (sqlc.UserTasteStat, *tasteStats, *tasteStats, error) {
+	stats, err := c.Q.GetUserTasteStatsForUpdate(ctx, sqlc.GetUserTasteStatsForUpdateParams{
+		UserID:   userID,
+		Category: category,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return stats, nil, nil, errStatsUnusable
+	}
+	if err != nil {
+		return stats, nil, nil, err
+	}
+	if stats.HalfLifeDays != halfLife {
+		// Stored weights assume the old half-life
+		return stats, nil, nil, errStatsUnusable
 	}
 
-	// Normalize weights (0-1 scale)
-	normalizeWeights(likedTags)
-	normalizeWeights(dislikedTags)
+	recent, err := decodeTasteStats(stats.RecentJson)
+	if err != nil {
+		log.Printf("Corrupt taste stats for user %v %s, recomputing: %v", userID, category, err)
+		return stats, nil, nil, errStatsUnusable
+	}
+	allTime, err := decodeTasteStats(stats.AllTimeJson)
+	if err != nil {
+		log.Printf("Corrupt taste stats for user %v %s, recomputing: %v", userID, category, err)
+		return stats, nil, nil, errStatsUnusable
+	}
 
-	// Store profile
-	likedJSON, _ := json.Marshal(likedTags)
-	dislikedJSON, _ := json.Marshal(dislikedTags)
+	recent.decay(decayWeight(stats.AsOf, now, halfLife))
+	return stats, recent, allTime, nil
+}
 
-	meanRatingPg := pgtype.Numeric{}
-	meanRatingPg.Scan(meanRating)
-	stdRatingPg := pgtype.Numeric{}
-	stdRatingPg.Scan(stdRating)
+func (c *TasteProfileComputer) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
 
-	_, err = c.Q.UpsertUserTasteProfile(ctx, sqlc.UpsertUserTasteProfileParams{
-		UserID:           userID,
-		Category:         category,
-		LikedTagsJson:    likedJSON,
-		DislikedTagsJson: dislikedJSON,
-		MeanRating:       meanRatingPg,
-		StdRating:        stdRatingPg,
-		PostCount:        pgtype.Int4{Int32: int32(len(posts)), Valid: true},
+func (c *TasteProfileComputer) saveContribution(ctx context.Context, userID pgtype.UUID, category string, post postContribution, weight float64, now time.Time) error {
+	tagsJSON, _ := json.Marshal(post.Tags)
+	return c.Q.UpsertTasteContribution(ctx, sqlc.UpsertTasteContributionParams{
+		UserID:    userID,
+		Category:  category,
+		PostID:    post.PostID,
+		Rating:    post.Rating,
+		Weight:    weight,
+		TagsJson:  tagsJSON,
+		AppliedAt: pgtype.Timestamptz{Time: now, Valid: true},
 	})
+}
 
+// saveStats stores the statistics and the profile materialized from them
+func (c *TasteProfileComputer) saveStats(ctx context.Context, userID pgtype.UUID, category string, halfLife pgtype.Int4, recent, allTime *tasteStats, asOf, fullRecomputeAt time.Time) error {
+	recentJSON, _ := json.Marshal(recent)
+	allTimeJSON, _ := json.Marshal(allTime)
+	if err := c.Q.UpsertUserTasteStats(ctx, sqlc.UpsertUserTasteStatsParams{
+		UserID:          userID,
+		Category:        category,
+		HalfLifeDays:    halfLife,
+		RecentJson:      recentJSON,
+		AllTimeJson:     allTimeJSON,
+		AsOf:            pgtype.Timestamptz{Time: asOf, Valid: true},
+		FullRecomputeAt: pgtype.Timestamptz{Time: fullRecomputeAt, Valid: true},
+	}); err != nil {
+		return err
+	}
+
+	recentSummary := recent.summary()
+	allTimeSummary := allTime.summary()
+
+	liked, disliked := recentSummary.liked, recentSummary.disliked
+	allTimeLikedJSON, _ := json.Marshal(allTimeSummary.liked)
+	allTimeDislikedJSON, _ := json.Marshal(allTimeSummary.disliked)
+
+	arg := sqlc.UpsertUserTasteProfileParams{
+		UserID:                  userID,
+		Category:                category,
+		MeanRating:              recentSummary.meanRating,
+		StdRating:               recentSummary.stdRating,
+		PostCount:               pgtype.Int4{Int32: int32(allTime.Posts), Valid: true},
+		AllTimeLikedTagsJson:    allTimeLikedJSON,
+		AllTimeDislikedTagsJson: allTimeDislikedJSON,
+		AllTimeMeanRating:       allTimeSummary.meanRating,
+		AllTimeStdRating:        allTimeSummary.stdRating,
+		HalfLifeDays:            halfLife,
+	}
+
+	// Quiz answers stand in for posts until there are enough of them
+	answers, err := c.Q.ListTasteQuizAnswers(ctx, sqlc.ListTasteQuizAnswersParams{UserID: userID, Category: category})
+	if err != nil {
+		return err
+	}
+	if share := quizShare(allTime.Posts); len(answers) > 0 && share > 0 {
+		quizLiked, quizDisliked := quizWeights(answers)
+		liked = blendWeights(liked, quizLiked, share)
+		disliked = blendWeights(disliked, quizDisliked, share)
+		arg.QuizWeight = share
+	}
+
+	// With no posts or quiz answers of its own, seed the recent profile from
+	// the user's other categories. The first post replaces it, clearing
+	// InferredFrom.
+	if allTime.Posts == 0 && arg.QuizWeight == 0 {
+		inferredLiked, inferredDisliked, sources, err := c.inferProfile(ctx, userID, category)
+		if err != nil {
+			return err
+		}
+		if len(sources) > 0 {
+			liked, disliked = inferredLiked, inferredDisliked
+			arg.InferredFrom = sources
+		}
+	}
+
+	// Feedback is replayed on top, so removing it from the log undoes it
+	liked, disliked, err = c.replayFeedback(ctx, userID, category, liked, disliked)
+	if err != nil {
+		return err
+	}
+	arg.LikedTagsJson, _ = json.Marshal(liked)
+	arg.DislikedTagsJson, _ = json.Marshal(disliked)
+
+	_, err = c.Q.UpsertUserTasteProfile(ctx, arg)
 	return err
 }
 
-// UpdateProfileWithFeedback incrementally updates taste profile based on explicit feedback
-func (c *TasteProfileComputer) UpdateProfileWithFeedback(ctx context.Context, userID pgtype.UUID, beverageID pgtype.UUID, feedbackType string) error {
-	// Get beverage tags
-	beverage, err := c.Q.GetBeverageWithTags(ctx, beverageID)
+// halfLifeDays reads the decay half-life for a category. An invalid result
+// means decay is disabled for it.
+func (c *TasteProfileComputer) halfLifeDays(ctx context.Context, category string) (pgtype.Int4, error) {
+	setting, err := c.Q.GetTasteDecaySetting(ctx, category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.Int4{Int32: DefaultHalfLifeDays, Valid: true}, nil
+	}
 	if err != nil {
-		return err
+		return pgtype.Int4{}, err
 	}
+	return setting.HalfLifeDays, nil
+}
 
-	// Parse existing tags from JSON
-	var beverageTags []struct {
-		Tag     string `json:"tag"`
-		TagType string `json:"tag_type"`
-		Count   int    `json:"count"`
+// decayWeight halves a post's influence every halfLife days
+func decayWeight(createdAt pgtype.Timestamptz, now time.Time, halfLife pgtype.Int4) float64 {
+	if !halfLife.Valid || halfLife.Int32 <= 0 || !createdAt.Valid {
+		return 1
 	}
-	if beverage.TagsJson != nil {
-		if tagsBytes, ok := beverage.TagsJson.([]byte); ok {
-			json.Unmarshal(tagsBytes, &beverageTags)
-		}
+	ageDays := now.Sub(createdAt.Time).Hours() / 24
+	if ageDays <= 0 {
+		return 1
 	}
+	return math.Exp2(-ageDays / float64(halfLife.Int32))
+}
 
-	if len(beverageTags) == 0 {
-		log.Printf("No tags for beverage %v, skipping taste profile update", beverageID)
+// UpdateProfileWithFeedback rebuilds the user's profile for the beverage's
+// category after feedback on it was given or removed. Profiles are a pure
+// function of posts, quiz answers and the feedback log, so removing feedback
+// undoes it exactly and repeating it has no further effect. Only the profile
+// is rebuilt, from the stored statistics; posts aren't rescanned. Feedback
+// that doesn't change taste needs no rebuild.
+func (c *TasteProfileComputer) UpdateProfileWithFeedback(ctx context.Context, userID pgtype.UUID, beverageID pgtype.UUID, feedbackType string) error {
+	if !changesTaste(feedbackType) {
 		return nil
 	}
+	beverage, err := c.Q.GetBeverageByID(ctx, beverageID)
+	if err != nil {
+		return err
+	}
+	return c.RefreshProfile(ctx, userID, beverage.Category)
+}
 
-	category := beverage.Category
+// TasteSummary is one side of a TasteProfileView
+type TasteSummary struct {
+	LikedTags    TagWeights `json:"liked_tags"`
+	DislikedTags TagWeights `json:"disliked_tags"`
+	MeanRating   *float64   `json:"mean_rating"`
+	StdRating    *float64   `json:"std_rating"`
+}
 
-	// Get or create taste profile
-	profile, err := c.Q.GetUserTasteProfile(ctx, sqlc.GetUserTasteProfileParams{
-		UserID:   userID,
-		Category: category,
-	})
-	if err != nil {
-		// Profile doesn't exist, compute it first
+// TasteProfileView backs GET /v1/recommendations/taste-profile?category=.
+// Recent is the decayed profile the ranker uses; AllTime weights every post equally.
+type TasteProfileView struct {
+	Category     string       `json:"category"`
+	PostCount    int32        `json:"post_count"`
+	HalfLifeDays *int32       `json:"half_life_days"`
+	Recent       TasteSummary `json:"recent"`
+	AllTime      TasteSummary `json:"all_time"`
+	ComputedAt   *time.Time   `json:"computed_at"`
+	// InferredFrom names the categories a provisional profile was seeded
+	// from; it is empty once the user has posted in this category
+	InferredFrom []string `json:"inferred_from,omitempty"`
+	// QuizWeight is the share of Recent that comes from onboarding quiz
+	// answers; it fades to 0 as the user posts
+	QuizWeight float64 `json:"quiz_weight"`
+}
+
+// Profile returns the user's recent and all-time tastes, computing the profile first if missing
+func (c *TasteProfileComputer) Profile(ctx context.Context, userID pgtype.UUID, category string) (TasteProfileView, error) {
+	params := sqlc.GetUserTasteProfileParams{UserID: userID, Category: category}
+	profile, err := c.Q.GetUserTasteProfile(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
 		if err := c.ComputeProfile(ctx, userID, category); err != nil {
-			return err
-		}
-		profile, err = c.Q.GetUserTasteProfile(ctx, sqlc.GetUserTasteProfileParams{
-			UserID:   userID,
-			Category: category,
-		})
-		if err != nil {
-			return err
+			return TasteProfileView{}, err
 		}
+		profile, err = c.Q.GetUserTasteProfile(ctx, params)
 	}
-
-	// Parse existing weights
-	var likedTags TagWeights
-	var dislikedTags TagWeights
-	json.Unmarshal(profile.LikedTagsJson, &likedTags)
-	json.Unmarshal(profile.DislikedTagsJson, &dislikedTags)
-
-	// Update weights based on feedback
-	feedbackWeight := 0.5 // Base weight for feedback
-
-	for _, tag := range beverageTags {
-		tagName := tag.Tag
-
-		switch feedbackType {
-		case "more_like_this":
-			likedTags[tagName] += feedbackWeight
-			// Reduce dislike if present
-			if dislikedTags[tagName] > 0 {
-				dislikedTags[tagName] = math.Max(0, dislikedTags[tagName]-feedbackWeight)
-			}
-		case "less_like_this":
-			dislikedTags[tagName] += feedbackWeight
-			// Reduce like if present
-			if likedTags[tagName] > 0 {
-				likedTags[tagName] = math.Max(0, likedTags[tagName]-feedbackWeight)
-			}
-		case "hide":
-			// Don't modify weights for hide, just exclude in queries
-			continue
-		}
+	if err != nil {
+		return TasteProfileView{}, err
 	}
 
-	// Normalize weights
-	normalizeWeights(likedTags)
-	normalizeWeights(dislikedTags)
-
-	// Update profile
-	likedJSON, _ := json.Marshal(likedTags)
-	dislikedJSON, _ := json.Marshal(dislikedTags)
+	view := TasteProfileView{
+		Category:     category,
+		PostCount:    profile.PostCount.Int32,
+		Recent:       tasteSummaryView(profile.LikedTagsJson, profile.DislikedTagsJson, profile.MeanRating, profile.StdRating),
+		AllTime:      tasteSummaryView(profile.AllTimeLikedTagsJson, profile.AllTimeDislikedTagsJson, profile.AllTimeMeanRating, profile.AllTimeStdRating),
+		InferredFrom: profile.InferredFrom,
+		QuizWeight:   profile.QuizWeight,
+	}
+	if profile.HalfLifeDays.Valid {
+		days := profile.HalfLifeDays.Int32
+		view.HalfLifeDays = &days
+	}
+	if profile.LastComputedAt.Valid {
+		computedAt := profile.LastComputedAt.Time
+		view.ComputedAt = &computedAt
+	}
+	return view, nil
+}
 
-	_, err = c.Q.UpsertUserTasteProfile(ctx, sqlc.UpsertUserTasteProfileParams{
-		UserID:           userID,
-		Category:         category,
-		LikedTagsJson:    likedJSON,
-		DislikedTagsJson: dislikedJSON,
-		MeanRating:       profile.MeanRating,
-		StdRating:        profile.StdRating,
-		PostCount:        profile.PostCount,
-	})
+func tasteSummaryView(likedJSON, dislikedJSON []byte, meanRating, stdRating pgtype.Numeric) TasteSummary {
+	summary := TasteSummary{
+		LikedTags:    TagWeights{},
+		DislikedTags: TagWeights{},
+		MeanRating:   numericPtr(meanRating),
+		StdRating:    numericPtr(stdRating),
+	}
+	json.Unmarshal(likedJSON, &summary.LikedTags)
+	json.Unmarshal(dislikedJSON, &summary.DislikedTags)
+	return summary
+}
 
-	return err
+func numericPtr(n pgtype.Numeric) *float64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return nil
+	}
+	return &f.Float64
 }
 
 // Helper functions
@@ -261,6 +431,13 @@ func stdDev(values []float64, mean float64) float64 {
 	return math.Sqrt(variance / float64(len(values)-1))
 }
 
+// floatToNumeric converts a statistic for storage in a NUMERIC(4,2) column
+func floatToNumeric(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	n.Scan(strconv.FormatFloat(f, 'f', 2, 64))
+	return n
+}
+
 func normalizeWeights(weights TagWeights) {
 	if len(weights) == 0 {
 		return
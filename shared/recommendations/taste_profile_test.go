@@ -0,0 +1,393 @@
+package recommendations
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestComputeProfileWithoutPosts(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+
+	if err := NewTasteProfileComputer(store).ComputeProfile(context.Background(), user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, liked, disliked := store.profile(t, user, "wine")
+	if p.PostCount.Int32 != 0 || p.MeanRating.Valid {
+		t.Errorf("post count = %d, mean valid = %v", p.PostCount.Int32, p.MeanRating.Valid)
+	}
+	if len(liked) != 0 || len(disliked) != 0 {
+		t.Errorf("liked = %v, disliked = %v", liked, disliked)
+	}
+}
+
+func TestComputeProfileSplitsLikedAndDisliked(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addPost(user, "beer", testUUID(1), 9.0, "hoppy", "citrus")
+	store.addPost(user, "beer", testUUID(2), 9.0, "hoppy")
+	store.addPost(user, "beer", testUUID(3), 3.0, "sour")
+	store.addPost(user, "beer", testUUID(4), 6.0, "malty")
+
+	if err := NewTasteProfileComputer(store).ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, liked, disliked := store.profile(t, user, "beer")
+
+	// Rows are per tag; stats must be per post
+	if p.PostCount.Int32 != 4 {
+		t.Errorf("post count = %d, want 4", p.PostCount.Int32)
+	}
+	meanRating, _ := p.MeanRating.Float64Value()
+	if !approx(meanRating.Float64, 6.75) {
+		t.Errorf("mean = %v, want 6.75", meanRating.Float64)
+	}
+	stdRating, _ := p.StdRating.Float64Value()
+	if !approx(stdRating.Float64, 2.87) {
+		t.Errorf("std = %v, want 2.87", stdRating.Float64)
+	}
+
+	assertWeights(t, "liked", liked, TagWeights{"hoppy": 1.0, "citrus": 0.5})
+	assertWeights(t, "disliked", disliked, TagWeights{"sour": 1.0})
+}
+
+// feedbackBase posts a beer history whose profile likes hoppy and citrus
+// and dislikes sour, and computes it
+func feedbackBase(t *testing.T, store *fakeStore, user pgtype.UUID) *TasteProfileComputer {
+	t.Helper()
+	store.addPost(user, "beer", testUUID(901), 9.0, "hoppy", "citrus")
+	store.addPost(user, "beer", testUUID(902), 9.0, "hoppy")
+	store.addPost(user, "beer", testUUID(903), 3.0, "sour")
+	store.addPost(user, "beer", testUUID(904), 6.0, "malty")
+	c := NewTasteProfileComputer(store)
+	if err := c.ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestGiveFeedbackMoreLikeThis(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Citrus IPA", "beer", 8.0, 10, "citrus", "pine", "sour")
+	c := feedbackBase(t, store, user)
+	scans := store.calls["GetUserPostsForCategory"]
+
+	// Repeated clicks don't compound
+	for range 2 {
+		if err := c.GiveFeedback(context.Background(), user, testUUID(1), MoreLikeThisFeedback); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, liked, disliked := store.profile(t, user, "beer")
+	assertWeights(t, "liked", liked, TagWeights{"hoppy": 1.0, "citrus": 1.0, "pine": 0.5, "sour": 0.5})
+	assertWeights(t, "disliked", disliked, TagWeights{"sour": 1.0})
+	if p.PostCount.Int32 != 4 {
+		t.Errorf("feedback changed post count to %d", p.PostCount.Int32)
+	}
+	// The profile is rebuilt from the stored stats, not by rescanning posts
+	if n := store.calls["GetUserPostsForCategory"] - scans; n != 0 {
+		t.Errorf("feedback rescanned posts %d times", n)
+	}
+}
+
+func TestGiveFeedbackLessLikeThis(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Citrus Wheat", "beer", 6.0, 10, "citrus")
+	c := feedbackBase(t, store, user)
+
+	if err := c.GiveFeedback(context.Background(), user, testUUID(1), LessLikeThisFeedback); err != nil {
+		t.Fatal(err)
+	}
+
+	_, liked, disliked := store.profile(t, user, "beer")
+	assertWeights(t, "liked", liked, TagWeights{"hoppy": 1.0})
+	assertWeights(t, "disliked", disliked, TagWeights{"sour": 1.0, "citrus": 0.5})
+}
+
+func TestRemoveFeedbackUndoesItExactly(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Citrus IPA", "beer", 8.0, 10, "citrus", "pine")
+	store.addBeverage(testUUID(2), "Gose", "beer", 6.0, 10, "sour")
+	c := feedbackBase(t, store, user)
+	_, wantLiked, wantDisliked := store.profile(t, user, "beer")
+
+	ctx := context.Background()
+	if err := c.GiveFeedback(ctx, user, testUUID(1), LessLikeThisFeedback); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GiveFeedback(ctx, user, testUUID(2), MoreLikeThisFeedback); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := c.RemoveFeedback(ctx, user, testUUID(1), LessLikeThisFeedback); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.RemoveFeedback(ctx, user, testUUID(2), MoreLikeThisFeedback); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, liked, disliked := store.profile(t, user, "beer")
+	assertWeights(t, "liked", liked, wantLiked)
+	assertWeights(t, "disliked", disliked, wantDisliked)
+}
+
+func TestGiveFeedbackWithoutTasteEffectKeepsWeights(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Sour", "beer", 6.0, 10, "sour", "hoppy")
+	c := feedbackBase(t, store, user)
+	writes := store.calls["UpsertUserTasteProfile"]
+
+	for _, feedbackType := range []string{HideFeedback, AlreadyTriedFeedback, NotAvailableFeedback} {
+		if err := c.GiveFeedback(context.Background(), user, testUUID(1), feedbackType); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := store.calls["UpsertUserTasteProfile"] - writes; n != 0 {
+		t.Errorf("profile rewritten %d times", n)
+	}
+	if n := len(store.feedback[user]); n != 3 {
+		t.Errorf("%d feedback rows recorded, want 3", n)
+	}
+	if err := c.GiveFeedback(context.Background(), user, testUUID(1), "meh"); err == nil {
+		t.Error("unknown feedback type accepted")
+	}
+	if err := c.RemoveFeedback(context.Background(), user, testUUID(1), "meh"); err == nil {
+		t.Error("unknown feedback type removed")
+	}
+}
+
+func TestGiveFeedbackOnUntaggedBeverageKeepsWeights(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Mystery", "beer", 6.0, 10)
+	c := feedbackBase(t, store, user)
+
+	if err := c.GiveFeedback(context.Background(), user, testUUID(1), MoreLikeThisFeedback); err != nil {
+		t.Fatal(err)
+	}
+
+	_, liked, disliked := store.profile(t, user, "beer")
+	assertWeights(t, "liked", liked, TagWeights{"hoppy": 1.0, "citrus": 0.5})
+	assertWeights(t, "disliked", disliked, TagWeights{"sour": 1.0})
+}
+
+func TestGiveFeedbackComputesMissingProfile(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addBeverage(testUUID(1), "Citrus IPA", "beer", 8.0, 10, "citrus", "pine")
+
+	if err := NewTasteProfileComputer(store).GiveFeedback(context.Background(), user, testUUID(1), MoreLikeThisFeedback); err != nil {
+		t.Fatal(err)
+	}
+
+	_, liked, _ := store.profile(t, user, "beer")
+	assertWeights(t, "liked", liked, TagWeights{"citrus": 1.0, "pine": 1.0})
+}
+
+func TestNormalizeWeights(t *testing.T) {
+	w := TagWeights{"a": 4, "b": 2, "c": 0}
+	normalizeWeights(w)
+	assertWeights(t, "normalized", w, TagWeights{"a": 1, "b": 0.5, "c": 0})
+
+	zero := TagWeights{"a": 0}
+	normalizeWeights(zero)
+	assertWeights(t, "all zero", zero, TagWeights{"a": 0})
+}
+
+func TestMeanAndStdDev(t *testing.T) {
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	m := mean(values)
+	if !approx(m, 5) {
+		t.Errorf("mean = %v", m)
+	}
+	if sd := stdDev(values, m); !approx(sd, 2.14) {
+		t.Errorf("stdDev = %v", sd)
+	}
+	if mean(nil) != 0 || stdDev([]float64{3}, 3) != 0 {
+		t.Error("empty and single-value inputs should give 0")
+	}
+}
+
+func approx(got, want float64) bool {
+	return math.Abs(got-want) < 0.01
+}
+
+func assertWeights(t *testing.T, label string, got, want TagWeights) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", label, got, want)
+		return
+	}
+	for tag, w := range want {
+		if g, ok := got[tag]; !ok || !approx(g, w) {
+			t.Errorf("%s[%s] = %v, want %v", label, tag, g, w)
+		}
+	}
+}
+
+var decayNow = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func decayingComputer(store *fakeStore) *TasteProfileComputer {
+	c := NewTasteProfileComputer(store)
+	c.Now = func() time.Time { return decayNow }
+	return c
+}
+
+func TestComputeProfileDecaysOldPosts(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setHalfLife("beer", pgtype.Int4{Int32: 30, Valid: true})
+	store.addPostAt(user, "beer", testUUID(1), 3.0, decayNow.AddDate(-3, 0, 0), "sour")
+	store.addPostAt(user, "beer", testUUID(2), 9.0, decayNow.AddDate(0, 0, -1), "hoppy")
+	store.addPostAt(user, "beer", testUUID(3), 7.0, decayNow.AddDate(0, 0, -2), "malty")
+
+	if err := decayingComputer(store).ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, liked, disliked := store.profile(t, user, "beer")
+	if p.HalfLifeDays.Int32 != 30 {
+		t.Errorf("half life = %d, want 30", p.HalfLifeDays.Int32)
+	}
+
+	// The three-year-old sour post barely registers in the recent view
+	recentMean, _ := p.MeanRating.Float64Value()
+	recentStd, _ := p.StdRating.Float64Value()
+	if !approx(recentMean.Float64, 8.0) || !approx(recentStd.Float64, 1.41) {
+		t.Errorf("recent mean = %v std = %v, want 8.00 and 1.41", recentMean.Float64, recentStd.Float64)
+	}
+	assertWeights(t, "recent liked", liked, TagWeights{"hoppy": 1.0})
+	assertWeights(t, "recent disliked", disliked, TagWeights{"malty": 1.0, "sour": 0})
+
+	view, err := decayingComputer(store).Profile(context.Background(), user, "beer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.AllTime.MeanRating == nil || !approx(*view.AllTime.MeanRating, 6.33) {
+		t.Errorf("all-time mean = %v, want 6.33", view.AllTime.MeanRating)
+	}
+	if view.AllTime.StdRating == nil || !approx(*view.AllTime.StdRating, 3.06) {
+		t.Errorf("all-time std = %v, want 3.06", view.AllTime.StdRating)
+	}
+	assertWeights(t, "all-time liked", view.AllTime.LikedTags, TagWeights{"hoppy": 1.0})
+	assertWeights(t, "all-time disliked", view.AllTime.DislikedTags, TagWeights{"sour": 1.0})
+	assertWeights(t, "view recent liked", view.Recent.LikedTags, liked)
+}
+
+func TestComputeProfileHalfLifeSettings(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setHalfLife("wine", pgtype.Int4{})
+	store.addPostAt(user, "wine", testUUID(1), 9.0, decayNow.AddDate(-5, 0, 0), "oaky")
+	store.addPostAt(user, "wine", testUUID(2), 4.0, decayNow, "tannic")
+	store.addPost(user, "beer", testUUID(3), 8.0, "hoppy")
+
+	c := decayingComputer(store)
+	if err := c.ComputeProfile(context.Background(), user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A NULL half-life disables decay, so recent equals all-time
+	p, liked, _ := store.profile(t, user, "wine")
+	if p.HalfLifeDays.Valid {
+		t.Errorf("half life = %d, want NULL", p.HalfLifeDays.Int32)
+	}
+	assertWeights(t, "wine liked", liked, TagWeights{"oaky": 1.0})
+	recentMean, _ := p.MeanRating.Float64Value()
+	allTimeMean, _ := p.AllTimeMeanRating.Float64Value()
+	if string(p.LikedTagsJson) != string(p.AllTimeLikedTagsJson) || recentMean != allTimeMean {
+		t.Errorf("recent %s differs from all-time %s without decay", p.LikedTagsJson, p.AllTimeLikedTagsJson)
+	}
+
+	// Categories without a setting fall back to the default
+	if p, _, _ := store.profile(t, user, "beer"); p.HalfLifeDays.Int32 != DefaultHalfLifeDays {
+		t.Errorf("beer half life = %d, want %d", p.HalfLifeDays.Int32, DefaultHalfLifeDays)
+	}
+}
+
+func TestGiveFeedbackKeepsAllTimeView(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addPostAt(user, "beer", testUUID(1), 9.0, decayNow, "hoppy")
+	store.addPostAt(user, "beer", testUUID(2), 3.0, decayNow, "sour")
+	store.addBeverage(testUUID(10), "Kettle Sour", "beer", 7.0, 10, "sour")
+
+	c := decayingComputer(store)
+	if err := c.ComputeProfile(context.Background(), user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GiveFeedback(context.Background(), user, testUUID(10), MoreLikeThisFeedback); err != nil {
+		t.Fatal(err)
+	}
+
+	view, err := c.Profile(context.Background(), user, "beer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWeights(t, "recent liked", view.Recent.LikedTags, TagWeights{"hoppy": 1.0, "sour": 0.5})
+	assertWeights(t, "all-time liked", view.AllTime.LikedTags, TagWeights{"hoppy": 1.0})
+	if view.HalfLifeDays == nil || *view.HalfLifeDays != DefaultHalfLifeDays {
+		t.Errorf("half life = %v, want %d", view.HalfLifeDays, DefaultHalfLifeDays)
+	}
+}
+
+func TestDecayWeight(t *testing.T) {
+	halfLife := pgtype.Int4{Int32: 100, Valid: true}
+	at := func(daysAgo int) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: decayNow.AddDate(0, 0, -daysAgo), Valid: true}
+	}
+
+	if w := decayWeight(at(100), decayNow, halfLife); !approx(w, 0.5) {
+		t.Errorf("one half-life = %v, want 0.5", w)
+	}
+	if w := decayWeight(at(200), decayNow, halfLife); !approx(w, 0.25) {
+		t.Errorf("two half-lives = %v, want 0.25", w)
+	}
+	if w := decayWeight(at(-5), decayNow, halfLife); w != 1 {
+		t.Errorf("future post = %v, want 1", w)
+	}
+	if w := decayWeight(pgtype.Timestamptz{}, decayNow, halfLife); w != 1 {
+		t.Errorf("missing created_at = %v, want 1", w)
+	}
+	if w := decayWeight(at(1000), decayNow, pgtype.Int4{}); w != 1 {
+		t.Errorf("decay disabled = %v, want 1", w)
+	}
+}
+
+func TestTasteStatsMatchUnweighted(t *testing.T) {
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	stats := newTasteStats()
+	for _, v := range values {
+		stats.add(postContribution{Rating: testNumeric(v)}, 1)
+	}
+
+	m := mean(values)
+	if !approx(stats.mean(), m) {
+		t.Errorf("stats mean = %v, want %v", stats.mean(), m)
+	}
+	if !approx(stats.stdDev(), stdDev(values, m)) {
+		t.Errorf("stats stdDev = %v, want %v", stats.stdDev(), stdDev(values, m))
+	}
+
+	single := newTasteStats()
+	single.add(postContribution{Rating: testNumeric(3)}, 0.5)
+	if single.stdDev() != 0 {
+		t.Error("single value should give 0")
+	}
+}
@@ -0,0 +1,216 @@
+package recommendations
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/burkebarcode/backend/shared/rating"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// tagStats accumulates the rated posts carrying one tag. W sums the post
+// weight times tag confidence; WR sums the same weights times the rating.
+type tagStats struct {
+	N  int     `json:"n"`
+	W  float64 `json:"w"`
+	WR float64 `json:"wr"`
+}
+
+// tasteStats are the sufficient statistics behind one view of a taste
+// profile. Posts can be added or taken back out without rescanning the
+// user's history, and summary rebuilds the profile from the sums alone.
+type tasteStats struct {
+	Posts int                  `json:"posts"`
+	Rated int                  `json:"rated"`
+	W     float64              `json:"w"`
+	W2    float64              `json:"w2"`
+	WR    float64              `json:"wr"`
+	WR2   float64              `json:"wr2"`
+	Tags  map[string]*tagStats `json:"tags"`
+}
+
+// postContribution is what one post adds to tasteStats
+type postContribution struct {
+	PostID    pgtype.UUID
+	Rating    pgtype.Numeric
+	CreatedAt pgtype.Timestamptz
+	Tags      map[string]float64 // tag -> confidence
+}
+
+func newTasteStats() *tasteStats {
+	return &tasteStats{Tags: make(map[string]*tagStats)}
+}
+
+func decodeTasteStats(data []byte) (*tasteStats, error) {
+	s := newTasteStats()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Tags == nil {
+		s.Tags = make(map[string]*tagStats)
+	}
+	return s, nil
+}
+
+// add folds a post in with weight w
+func (s *tasteStats) add(c postContribution, w float64) {
+	s.apply(c, w, 1)
+}
+
+// remove takes back a post added earlier; w is its weight rescaled to now
+func (s *tasteStats) remove(c postContribution, w float64) {
+	s.apply(c, w, -1)
+}
+
+func (s *tasteStats) apply(c postContribution, w float64, sign int) {
+	s.Posts += sign
+
+	// Unrated posts only count towards Posts
+	r, ok := rating.FromNumeric(c.Rating)
+	if !ok {
+		return
+	}
+	rf := r.Float64()
+	sw := float64(sign) * w
+
+	s.Rated += sign
+	s.W += sw
+	s.W2 += float64(sign) * w * w
+	s.WR += sw * rf
+	s.WR2 += sw * rf * rf
+	if s.Rated <= 0 {
+		// Clear rounding residue once the last rated post is gone
+		s.Rated, s.W, s.W2, s.WR, s.WR2 = 0, 0, 0, 0, 0
+	}
+
+	for tag, confidence := range c.Tags {
+		t := s.Tags[tag]
+		if t == nil {
+			t = &tagStats{}
+			s.Tags[tag] = t
+		}
+		t.N += sign
+		t.W += sw * confidence
+		t.WR += sw * confidence * rf
+		if t.N <= 0 {
+			delete(s.Tags, tag)
+		}
+	}
+}
+
+// decay scales every sum by factor, moving the stats' reference time forward
+func (s *tasteStats) decay(factor float64) {
+	s.W *= factor
+	s.W2 *= factor * factor
+	s.WR *= factor
+	s.WR2 *= factor
+	for _, t := range s.Tags {
+		t.W *= factor
+		t.WR *= factor
+	}
+}
+
+func (s *tasteStats) mean() float64 {
+	if s.Rated == 0 || s.W <= 0 {
+		return 0
+	}
+	return s.WR / s.W
+}
+
+// stdDev is the weighted sample standard deviation with reliability weights.
+// With all weights 1 it equals the plain sample standard deviation.
+func (s *tasteStats) stdDev() float64 {
+	if s.Rated < 2 || s.W <= 0 {
+		return 0
+	}
+	m := s.mean()
+	sq := s.WR2 - s.W*m*m
+	denom := s.W - s.W2/s.W
+	if sq <= 0 || denom <= 0 {
+		return 0
+	}
+	return math.Sqrt(sq / denom)
+}
+
+// summary materializes the profile. A tag is liked when the posts carrying it
+// average at least mean + 0.5*std and disliked at or below mean - 0.5*std;
+// its weight is the evidence behind it, normalized to 0-1.
+func (s *tasteStats) summary() tasteSummary {
+	summary := tasteSummary{
+		liked:    make(TagWeights),
+		disliked: make(TagWeights),
+	}
+	if s.Posts <= 0 {
+		// No posts yet, leave the profile empty
+		return summary
+	}
+
+	meanRating := s.mean()
+	stdRating := s.stdDev()
+	summary.meanRating = floatToNumeric(meanRating)
+	summary.stdRating = floatToNumeric(stdRating)
+
+	// Small tolerance so a tag on a single post still matches the mean it produced
+	const eps = 1e-9
+	highThreshold := meanRating + 0.5*stdRating - eps
+	lowThreshold := meanRating - 0.5*stdRating + eps
+
+	for tag, t := range s.Tags {
+		if t.W <= 0 {
+			continue
+		}
+		tagMean := t.WR / t.W
+		if tagMean >= highThreshold {
+			summary.liked[tag] = t.W
+		} else if tagMean <= lowThreshold {
+			summary.disliked[tag] = t.W
+		}
+	}
+
+	normalizeWeights(summary.liked)
+	normalizeWeights(summary.disliked)
+
+	return summary
+}
+
+// contributionsFromPosts groups GetUserPostsForCategory rows, one per post tag,
+// into one contribution per post
+func contributionsFromPosts(posts []sqlc.GetUserPostsForCategoryRow) []postContribution {
+	var out []postContribution
+	index := make(map[pgtype.UUID]int)
+	for _, post := range posts {
+		i, ok := index[post.ID]
+		if !ok {
+			i = len(out)
+			index[post.ID] = i
+			out = append(out, postContribution{
+				PostID:    post.ID,
+				Rating:    post.Rating,
+				CreatedAt: post.CreatedAt,
+				Tags:      make(map[string]float64),
+			})
+		}
+		if !post.Tag.Valid {
+			continue
+		}
+		confidence := 1.0
+		if post.Confidence.Valid {
+			f, _ := post.Confidence.Float64Value()
+			confidence = f.Float64
+		}
+		out[i].Tags[post.Tag.String] += confidence
+	}
+	return out
+}
+
+// contributionFromRow rebuilds what a post last added from its ledger row
+func contributionFromRow(row sqlc.UserTasteContribution) postContribution {
+	c := postContribution{
+		PostID: row.PostID,
+		Rating: row.Rating,
+		Tags:   make(map[string]float64),
+	}
+	json.Unmarshal(row.TagsJson, &c.Tags)
+	return c
+}
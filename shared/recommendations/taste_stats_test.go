@@ -0,0 +1,206 @@
+package recommendations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// clock is a TasteProfileComputer.Now that tests move forward by hand
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+// assertMatchesFullRecompute recomputes the profile from scratch in a copy of
+// the store's posts and compares it with the incrementally maintained one
+func assertMatchesFullRecompute(t *testing.T, store *fakeStore, user pgtype.UUID, category string, now time.Time) {
+	t.Helper()
+
+	full := newFakeStore()
+	full.decay = store.decay
+	full.posts = store.posts
+	c := NewTasteProfileComputer(full)
+	c.Now = func() time.Time { return now }
+	if err := c.ComputeProfile(context.Background(), user, category); err != nil {
+		t.Fatal(err)
+	}
+
+	got, gotLiked, gotDisliked := store.profile(t, user, category)
+	want, wantLiked, wantDisliked := full.profile(t, user, category)
+
+	assertWeights(t, "liked", gotLiked, wantLiked)
+	assertWeights(t, "disliked", gotDisliked, wantDisliked)
+	if got.PostCount != want.PostCount {
+		t.Errorf("post count = %d, want %d", got.PostCount.Int32, want.PostCount.Int32)
+	}
+	for _, pair := range [][2]pgtype.Numeric{
+		{got.MeanRating, want.MeanRating},
+		{got.StdRating, want.StdRating},
+		{got.AllTimeMeanRating, want.AllTimeMeanRating},
+		{got.AllTimeStdRating, want.AllTimeStdRating},
+	} {
+		g, _ := pair[0].Float64Value()
+		w, _ := pair[1].Float64Value()
+		if g.Valid != w.Valid || !approx(g.Float64, w.Float64) {
+			t.Errorf("stat = %v, want %v", g, w)
+		}
+	}
+}
+
+func TestApplyPostMatchesFullRecompute(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setHalfLife("beer", pgtype.Int4{Int32: 60, Valid: true})
+	clk := &clock{t: decayNow}
+
+	store.addPostAt(user, "beer", testUUID(1), 9.0, clk.t.AddDate(0, -8, 0), "hoppy", "citrus")
+	store.addPostAt(user, "beer", testUUID(2), 4.0, clk.t.AddDate(0, -6, 0), "sour")
+	store.addPostAt(user, "beer", testUUID(3), 7.0, clk.t.AddDate(0, -1, 0), "malty")
+
+	c := NewTasteProfileComputer(store)
+	c.Now = clk.now
+	ctx := context.Background()
+	if err := c.ComputeProfile(ctx, user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new post is tagged a week later
+	clk.t = clk.t.AddDate(0, 0, 7)
+	store.addPostAt(user, "beer", testUUID(4), 8.5, clk.t, "sour", "funky")
+	if err := c.ApplyPost(ctx, user, "beer", testUUID(4)); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFullRecompute(t, store, user, "beer", clk.t)
+
+	// An old post is edited
+	clk.t = clk.t.AddDate(0, 0, 3)
+	store.setPostRating(testUUID(2), 2.0)
+	if err := c.ApplyPost(ctx, user, "beer", testUUID(2)); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFullRecompute(t, store, user, "beer", clk.t)
+
+	// And another is deleted
+	clk.t = clk.t.AddDate(0, 1, 0)
+	store.removePost(testUUID(1))
+	if err := c.ApplyPost(ctx, user, "beer", testUUID(1)); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFullRecompute(t, store, user, "beer", clk.t)
+
+	if n := store.calls["GetUserPostsForCategory"]; n != 1 {
+		t.Errorf("posts rescanned %d times, want only the initial compute", n)
+	}
+	if _, ok := store.ledger[contributionKey{profileKey{user, "beer"}, testUUID(1)}]; ok {
+		t.Error("deleted post still has a contribution")
+	}
+}
+
+func TestApplyPostIsIdempotent(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addPostAt(user, "wine", testUUID(1), 9.0, decayNow.AddDate(0, -2, 0), "oaky")
+	store.addPostAt(user, "wine", testUUID(2), 5.0, decayNow.AddDate(0, -1, 0), "tannic")
+
+	c := decayingComputer(store)
+	ctx := context.Background()
+	if err := c.ComputeProfile(ctx, user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+	// Duplicate events for an unchanged post must not double count it
+	for i := 0; i < 3; i++ {
+		if err := c.ApplyPost(ctx, user, "wine", testUUID(2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertMatchesFullRecompute(t, store, user, "wine", decayNow)
+}
+
+func TestApplyPostWithoutStatsRecomputes(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addPostAt(user, "beer", testUUID(1), 9.0, decayNow, "hoppy")
+
+	if err := decayingComputer(store).ApplyPost(context.Background(), user, "beer", testUUID(1)); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.calls["GetUserPostsForCategory"]; n != 1 {
+		t.Errorf("missing stats should trigger a full recompute, got %d scans", n)
+	}
+	_, liked, _ := store.profile(t, user, "beer")
+	assertWeights(t, "liked", liked, TagWeights{"hoppy": 1.0})
+}
+
+func TestApplyPostRecomputesWhenHalfLifeChanges(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addPostAt(user, "beer", testUUID(1), 9.0, decayNow.AddDate(-1, 0, 0), "hoppy")
+	store.addPostAt(user, "beer", testUUID(2), 3.0, decayNow, "sour")
+
+	c := decayingComputer(store)
+	ctx := context.Background()
+	if err := c.ComputeProfile(ctx, user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+
+	store.setHalfLife("beer", pgtype.Int4{Int32: 30, Valid: true})
+	if err := c.ApplyPost(ctx, user, "beer", testUUID(2)); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.calls["GetUserPostsForCategory"]; n != 2 {
+		t.Errorf("half-life change should trigger a full recompute, got %d scans", n)
+	}
+	if p, _, _ := store.profile(t, user, "beer"); p.HalfLifeDays.Int32 != 30 {
+		t.Errorf("half life = %d, want 30", p.HalfLifeDays.Int32)
+	}
+}
+
+func TestApplyPostMovedToOtherCategory(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.addPostAt(user, "beer", testUUID(1), 9.0, decayNow, "hoppy")
+	store.addPostAt(user, "beer", testUUID(2), 4.0, decayNow, "sweet")
+
+	c := decayingComputer(store)
+	ctx := context.Background()
+	if err := c.ComputeProfile(ctx, user, "beer"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The post is recategorized as a cocktail
+	beer := profileKey{user, "beer"}
+	moved := store.posts[beer][1]
+	moved.DrinkCategory = "cocktail"
+	store.posts[beer] = store.posts[beer][:1]
+	store.posts[profileKey{user, "cocktail"}] = append(store.posts[profileKey{user, "cocktail"}], moved)
+
+	if err := c.ApplyPost(ctx, user, "beer", testUUID(2)); err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesFullRecompute(t, store, user, "beer", decayNow)
+	if p, _, _ := store.profile(t, user, "beer"); p.PostCount.Int32 != 1 {
+		t.Errorf("beer post count = %d, want 1", p.PostCount.Int32)
+	}
+}
+
+func TestDedupeProfileEvents(t *testing.T) {
+	user := testUUID(100)
+	events := []sqlc.TasteProfileEvent{
+		{ID: 1, UserID: user, Category: "beer", PostID: testUUID(1), Reason: "tagged"},
+		{ID: 2, UserID: user, Category: "beer", PostID: testUUID(2), Reason: "edited"},
+		{ID: 3, UserID: user, Category: "beer", PostID: testUUID(1), Reason: "edited"},
+		{ID: 4, UserID: user, Category: "wine", PostID: testUUID(1), Reason: "edited"},
+	}
+
+	got := dedupeProfileEvents(events)
+	var ids []int64
+	for _, e := range got {
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 4 {
+		t.Errorf("kept events %v, want [1 2 4]", ids)
+	}
+}
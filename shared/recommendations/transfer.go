@@ -0,0 +1,138 @@
+package recommendations
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// minInferredWeight drops transferred tag weights too faint to rank on
+const minInferredWeight = 0.05
+
+// tagTransfer is a tag a preference carries over to in another category
+type tagTransfer struct {
+	tag    string
+	factor float64
+}
+
+// tagTransfers maps a tag to the tags it implies in other categories and
+// how much of the preference carries over. Descriptor and structure tags
+// that mean the same thing everywhere map to themselves; a few
+// category-specific words map to their nearest counterparts. Tags not listed
+// don't transfer.
+var tagTransfers = map[string][]tagTransfer{
+	// Structure
+	"dry":          {{"dry", 0.8}},
+	"sweet":        {{"sweet", 0.8}},
+	"bitter":       {{"bitter", 0.8}},
+	"sour":         {{"sour", 0.7}, {"tart", 0.5}},
+	"tart":         {{"tart", 0.7}, {"sour", 0.5}},
+	"acidic":       {{"acidic", 0.6}, {"tart", 0.4}},
+	"crisp":        {{"crisp", 0.7}},
+	"light-bodied": {{"light-bodied", 0.6}},
+	"full-bodied":  {{"full-bodied", 0.6}},
+	"strong":       {{"strong", 0.6}},
+	"creamy":       {{"creamy", 0.5}},
+
+	// Descriptors
+	"citrus":    {{"citrus", 0.9}},
+	"fruity":    {{"fruity", 0.7}},
+	"tropical":  {{"tropical", 0.8}},
+	"berry":     {{"berry", 0.7}},
+	"floral":    {{"floral", 0.7}},
+	"herbal":    {{"herbal", 0.8}},
+	"spicy":     {{"spicy", 0.8}},
+	"peppery":   {{"peppery", 0.7}, {"spicy", 0.4}},
+	"smoky":     {{"smoky", 0.9}},
+	"oaky":      {{"oaky", 0.9}},
+	"vanilla":   {{"vanilla", 0.8}},
+	"caramel":   {{"caramel", 0.7}},
+	"honey":     {{"honey", 0.7}},
+	"chocolate": {{"chocolate", 0.7}},
+	"coffee":    {{"coffee", 0.7}},
+	"earthy":    {{"earthy", 0.7}},
+	"nutty":     {{"nutty", 0.7}},
+	"salty":     {{"salty", 0.6}},
+
+	// Category words and their nearest counterparts
+	"hoppy":       {{"bitter", 0.5}, {"herbal", 0.3}},
+	"roasty":      {{"coffee", 0.5}, {"chocolate", 0.4}},
+	"malty":       {{"caramel", 0.4}, {"nutty", 0.3}},
+	"tannic":      {{"dry", 0.4}, {"bitter", 0.3}},
+	"buttery":     {{"creamy", 0.4}, {"vanilla", 0.3}},
+	"peaty":       {{"smoky", 0.7}},
+	"barrel-aged": {{"oaky", 0.7}, {"vanilla", 0.3}},
+	"minerally":   {{"salty", 0.3}, {"crisp", 0.3}},
+}
+
+// transferTags carries tag weights over to another category. A target tag
+// reached from several source tags keeps the strongest transfer.
+func transferTags(weights TagWeights) TagWeights {
+	out := make(TagWeights)
+	for tag, w := range weights {
+		for _, t := range tagTransfers[strings.ToLower(tag)] {
+			out[t.tag] = max(out[t.tag], w*t.factor)
+		}
+	}
+	return out
+}
+
+// inferProfile builds a provisional profile for a category the user has no
+// posts in from their profiles in other categories, weighting each source
+// by its post count. Only profiles built from posts are sources, so
+// inferences don't chain. sources is empty when there is nothing to infer from.
+func (c *TasteProfileComputer) inferProfile(ctx context.Context, userID pgtype.UUID, category string) (liked, disliked TagWeights, sources []string, err error) {
+	profiles, err := c.Q.ListTasteProfilesForUsers(ctx, []pgtype.UUID{userID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Category < profiles[j].Category })
+
+	liked, disliked = make(TagWeights), make(TagWeights)
+	total := 0.0
+	for _, p := range profiles {
+		if p.Category == category || len(p.InferredFrom) > 0 || p.PostCount.Int32 < coldStartPostCount {
+			continue
+		}
+		summary := tasteSummaryView(p.LikedTagsJson, p.DislikedTagsJson, p.MeanRating, p.StdRating)
+		share := float64(p.PostCount.Int32)
+		for tag, w := range transferTags(summary.LikedTags) {
+			liked[tag] += share * w
+		}
+		for tag, w := range transferTags(summary.DislikedTags) {
+			disliked[tag] += share * w
+		}
+		total += share
+		sources = append(sources, p.Category)
+	}
+	if total == 0 {
+		return nil, nil, nil, nil
+	}
+
+	for _, weights := range []TagWeights{liked, disliked} {
+		for tag, w := range weights {
+			if w /= total; w < minInferredWeight {
+				delete(weights, tag)
+			} else {
+				weights[tag] = w
+			}
+		}
+	}
+	return liked, disliked, sources, nil
+}
+
+// inferredReason is the reason given first on recommendations ranked from
+// an inferred profile
+func inferredReason(sources []string) string {
+	return "Based on your taste in " + strings.Join(sources, " and ")
+}
+
+// personalized reports whether a profile is ranked on rather than treated as
+// cold start: it has enough posts, onboarding quiz answers, or weights
+// inferred from other categories
+func personalized(p sqlc.UserTasteProfile) bool {
+	return p.PostCount.Int32 >= coldStartPostCount || p.QuizWeight > 0 || len(p.InferredFrom) > 0
+}
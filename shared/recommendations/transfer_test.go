@@ -0,0 +1,122 @@
+package recommendations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTransferTags(t *testing.T) {
+	got := transferTags(TagWeights{"citrus": 1.0, "hoppy": 0.8, "bitter": 0.2, "lacing": 1.0})
+	assertWeights(t, "transferred", got, TagWeights{
+		"citrus": 0.9,
+		// hoppy's 0.8 * 0.5 beats bitter's own 0.2 * 0.8
+		"bitter": 0.4,
+		"herbal": 0.24,
+	})
+}
+
+func TestComputeProfileInfersFromOtherCategories(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"citrus": 1.0, "hoppy": 0.5}, TagWeights{"sweet": 1.0}, 9)
+	store.setProfile(user, "cocktail", TagWeights{"smoky": 1.0}, TagWeights{}, 3)
+
+	c := NewTasteProfileComputer(store)
+	if err := c.ComputeProfile(context.Background(), user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, liked, disliked := store.profile(t, user, "wine")
+	if want := []string{"beer", "cocktail"}; !reflect.DeepEqual(p.InferredFrom, want) {
+		t.Errorf("inferred from = %v, want %v", p.InferredFrom, want)
+	}
+	if p.PostCount.Int32 != 0 {
+		t.Errorf("post count = %d, want 0", p.PostCount.Int32)
+	}
+	// Weighted 9:3 by post count
+	assertWeights(t, "liked", liked, TagWeights{"citrus": 0.675, "smoky": 0.225, "bitter": 0.1875, "herbal": 0.1125})
+	assertWeights(t, "disliked", disliked, TagWeights{"sweet": 0.6})
+
+	view, err := c.Profile(context.Background(), user, "wine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view.InferredFrom) != 2 {
+		t.Errorf("view inferred from = %v", view.InferredFrom)
+	}
+
+	// The first post replaces the inferred profile
+	store.addPost(user, "wine", testUUID(1), 8.0, "oaky")
+	if err := c.ComputeProfile(context.Background(), user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+	p, liked, _ = store.profile(t, user, "wine")
+	if len(p.InferredFrom) != 0 {
+		t.Errorf("inferred from = %v after a post", p.InferredFrom)
+	}
+	assertWeights(t, "liked", liked, TagWeights{"oaky": 1.0})
+}
+
+func TestComputeProfileInfersOnlyFromPostedProfiles(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"citrus": 1.0}, TagWeights{}, 5)
+
+	c := NewTasteProfileComputer(store)
+	if err := c.ComputeProfile(context.Background(), user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+	// Too few posts to transfer from
+	store.setProfile(user, "beer", TagWeights{"citrus": 1.0}, TagWeights{}, 2)
+	if err := c.ComputeProfile(context.Background(), user, "cocktail"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, liked, _ := store.profile(t, user, "cocktail")
+	if len(p.InferredFrom) != 0 || len(liked) != 0 {
+		t.Errorf("inferred from = %v, liked = %v; want nothing from an inferred or cold-start profile", p.InferredFrom, liked)
+	}
+}
+
+func TestRankRecommendationsWithInferredProfile(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"citrus": 1.0}, TagWeights{}, 8)
+	store.addBeverage(testUUID(1), "Popular Red", "wine", 8.0, 400, "jammy")
+	store.addBeverage(testUUID(2), "Zesty White", "wine", 7.5, 20, "citrus")
+
+	if err := NewTasteProfileComputer(store).ComputeProfile(context.Background(), user, "wine"); err != nil {
+		t.Fatal(err)
+	}
+	results, err := NewRanker(store).RankRecommendations(context.Background(), user, "wine", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "Zesty White" {
+		t.Fatalf("results = %v, want the citrus wine first", names(results))
+	}
+	if results[0].Reasons[0] != "Based on your taste in beer" {
+		t.Errorf("reasons = %v", results[0].Reasons)
+	}
+}
+
+func TestRankRecommendationsInfersMissingProfile(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	store.setProfile(user, "beer", TagWeights{"citrus": 1.0}, TagWeights{}, 8)
+	store.addBeverage(testUUID(1), "Popular Red", "wine", 8.0, 400, "jammy")
+	store.addBeverage(testUUID(2), "Zesty White", "wine", 7.5, 20, "citrus")
+
+	// No wine profile row exists until the ranker computes one
+	results, err := NewRanker(store).RankRecommendations(context.Background(), user, "wine", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "Zesty White" {
+		t.Fatalf("results = %v, want the inferred citrus match first", names(results))
+	}
+	if results[0].Reasons[0] != "Based on your taste in beer" {
+		t.Errorf("reasons = %v", results[0].Reasons)
+	}
+}
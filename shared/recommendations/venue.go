@@ -0,0 +1,93 @@
+package recommendations
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/burkebarcode/backend/shared/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// venueBlendWeight is the score a beverage seen at the venue today adds
+	venueBlendWeight = 20.0
+	// venueSightingHalfLifeDays halves a sighting's boost every 30 days, since
+	// menus change
+	venueSightingHalfLifeDays = 30.0
+	// venueReasonThreshold is the recency above which the sighting is given as a reason
+	venueReasonThreshold = 0.5
+)
+
+// VenueRef identifies a venue by our ID or by its map provider place ID;
+// either may be left empty
+type VenueRef struct {
+	ID              pgtype.UUID
+	ExternalPlaceID string
+}
+
+// venueSighting is what posts at a venue say about one beverage
+type venueSighting struct {
+	sightings  int
+	lastSeen   time.Time
+	priceCents int32
+}
+
+// RankAtVenue is RankRecommendations limited to beverages posted at the
+// venue; backs GET /v1/venues/{id}/recommendations
+func (r *Ranker) RankAtVenue(ctx context.Context, userID pgtype.UUID, category string, venue VenueRef, limit int32) ([]RankedBeverage, error) {
+	opts := DefaultRankOptions(limit)
+	opts.Venue = &venue
+	results, err := r.RankWithOptions(ctx, userID, category, opts)
+	if err != nil {
+		return nil, err
+	}
+	if r.Impressions != nil {
+		r.Impressions.LogList(ctx, userID, pgtype.UUID{}, results)
+	}
+	return results, nil
+}
+
+// loadVenueCandidates returns the beverages seen at the venue, most recent
+// first, with their sightings. Unlike GetRecommendationCandidates it keeps
+// beverages the user has posted: at a venue the menu is what it is.
+func (r *Ranker) loadVenueCandidates(ctx context.Context, userID pgtype.UUID, category string, venue VenueRef, filter RecommendationFilter, limit int32) ([]sqlc.GetRecommendationCandidatesRow, map[pgtype.UUID]*venueSighting, error) {
+	rows, err := r.Q.GetVenueCandidates(ctx, filter.venueParams(venue, category, userID, limit))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := make([]sqlc.GetRecommendationCandidatesRow, 0, len(rows))
+	sightings := make(map[pgtype.UUID]*venueSighting, len(rows))
+	for _, row := range rows {
+		candidates = append(candidates, sqlc.GetRecommendationCandidatesRow{
+			ID:          row.ID,
+			Name:        row.Name,
+			Brand:       row.Brand,
+			Category:    row.Category,
+			ImageUrl:    row.ImageUrl,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			ReviewCount: row.ReviewCount,
+			AvgRating:   row.AvgRating,
+			ProducerID:  row.ProducerID,
+			Style:       row.Style,
+		})
+		sightings[row.ID] = &venueSighting{
+			sightings:  int(row.Sightings),
+			lastSeen:   row.LastSeenAt.Time,
+			priceCents: row.LastPriceCents,
+		}
+	}
+	return candidates, sightings, nil
+}
+
+// sightingRecency is 1 for a beverage seen today, halving every
+// venueSightingHalfLifeDays
+func sightingRecency(lastSeen, now time.Time) float64 {
+	ageDays := now.Sub(lastSeen).Hours() / 24
+	if ageDays <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, ageDays/venueSightingHalfLifeDays)
+}
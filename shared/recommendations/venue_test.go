@@ -0,0 +1,84 @@
+package recommendations
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRankAtVenueOnlyReturnsVenueBeverages(t *testing.T) {
+	store := newFakeStore()
+	user := testUUID(100)
+	bar := testUUID(500)
+	store.setProfile(user, "beer", TagWeights{"hoppy": 1.0}, TagWeights{}, 12)
+	store.addBeverage(testUUID(1), "Popular Lager", "beer", 9.0, 500, "crisp")
+	store.addBeverage(testUUID(2), "Tap IPA", "beer", 7.5, 20, "hoppy")
+	store.addBeverage(testUUID(3), "Old Bottle IPA", "beer", 7.5, 20, "hoppy")
+	store.addBeverage(testUUID(4), "House Stout", "beer", 7.0, 10, "roasty")
+	now := time.Now()
+	store.addSighting(bar, testUUID(2), 4, now.Add(-24*time.Hour), 750)
+	store.addSighting(bar, testUUID(3), 1, now.AddDate(0, -6, 0), 0)
+	store.addSighting(bar, testUUID(4), 2, now.AddDate(0, 0, -3), 800)
+
+	results, err := NewRanker(store).RankAtVenue(context.Background(), user, "beer", VenueRef{ID: bar}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lager is never seen at the bar; the recently seen IPA beats the
+	// identical one last seen six months ago
+	want := []string{"Tap IPA", "Old Bottle IPA", "House Stout"}
+	if got := names(results); !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+
+	tap := results[0]
+	if tap.LastSeenPriceCents == nil || *tap.LastSeenPriceCents != 750 || tap.VenueSightings != 4 {
+		t.Errorf("tap sighting = %+v", tap)
+	}
+	if tap.LastSeenAt == nil || !tap.LastSeenAt.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("last seen = %v", tap.LastSeenAt)
+	}
+	if results[1].LastSeenPriceCents != nil {
+		t.Errorf("price without a priced post = %d", *results[1].LastSeenPriceCents)
+	}
+	if store.calls["GetRecommendationCandidates"] != 0 {
+		t.Error("venue mode loaded global candidates")
+	}
+}
+
+func TestRankAtVenueColdStartWeighsRecency(t *testing.T) {
+	store := newFakeStore()
+	bar := testUUID(500)
+	store.addBeverage(testUUID(1), "Stale Favorite", "beer", 8.0, 100, "crisp")
+	store.addBeverage(testUUID(2), "New Arrival", "beer", 8.0, 90, "crisp")
+	store.addSighting(bar, testUUID(1), 10, time.Now().AddDate(-1, 0, 0), 600)
+	store.addSighting(bar, testUUID(2), 1, time.Now(), 700)
+
+	results, err := NewRanker(store).RankAtVenue(context.Background(), testUUID(100), "beer", VenueRef{ID: bar}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "New Arrival" {
+		t.Fatalf("results = %v", names(results))
+	}
+	if results[0].Reasons[len(results[0].Reasons)-1] != "Spotted here recently" {
+		t.Errorf("reasons = %v", results[0].Reasons)
+	}
+
+	empty, err := NewRanker(store).RankAtVenue(context.Background(), testUUID(100), "beer", VenueRef{ID: testUUID(501)}, 2)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("unknown venue returned %v, %v", names(empty), err)
+	}
+}
+
+func TestSightingRecency(t *testing.T) {
+	now := time.Now()
+	if got := sightingRecency(now, now); got != 1 {
+		t.Errorf("today = %f", got)
+	}
+	if got := sightingRecency(now.AddDate(0, 0, -30), now); math.Abs(got-0.5) > 0.01 {
+		t.Errorf("one half-life = %f", got)
+	}
+}
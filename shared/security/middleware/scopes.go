@@ -0,0 +1,32 @@
+package security
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/burkebarcode/backend/shared/security/keys"
+	"github.com/gin-gonic/gin"
+)
+
+// ScopeCatalogCurate grants access to the catalog curation endpoints
+const ScopeCatalogCurate = "catalog:curate"
+
+// RequireScope rejects requests whose token does not carry scope.
+// Must be mounted after JWTAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get("claims")
+		claims, ok := v.(*keys.Claims)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			return
+		}
+
+		if !slices.Contains(claims.Scopes, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+			return
+		}
+
+		c.Next()
+	}
+}